@@ -7,10 +7,8 @@ import (
 )
 
 func main() {
-	cfg := clipcat.ParseArgs()
-
-	if err := clipcat.Run(cfg); err != nil {
+	if err := clipcat.Execute(os.Args[1:]); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		os.Exit(clipcat.ExitCode(err))
 	}
 }
\ No newline at end of file
@@ -2,6 +2,15 @@ module clipcat
 
 go 1.24.0
 
-require github.com/sabhiram/go-gitignore v0.0.0-20210923224102-525f6e181f06
+require (
+	github.com/alecthomas/chroma/v2 v2.20.0
+	github.com/bmatcuk/doublestar/v4 v4.9.1
+	github.com/klauspost/compress v1.18.0
+	github.com/sabhiram/go-gitignore v0.0.0-20210923224102-525f6e181f06
+	golang.org/x/term v0.34.0
+)
 
-require github.com/bmatcuk/doublestar/v4 v4.9.1 // indirect
+require (
+	github.com/dlclark/regexp2 v1.11.5 // indirect
+	golang.org/x/sys v0.35.0 // indirect
+)
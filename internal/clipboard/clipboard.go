@@ -2,30 +2,266 @@ package clipboard
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
 )
 
+// DefaultLimits are the largest payloads, in bytes as PayloadSize counts
+// them, that each backend copies reliably. Above them clipcat writes a temp file and copies its
+// path instead; the [clipboard.limits] config table overrides them.
+var DefaultLimits = map[string]int64{
+	"xclip":                64 << 20, // clipboard managers stall on larger X11 selections
+	"xsel":                 64 << 20,
+	"wl-copy":              64 << 20,
+	"pbcopy":               256 << 20,
+	"clip.exe":             32 << 20, // fails with "exit status 1" on larger input
+	"termux-clipboard-set": 1 << 20,  // Android drops clips over its ~1MB binder transaction limit
+}
+
+// Backend returns the name of the command CopyToClipboard uses, e.g. "xclip".
+func Backend() (string, error) {
+	cmd, err := copyCommand(context.Background())
+	if err != nil {
+		return "", err
+	}
+	return filepath.Base(cmd.Args[0]), nil
+}
+
+// backends are the clipboard commands copyCommand looks for, in order.
+var backends = []struct {
+	name string
+	args []string
+}{
+	{"xclip", []string{"-selection", "clipboard"}}, // Linux X11
+	{"xsel", []string{"--clipboard", "--input"}},   // X11 without xclip
+	{"pbcopy", nil},               // macOS
+	{"clip.exe", nil},             // Windows
+	{"wl-copy", nil},              // Wayland
+	{"termux-clipboard-set", nil}, // Android (Termux:API)
+}
+
+func copyCommand(ctx context.Context) (*exec.Cmd, error) {
+	names := make([]string, len(backends))
+	for i, b := range backends {
+		if _, err := exec.LookPath(b.name); err == nil {
+			return exec.CommandContext(ctx, b.name, b.args...), nil
+		}
+		names[i] = b.name
+	}
+	return nil, fmt.Errorf("no clipboard command found (tried %s)", strings.Join(names, ", "))
+}
+
+// Clipboard commands fail now and then when another client grabs the
+// selection at the same moment (X11, Wayland), so a failed copy is retried.
+const (
+	copyAttempts = 3
+	retryDelay   = 100 * time.Millisecond
+)
+
+// CopyToClipboard copies data with the first clipboard command found. When
+// the command fails, the error includes what it printed on stderr.
 func CopyToClipboard(data []byte) error {
-	// Detect clipboard command based on platform
+	return CopyContext(context.Background(), data)
+}
+
+// CopyContext is CopyToClipboard with cancellation: the clipboard command
+// is killed, and no retry started, once ctx is done.
+func CopyContext(ctx context.Context, data []byte) error {
+	var err error
+	for attempt := 1; attempt <= copyAttempts; attempt++ {
+		if attempt > 1 {
+			select {
+			case <-time.After(time.Duration(attempt-1) * retryDelay):
+			case <-ctx.Done():
+				return fmt.Errorf("%w (after %d attempts)", ctx.Err(), attempt-1)
+			}
+		}
+		var cmd *exec.Cmd
+		if cmd, err = copyCommand(ctx); err != nil {
+			return err
+		}
+
+		var stderr bytes.Buffer
+		cmd.Stdin = bytes.NewReader(data)
+		if isClipExe(cmd.Args[0]) {
+			cmd.Stdin = bytes.NewReader(UTF16(data))
+		}
+		cmd.Stderr = &stderr
+		// A killed command's children may hold stderr open
+		cmd.WaitDelay = time.Second
+		runErr := cmd.Run()
+		if runErr == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return fmt.Errorf("%s: %w", cmd.Args[0], ctx.Err())
+		}
+		var exitErr *exec.ExitError
+		if !errors.As(runErr, &exitErr) {
+			// The command could not be started; trying again won't help
+			return fmt.Errorf("%s: %w", cmd.Args[0], runErr)
+		}
+		// A bare "exit status 1" says nothing, so pass on what the command printed
+		err = fmt.Errorf("%s: %v", cmd.Args[0], runErr)
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = fmt.Errorf("%s: %v: %s", cmd.Args[0], runErr, msg)
+		}
+	}
+	return fmt.Errorf("%w (after %d attempts)", err, copyAttempts)
+}
+
+// Writer streams into a clipboard command, or to the terminal for OSC52.
+// Unlike CopyContext it cannot retry, since the data is gone once written.
+type Writer struct {
+	cmd    *exec.Cmd // nil for OSC52
+	stdin  io.WriteCloser
+	utf16  *utf16Writer // converts the input for clip.exe
+	stderr bytes.Buffer
+}
+
+// Open starts command, split into words like "xclip -selection primary",
+// or the first clipboard command found when command is empty, and returns
+// a Writer for its input. OSC52 copies through the terminal instead.
+func Open(ctx context.Context, command string) (*Writer, error) {
+	if command == OSC52 {
+		tty, err := openOSC52()
+		if err != nil {
+			return nil, err
+		}
+		return &Writer{stdin: tty}, nil
+	}
+	var cmd *exec.Cmd
+	if args := strings.Fields(command); len(args) > 0 {
+		cmd = exec.CommandContext(ctx, args[0], args[1:]...)
+	} else {
+		var err error
+		if cmd, err = copyCommand(ctx); err != nil {
+			return nil, err
+		}
+	}
+	w := &Writer{cmd: cmd}
+	cmd.Stderr = &w.stderr
+	cmd.WaitDelay = time.Second
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("%s: %w", cmd.Args[0], err)
+	}
+	w.stdin = stdin
+	if isClipExe(cmd.Args[0]) {
+		w.utf16 = &utf16Writer{w: stdin}
+	}
+	return w, nil
+}
+
+// Name is the command's name, e.g. "wl-copy", or OSC52.
+func (w *Writer) Name() string {
+	if w.cmd == nil {
+		return OSC52
+	}
+	return filepath.Base(w.cmd.Args[0])
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	if w.utf16 != nil {
+		return w.utf16.Write(p)
+	}
+	return w.stdin.Write(p)
+}
+
+// Close ends the input and waits for the command. Its error includes what
+// the command printed on stderr.
+func (w *Writer) Close() error {
+	if w.utf16 != nil {
+		w.utf16.Flush()
+	}
+	if w.cmd == nil {
+		return w.stdin.Close()
+	}
+	w.stdin.Close()
+	if err := w.cmd.Wait(); err != nil {
+		if msg := strings.TrimSpace(w.stderr.String()); msg != "" {
+			return fmt.Errorf("%s: %v: %s", w.cmd.Args[0], err, msg)
+		}
+		return fmt.Errorf("%s: %w", w.cmd.Args[0], err)
+	}
+	return nil
+}
+
+func ReadFromClipboard() ([]byte, error) {
+	// Mirror the detection order used for copying
 	var cmd *exec.Cmd
 
-	// Try xclip (Linux X11)
 	if _, err := exec.LookPath("xclip"); err == nil {
-		cmd = exec.Command("xclip", "-selection", "clipboard")
-	} else if _, err := exec.LookPath("pbcopy"); err == nil {
+		cmd = exec.Command("xclip", "-selection", "clipboard", "-o")
+	} else if _, err := exec.LookPath("xsel"); err == nil {
+		cmd = exec.Command("xsel", "--clipboard", "--output")
+	} else if _, err := exec.LookPath("pbpaste"); err == nil {
 		// macOS
-		cmd = exec.Command("pbcopy")
-	} else if _, err := exec.LookPath("clip.exe"); err == nil {
-		// Windows
-		cmd = exec.Command("clip.exe")
-	} else if _, err := exec.LookPath("wl-copy"); err == nil {
+		cmd = exec.Command("pbpaste")
+	} else if _, err := exec.LookPath("powershell.exe"); err == nil {
+		// Windows; PowerShell prints in the console's code page unless told otherwise
+		cmd = exec.Command("powershell.exe", "-NoProfile", "-Command", "[Console]::OutputEncoding = [Text.Encoding]::UTF8; Get-Clipboard -Raw")
+	} else if _, err := exec.LookPath("wl-paste"); err == nil {
 		// Wayland
-		cmd = exec.Command("wl-copy")
+		cmd = exec.Command("wl-paste", "--no-newline")
+	} else if _, err := exec.LookPath("termux-clipboard-get"); err == nil {
+		// Android (Termux:API)
+		cmd = exec.Command("termux-clipboard-get")
 	} else {
-		return fmt.Errorf("no clipboard command found (tried xclip, wl-copy, pbcopy, clip.exe)")
+		return nil, fmt.Errorf("no clipboard command found (tried xclip, xsel, wl-paste, pbpaste, powershell.exe, termux-clipboard-get)")
 	}
 
+	return cmd.Output()
+}
+
+// holdFlags keep each clipboard command that owns the selection itself in
+// the foreground. The others, e.g. pbcopy and clip.exe, hand the copy to a
+// system clipboard that keeps it.
+var holdFlags = map[string]string{
+	"wl-copy": "--foreground",
+	"xclip":   "-quiet",
+	"xsel":    "--nodetach",
+}
+
+// CanHold reports whether Hold keeps backend's selection alive; for the
+// other backends there is nothing to hold.
+func CanHold(backend string) bool {
+	_, ok := holdFlags[backend]
+	return ok
+}
+
+// Hold copies data with the clipboard command kept in the foreground, so
+// the selection lives as long as the command does on Wayland and X11
+// setups where it dies with its source. It returns once another copy
+// replaces the selection, or when ctx is done, which ends the selection.
+func Hold(ctx context.Context, data []byte) error {
+	cmd, err := copyCommand(ctx)
+	if err != nil {
+		return err
+	}
+	flag, ok := holdFlags[filepath.Base(cmd.Args[0])]
+	if !ok {
+		return fmt.Errorf("%s keeps copies without a running process; nothing to hold", cmd.Args[0])
+	}
+	cmd.Args = append(cmd.Args, flag)
+	var stderr bytes.Buffer
 	cmd.Stdin = bytes.NewReader(data)
-	return cmd.Run()
-}
\ No newline at end of file
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = time.Second
+	if err := cmd.Run(); err != nil && ctx.Err() == nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%s: %v: %s", cmd.Args[0], err, msg)
+		}
+		return fmt.Errorf("%s: %w", cmd.Args[0], err)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package clipboard
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// OSC52 is the --clipboard name of the terminal's own clipboard: the copy
+// goes to the terminal as an OSC 52 escape sequence, which reaches the
+// desktop over SSH and from containers where no clipboard command can.
+const OSC52 = "osc52"
+
+// osc52Writer encodes what is written as the payload of one sequence.
+type osc52Writer struct {
+	w    io.Writer
+	enc  io.WriteCloser // base64 into w
+	end  string
+	err  error
+	done bool
+}
+
+// NewOSC52 returns a writer that copies to the clipboard of the terminal
+// w is attached to. Inside tmux (tmux true) the sequence is wrapped so
+// tmux passes it on. Close ends the sequence; w is left open.
+func NewOSC52(w io.Writer, tmux bool) io.WriteCloser {
+	start, end := "\x1b]52;c;", "\a"
+	if tmux {
+		start, end = "\x1bPtmux;\x1b"+start, end+"\x1b\\"
+	}
+	o := &osc52Writer{w: w, end: end}
+	_, o.err = io.WriteString(w, start)
+	o.enc = base64.NewEncoder(base64.StdEncoding, w)
+	return o
+}
+
+func (o *osc52Writer) Write(p []byte) (int, error) {
+	if o.err != nil {
+		return 0, o.err
+	}
+	n, err := o.enc.Write(p)
+	o.err = err
+	return n, err
+}
+
+func (o *osc52Writer) Close() error {
+	if o.done {
+		return o.err
+	}
+	o.done = true
+	if o.err == nil {
+		o.err = o.enc.Close()
+	}
+	if o.err == nil {
+		_, o.err = io.WriteString(o.w, o.end)
+	}
+	return o.err
+}
+
+// openOSC52 copies to the controlling terminal's clipboard.
+func openOSC52() (io.WriteCloser, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0)
+	if err != nil {
+		return nil, fmt.Errorf("%s: no terminal to copy through: %w", OSC52, err)
+	}
+	o := NewOSC52(tty, os.Getenv("TMUX") != "")
+	return closeBoth{o, tty}, nil
+}
+
+// closeBoth closes the sequence, then the terminal it went to.
+type closeBoth struct {
+	io.WriteCloser
+	tty io.Closer
+}
+
+func (c closeBoth) Close() error {
+	return errors.Join(c.WriteCloser.Close(), c.tty.Close())
+}
\ No newline at end of file
@@ -0,0 +1,104 @@
+package clipboard
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// ErrRichUnsupported is returned by CopyRich when the clipboard backend can
+// only hold one flavor, e.g. xclip and wl-copy.
+var ErrRichUnsupported = errors.New("this clipboard backend can't hold plain text and HTML at once")
+
+// CopyRich puts both a plain-text and an HTML flavor on the clipboard, so
+// rich editors paste the HTML and everything else pastes the text.
+// The command is killed once ctx is done.
+func CopyRich(ctx context.Context, plain, html []byte) error {
+	switch runtime.GOOS {
+	case "darwin":
+		if _, err := exec.LookPath("osascript"); err == nil {
+			return copyRichMac(ctx, plain, html)
+		}
+	case "windows":
+		if _, err := exec.LookPath("powershell.exe"); err == nil {
+			return copyRichWindows(ctx, plain, html)
+		}
+	}
+	return ErrRichUnsupported
+}
+
+// copyRichMac sets both flavors in one AppleScript record; the data is hex
+// encoded so no quoting is needed, and the script goes through stdin to
+// stay clear of argument length limits.
+func copyRichMac(ctx context.Context, plain, html []byte) error {
+	script := fmt.Sprintf("set the clipboard to {«class HTML»:«data HTML%s», «class utf8»:«data utf8%s»}\n",
+		strings.ToUpper(hex.EncodeToString(html)), strings.ToUpper(hex.EncodeToString(plain)))
+	cmd := exec.CommandContext(ctx, "osascript", "-")
+	cmd.Stdin = strings.NewReader(script)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("osascript: %v: %s", err, bytes.TrimSpace(out))
+	}
+	return nil
+}
+
+// copyRichWindows hands the text and the CF_HTML payload to PowerShell via
+// temporary files and sets them as one DataObject.
+func copyRichWindows(ctx context.Context, plain, html []byte) error {
+	dir, err := os.MkdirTemp("", "clipcat-rich")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	textPath, htmlPath := filepath.Join(dir, "text.txt"), filepath.Join(dir, "html.txt")
+	if err := os.WriteFile(textPath, plain, 0600); err != nil {
+		return err
+	}
+	if err := os.WriteFile(htmlPath, CFHTML(html), 0600); err != nil {
+		return err
+	}
+
+	script := fmt.Sprintf(`Add-Type -AssemblyName System.Windows.Forms
+$d = New-Object System.Windows.Forms.DataObject
+$d.SetText([IO.File]::ReadAllText('%s', [Text.Encoding]::UTF8))
+$d.SetData([System.Windows.Forms.DataFormats]::Html, [IO.File]::ReadAllText('%s', [Text.Encoding]::UTF8))
+[System.Windows.Forms.Clipboard]::SetDataObject($d, $true)`, psQuote(textPath), psQuote(htmlPath))
+	cmd := exec.CommandContext(ctx, "powershell.exe", "-NoProfile", "-STA", "-Command", script)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("powershell: %v: %s", err, bytes.TrimSpace(out))
+	}
+	return nil
+}
+
+// psQuote escapes s for a single-quoted PowerShell string.
+func psQuote(s string) string {
+	return strings.ReplaceAll(s, "'", "''")
+}
+
+// CFHTML wraps an HTML fragment in the Windows clipboard HTML format, whose
+// header gives the byte offsets of the document and the fragment.
+func CFHTML(fragment []byte) []byte {
+	const header = "Version:0.9\r\nStartHTML:%010d\r\nEndHTML:%010d\r\nStartFragment:%010d\r\nEndFragment:%010d\r\n"
+	const prefix = "<html><body>\r\n<!--StartFragment-->"
+	const suffix = "<!--EndFragment-->\r\n</body></html>"
+
+	headerLen := len(fmt.Sprintf(header, 0, 0, 0, 0))
+	startHTML := headerLen
+	startFragment := startHTML + len(prefix)
+	endFragment := startFragment + len(fragment)
+	endHTML := endFragment + len(suffix)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, header, startHTML, endHTML, startFragment, endFragment)
+	buf.WriteString(prefix)
+	buf.Write(fragment)
+	buf.WriteString(suffix)
+	return buf.Bytes()
+}
\ No newline at end of file
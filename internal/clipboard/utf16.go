@@ -0,0 +1,111 @@
+package clipboard
+
+import (
+	"bytes"
+	"io"
+	"path/filepath"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// clip.exe reads its input in the console's code page unless it starts
+// with a UTF-16 byte order mark, so UTF-8 emoji and CJK text arrive as
+// mojibake. Its input is converted to UTF-16LE with a BOM, and lone LFs to
+// the CRLFs Windows applications expect when pasting.
+
+// isClipExe reports whether the command named name is Windows' clip.exe,
+// also when run from WSL.
+func isClipExe(name string) bool {
+	return strings.TrimSuffix(strings.ToLower(filepath.Base(name)), ".exe") == "clip"
+}
+
+// UTF16 returns data, UTF-8 text, as clip.exe takes it: UTF-16LE with a
+// byte order mark and CRLF line endings. Invalid UTF-8 becomes U+FFFD.
+func UTF16(data []byte) []byte {
+	var buf bytes.Buffer
+	buf.Grow(2*len(data) + 2)
+	e := &utf16Writer{w: &buf}
+	e.Write(data)
+	e.Flush()
+	return buf.Bytes()
+}
+
+// PayloadSize is the number of bytes the backend receives for data: its
+// UTF16 size for clip.exe, len(data) for the others.
+func PayloadSize(backend string, data []byte) int64 {
+	if !isClipExe(backend) {
+		return int64(len(data))
+	}
+	size, cr := int64(2), false
+	for len(data) > 0 {
+		r, n := utf8.DecodeRune(data)
+		if r == '\n' && !cr {
+			size += 2
+		}
+		cr = r == '\r'
+		size += 2 * int64(utf16.RuneLen(r))
+		data = data[n:]
+	}
+	return size
+}
+
+// utf16Writer converts what is written to it as UTF16 does, keeping a
+// character or CRLF split across writes whole.
+type utf16Writer struct {
+	w       io.Writer
+	pending []byte // the start of a character cut off by the last write
+	bom, cr bool
+}
+
+func (e *utf16Writer) Write(p []byte) (int, error) {
+	buf := make([]byte, 0, 2*len(p)+4)
+	if !e.bom {
+		buf, e.bom = append(buf, 0xFF, 0xFE), true
+	}
+	data := p
+	if len(e.pending) > 0 {
+		data, e.pending = append(e.pending, p...), nil
+	}
+	for len(data) > 0 {
+		if !utf8.FullRune(data) {
+			e.pending = bytes.Clone(data)
+			break
+		}
+		r, n := utf8.DecodeRune(data)
+		buf = e.appendRune(buf, r)
+		data = data[n:]
+	}
+	if _, err := e.w.Write(buf); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Flush writes the BOM if nothing was written yet, and U+FFFD for a
+// character left incomplete at the end.
+func (e *utf16Writer) Flush() error {
+	var buf []byte
+	if !e.bom {
+		buf, e.bom = append(buf, 0xFF, 0xFE), true
+	}
+	if len(e.pending) > 0 {
+		buf, e.pending = e.appendRune(buf, utf8.RuneError), nil
+	}
+	if len(buf) == 0 {
+		return nil
+	}
+	_, err := e.w.Write(buf)
+	return err
+}
+
+func (e *utf16Writer) appendRune(buf []byte, r rune) []byte {
+	if r == '\n' && !e.cr {
+		buf = append(buf, '\r', 0)
+	}
+	e.cr = r == '\r'
+	for _, u := range utf16.AppendRune(nil, r) {
+		buf = append(buf, byte(u), byte(u>>8))
+	}
+	return buf
+}
\ No newline at end of file
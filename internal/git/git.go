@@ -0,0 +1,159 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// run executes git in dir and returns its stdout. Failures carry git's own
+// error message rather than a bare exit status.
+func run(dir string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return nil, fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return out, nil
+}
+
+// splitNUL splits -z output into its non-empty entries.
+func splitNUL(out []byte) []string {
+	var entries []string
+	for _, entry := range strings.Split(string(out), "\x00") {
+		if entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// LsFiles lists the tracked files under dir, including those inside
+// submodules, as paths relative to dir.
+func LsFiles(dir string) ([]string, error) {
+	out, err := run(dir, "ls-files", "-z", "--recurse-submodules")
+	if err != nil {
+		return nil, err
+	}
+	return splitNUL(out), nil
+}
+
+// Root returns the top-level directory of the repository containing dir.
+func Root(dir string) (string, error) {
+	out, err := run(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// MergeBase returns the commit where ref and HEAD diverged, so "changed since
+// main" means this branch's changes rather than everything main gained since.
+// Refs without a common ancestor fall back to ref itself.
+func MergeBase(dir, ref string) string {
+	out, err := run(dir, "merge-base", ref, "HEAD")
+	if err != nil {
+		return ref
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// ChangedFiles lists files (relative to the repository root) that differ
+// between the working tree and ref, including untracked files.
+func ChangedFiles(dir, ref string) ([]string, error) {
+	out, err := run(dir, "diff", "--name-only", "-z", MergeBase(dir, ref), "--")
+	if err != nil {
+		return nil, err
+	}
+	untracked, err := UntrackedFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	return append(splitNUL(out), untracked...), nil
+}
+
+// StagedFiles lists files (relative to the repository root) with changes in
+// the index.
+func StagedFiles(dir string) ([]string, error) {
+	out, err := run(dir, "diff", "--name-only", "-z", "--cached")
+	if err != nil {
+		return nil, err
+	}
+	return splitNUL(out), nil
+}
+
+// UnstagedFiles lists files (relative to the repository root) with working
+// tree changes not yet in the index, including untracked files.
+func UnstagedFiles(dir string) ([]string, error) {
+	out, err := run(dir, "diff", "--name-only", "-z")
+	if err != nil {
+		return nil, err
+	}
+	untracked, err := UntrackedFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	return append(splitNUL(out), untracked...), nil
+}
+
+// UntrackedFiles lists untracked, non-ignored files relative to the
+// repository root.
+func UntrackedFiles(dir string) ([]string, error) {
+	out, err := run(dir, "ls-files", "-z", "--others", "--exclude-standard", "--full-name", ":/")
+	if err != nil {
+		return nil, err
+	}
+	return splitNUL(out), nil
+}
+
+// Diff returns the unified diff of path between base and the working tree,
+// or nil when the file is unchanged or untracked.
+func Diff(dir, base, path string) ([]byte, error) {
+	return run(dir, "diff", base, "--", path)
+}
+
+// Commit describes the last commit that touched a file.
+type Commit struct {
+	Hash   string
+	Author string
+	Date   string
+}
+
+// LastCommit returns the most recent commit touching path, or nil when the
+// file has no history (untracked or outside a repository).
+func LastCommit(dir, path string) (*Commit, error) {
+	out, err := run(dir, "log", "-1", "--format=%h%x00%an%x00%as", "--", path)
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Split(strings.TrimSpace(string(out)), "\x00")
+	if len(fields) != 3 {
+		return nil, nil
+	}
+	return &Commit{Hash: fields[0], Author: fields[1], Date: fields[2]}, nil
+}
+
+
+// Head describes HEAD of the repository containing dir: the branch ("HEAD"
+// when detached), the short commit hash, and whether the working tree has
+// uncommitted changes.
+func Head(dir string) (branch, commit string, dirty bool, err error) {
+	out, err := run(dir, "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return "", "", false, err
+	}
+	branch = strings.TrimSpace(string(out))
+	if out, err = run(dir, "rev-parse", "--short", "HEAD"); err != nil {
+		return "", "", false, err
+	}
+	commit = strings.TrimSpace(string(out))
+	out, err = run(dir, "status", "--porcelain")
+	return branch, commit, err == nil && len(out) > 0, nil
+}
\ No newline at end of file
@@ -0,0 +1,154 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Providers lists the API dialects accepted by Endpoint.Provider. "openai"
+// also covers compatible servers such as Ollama, vLLM and OpenRouter.
+var Providers = []string{"anthropic", "openai"}
+
+// Defaults for each provider when the config leaves them out.
+var (
+	DefaultURLs = map[string]string{
+		"anthropic": "https://api.anthropic.com/v1/messages",
+		"openai":    "https://api.openai.com/v1/chat/completions",
+	}
+	DefaultModels = map[string]string{
+		"anthropic": "claude-3-5-sonnet-latest",
+		"openai":    "gpt-4o",
+	}
+)
+
+// Endpoint is a chat completion API to send questions to.
+type Endpoint struct {
+	Provider  string
+	URL       string
+	Model     string
+	APIKey    string // may be empty for local servers
+	MaxTokens int    // answer length cap; 0 means 4096
+}
+
+// Stream sends prompt as a single user message and copies the answer to w
+// as it arrives.
+func Stream(ctx context.Context, ep Endpoint, prompt string, w io.Writer) error {
+	maxTokens := ep.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = 4096
+	}
+	payload, err := json.Marshal(map[string]any{
+		"model":      ep.Model,
+		"max_tokens": maxTokens,
+		"stream":     true,
+		"messages":   []map[string]string{{"role": "user", "content": prompt}},
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ep.URL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("User-Agent", "clipcat")
+	switch ep.Provider {
+	case "anthropic":
+		req.Header.Set("anthropic-version", "2023-06-01")
+		if ep.APIKey != "" {
+			req.Header.Set("x-api-key", ep.APIKey)
+		}
+	case "openai":
+		if ep.APIKey != "" {
+			req.Header.Set("Authorization", "Bearer "+ep.APIKey)
+		}
+	default:
+		return fmt.Errorf("unknown provider %q (expected %s)", ep.Provider, strings.Join(Providers, " or "))
+	}
+
+	// Answers can take minutes to stream, so only ctx bounds the request
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		return fmt.Errorf("%s: %s: %s", req.URL.Host, resp.Status, errorMessage(body))
+	}
+	return readEvents(resp.Body, w)
+}
+
+// event covers the fields clipcat needs from both providers' stream events.
+type event struct {
+	Type  string `json:"type"`
+	Delta struct {
+		Text string `json:"text"`
+	} `json:"delta"`
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+	} `json:"choices"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// readEvents copies the text deltas of a server-sent event stream to w.
+func readEvents(r io.Reader, w io.Writer) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64<<10), 1<<20)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			return nil
+		}
+
+		var ev event
+		if err := json.Unmarshal([]byte(data), &ev); err != nil {
+			continue
+		}
+		if ev.Error != nil {
+			return fmt.Errorf("stream error: %s", ev.Error.Message)
+		}
+		text := ev.Delta.Text
+		for _, choice := range ev.Choices {
+			text += choice.Delta.Content
+		}
+		if _, err := io.WriteString(w, text); err != nil {
+			return err
+		}
+		if ev.Type == "message_stop" {
+			return nil
+		}
+	}
+	return scanner.Err()
+}
+
+// errorMessage extracts the message from a JSON error body, which both
+// providers wrap as {"error": {"message": ...}}.
+func errorMessage(body []byte) string {
+	var resp struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(body, &resp) == nil && resp.Error.Message != "" {
+		return resp.Error.Message
+	}
+	return strings.TrimSpace(string(body))
+}
\ No newline at end of file
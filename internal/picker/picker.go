@@ -0,0 +1,323 @@
+// Package picker is the fuzzy finder of `clipcat pick`: type to narrow a
+// list down, Tab to select several entries, Enter to accept.
+package picker
+
+import (
+	"bufio"
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+// ErrCanceled is returned when the user leaves with Esc or Ctrl-C, or
+// accepts an empty list.
+var ErrCanceled = errors.New("nothing selected")
+
+// Match reports whether the characters of query appear in item in order,
+// and scores how well: runs of consecutive characters, characters at the
+// start of a path segment or word, and query found whole in the file name
+// score higher. Matching ignores case unless query has an upper-case letter.
+func Match(query, item string) (int, bool) {
+	if query == "" {
+		return 0, true
+	}
+	q, s := []rune(query), []rune(item)
+	if !slices.ContainsFunc(q, unicode.IsUpper) {
+		q, s = []rune(strings.ToLower(query)), []rune(strings.ToLower(item))
+	}
+	orig := []rune(item)
+
+	// Try each place the first character occurs and keep the best
+	best, found := 0, false
+	for start := range s {
+		if s[start] != q[0] {
+			continue
+		}
+		score, qi, last := 0, 0, -1
+		for i := start; i < len(s) && qi < len(q); i++ {
+			if s[i] != q[qi] {
+				continue
+			}
+			score++
+			switch {
+			case last == i-1:
+				score += 4
+			case last >= 0:
+				score -= min(i-last-1, 3)
+			}
+			if boundary(orig, i) {
+				score += 3
+			}
+			last = i
+			qi++
+		}
+		if qi == len(q) && (!found || score > best) {
+			best, found = score, true
+		}
+	}
+	if !found {
+		return 0, false
+	}
+	name := string(s)
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	if strings.Contains(name, string(q)) {
+		best += 10
+	} else if strings.Contains(string(s), string(q)) {
+		best += 5
+	}
+	return best, true
+}
+
+// boundary reports whether s[i] starts a path segment or a word.
+func boundary(s []rune, i int) bool {
+	if i == 0 {
+		return true
+	}
+	prev := s[i-1]
+	return strings.ContainsRune("/\\_-. ", prev) || (unicode.IsLower(prev) && unicode.IsUpper(s[i]))
+}
+
+// Filter returns the items matching query, best first; items that score
+// the same keep their order, shorter ones first.
+func Filter(query string, items []string) []string {
+	type scored struct {
+		item  string
+		score int
+	}
+	var matches []scored
+	for _, item := range items {
+		if score, ok := Match(query, item); ok {
+			matches = append(matches, scored{item, score})
+		}
+	}
+	if query != "" {
+		slices.SortStableFunc(matches, func(a, b scored) int {
+			return cmp.Or(cmp.Compare(b.score, a.score), cmp.Compare(len(a.item), len(b.item)))
+		})
+	}
+	out := make([]string, len(matches))
+	for i, m := range matches {
+		out[i] = m.item
+	}
+	return out
+}
+
+// RunTerminal runs the picker on the controlling terminal, so it works
+// while standard input and output are redirected.
+func RunTerminal(items []string) ([]string, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return nil, errors.New("pick needs a terminal")
+	}
+	defer tty.Close()
+	state, err := term.MakeRaw(int(tty.Fd()))
+	if err != nil {
+		return nil, fmt.Errorf("pick: %w", err)
+	}
+	defer term.Restore(int(tty.Fd()), state)
+	width, height := 0, 20
+	if cols, rows, err := term.GetSize(int(tty.Fd())); err == nil {
+		width, height = cols, max(rows/2, 3)
+	}
+	return Run(tty, tty, items, width, height)
+}
+
+// Run is the picker reading keys from in and drawing on out, showing at
+// most height entries at a time in lines cut to width (0 for no limit),
+// since a wrapped line would throw the redrawing off. It returns the
+// selected items in their order in items, or the one under the cursor when
+// none is selected.
+//
+// Keys: type to filter, Backspace and Ctrl-U to edit, Up/Down (Ctrl-P and
+// Ctrl-N) to move, Tab and Shift-Tab to select, Ctrl-A to select every
+// match, Enter to accept, Esc or Ctrl-C to cancel.
+func Run(in io.Reader, out io.Writer, items []string, width, height int) ([]string, error) {
+	p := &state{items: items, matches: items, selected: map[string]bool{}, width: width, height: height, out: out}
+	defer p.clear()
+	r := bufio.NewReader(in)
+	for {
+		p.draw()
+		c, err := r.ReadByte()
+		if err != nil {
+			return nil, ErrCanceled
+		}
+		switch c {
+		case 3, 7: // Ctrl-C, Ctrl-G
+			return nil, ErrCanceled
+		case 27: // Esc, alone or starting a sequence
+			if r.Buffered() == 0 {
+				return nil, ErrCanceled
+			}
+			next, _ := r.ReadByte()
+			if next != '[' && next != 'O' {
+				return nil, ErrCanceled
+			}
+			switch key, _ := r.ReadByte(); key {
+			case 'A':
+				p.move(-1)
+			case 'B':
+				p.move(1)
+			case 'Z':
+				p.toggle()
+				p.move(-1)
+			}
+		case '\r', '\n':
+			return p.accept()
+		case '\t':
+			p.toggle()
+			p.move(1)
+		case 1: // Ctrl-A
+			p.toggleAll()
+		case 14: // Ctrl-N
+			p.move(1)
+		case 16: // Ctrl-P
+			p.move(-1)
+		case 21: // Ctrl-U
+			p.setQuery("")
+		case 127, 8: // Backspace
+			if q := []rune(p.query); len(q) > 0 {
+				p.setQuery(string(q[:len(q)-1]))
+			}
+		default:
+			if c < 32 {
+				continue
+			}
+			// Gather the rest of a multi-byte character
+			buf := []byte{c}
+			for !utf8.FullRune(buf) {
+				b, err := r.ReadByte()
+				if err != nil {
+					break
+				}
+				buf = append(buf, b)
+			}
+			p.setQuery(p.query + string(buf))
+		}
+	}
+}
+
+type state struct {
+	items, matches []string
+	query          string
+	cursor, offset int
+	selected       map[string]bool
+	width, height  int
+	out            io.Writer
+	drawn          int // lines below the prompt on screen
+}
+
+func (p *state) setQuery(query string) {
+	p.query = query
+	p.matches = Filter(query, p.items)
+	p.cursor, p.offset = 0, 0
+}
+
+func (p *state) move(delta int) {
+	if len(p.matches) == 0 {
+		return
+	}
+	p.cursor = min(max(p.cursor+delta, 0), len(p.matches)-1)
+	if p.cursor < p.offset {
+		p.offset = p.cursor
+	} else if p.cursor >= p.offset+p.height {
+		p.offset = p.cursor - p.height + 1
+	}
+}
+
+func (p *state) toggle() {
+	if len(p.matches) > 0 {
+		item := p.matches[p.cursor]
+		p.selected[item] = !p.selected[item]
+	}
+}
+
+func (p *state) toggleAll() {
+	all := !slices.ContainsFunc(p.matches, func(item string) bool { return !p.selected[item] })
+	for _, item := range p.matches {
+		p.selected[item] = !all
+	}
+}
+
+func (p *state) accept() ([]string, error) {
+	var picked []string
+	for _, item := range p.items {
+		if p.selected[item] {
+			picked = append(picked, item)
+		}
+	}
+	if len(picked) == 0 && len(p.matches) > 0 {
+		picked = []string{p.matches[p.cursor]}
+	}
+	if len(picked) == 0 {
+		return nil, ErrCanceled
+	}
+	return picked, nil
+}
+
+// draw repaints the prompt, a status line and the visible matches below
+// where the cursor was, and puts the cursor back after the query.
+func (p *state) draw() {
+	var b strings.Builder
+	b.WriteString("\r\x1b[J> " + p.query)
+	count := 0
+	for item := range p.selected {
+		if p.selected[item] {
+			count++
+		}
+	}
+	fmt.Fprintf(&b, "\r\n  %d/%d", len(p.matches), len(p.items))
+	if count > 0 {
+		fmt.Fprintf(&b, " (%d selected)", count)
+	}
+	b.WriteString(p.fit("  Tab: select  Enter: copy  Esc: cancel", false))
+	end := min(p.offset+p.height, len(p.matches))
+	for i := p.offset; i < end; i++ {
+		cursor, mark := ' ', ' '
+		if i == p.cursor {
+			cursor = '>'
+		}
+		if p.selected[p.matches[i]] {
+			mark = '*'
+		}
+		line := fmt.Sprintf("%c%c %s", cursor, mark, p.fit(p.matches[i], true))
+		if i == p.cursor {
+			line = "\x1b[1m" + line + "\x1b[0m"
+		}
+		b.WriteString("\r\n" + line)
+	}
+	p.drawn = 1 + end - p.offset
+	fmt.Fprintf(&b, "\x1b[%dA\r\x1b[%dC", p.drawn, 2+len([]rune(p.query)))
+	io.WriteString(p.out, b.String())
+}
+
+// fit cuts s to the width left on the line, keeping its end for paths,
+// where the file name is, and its start otherwise.
+func (p *state) fit(s string, path bool) string {
+	room := p.width - 4
+	r := []rune(s)
+	if p.width <= 0 || len(r) <= room {
+		return s
+	}
+	if room < 1 {
+		return ""
+	}
+	if path {
+		return "…" + string(r[len(r)-room+1:])
+	}
+	return string(r[:room-1]) + "…"
+}
+
+// clear erases the picker from the screen.
+func (p *state) clear() {
+	io.WriteString(p.out, "\r\x1b[J")
+}
\ No newline at end of file
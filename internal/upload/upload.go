@@ -0,0 +1,164 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Targets lists the named paste services accepted by --upload; any http(s)
+// URL is also accepted and receives the bundle as a raw POST body.
+var Targets = []string{"gist", "paste.rs", "0x0.st"}
+
+var client = &http.Client{Timeout: 60 * time.Second}
+
+// Binary reports whether target keeps binary data intact; gists and
+// paste.rs hold text only.
+func Binary(target string) bool {
+	return target != "gist" && target != "paste.rs"
+}
+
+// compression returns the content type and file extension of a --compress
+// upload, or "" for the others.
+func compression(data []byte) (contentType, ext string) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0x1f, 0x8b}):
+		return "application/gzip", ".gz"
+	case bytes.HasPrefix(data, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		return "application/zstd", ".zst"
+	}
+	return "", ""
+}
+
+// Upload sends data to target and returns the URL where it can be viewed.
+// The request is abandoned once ctx is done.
+func Upload(ctx context.Context, target string, data []byte) (string, error) {
+	switch target {
+	case "gist":
+		return uploadGist(ctx, data)
+	case "paste.rs":
+		return uploadRaw(ctx, "https://paste.rs/", data)
+	case "0x0.st":
+		return uploadForm(ctx, "https://0x0.st", data)
+	default:
+		if strings.HasPrefix(target, "https://") || strings.HasPrefix(target, "http://") {
+			return uploadRaw(ctx, target, data)
+		}
+		return "", fmt.Errorf("unknown upload target %q (expected %s, or a URL)", target, strings.Join(Targets, ", "))
+	}
+}
+
+// uploadRaw POSTs the bundle as the request body; paste services of this kind
+// answer with the paste URL as the response body (or a Location header).
+func uploadRaw(ctx context.Context, endpoint string, data []byte) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if contentType, _ := compression(data); contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	return doUpload(req)
+}
+
+// uploadForm POSTs the bundle as a multipart "file" field, as 0x0.st expects.
+func uploadForm(ctx context.Context, endpoint string, data []byte) (string, error) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	_, ext := compression(data)
+	name := "clipcat.txt" + ext
+	part, err := form.CreateFormFile("file", name)
+	if err != nil {
+		return "", err
+	}
+	part.Write(data)
+	form.Close()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, &body)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	return doUpload(req)
+}
+
+func doUpload(req *http.Request) (string, error) {
+	req.Header.Set("User-Agent", "clipcat")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", fmt.Errorf("upload to %s failed: %s: %s", req.URL.Host, resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	if loc := resp.Header.Get("Location"); loc != "" {
+		return loc, nil
+	}
+	url := strings.TrimSpace(string(body))
+	if i := strings.IndexByte(url, '\n'); i >= 0 {
+		url = strings.TrimSpace(url[:i])
+	}
+	if url == "" {
+		return "", fmt.Errorf("upload to %s returned no URL", req.URL.Host)
+	}
+	return url, nil
+}
+
+// uploadGist creates a secret gist using the token in GITHUB_TOKEN or GH_TOKEN.
+func uploadGist(ctx context.Context, data []byte) (string, error) {
+	token := os.Getenv("GITHUB_TOKEN")
+	if token == "" {
+		token = os.Getenv("GH_TOKEN")
+	}
+	if token == "" {
+		return "", fmt.Errorf("gist upload requires GITHUB_TOKEN or GH_TOKEN")
+	}
+
+	payload, err := json.Marshal(map[string]any{
+		"description": "clipcat bundle",
+		"public":      false,
+		"files": map[string]any{
+			"clipcat.txt": map[string]string{"content": string(data)},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://api.github.com/gists", bytes.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("User-Agent", "clipcat")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		HTMLURL string `json:"html_url"`
+		Message string `json:"message"`
+	}
+	json.NewDecoder(resp.Body).Decode(&result)
+	if resp.StatusCode != http.StatusCreated {
+		return "", fmt.Errorf("gist upload failed: %s: %s", resp.Status, result.Message)
+	}
+	return result.HTMLURL, nil
+}
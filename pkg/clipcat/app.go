@@ -1,71 +1,627 @@
 package clipcat
 
 import (
-	"bytes"
+	"bufio"
 	"clipcat/internal/clipboard"
-	"clipcat/pkg/collector"
-	"clipcat/pkg/exclude"
+	"clipcat/internal/upload"
+	"clipcat/pkg/crypto"
 	"clipcat/pkg/output"
+	"clipcat/pkg/remote"
+	"clipcat/pkg/tokens"
+	"clipcat/pkg/unpack"
+	"cmp"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
-	"sort"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
 )
 
 func Run(cfg *Config) error {
-	// Build exclude matcher
-	matcher, err := exclude.BuildMatcher(cfg.ExcludeFiles, cfg.Excludes, cfg.IgnoreCase)
-	if err != nil {
-		return fmt.Errorf("loading exclude patterns: %w", err)
+	if cfg.Report == "" {
+		return runWithin(cfg, nil)
+	}
+	report := newReport(cfg.Paths)
+	err := runWithin(cfg, report)
+	if werr := writeReport(cfg, report, err); werr != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not write the run report: %v\n", werr)
 	}
+	return err
+}
 
-	// Collect all files
-	files, err := collector.CollectFiles(cfg.Paths, matcher, cfg.IgnoreCase)
-	if err != nil {
-		return fmt.Errorf("collecting files: %w", err)
+// run is Run, recording into report when it is not nil and tracking its
+// progress for --timeout in p.
+func run(ctx context.Context, cfg *Config, report *Report, p *progress) (err error) {
+	paths := cfg.Paths
+	ghDir := "" // the `clipcat gh` checkout
+	label := func(path string) string { return path }
+	if cfg.Relative {
+		label = relativeLabel
+	}
+
+	if cfg.GitHub != "" {
+		spec, err := remote.ParseGitHubSpec(cfg.GitHub)
+		if err != nil {
+			return err
+		}
+
+		tmp, err := os.MkdirTemp("", "clipcat-gh-")
+		if err != nil {
+			return fmt.Errorf("creating checkout directory: %w", err)
+		}
+		defer os.RemoveAll(tmp)
+		// Named after the repository, which is what --tree shows for it
+		dir := filepath.Join(tmp, cmp.Or(spec.Repo, spec.Gist))
+
+		fmt.Fprintf(os.Stderr, "Fetching %s...\n", spec)
+		if err := remote.Clone(spec, dir); err != nil {
+			return err
+		}
+		// Nothing downstream needs the history, and it must not be collected
+		os.RemoveAll(filepath.Join(dir, ".git"))
+
+		// Collect inside the checkout: the paths, patterns and excludes given
+		// are relative to it, not to the working directory
+		label = func(path string) string {
+			rel, err := filepath.Rel(dir, path)
+			if err != nil || strings.HasPrefix(rel, "..") {
+				return path
+			}
+			if rel == "." {
+				return spec.String()
+			}
+			return spec.String() + "/" + filepath.ToSlash(rel)
+		}
+
+		if len(paths) == 0 {
+			paths = []string{cmp.Or(spec.Path, ".")}
+		}
+		paths = slices.Clone(paths)
+		for i, path := range paths {
+			if !filepath.IsAbs(path) && !remote.IsURL(path) {
+				paths[i] = filepath.Join(dir, path)
+			}
+		}
+		ghDir = dir
 	}
 
-	if len(files) == 0 {
-		return fmt.Errorf("no files matched after applying excludes")
+	options := []Option{WithLabel(label), WithWarnings(os.Stderr), withReport(report), withProgress(p), withDir(ghDir)}
+	if cfg.AppendFile && cfg.DedupeContent {
+		sums, err := previousSums(cfg.Output)
+		if err != nil {
+			return fmt.Errorf("reading --output: %w", err)
+		}
+		options = append(options, withSeen(sums))
+	}
+	if len(cfg.Expand) > 0 {
+		files, ids, err := expandIDs(cfg.Expand)
+		if err != nil {
+			return err
+		}
+		paths = append(files, paths...)
+		options = append(options, withFixedIDs(ids))
+	}
+
+	bundleCfg := *cfg
+	bundleCfg.Paths = paths
+	b := New(append([]Option{WithConfig(bundleCfg)}, options...)...)
+
+	if cfg.Pick {
+		picked, err := pickFiles(ctx, b)
+		if err != nil {
+			return err
+		}
+		// The picked files are the whole selection now, so the inputs that
+		// add files of their own have had their say
+		bundleCfg.Paths = picked
+		bundleCfg.GoDeps, bundleCfg.FollowImports = nil, nil
+		bundleCfg.FromQuickfix, bundleCfg.FromDiagnostics = nil, nil
+		bundleCfg.WithTests, bundleCfg.WithSources = false, false
+		bundleCfg.Git, bundleCfg.Preset = false, ""
+		b = New(append([]Option{WithConfig(bundleCfg)}, options...)...)
 	}
 
-	// Sort for consistent output
-	sort.Strings(files)
+	if len(cfg.Explain) > 0 {
+		opts, files, _, err := b.collect(ctx)
+		if err != nil {
+			return err
+		}
+		files, dropped := b.capFiles(files)
+		return explain(os.Stdout, cfg.Explain, opts, files, dropped)
+	}
 
-	// Build output
-	var outputBuf bytes.Buffer
+	if cfg.Stats {
+		return b.WriteStats(ctx, os.Stdout)
+	}
 
-	if cfg.ShowTree {
-		output.WriteHeader(&outputBuf, "FILE HIERARCHY")
-		output.WriteTree(&outputBuf, cfg.Paths, files)
-		outputBuf.WriteString("\n")
+	// --output and --clipboard get the bundle as it is rendered, unless
+	// something needs all of it before the copy
+	if streamSinks(cfg) {
+		start := time.Now()
+		defer report.phase("copy", start)
+		doc, err := copyToSinks(ctx, cfg, report, p, func(w io.Writer) (*document, error) {
+			return b.renderTo(ctx, w)
+		})
+		if doc == nil {
+			return err
+		}
+		saveDocIDs(cfg, doc)
+		if err == nil {
+			err = b.strictError(doc)
+		}
+		return err
+	}
+
+	doc, err := b.render(ctx)
+	if err != nil {
+		return err
+	}
+	// --strict fails once the output is out, so CI still gets the bundle
+	defer func() {
+		if err == nil {
+			err = b.strictError(doc)
+		}
+	}()
+	if cfg.DiffAgainst != "" {
+		data, err := readInput(cfg.DiffAgainst)
+		if err == nil {
+			data, err = unpack.Decompress(data)
+		}
+		if err != nil {
+			return fmt.Errorf("reading bundle: %w", err)
+		}
+		return writeBundleDiff(os.Stdout, unpack.Parse(data), unpack.Parse(doc.data), false)
 	}
+	files, urls := doc.files, doc.urls
+	report.set(func(r *Report) {
+		r.Bytes, r.Tokens = int(doc.size()), doc.tokens(cfg)
+		r.Backend, _ = clipboard.Backend()
+	})
+	start := time.Now()
+	defer report.phase("copy", start)
 
-	if !cfg.OnlyTree {
-		for _, file := range files {
-			output.WriteHeader(&outputBuf, file)
-			if err := output.WriteFileContent(&outputBuf, file); err != nil {
-				outputBuf.WriteString("[unreadable]\n")
+	saveDocIDs(cfg, doc)
+
+	if err := confirmSize(cfg, doc.data); err != nil {
+		return err
+	}
+
+	// The other destinations are copies too: hooked, audited and remembered
+	if cfg.Ask != "" || cfg.SplitSize > 0 || cfg.SplitTokens > 0 || cfg.Upload != "" {
+		if err := preCopy(ctx, cfg, doc); err != nil {
+			return err
+		}
+		var destination string
+		switch {
+		case cfg.Ask != "":
+			destination, err = ask(ctx, cfg, doc.data, len(files)+len(urls))
+		case cfg.Upload != "":
+			report.set(func(r *Report) { r.Destination = "upload:" + cfg.Upload })
+			destination, err = uploadOutput(ctx, cfg, doc)
+		default:
+			sections := make([][]byte, len(doc.sectionEnds))
+			start := 0
+			for i, end := range doc.sectionEnds {
+				sections[i] = doc.data[start:end]
+				start = end
 			}
-			outputBuf.WriteString("\n")
+			destination, err = splitOutput(ctx, cfg, sections)
+		}
+		if err != nil {
+			return err
 		}
+		finishCopy(ctx, cfg, doc, destination)
+		return nil
+	}
+
+	// Piped without a clipboard (CI, plain SSH), clipcat is a concatenator
+	sinks := cfg.Output != "" || cfg.Clipboard != ""
+	piped := !cfg.PrintOut && !sinks && !isTerminal(os.Stdout)
+	if _, err := clipboard.Backend(); err != nil && piped {
+		return printInstead(cfg, doc, err, report)
+	}
+
+	if err := preCopy(ctx, cfg, doc); err != nil {
+		return err
+	}
+
+	// --output and --clipboard tee the bundle to each destination at once
+	if sinks {
+		_, err := copyToSinks(ctx, cfg, report, p, func(w io.Writer) (*document, error) {
+			_, err := w.Write(doc.data)
+			return doc, err
+		})
+		return err
 	}
 
 	// Copy to clipboard
-	if err := clipboard.CopyToClipboard(outputBuf.Bytes()); err != nil {
-		return fmt.Errorf("copying to clipboard: %w", err)
+	p.start("copy")
+	if backend, err := clipboard.Backend(); err == nil {
+		p.visit(backend)
 	}
+	tempFile, copyErr := copyDocument(ctx, cfg, doc)
 
-	// Optionally print to stdout
+	// Optionally print to stdout, even when the clipboard is unavailable
 	if cfg.PrintOut {
-		os.Stdout.Write(outputBuf.Bytes())
+		printDocument(cfg, doc)
+	}
+	if copyErr != nil && piped && ctx.Err() == nil {
+		return printInstead(cfg, doc, copyErr, report)
+	}
+	if copyErr == nil {
+		report.set(func(r *Report) {
+			r.Destination = "clipboard"
+			if tempFile != "" {
+				r.Destination = "file:" + tempFile
+			}
+		})
+	}
+	if copyErr != nil {
+		return &ClipboardError{Err: copyErr}
 	}
 
 	// Success message
-	if cfg.OnlyTree {
+	if tempFile != "" {
+		fmt.Printf("Wrote %d files to %s and copied its path to the clipboard.\n", len(files)+len(urls), tempFile)
+	} else if cfg.PathsOnly {
+		fmt.Printf("Copied %d paths to clipboard.\n", len(files)+len(urls))
+	} else if cfg.ManifestOnly {
+		fmt.Printf("Copied manifest for %d files to clipboard.\n", len(files)+len(urls))
+	} else if cfg.OnlyTree {
 		fmt.Printf("Copied file hierarchy for %d files to clipboard.\n", len(files))
 	} else {
-		fmt.Printf("Copied %d files to clipboard.\n", len(files))
+		fmt.Printf("Copied %d files to clipboard.\n", len(files)+len(urls))
 	}
 
+	destination := "clipboard"
+	if tempFile != "" {
+		destination = "file:" + tempFile
+	}
+	finishCopy(ctx, cfg, doc, destination)
+	if cfg.Hold && tempFile == "" {
+		if err := holdClipboard(ctx, cfg, doc.data); err != nil {
+			return &ClipboardError{Err: err}
+		}
+	}
 	return nil
+}
+
+// copyToSinks writes the bundle render produces to --output, --clipboard
+// and --print in one pass, reports where it went and finishes the copy.
+func copyToSinks(ctx context.Context, cfg *Config, report *Report, p *progress, render func(io.Writer) (*document, error)) (*document, error) {
+	doc, written, err := writeSinks(ctx, cfg, p, render)
+	names, dests := written.written()
+	report.set(func(r *Report) {
+		if doc != nil {
+			r.Bytes, r.Tokens = int(doc.size()), doc.tokens(cfg)
+		}
+		r.Destination = strings.Join(dests, ",")
+		if r.Backend = ""; cfg.Clipboard != "" && cfg.Clipboard != "auto" {
+			r.Backend = strings.Fields(cfg.Clipboard)[0]
+		} else if cfg.Clipboard != "" {
+			r.Backend, _ = clipboard.Backend()
+		}
+	})
+	if err != nil {
+		return doc, err
+	}
+	if count := len(doc.files) + len(doc.urls); len(names) == 1 && names[0] == "the clipboard" {
+		fmt.Printf("Copied %d files to clipboard.\n", count)
+	} else {
+		fmt.Printf("Wrote %d files to %s.\n", count, strings.Join(names, " and "))
+	}
+	finishCopy(ctx, cfg, doc, strings.Join(dests, ","))
+	return doc, nil
+}
+
+// streamSinks reports whether the bundle can go to --output and --clipboard
+// as it is rendered: nothing needs all of it before the copy, and --print
+// does not highlight it afterwards.
+func streamSinks(cfg *Config) bool {
+	if cfg.Output == "" && cfg.Clipboard == "" {
+		return false
+	}
+	return cfg.DiffAgainst == "" && cfg.Ask == "" && cfg.SplitSize == 0 && cfg.SplitTokens == 0 &&
+		cfg.Upload == "" && cfg.PreCopy == "" && (cfg.Force || cfg.ConfirmOver <= 0) &&
+		!(cfg.PrintOut && useColor(cfg.Color))
+}
+
+// saveDocIDs saves the --ids of doc for clipcat expand. Expanding keeps the
+// mapping, so further IDs from the same answer resolve.
+func saveDocIDs(cfg *Config, doc *document) {
+	if doc.ids == nil || len(cfg.Expand) > 0 {
+		return
+	}
+	if err := saveIDs(doc.ids); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not save --ids for clipcat expand: %v\n", err)
+	}
+}
+
+// preCopy runs the pre_copy hook, whose failure vetoes the copy.
+func preCopy(ctx context.Context, cfg *Config, doc *document) error {
+	if cfg.PreCopy == "" {
+		return nil
+	}
+	if err := runHook(ctx, "pre_copy", cfg.PreCopy, cfg, doc); err != nil {
+		return fmt.Errorf("%w; nothing was copied", err)
+	}
+	return nil
+}
+
+// finishCopy logs a copy that went to destination, runs the post_copy hook
+// and records the command line in the history.
+func finishCopy(ctx context.Context, cfg *Config, doc *document, destination string) {
+	if cfg.AuditLog != "" {
+		if err := recordAudit(cfg, doc, destination); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not write the audit log: %v\n", err)
+		}
+	}
+
+	if cfg.PostCopy != "" {
+		if err := runHook(ctx, "post_copy", cfg.PostCopy, cfg, doc); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+
+	// Only command lines can be rerun
+	if cfg.Args != nil {
+		if err := recordHistory(cfg, doc); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not update the history: %v\n", err)
+		}
+	}
+}
+
+// relativeLabel shows paths under the working directory relative to it, for
+// --relative; paths elsewhere stay absolute.
+func relativeLabel(path string) string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return path
+	}
+	if rel, err := filepath.Rel(wd, path); err == nil && filepath.IsLocal(rel) {
+		return rel
+	}
+	return path
+}
+
+// confirmSize asks before copying output over the --confirm-over threshold,
+// since very large copies can freeze clipboard managers. Without a terminal
+// to ask on, the copy is refused unless --force is given.
+func confirmSize(cfg *Config, data []byte) error {
+	size := int64(len(data))
+	if cfg.Force || cfg.ConfirmOver <= 0 || size <= cfg.ConfirmOver {
+		return nil
+	}
+
+	summary := fmt.Sprintf("output is %s (~%d tokens), over the --confirm-over limit of %s",
+		formatSize(size), tokenCounter(cfg)(data), formatSize(cfg.ConfirmOver))
+	if !isTerminal(os.Stdin) {
+		return fmt.Errorf("%s; use --force to copy anyway", summary)
+	}
+
+	fmt.Fprintf(os.Stderr, "Warning: %s. Copy anyway? [y/N] ", summary)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return nil
+	}
+	return fmt.Errorf("aborted: %s", summary)
+}
+
+// copyDocument puts doc on the clipboard, adding a highlighted HTML flavor
+// with --rich when the backend can hold one. Output over the backend's size
+// limit is written to a temp file instead, whose path is copied and returned.
+func copyDocument(ctx context.Context, cfg *Config, doc *document) (string, error) {
+	if backend, err := clipboard.Backend(); err == nil {
+		limit, ok := cfg.ClipboardLimits[backend]
+		if !ok {
+			limit = clipboard.DefaultLimits[backend]
+		}
+		if limit > 0 && clipboard.PayloadSize(backend, doc.data) > limit {
+			return copyAsFile(ctx, cfg, doc.data, backend, limit)
+		}
+	}
+
+	if cfg.Rich {
+		err := clipboard.CopyRich(ctx, doc.data, output.HTML(doc.data, doc.spans))
+		if !errors.Is(err, clipboard.ErrRichUnsupported) {
+			return "", err
+		}
+		fmt.Fprintf(os.Stderr, "Warning: --rich: %v; copying plain text only\n", err)
+	}
+	return "", clipboard.CopyContext(ctx, doc.data)
+}
+
+// holdClipboard serves data on the clipboard for --hold until another copy
+// replaces it, Ctrl-C, or --hold-timeout.
+func holdClipboard(ctx context.Context, cfg *Config, data []byte) error {
+	if backend, err := clipboard.Backend(); err == nil && !clipboard.CanHold(backend) {
+		fmt.Fprintf(os.Stderr, "Warning: --hold: %s keeps the copy by itself; nothing to hold\n", backend)
+		return nil
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+	if cfg.HoldTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.HoldTimeout)
+		defer cancel()
+	}
+
+	fmt.Fprintf(os.Stderr, "Holding the clipboard; press Ctrl-C to stop.\n")
+	return clipboard.Hold(ctx, data)
+}
+
+// copyAsFile writes data to a temp file and copies the file's path.
+func copyAsFile(ctx context.Context, cfg *Config, data []byte, backend string, limit int64) (string, error) {
+	ext := ".txt"
+	switch cfg.Format {
+	case "markdown":
+		ext = ".md"
+	case "xml", "repomix":
+		ext = ".xml"
+	case "json":
+		ext = ".json"
+	}
+	f, err := os.CreateTemp("", "clipcat-*"+ext)
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+
+	fmt.Fprintf(os.Stderr, "Warning: output is %s, over the %s limit for %s; wrote it to %s and copying that path instead\n",
+		formatSize(clipboard.PayloadSize(backend, data)), formatSize(limit), backend, f.Name())
+	return f.Name(), clipboard.CopyContext(ctx, []byte(f.Name()))
+}
+
+// printDocument writes doc to stdout, highlighting file contents when
+// --color allows it. The clipboard always gets doc.data unchanged.
+func printDocument(cfg *Config, doc *document) {
+	if useColor(cfg.Color) {
+		output.Highlight(os.Stdout, doc.data, doc.spans)
+		return
+	}
+	os.Stdout.Write(doc.data)
+}
+
+// printInstead writes the bundle to stdout when it cannot be copied and
+// stdout is not a terminal, so pipelines and CI jobs still get it.
+func printInstead(cfg *Config, doc *document, reason error, report *Report) error {
+	report.set(func(r *Report) { r.Destination = "stdout" })
+	fmt.Fprintf(os.Stderr, "Warning: %v; writing to stdout instead\n", reason)
+	printDocument(cfg, doc)
+	return nil
+}
+
+// useColor resolves --color: auto colors only a terminal and honours NO_COLOR.
+func useColor(mode string) bool {
+	switch mode {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+	return os.Getenv("NO_COLOR") == "" && isTerminal(os.Stdout)
+}
+
+// isTerminal reports whether f is attached to a terminal.
+func isTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
+
+// uploadOutput sends the bundle to the configured paste target and puts the
+// resulting link, rather than the content, on the clipboard. It returns the
+// link.
+func uploadOutput(ctx context.Context, cfg *Config, doc *document) (string, error) {
+	data, count := doc.data, len(doc.files)+len(doc.urls)
+	// Encrypted uploads are armored instead, which also covers compression
+	text := !upload.Binary(cfg.Upload)
+	if cfg.Compress != "" {
+		var err error
+		if data, err = unpack.Compress(data, cfg.Compress, text && cfg.Encrypt == ""); err != nil {
+			return "", err
+		}
+	}
+	if cfg.Encrypt != "" {
+		var err error
+		if data, err = crypto.Encrypt(ctx, cfg.Encrypt, text, data); err != nil {
+			return "", fmt.Errorf("encrypting: %w", err)
+		}
+	}
+	url, err := upload.Upload(ctx, cfg.Upload, data)
+	if err != nil {
+		return "", fmt.Errorf("uploading: %w", err)
+	}
+
+	if cfg.PrintOut {
+		os.Stdout.Write(doc.data)
+	}
+
+	if err := clipboard.CopyContext(ctx, []byte(url)); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not copy link to clipboard: %v\n", err)
+		fmt.Printf("Uploaded %d files to %s\n", count, url)
+		return url, nil
+	}
+
+	fmt.Printf("Uploaded %d files to %s (link copied to clipboard).\n", count, url)
+	return url, nil
+}
+
+// tokenCounter returns the token estimator for --model. Library callers
+// may leave Model unvalidated, so unknown names fall back to the default.
+func tokenCounter(cfg *Config) tokens.Counter {
+	if count, err := tokens.ForModel(cfg.Model); err == nil {
+		return count
+	}
+	return tokens.Estimate
+}
+
+// splitOutput partitions the bundle into numbered parts and either writes
+// them to files or copies them to the clipboard one at a time. It returns
+// where the parts went.
+func splitOutput(ctx context.Context, cfg *Config, sections [][]byte) (string, error) {
+	// Leave room for the part banner in every chunk
+	limit, measure := int(cfg.SplitSize)-32, func(b []byte) int { return len(b) }
+	if cfg.SplitTokens > 0 {
+		limit, measure = cfg.SplitTokens-8, tokenCounter(cfg)
+	}
+	if limit < 1 {
+		return "", fmt.Errorf("split limit is too small")
+	}
+
+	chunks := output.Split(sections, limit, measure)
+	for i, chunk := range chunks {
+		chunks[i] = append([]byte(output.PartBanner(i+1, len(chunks))), chunk...)
+	}
+
+	if cfg.SplitOutput != "" {
+		ext := filepath.Ext(cfg.SplitOutput)
+		base := strings.TrimSuffix(cfg.SplitOutput, ext)
+		if ext == "" {
+			ext = ".txt"
+		}
+		for i, chunk := range chunks {
+			name := fmt.Sprintf("%s.part%d%s", base, i+1, ext)
+			if err := os.WriteFile(name, chunk, 0644); err != nil {
+				return "", fmt.Errorf("writing %s: %w", name, err)
+			}
+		}
+		fmt.Printf("Wrote %d parts to %s.part*%s\n", len(chunks), base, ext)
+		return fmt.Sprintf("file:%s.part*%s", base, ext), nil
+	}
+
+	stdin := bufio.NewReader(os.Stdin)
+	for i, chunk := range chunks {
+		copyErr := clipboard.CopyContext(ctx, chunk)
+		if cfg.PrintOut {
+			os.Stdout.Write(chunk)
+		}
+		if copyErr != nil {
+			return "", &ClipboardError{Err: fmt.Errorf("part %d: %w", i+1, copyErr)}
+		}
+
+		if i == len(chunks)-1 {
+			fmt.Printf("Copied part %d/%d to clipboard.\n", i+1, len(chunks))
+			break
+		}
+		fmt.Printf("Copied part %d/%d to clipboard. Press Enter for the next part...", i+1, len(chunks))
+		if _, err := stdin.ReadString('\n'); err != nil {
+			fmt.Println()
+			return "", fmt.Errorf("stopped after part %d/%d: %w", i+1, len(chunks), err)
+		}
+	}
+	return "clipboard", nil
 }
\ No newline at end of file
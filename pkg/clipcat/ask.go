@@ -0,0 +1,77 @@
+package clipcat
+
+import (
+	"clipcat/internal/llm"
+	"clipcat/pkg/config"
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+)
+
+// ask sends the question followed by the bundle to the API configured in
+// the [ask] table and streams the answer to stdout. It returns where the
+// bundle went, for the audit log.
+func ask(ctx context.Context, cfg *Config, data []byte, count int) (string, error) {
+	conf, err := config.Load()
+	if err != nil {
+		return "", err
+	}
+	ep, err := askEndpoint(conf.Ask)
+	if err != nil {
+		return "", err
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+
+	prompt := cfg.Ask + "\n\n" + string(data)
+	fmt.Fprintf(os.Stderr, "Asking %s about %d files (~%d tokens)...\n\n", ep.Model, count, tokenCounter(cfg)([]byte(prompt)))
+	if err := llm.Stream(ctx, ep, prompt, os.Stdout); err != nil {
+		return "", fmt.Errorf("ask: %w", err)
+	}
+	fmt.Println()
+	return "ask:" + ep.URL, nil
+}
+
+// askEndpoint fills in the provider defaults. Without a provider, it picks
+// anthropic when ANTHROPIC_API_KEY is set and openai otherwise; the key is
+// read from api_key_env, else from ANTHROPIC_API_KEY or OPENAI_API_KEY.
+func askEndpoint(conf config.Ask) (llm.Endpoint, error) {
+	provider := strings.ToLower(conf.Provider)
+	if provider == "" {
+		provider = "openai"
+		if os.Getenv("ANTHROPIC_API_KEY") != "" {
+			provider = "anthropic"
+		}
+	}
+	if !slices.Contains(llm.Providers, provider) {
+		return llm.Endpoint{}, fmt.Errorf("[ask] provider %q is not one of %s", conf.Provider, strings.Join(llm.Providers, ", "))
+	}
+
+	ep := llm.Endpoint{
+		Provider:  provider,
+		URL:       conf.URL,
+		Model:     conf.Model,
+		MaxTokens: conf.MaxTokens,
+	}
+	keyEnv := conf.APIKeyEnv
+	if keyEnv == "" {
+		keyEnv = strings.ToUpper(provider) + "_API_KEY"
+	}
+	ep.APIKey = os.Getenv(keyEnv)
+
+	if ep.URL == "" {
+		// Local OpenAI-compatible servers need no key, but the hosted APIs do
+		if ep.APIKey == "" {
+			return llm.Endpoint{}, fmt.Errorf("ask requires an API key in %s, or a url in the [ask] config table", keyEnv)
+		}
+		ep.URL = llm.DefaultURLs[provider]
+	}
+	if ep.Model == "" {
+		ep.Model = llm.DefaultModels[provider]
+	}
+	return ep, nil
+}
\ No newline at end of file
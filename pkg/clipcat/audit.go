@@ -0,0 +1,151 @@
+package clipcat
+
+import (
+	"bufio"
+	"clipcat/pkg/config"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// auditShown is how many records `clipcat audit show` prints by default.
+const auditShown = 20
+
+// AuditEntry is one line of the audit log: what was copied, from where, and
+// where it went.
+type AuditEntry struct {
+	Time        time.Time   `json:"time"`
+	Dir         string      `json:"dir"`
+	Destination string      `json:"destination"` // clipboard, file:PATH or the upload link
+	Files       []AuditFile `json:"files"`
+	Size        int64       `json:"size"`   // bytes copied
+	SHA256      string      `json:"sha256"` // of the copied output
+}
+
+// AuditFile is a copied file or URL and its size on disk, when it has one.
+type AuditFile struct {
+	Path string `json:"path"`
+	Size int64  `json:"size,omitempty"`
+}
+
+// recordAudit appends a copy to cfg.AuditLog as one JSON line. The log is
+// only ever appended to, so concurrent copies do not lose records.
+func recordAudit(cfg *Config, doc *document, destination string) error {
+	entry := AuditEntry{
+		Time:        time.Now().UTC(),
+		Destination: destination,
+		Size:        doc.size(),
+		SHA256:      doc.sha256(),
+	}
+	entry.Dir, _ = os.Getwd()
+	for _, file := range doc.files {
+		f := AuditFile{Path: file}
+		if info, err := os.Stat(file); err == nil {
+			f.Size = info.Size()
+		}
+		entry.Files = append(entry.Files, f)
+	}
+	for _, url := range doc.urls {
+		entry.Files = append(entry.Files, AuditFile{Path: url})
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(cfg.AuditLog), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(cfg.AuditLog, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Audit implements `clipcat audit show [N]`: the last N records of the
+// audit log, oldest first, each with its files.
+func Audit(args []string, w io.Writer) error {
+	if len(args) == 0 || args[0] != "show" || len(args) > 2 {
+		fmt.Fprintf(os.Stderr, "Usage: clipcat audit show [N]\n")
+		os.Exit(2)
+	}
+	n := auditShown
+	if len(args) == 2 {
+		var err error
+		if n, err = strconv.Atoi(args[1]); err != nil || n < 1 {
+			return fmt.Errorf("invalid record count %q", args[1])
+		}
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	path := config.AuditPath()
+	if cfg.Audit.Path != "" && filepath.Base(cfg.Audit.Source) != config.ProjectFile {
+		path = cfg.Audit.Path
+	}
+	entries, err := loadAudit(path)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		if !cfg.Audit.Enabled {
+			fmt.Fprintf(w, "The audit log is off. Turn it on with enabled = true in the [audit] table of %s.\n", config.UserPath())
+		} else {
+			fmt.Fprintf(w, "No copies recorded in %s yet.\n", path)
+		}
+		return nil
+	}
+
+	for _, e := range entries[max(0, len(entries)-n):] {
+		fmt.Fprintf(w, "%s  %s  %d files  %s  sha256:%s\n", e.Time.Local().Format("2006-01-02 15:04:05"),
+			e.Destination, len(e.Files), formatSize(e.Size), e.SHA256[:min(len(e.SHA256), 12)])
+		fmt.Fprintf(w, "  dir: %s\n", e.Dir)
+		for _, f := range e.Files {
+			if f.Size > 0 {
+				fmt.Fprintf(w, "  %s (%s)\n", f.Path, formatSize(f.Size))
+			} else {
+				fmt.Fprintf(w, "  %s\n", f.Path)
+			}
+		}
+	}
+	return nil
+}
+
+// loadAudit reads the records of the audit log at path; a missing log has
+// none.
+func loadAudit(path string) ([]AuditEntry, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []AuditEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 64<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
\ No newline at end of file
@@ -0,0 +1,75 @@
+package clipcat
+
+import (
+	"clipcat/pkg/tokens"
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
+// benchRuns is how many times `clipcat bench` repeats each stage; the best
+// time is reported, which is the least noisy on a busy machine.
+const benchRuns = 3
+
+// Bench implements the hidden `clipcat bench [OPTIONS] [PATHS]` command. It
+// runs the copy pipeline on the inputs without touching the clipboard and
+// reports how long collecting, rendering and token counting took, so changes
+// to the walker or matcher can be checked against a real tree.
+func Bench(cfg *Config, w io.Writer) error {
+	ctx := context.Background()
+	b := New(WithConfig(*cfg), WithWarnings(io.Discard))
+
+	var files []string
+	collect, err := bestOf(func() error {
+		var err error
+		_, files, _, err = b.collect(ctx)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	var doc *document
+	bundle, err := bestOf(func() error {
+		var err error
+		doc, err = b.render(ctx)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	count := tokenCounter(cfg)
+	var tokenCount int
+	estimate, _ := bestOf(func() error {
+		tokenCount = count(doc.data)
+		return nil
+	})
+
+	model := cfg.Model
+	if model == "" {
+		model = tokens.DefaultModel
+	}
+	fmt.Fprintf(w, "clipcat bench: best of %d runs\n", benchRuns)
+	fmt.Fprintf(w, "%-8s %-24s %s\n", "collect", fmt.Sprintf("%d files", len(files)), collect)
+	fmt.Fprintf(w, "%-8s %-24s %s (collect, read, filter, format)\n", "bundle", formatSize(int64(len(doc.data))), bundle)
+	fmt.Fprintf(w, "%-8s %-24s %s\n", "tokens", fmt.Sprintf("~%d (%s)", tokenCount, model), estimate)
+	return nil
+}
+
+// bestOf runs f benchRuns times and returns its fastest run, rounded for
+// display.
+func bestOf(f func() error) (time.Duration, error) {
+	var best time.Duration
+	for i := range benchRuns {
+		start := time.Now()
+		if err := f(); err != nil {
+			return 0, err
+		}
+		if elapsed := time.Since(start); i == 0 || elapsed < best {
+			best = elapsed
+		}
+	}
+	return best.Round(10 * time.Microsecond), nil
+}
\ No newline at end of file
@@ -0,0 +1,1460 @@
+package clipcat
+
+import (
+	"bytes"
+	"clipcat/pkg/collector"
+	"clipcat/pkg/config"
+	"clipcat/pkg/exclude"
+	"clipcat/pkg/output"
+	"clipcat/pkg/remote"
+	"clipcat/pkg/tokens"
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Bundler collects files and renders them as a clipcat document. It never
+// touches the clipboard, os.Stdout or os.Stderr, so other tools can embed it:
+//
+//	err := clipcat.New(clipcat.WithPaths("src/"), clipcat.WithTree(true)).Write(ctx, w)
+type Bundler struct {
+	cfg       Config
+	label     func(path string) string
+	warn      io.Writer
+	formatter output.Formatter
+	filters   []output.Filter
+	ids       map[string]string // fixed --ids by file, for `clipcat expand`
+	excerpts  map[string][]output.LineRange // lines referenced by quickfix or diagnostics files
+	matches   map[string]int                // --grep matches by file, for --grep-counts
+	report    *Report                       // --report json, when requested
+	excluded  []output.Exclusion            // what exclude rules kept out, for --tree-show-excluded
+	entries   map[string]collector.Entry    // the collected files, with their size, mode and mtime
+	warnings  []collector.Warning           // inputs and files the collector could not use
+	progress  *progress                     // where the run is, for --timeout
+	seen      map[string]string             // SHA-256 to where it was copied, for --dedupe-content
+	dir       string                        // what -e patterns match from, for gh and serve
+}
+
+// Option configures a Bundler.
+type Option func(*Bundler)
+
+// New returns a Bundler with the same defaults as the command line: default
+// excludes on, 30s URL timeout and a 10M cap per URL.
+func New(opts ...Option) *Bundler {
+	b := &Bundler{
+		cfg: Config{
+			DefaultExcludes: exclude.DefaultExcludes,
+			URLTimeout:      30 * time.Second,
+			URLMaxSize:      10 << 20,
+		},
+		label: func(path string) string { return path },
+		warn:  io.Discard,
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+// WithConfig takes every collection and rendering setting from cfg, as
+// parsed from command-line arguments. Output settings such as PrintOut,
+// Upload and the split options are ignored.
+func WithConfig(cfg Config) Option {
+	return func(b *Bundler) { b.cfg = cfg }
+}
+
+// WithPaths adds files, directories, glob patterns or URLs to collect.
+func WithPaths(paths ...string) Option {
+	return func(b *Bundler) { b.cfg.Paths = append(b.cfg.Paths, paths...) }
+}
+
+// WithExcludes adds -e style exclude patterns.
+func WithExcludes(patterns ...string) Option {
+	return func(b *Bundler) { b.cfg.Excludes = append(b.cfg.Excludes, patterns...) }
+}
+
+// WithExcludeFiles adds .gitignore-style exclude files.
+func WithExcludeFiles(files ...string) Option {
+	return func(b *Bundler) { b.cfg.ExcludeFiles = append(b.cfg.ExcludeFiles, files...) }
+}
+
+// WithDefaultExcludes replaces the patterns skipped while walking
+// directories; call it without arguments to walk everything.
+func WithDefaultExcludes(patterns ...string) Option {
+	return func(b *Bundler) { b.cfg.DefaultExcludes = patterns }
+}
+
+// WithForceInclude adds files to collect even if excludes, defaults or
+// filters would skip them. The copy policy still applies.
+func WithForceInclude(files ...string) Option {
+	return func(b *Bundler) { b.cfg.ForceInclude = append(b.cfg.ForceInclude, files...) }
+}
+
+// WithQuickfix adds the files named in Vim quickfix or compiler-style
+// "path:line: message" files, see WithContextLines.
+func WithQuickfix(paths ...string) Option {
+	return func(b *Bundler) { b.cfg.FromQuickfix = append(b.cfg.FromQuickfix, paths...) }
+}
+
+// WithGoDeps adds the Go files of the packages matching patterns (import
+// paths or ./dir patterns, as for go list) and of the packages of the main
+// module they import, see WithGoDepsDepth.
+func WithGoDeps(patterns ...string) Option {
+	return func(b *Bundler) { b.cfg.GoDeps = append(b.cfg.GoDeps, patterns...) }
+}
+
+// WithFollowImports adds the JavaScript and TypeScript entry files and the
+// local files they import, directly or not, outside node_modules.
+func WithFollowImports(entries ...string) Option {
+	return func(b *Bundler) { b.cfg.FollowImports = append(b.cfg.FollowImports, entries...) }
+}
+
+// WithTests adds the conventional test file of each collected source file,
+// e.g. foo_test.go, foo.test.ts or test_foo.py.
+func WithTests(tests bool) Option {
+	return func(b *Bundler) { b.cfg.WithTests = tests }
+}
+
+// WithSources adds the source file of each collected test file, the
+// reverse of WithTests.
+func WithSources(sources bool) Option {
+	return func(b *Bundler) { b.cfg.WithSources = sources }
+}
+
+// WithPreset applies the source preset of an ecosystem: "go", "node",
+// "rust" or "python", several separated by commas, PresetAuto to detect them
+// from the manifests in the inputs, or PresetNone.
+func WithPreset(name string) Option {
+	return func(b *Bundler) { b.cfg.Preset = name }
+}
+
+// WithGoDepsDepth follows the imports of WithGoDeps packages at most depth
+// levels deep; 0 (the default) follows them all.
+func WithGoDepsDepth(depth int) Option {
+	return func(b *Bundler) { b.cfg.GoDepsDepth = depth }
+}
+
+// WithDiagnostics adds the files named in JSON diagnostics files (flat
+// file/line objects, LSP ranges or ESLint output), see WithContextLines.
+func WithDiagnostics(paths ...string) Option {
+	return func(b *Bundler) { b.cfg.FromDiagnostics = append(b.cfg.FromDiagnostics, paths...) }
+}
+
+// WithContextLines copies only the lines referenced by WithQuickfix and
+// WithDiagnostics, or matched by WithGrep and WithGrepRegexp, with n lines
+// of context around them, instead of the whole files.
+func WithContextLines(n int) Option {
+	return func(b *Bundler) { b.cfg.Excerpt, b.cfg.ContextLines = true, n }
+}
+
+// WithGrep keeps only files containing one of the literal strings.
+func WithGrep(patterns ...string) Option {
+	return func(b *Bundler) { b.cfg.Grep = append(b.cfg.Grep, patterns...) }
+}
+
+// WithGrepRegexp keeps only files matching one of the regular expressions.
+func WithGrepRegexp(patterns ...string) Option {
+	return func(b *Bundler) { b.cfg.GrepRegexps = append(b.cfg.GrepRegexps, patterns...) }
+}
+
+// WithGrepCounts adds the number of --grep matches to each file header.
+func WithGrepCounts(counts bool) Option {
+	return func(b *Bundler) { b.cfg.GrepCounts = counts }
+}
+
+// WithSummarizeLocks copies lockfiles (go.sum, package-lock.json, yarn.lock
+// and others, see output.IsLockfile) as a list of dependencies instead of
+// skipping them.
+func WithSummarizeLocks(summarize bool) Option {
+	return func(b *Bundler) { b.cfg.SummarizeLocks = summarize }
+}
+
+// WithIncludeMinified copies minified JavaScript and CSS (see
+// output.IsMinified) instead of a placeholder.
+func WithIncludeMinified(include bool) Option {
+	return func(b *Bundler) { b.cfg.IncludeMinified = include }
+}
+
+// WithJSON re-indents (output.JSONPretty) or compacts (output.JSONMinify)
+// .json files; minify also drops comments and blank lines from YAML.
+func WithJSON(mode string) Option {
+	return func(b *Bundler) { b.cfg.JSON = mode }
+}
+
+// WithTablePreview copies CSV and TSV files as an aligned table of the
+// header and the first rows, with the total row count.
+func WithTablePreview(rows int) Option {
+	return func(b *Bundler) { b.cfg.TablePreview = rows }
+}
+
+// WithIgnoreCase makes pattern matching case-insensitive.
+func WithIgnoreCase(ignoreCase bool) Option {
+	return func(b *Bundler) { b.cfg.IgnoreCase = ignoreCase }
+}
+
+// WithIgnoreCaseExcludes makes only the exclude patterns case-insensitive.
+func WithIgnoreCaseExcludes(ignoreCase bool) Option {
+	return func(b *Bundler) { b.cfg.IgnoreCaseExcludes = ignoreCase }
+}
+
+// WithGit collects only files tracked by git.
+func WithGit(git bool) Option {
+	return func(b *Bundler) { b.cfg.Git = git }
+}
+
+// WithRoot searches for glob inputs under dir instead of the working
+// directory.
+func WithRoot(dir string) Option {
+	return func(b *Bundler) { b.cfg.Root = dir }
+}
+
+// WithModifiedBetween keeps files modified after after and before before;
+// a zero time leaves that side open.
+func WithModifiedBetween(after, before time.Time) Option {
+	return func(b *Bundler) {
+		b.cfg.NewerThan = after
+		b.cfg.OlderThan = before
+	}
+}
+
+// WithLanguages keeps only files in the given languages or with the given
+// extensions, e.g. "go", "python" or "ts".
+func WithLanguages(names ...string) Option {
+	return func(b *Bundler) { b.cfg.Languages = append(b.cfg.Languages, names...) }
+}
+
+// WithTypes keeps only files in the given categories: text, code, config,
+// docs or data (see lang.Category).
+func WithTypes(categories ...string) Option {
+	return func(b *Bundler) { b.cfg.Types = append(b.cfg.Types, categories...) }
+}
+
+// WithTree prepends the FILE HIERARCHY section.
+func WithTree(tree bool) Option {
+	return func(b *Bundler) { b.cfg.ShowTree = tree }
+}
+
+// WithOnlyTree renders only the FILE HIERARCHY section.
+func WithOnlyTree(onlyTree bool) Option {
+	return func(b *Bundler) {
+		b.cfg.OnlyTree = onlyTree
+		b.cfg.ShowTree = b.cfg.ShowTree || onlyTree
+	}
+}
+
+// WithTreeFormat selects how the FILE HIERARCHY is drawn:
+// output.TreeText (the default) or output.TreeJSON, which also turns the
+// tree on.
+func WithTreeFormat(format string) Option {
+	return func(b *Bundler) {
+		b.cfg.TreeFormat = format
+		b.cfg.ShowTree = b.cfg.ShowTree || format == output.TreeJSON
+	}
+}
+
+// WithTreeShowExcluded adds what the exclude rules kept out to the FILE
+// HIERARCHY, each entry followed by "[excluded: RULE]", and turns the tree
+// on.
+func WithTreeShowExcluded(show bool) Option {
+	return func(b *Bundler) {
+		b.cfg.TreeShowExcluded = show
+		b.cfg.ShowTree = b.cfg.ShowTree || show
+	}
+}
+
+// WithPathsOnly renders just the file paths (as labelled) and URLs, one per
+// line, without headers or contents.
+func WithPathsOnly(pathsOnly bool) Option {
+	return func(b *Bundler) { b.cfg.PathsOnly = pathsOnly }
+}
+
+// WithLong renders the FILE HIERARCHY section as an `ls -l`-style listing
+// of mode, size, modification time and path.
+func WithLong(long bool) Option {
+	return func(b *Bundler) {
+		b.cfg.Long = long
+		b.cfg.ShowTree = b.cfg.ShowTree || long
+	}
+}
+
+// WithFilterCommands pipes each file through the shell commands, in order,
+// before the other content filters; see output.CommandHandler.
+func WithFilterCommands(commands ...string) Option {
+	return func(b *Bundler) { b.cfg.FilterCmds = append(b.cfg.FilterCmds, commands...) }
+}
+
+// WithRun appends a section per command with its combined stdout and
+// stderr, run through the shell in the working directory.
+func WithRun(commands ...string) Option {
+	return func(b *Bundler) { b.cfg.Run = append(b.cfg.Run, commands...) }
+}
+
+// WithEnvInfo appends an ENVIRONMENT section with the OS, Go version, git
+// HEAD and the EnvAllowlist variables, plus vars when they are set.
+func WithEnvInfo(envInfo bool, vars ...string) Option {
+	return func(b *Bundler) {
+		b.cfg.EnvInfo = envInfo
+		b.cfg.EnvVars = append(b.cfg.EnvVars, vars...)
+	}
+}
+
+// WithStrict makes Write fail with ErrChanged when a file is removed or
+// changed while the bundle is read, instead of copying a placeholder, and
+// with a *StrictError after writing when inputs were skipped or files could
+// not be read.
+func WithStrict(strict bool) Option {
+	return func(b *Bundler) { b.cfg.Strict = strict }
+}
+
+// WithMaxFiles keeps only the first n files in sort order; 0 means no
+// limit. What is left out is reported to the WithWarnings writer.
+func WithMaxFiles(n int) Option {
+	return func(b *Bundler) { b.cfg.MaxFiles = n }
+}
+
+// WithSort selects output.SortNatural (the default) or output.SortLexical.
+func WithSort(mode string) Option {
+	return func(b *Bundler) { b.cfg.Sort = mode }
+}
+
+// WithSmartOrder puts READMEs, build manifests, entrypoints and the files
+// others import ahead of the rest, which keep their sort order.
+func WithSmartOrder(smart bool) Option {
+	return func(b *Bundler) { b.cfg.SmartOrder = smart }
+}
+
+// WithCollate selects how names compare when sorting: output.CollateByte
+// (the default), output.CollateUnicode or output.CollateLocale.
+func WithCollate(collation string) Option {
+	return func(b *Bundler) { b.cfg.Collate = collation }
+}
+
+// WithDeterministic makes the output depend only on the files: paths are
+// relative with forward slashes and ordered by them, and timestamps are
+// left out, so identical trees render byte-identical documents.
+func WithDeterministic(deterministic bool) Option {
+	return func(b *Bundler) { b.cfg.Deterministic = deterministic }
+}
+
+// WithManifest appends the size and SHA-256 of every file (as read from
+// disk, before filters) so recipients can verify the tree state.
+func WithManifest(manifest bool) Option {
+	return func(b *Bundler) { b.cfg.Manifest = manifest }
+}
+
+// WithManifestOnly renders only the manifest, without file contents.
+func WithManifestOnly(manifestOnly bool) Option {
+	return func(b *Bundler) {
+		b.cfg.ManifestOnly = manifestOnly
+		b.cfg.Manifest = b.cfg.Manifest || manifestOnly
+	}
+}
+
+// WithSummary prepends a block describing how the document was produced:
+// time, clipcat version, inputs, file, line and token counts and excludes.
+func WithSummary(summary bool) Option {
+	return func(b *Bundler) { b.cfg.Summary = summary }
+}
+
+// WithFormat selects a registered formatter by name, e.g. "markdown".
+func WithFormat(name string) Option {
+	return func(b *Bundler) { b.cfg.Format = name }
+}
+
+// WithFormatter renders with f instead of a registered formatter.
+func WithFormatter(f output.Formatter) Option {
+	return func(b *Bundler) { b.formatter = f }
+}
+
+// WithGroupBy puts a header above each group of files: GroupDir, GroupExt
+// or GroupRoot; GroupNone or "" leaves the files ungrouped.
+func WithGroupBy(mode string) Option {
+	return func(b *Bundler) { b.cfg.GroupBy = mode }
+}
+
+// WithIDs gives each file a short ID ("F1", "F2", ... in output order) in
+// its header and in the tree, for markdown, xml and json output.
+func WithIDs(ids bool) Option {
+	return func(b *Bundler) { b.cfg.IDs = ids }
+}
+
+// WithDedupeContent leaves out files whose bytes match a file before them
+// in the output, so copies of one file are included once.
+func WithDedupeContent(dedupe bool) Option {
+	return func(b *Bundler) { b.cfg.DedupeContent = dedupe }
+}
+
+// withReport records what the run copies and leaves out in r.
+func withReport(r *Report) Option {
+	return func(b *Bundler) { b.report = r }
+}
+
+// withProgress tracks in p where the run is, for --timeout.
+func withProgress(p *progress) Option {
+	return func(b *Bundler) { b.progress = p }
+}
+
+// withDir matches -e patterns relative to dir rather than the working
+// directory, for `clipcat gh` checkouts and the `clipcat serve` root.
+func withDir(dir string) Option {
+	return func(b *Bundler) { b.dir = dir }
+}
+
+// withSeen makes --dedupe-content also leave out files with these SHA-256
+// sums, copied by an earlier run.
+func withSeen(sums []string) Option {
+	return func(b *Bundler) {
+		b.seen = make(map[string]string, len(sums))
+		for _, sum := range sums {
+			b.seen[sum] = "an earlier run"
+		}
+	}
+}
+
+// withFixedIDs makes files keep the IDs of an earlier --ids run.
+func withFixedIDs(ids map[string]string) Option {
+	return func(b *Bundler) {
+		b.cfg.IDs = true
+		b.ids = ids
+	}
+}
+
+// WithTemplate renders the document with the Go template in file instead of
+// a format; see output.TemplateDocument for the data it is executed with.
+func WithTemplate(file string) Option {
+	return func(b *Bundler) { b.cfg.Template = file }
+}
+
+// WithFilters adds content filters. They run after comment stripping and
+// redaction and before --max-lines truncation and line numbering.
+func WithFilters(filters ...output.Filter) Option {
+	return func(b *Bundler) { b.filters = append(b.filters, filters...) }
+}
+
+// WithJobs sets how many files are read concurrently; 0 means one per CPU.
+func WithJobs(n int) Option {
+	return func(b *Bundler) { b.cfg.Jobs = n }
+}
+
+// WithInlineImages embeds images as base64 data URIs in the markdown format
+// instead of describing them with a placeholder.
+func WithInlineImages(inline bool) Option {
+	return func(b *Bundler) { b.cfg.InlineImages = inline }
+}
+
+// WithNotebook sets how Jupyter notebooks are copied: output.NotebookRender
+// (the default), output.NotebookRaw or output.NotebookSkip.
+func WithNotebook(mode string) Option {
+	return func(b *Bundler) { b.cfg.Notebook = mode }
+}
+
+// WithUnreadablePlaceholder sets the text shown for a file that could not
+// be read, with {path}, {error} and {size} filled in, instead of the
+// default "[unreadable: permission denied, 4.2MB]".
+func WithUnreadablePlaceholder(text string) Option {
+	return func(b *Bundler) { b.cfg.UnreadablePlaceholder = text }
+}
+
+// WithWarnings sends non-fatal problems, such as missing inputs, to w.
+// They are discarded by default.
+func WithWarnings(w io.Writer) Option {
+	return func(b *Bundler) { b.warn = w }
+}
+
+// WithLabel maps each file's absolute path to the name shown in its header.
+func WithLabel(label func(path string) string) Option {
+	return func(b *Bundler) { b.label = label }
+}
+
+// Write collects the files and writes the rendered document to w section
+// by section, as it is rendered. It returns ErrNoFiles, before writing
+// anything, when nothing is left after excludes.
+func (b *Bundler) Write(ctx context.Context, w io.Writer) error {
+	doc, err := b.renderTo(ctx, w)
+	if err != nil {
+		return err
+	}
+	return b.strictError(doc)
+}
+
+// Files returns the files Write would include, in output order.
+func (b *Bundler) Files(ctx context.Context) ([]string, error) {
+	opts, files, _, err := b.collect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	files, _ = b.capFiles(files)
+	if b.cfg.DedupeContent {
+		files = b.dedupe(files)
+	}
+	files, _ = b.groupFiles(opts.Paths, files)
+	return files, nil
+}
+
+// document is a rendered bundle. sectionEnds marks where the tree and each
+// file or URL section end, so --split-* can keep sections whole.
+type document struct {
+	data        []byte
+	sectionEnds []int
+	files       []string
+	urls        []string
+	spans       []output.Span // where each file's content sits, for --color
+	ids         map[string]string // --ids by file
+	problems    []string          // skipped inputs and unreadable sections, for --strict
+
+	// A streamed document is written to out section by section instead of
+	// being kept in data; what the report, hooks and audit log need to know
+	// about it is tallied on the way.
+	out        io.Writer
+	count      tokens.Counter
+	flushed    int
+	tokenCount int
+	sum        hash.Hash
+}
+
+// stream makes doc write its sections to out as they end.
+func (doc *document) stream(out io.Writer, count tokens.Counter) {
+	doc.out, doc.count, doc.sum = out, count, sha256.New()
+}
+
+// pos is the offset in the whole document of the end of buf.
+func (doc *document) pos(buf *bytes.Buffer) int {
+	return doc.flushed + buf.Len()
+}
+
+// endSection marks the end of a section at the end of buf and, when doc
+// streams, hands buf over to out.
+func (doc *document) endSection(buf *bytes.Buffer) error {
+	doc.sectionEnds = append(doc.sectionEnds, doc.pos(buf))
+	return doc.flush(buf)
+}
+
+// flush writes what buf holds to out and empties it, when doc streams.
+func (doc *document) flush(buf *bytes.Buffer) error {
+	if doc.out == nil {
+		return nil
+	}
+	data := buf.Bytes()
+	if _, err := doc.out.Write(data); err != nil {
+		return err
+	}
+	doc.flushed += len(data)
+	doc.tokenCount += doc.count(data)
+	doc.sum.Write(data)
+	buf.Reset()
+	return nil
+}
+
+// size, sha256 and tokens describe the whole document, streamed or not.
+func (doc *document) size() int64 {
+	if doc.out != nil {
+		return int64(doc.flushed)
+	}
+	return int64(len(doc.data))
+}
+
+func (doc *document) sha256() string {
+	if doc.out != nil {
+		return hex.EncodeToString(doc.sum.Sum(nil))
+	}
+	return sha256Hex(doc.data)
+}
+
+func (doc *document) tokens(cfg *Config) int {
+	if doc.out != nil {
+		return doc.tokenCount
+	}
+	return tokenCounter(cfg)(doc.data)
+}
+
+// writeFile renders section into buf and records where its content landed.
+// Formatters that escape content (xml, json) leave no span.
+func (doc *document) writeFile(f output.Formatter, buf *bytes.Buffer, section output.File) error {
+	start := buf.Len()
+	if err := f.WriteFile(buf, section); err != nil {
+		return err
+	}
+	if len(section.Content) > 0 {
+		if i := bytes.LastIndex(buf.Bytes()[start:], section.Content); i >= 0 {
+			doc.spans = append(doc.spans, output.Span{
+				Start: doc.flushed + start + i,
+				End:   doc.flushed + start + i + len(section.Content),
+				Path:  section.Path,
+				Diff:  section.DiffRef != "",
+			})
+		}
+	}
+	return nil
+}
+
+// collect resolves the local inputs into files and separates the URLs, which
+// are fetched while rendering.
+func (b *Bundler) collect(ctx context.Context) (collector.Options, []string, []string, error) {
+	cfg := &b.cfg
+
+	matcher, err := exclude.BuildMatcher(cfg.ExcludeFiles, cfg.Excludes, cfg.IgnoreCase || cfg.IgnoreCaseExcludes)
+	if err != nil {
+		return collector.Options{}, nil, nil, fmt.Errorf("loading exclude patterns: %w", err)
+	}
+	if b.dir != "" {
+		matcher = matcher.WithBase(b.dir)
+	}
+
+	// The organization policy applies to every caller, library ones included
+	deny, err := config.LoadPolicy(config.PolicyPath)
+	if err != nil {
+		return collector.Options{}, nil, nil, err
+	}
+	var policy *exclude.Policy
+	if len(deny) > 0 {
+		policy = exclude.NewPolicy(config.PolicyPath, deny)
+	}
+
+	var localPaths, urls []string
+	for _, path := range cfg.Paths {
+		if remote.IsURL(path) {
+			urls = append(urls, path)
+		} else {
+			localPaths = append(localPaths, path)
+		}
+	}
+
+	// Quickfix and diagnostics files name files to add, and lines to excerpt
+	if len(cfg.FromQuickfix) > 0 || len(cfg.FromDiagnostics) > 0 {
+		refs, ranges, err := b.references()
+		if err != nil {
+			return collector.Options{}, nil, nil, err
+		}
+		localPaths = append(localPaths, refs...)
+		b.excerpts = ranges
+	}
+
+	if len(cfg.FollowImports) > 0 {
+		files, err := b.followImports()
+		if err != nil {
+			return collector.Options{}, nil, nil, fmt.Errorf("--follow-imports: %w", err)
+		}
+		localPaths = append(localPaths, files...)
+	}
+	if len(cfg.GoDeps) > 0 {
+		files, err := b.goDeps(ctx)
+		if err != nil {
+			return collector.Options{}, nil, nil, fmt.Errorf("--go-deps: %w", err)
+		}
+		localPaths = append(localPaths, files...)
+	}
+
+	// Summarized lockfiles are worth walking into
+	defaults := cfg.DefaultExcludes
+	if cfg.SummarizeLocks {
+		defaults = slices.DeleteFunc(slices.Clone(defaults), output.IsLockfile)
+	}
+
+	// Presets add to the default excludes, and pick the languages unless
+	// --ext does
+	languages := cfg.Languages
+	for _, p := range b.activePresets(localPaths) {
+		defaults = slices.Concat(defaults, p.excludes)
+		if len(cfg.Languages) == 0 {
+			languages = append(languages, p.languages...)
+		}
+	}
+
+	var only map[string]bool
+	if cfg.ChangedSince != "" || cfg.Staged || cfg.Unstaged {
+		if only, err = changedFileSet(cfg); err != nil {
+			return collector.Options{}, nil, nil, fmt.Errorf("listing changed files: %w", err)
+		}
+	}
+
+	opts := collector.Options{
+		Paths:      localPaths,
+		Matcher:    matcher,
+		IgnoreCase: cfg.IgnoreCase,
+		Git:        cfg.Git,
+		Root:       cfg.Root,
+		Only:       only,
+		Defaults:   defaults,
+		Policy:     policy,
+		Force:      cfg.ForceInclude,
+		Warnings:   b.warn,
+
+		ModifiedAfter:  cfg.NewerThan,
+		ModifiedBefore: cfg.OlderThan,
+		Languages:      languages,
+		Types:          cfg.Types,
+	}
+	b.excluded = nil
+	if b.report != nil || cfg.TreeShowExcluded {
+		opts.Skipped = func(path, reason string) {
+			b.report.skip(b.labelFor(path), reason)
+			if rule, ok := strings.CutPrefix(reason, "exclude: "); cfg.TreeShowExcluded && (ok || reason == "excluded") {
+				info, err := os.Stat(path)
+				b.excluded = append(b.excluded, output.Exclusion{Path: path, Dir: err == nil && info.IsDir(), Rule: cmp.Or(rule, "no rule")})
+			}
+		}
+	}
+	b.progress.start("collect")
+	opts.Visit = b.progress.visit
+	entries, warnings, err := collector.CollectEntries(ctx, opts)
+	b.warnings = warnings
+	for _, w := range warnings {
+		fmt.Fprintln(b.warn, w)
+	}
+	b.report.warn(warnings)
+	if err != nil {
+		return opts, nil, nil, fmt.Errorf("collecting files: %w", err)
+	}
+	if cfg.WithTests || cfg.WithSources {
+		// Tests and sources go through the excludes and the policy too, but
+		// not the filters that picked their counterparts
+		var paths []string
+		for _, e := range entries {
+			paths = append(paths, e.Path)
+		}
+		if pairs := b.pairedFiles(paths); len(pairs) > 0 {
+			probe := opts
+			probe.Paths = pairs
+			probe.Only = nil
+			probe.ModifiedAfter, probe.ModifiedBefore = time.Time{}, time.Time{}
+			more, _, err := collector.CollectEntries(ctx, probe)
+			if err != nil {
+				return opts, nil, nil, fmt.Errorf("collecting files: %w", err)
+			}
+			entries = append(entries, more...)
+		}
+	}
+	files := make([]string, len(entries))
+	b.entries = make(map[string]collector.Entry, len(entries))
+	for i, e := range entries {
+		files[i] = e.Path
+		b.entries[e.Path] = e
+	}
+	if cfg.Notebook == output.NotebookSkip {
+		files = slices.DeleteFunc(files, func(file string) bool {
+			if output.IsNotebook(file) {
+				b.report.skip(b.labelFor(file), "--notebook skip")
+				return true
+			}
+			return false
+		})
+	}
+	if len(cfg.Grep) > 0 || len(cfg.GrepRegexps) > 0 {
+		re, err := grepPattern(cfg)
+		if err != nil {
+			return opts, nil, nil, err
+		}
+		searched := files
+		var counts []int
+		if files, counts, err = collector.Grep(ctx, files, re, cfg.Jobs); err != nil {
+			return opts, nil, nil, fmt.Errorf("searching files: %w", err)
+		}
+		b.matches = make(map[string]int, len(files))
+		for i, file := range files {
+			b.matches[file] = counts[i]
+		}
+		for _, file := range searched {
+			if _, ok := b.matches[file]; !ok {
+				b.report.skip(b.labelFor(file), "no --grep match")
+			}
+		}
+	}
+	return opts, files, urls, nil
+}
+
+func (b *Bundler) render(ctx context.Context) (*document, error) {
+	return b.renderTo(ctx, nil)
+}
+
+// renderTo renders the document, writing it to out as each section ends
+// when out is not nil, so the whole bundle is never held in memory. The
+// returned document then has no data.
+func (b *Bundler) renderTo(ctx context.Context, out io.Writer) (*document, error) {
+	cfg := &b.cfg
+	start := time.Now()
+	opts, files, urls, err := b.collect(ctx)
+	b.report.phase("collect", start)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 && len(urls) == 0 && len(cfg.Run) == 0 && !cfg.EnvInfo {
+		return nil, ErrNoFiles
+	}
+	start = time.Now()
+	defer b.report.phase("render", start)
+
+	// Sort for consistent output; --max-files keeps the first files in order
+	files, dropped := b.capFiles(files)
+	for _, file := range dropped {
+		b.report.skip(b.labelFor(file), fmt.Sprintf("beyond --max-files %d", cfg.MaxFiles))
+	}
+	if cfg.DedupeContent {
+		files = b.dedupe(files)
+		if len(files) == 0 && len(urls) == 0 && len(cfg.Run) == 0 && !cfg.EnvInfo {
+			return nil, ErrNoFiles
+		}
+	}
+	files, groups := b.groupFiles(opts.Paths, files)
+
+	if cfg.PathsOnly {
+		return b.pathList(files, urls, out)
+	}
+
+	f := b.formatter
+	if f == nil && cfg.Template != "" {
+		tmpl, err := output.ParseTemplate(cfg.Template)
+		if err != nil {
+			return nil, fmt.Errorf("template: %w", err)
+		}
+		f = output.NewTemplateFormatter(tmpl)
+	}
+	if f == nil {
+		name := cfg.Format
+		if name == "" {
+			name = "plain"
+		}
+		if f, err = output.NewFormatter(name); err != nil {
+			return nil, err
+		}
+	}
+
+	filters, err := b.filterChain()
+	if err != nil {
+		return nil, err
+	}
+	redact, err := b.redaction()
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	doc := &document{files: files, urls: urls}
+	// --summary goes above the files, so it holds the document back until
+	// its totals are known
+	if out != nil && !cfg.Summary {
+		doc.stream(out, tokenCounter(cfg))
+	}
+	for _, w := range b.warnings {
+		doc.problems = append(doc.problems, w.Message)
+	}
+	if cfg.IDs {
+		doc.ids = make(map[string]string, len(files))
+		for i, file := range files {
+			doc.ids[file] = cmp.Or(b.ids[file], fmt.Sprintf("F%d", i+1))
+		}
+	}
+	if err := f.BeginDocument(&buf); err != nil {
+		return nil, err
+	}
+	if ww, ok := f.(output.WarningWriter); ok && len(b.warnings) > 0 {
+		if err := ww.WriteWarnings(&buf, b.warnings); err != nil {
+			return nil, err
+		}
+	}
+	// --summary totals the lines and tokens of every section's content
+	summaryAt, lines, tokenCount := buf.Len(), 0, 0
+	tally := func([]byte) {}
+	if cfg.Summary {
+		count := tokenCounter(cfg)
+		tally = func(content []byte) {
+			lines += countLines(content)
+			tokenCount += count(content)
+		}
+	}
+
+	// --manifest lists what was read, after the last file
+	var manifest []output.ManifestEntry
+	var unreadable []string
+	mw, hasManifest := f.(output.ManifestWriter)
+	if cfg.ManifestOnly && !hasManifest {
+		return nil, fmt.Errorf("format %s cannot render a manifest", cmp.Or(cfg.Format, "plain"))
+	}
+
+	if cfg.ShowTree {
+		// Modification times would differ between checkouts
+		if cfg.TreeFormat == output.TreeJSON || cfg.TreeShowExcluded {
+			nw, ok := f.(output.NodeTreeWriter)
+			if !ok {
+				return nil, fmt.Errorf("format %s cannot render --tree-format json or --tree-show-excluded", cmp.Or(cfg.Format, "plain"))
+			}
+			err = nw.WriteTreeNodes(&buf, output.BuildTree(opts.Paths, files, doc.ids, b.excluded), cmp.Or(cfg.TreeFormat, output.TreeText))
+		} else if lw, ok := f.(output.ListingWriter); ok && cfg.Long && !cfg.Deterministic {
+			err = lw.WriteListing(&buf, b.listing(files))
+		} else if tw, ok := f.(output.IDTreeWriter); ok && doc.ids != nil {
+			err = tw.WriteTreeIDs(&buf, opts.Paths, files, doc.ids)
+		} else {
+			err = f.WriteTree(&buf, opts.Paths, files)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := doc.endSection(&buf); err != nil {
+			return nil, err
+		}
+	}
+
+	if !cfg.OnlyTree {
+		diffOf := func(string) []byte { return nil }
+		if cfg.WithDiff != "" {
+			diffOf = fileDiffer(cfg.WithDiff)
+		}
+
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		b.progress.start("read")
+		contents := readFiles(ctx, files, cfg, filters, b.progress)
+
+		// --group-by headers go before the first file of each group
+		gw, _ := f.(output.GroupWriter)
+		if groups == nil || cfg.ManifestOnly {
+			gw = nil
+		}
+
+		for i, file := range files {
+			var content fileContent
+			select {
+			case content = <-contents[i]:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			if errors.Is(content.err, ErrChanged) {
+				if cfg.Strict {
+					return nil, fmt.Errorf("%s: %w", b.labelFor(file), ErrChanged)
+				}
+				fmt.Fprintf(b.warn, "Warning: %s was removed or changed during the run; copied a placeholder\n", b.labelFor(file))
+			}
+			if content.warning != "" {
+				fmt.Fprintf(b.warn, "Warning: %s: %s; copied as it is\n", b.labelFor(file), content.warning)
+			}
+			if cfg.Manifest && content.err == nil {
+				manifest = append(manifest, output.ManifestEntry{Path: b.labelFor(file), Size: content.size, SHA256: content.sum})
+			}
+			if cfg.ManifestOnly {
+				continue
+			}
+			b.report.file(b.labelFor(file), b.entries[file].Size, content.data, content.err)
+			if gw != nil && (i == 0 || groups[i] != groups[i-1]) {
+				if err := gw.WriteGroup(&buf, groups[i]); err != nil {
+					return nil, err
+				}
+			}
+			diff := diffOf(file)
+
+			// --diff-only replaces the content of changed files with their diff
+			if !cfg.DiffOnly || len(diff) == 0 {
+				section := output.File{Path: b.labelFor(file), ID: doc.ids[file]}
+				if cfg.GitMeta {
+					section.Meta = gitMeta(file)
+				}
+				if cfg.GrepCounts && b.matches != nil {
+					section.Meta = strings.Join(slices.DeleteFunc([]string{section.Meta, matchCount(b.matches[file])}, func(s string) bool { return s == "" }), "; ")
+				}
+				section.Content = content.data
+				section.Image = content.image
+				section.Removed = errors.Is(content.err, ErrChanged)
+				section.Unreadable = content.err != nil && !section.Removed
+				if section.Unreadable {
+					b.unreadable(&section, content.err, b.entries[file].Size)
+					unreadable = append(unreadable, section.Path)
+					doc.problems = append(doc.problems, fmt.Sprintf("Could not read %s: %s", section.Path, section.Error))
+				}
+				tally(section.Content)
+				if err := doc.writeFile(f, &buf, section); err != nil {
+					return nil, err
+				}
+			}
+
+			if len(diff) > 0 {
+				// A secret on a changed line must not survive in the diff
+				diff = output.ApplyFilters(redact, file, diff)
+				section := output.File{Path: b.labelFor(file), Content: diff, DiffRef: cfg.WithDiff}
+				if err := doc.writeFile(f, &buf, section); err != nil {
+					return nil, err
+				}
+			}
+			if err := doc.endSection(&buf); err != nil {
+				return nil, err
+			}
+		}
+		if gw != nil && len(files) > 0 {
+			if err := gw.WriteGroup(&buf, ""); err != nil {
+				return nil, err
+			}
+		}
+
+		for _, url := range urls {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			section := output.File{Path: url}
+			data, truncated, err := remote.Fetch(ctx, url, cfg.URLTimeout, cfg.URLMaxSize)
+			if err == nil && cfg.Manifest {
+				manifest = append(manifest, output.ManifestEntry{Path: url, Size: int64(len(data)), SHA256: sha256Hex(data)})
+			}
+			if err == nil && cfg.ManifestOnly {
+				continue
+			}
+			if err != nil {
+				fmt.Fprintf(b.warn, "Warning: Could not fetch %s: %v\n", url, err)
+				section.Unreadable = true
+				b.unreadable(&section, err, 0)
+				doc.problems = append(doc.problems, fmt.Sprintf("Could not fetch %s: %v", url, err))
+			} else {
+				if head, tail := cfg.headTail(url); head > 0 || tail > 0 {
+					data = output.HeadTail(head, tail)(url, data)
+				}
+				section.Content = output.ApplyFilters(filters, url, data)
+				tally(section.Content)
+				if truncated {
+					section.Content = fmt.Appendf(section.Content, "\n[truncated at %d bytes]\n", cfg.URLMaxSize)
+				}
+			}
+			b.report.file(url, int64(len(data)), section.Content, err)
+			if err := doc.writeFile(f, &buf, section); err != nil {
+				return nil, err
+			}
+			if err := doc.endSection(&buf); err != nil {
+				return nil, err
+			}
+		}
+
+		// --run output comes last, after the source it is about
+		for _, command := range cfg.Run {
+			if cfg.ManifestOnly {
+				break
+			}
+			section := output.File{Path: RunPrefix + command}
+			data, err := runCommand(ctx, command)
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			if err != nil {
+				fmt.Fprintf(b.warn, "Warning: Could not run %s: %v\n", command, err)
+				section.Unreadable = true
+				b.unreadable(&section, err, 0)
+				doc.problems = append(doc.problems, fmt.Sprintf("Could not run %s: %v", command, err))
+			} else {
+				if head, tail := cfg.headTail(section.Path); head > 0 || tail > 0 {
+					data = output.HeadTail(head, tail)(section.Path, data)
+				}
+				section.Content = output.ApplyFilters(filters, section.Path, data)
+				tally(section.Content)
+			}
+			if err := doc.writeFile(f, &buf, section); err != nil {
+				return nil, err
+			}
+			if err := doc.endSection(&buf); err != nil {
+				return nil, err
+			}
+		}
+
+		if cfg.EnvInfo && !cfg.ManifestOnly {
+			section := output.File{Path: EnvInfoLabel}
+			section.Content = output.ApplyFilters(filters, section.Path, envInfo(cfg.EnvVars))
+			tally(section.Content)
+			if err := doc.writeFile(f, &buf, section); err != nil {
+				return nil, err
+			}
+			if err := doc.endSection(&buf); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// URLs and --run commands were warned about one by one
+	if len(unreadable) > 0 {
+		names := strings.Join(unreadable[:min(len(unreadable), 3)], ", ")
+		if len(unreadable) > 3 {
+			names += ", ..."
+		}
+		noun := "files"
+		if len(unreadable) == 1 {
+			noun = "file"
+		}
+		fmt.Fprintf(b.warn, "Warning: %d %s could not be read (%s); copied a placeholder instead\n", len(unreadable), noun, names)
+	}
+
+	if hasManifest && cfg.Manifest {
+		if err := mw.WriteManifest(&buf, manifest); err != nil {
+			return nil, err
+		}
+		if err := doc.endSection(&buf); err != nil {
+			return nil, err
+		}
+	}
+
+	if sw, ok := f.(output.SummaryWriter); ok && cfg.Summary {
+		// Totals are only known now, so the summary is inserted afterwards
+		var summary bytes.Buffer
+		if err := sw.WriteSummary(&summary, b.summary(files, urls, lines, tokenCount)); err != nil {
+			return nil, err
+		}
+		doc.insert(&buf, summaryAt, summary.Bytes())
+	}
+
+	if err := f.EndDocument(&buf); err != nil {
+		return nil, err
+	}
+	if len(doc.sectionEnds) == 0 || doc.sectionEnds[len(doc.sectionEnds)-1] != doc.pos(&buf) {
+		doc.sectionEnds = append(doc.sectionEnds, doc.pos(&buf))
+	}
+
+	if out != nil && doc.out == nil {
+		doc.stream(out, tokenCounter(cfg))
+	}
+	if doc.out != nil {
+		return doc, doc.flush(&buf)
+	}
+	doc.data = buf.Bytes()
+	return doc, nil
+}
+
+// strictError lists the problems of doc when --strict is on.
+func (b *Bundler) strictError(doc *document) error {
+	if !b.cfg.Strict || len(doc.problems) == 0 {
+		return nil
+	}
+	return &StrictError{Problems: doc.problems}
+}
+
+// unreadable fills in why section could not be read, for the placeholder
+// and the structured formats: the error without the path an *fs.PathError
+// repeats, and the size on disk when known.
+func (b *Bundler) unreadable(section *output.File, err error, size int64) {
+	section.Error = err.Error()
+	if pathErr := (*fs.PathError)(nil); errors.As(err, &pathErr) {
+		section.Error = pathErr.Err.Error()
+	}
+	section.Size = size
+	section.Placeholder = b.cfg.UnreadablePlaceholder
+}
+
+// pathList renders the --paths-only document: one label or URL per line.
+func (b *Bundler) pathList(files, urls []string, out io.Writer) (*document, error) {
+	var buf bytes.Buffer
+	doc := &document{files: files, urls: urls}
+	for _, file := range files {
+		buf.WriteString(b.labelFor(file) + "\n")
+		doc.sectionEnds = append(doc.sectionEnds, buf.Len())
+	}
+	for _, url := range urls {
+		buf.WriteString(url + "\n")
+		doc.sectionEnds = append(doc.sectionEnds, buf.Len())
+	}
+	if out != nil {
+		doc.stream(out, tokenCounter(&b.cfg))
+		return doc, doc.flush(&buf)
+	}
+	doc.data = buf.Bytes()
+	return doc, nil
+}
+
+// summary describes the document for --summary; lines and tokenCount are
+// the totals over the file and URL contents.
+func (b *Bundler) summary(files, urls []string, lines, tokenCount int) output.Summary {
+	cfg := &b.cfg
+	s := output.Summary{
+		Generated: time.Now(),
+		Version:   Version,
+		Roots:     cfg.Paths,
+		Files:     len(files) + len(urls),
+		Lines:     lines,
+		Tokens:    tokenCount,
+		Model:     cfg.Model,
+		Excludes:  slices.Clone(cfg.Excludes),
+	}
+	if s.Model == "" {
+		s.Model = tokens.DefaultModel
+	}
+	if cfg.Deterministic {
+		s.Generated = time.Time{}
+		s.Roots = make([]string, len(cfg.Paths))
+		for i, path := range cfg.Paths {
+			s.Roots[i] = b.normalize(path)
+		}
+	}
+	if b.dir != "" {
+		// Show the roots within the gh checkout or served directory, whose
+		// own path means nothing to the reader
+		s.Roots = make([]string, len(cfg.Paths))
+		for i, path := range cfg.Paths {
+			if rel, err := filepath.Rel(b.dir, path); err == nil {
+				path = rel
+			}
+			s.Roots[i] = filepath.ToSlash(path)
+		}
+	}
+	if cfg.GitHub != "" {
+		s.Roots = append([]string{cfg.GitHub}, s.Roots...)
+	}
+	for _, file := range cfg.ExcludeFiles {
+		s.Excludes = append(s.Excludes, "from "+b.normalize(file))
+	}
+	if !cfg.NoDefaultExcludes && len(cfg.DefaultExcludes) > 0 {
+		s.Excludes = append(s.Excludes, "default excludes")
+	}
+	return s
+}
+
+// labelFor is the name shown for file: its label, normalized under
+// --deterministic.
+func (b *Bundler) labelFor(file string) string {
+	return b.normalize(b.label(file))
+}
+
+// normalize makes path relative to workDir with forward
+// slashes under --deterministic, so the output does not depend on where
+// the tree is checked out or on the OS; otherwise path is kept.
+func (b *Bundler) normalize(path string) string {
+	if !b.cfg.Deterministic || remote.IsURL(path) {
+		return path
+	}
+	if filepath.IsAbs(path) {
+		if wd := b.workDir(); wd != "" {
+			if rel, err := filepath.Rel(wd, path); err == nil {
+				path = rel
+			}
+		}
+	}
+	return filepath.ToSlash(path)
+}
+
+// workDir is the directory collected paths are shown relative to: the
+// withDir checkout, or else the working directory.
+func (b *Bundler) workDir() string {
+	if b.dir != "" {
+		return b.dir
+	}
+	wd, _ := os.Getwd()
+	return wd
+}
+
+// sortFiles orders files by --sort and --collate, then by --smart-order.
+// Under --deterministic they are ordered by their normalized labels instead
+// of their absolute paths.
+func (b *Bundler) sortFiles(files []string) {
+	b.sortPaths(files)
+	if b.cfg.SmartOrder {
+		smartOrder(files)
+	}
+}
+
+func (b *Bundler) sortPaths(files []string) {
+	if !b.cfg.Deterministic {
+		output.SortPathsCollated(files, b.cfg.Sort, b.cfg.Collate)
+		return
+	}
+	byLabel := make(map[string]string, len(files))
+	labels := make([]string, len(files))
+	for i, file := range files {
+		labels[i] = b.labelFor(file)
+		byLabel[labels[i]] = file
+	}
+	if len(byLabel) != len(files) {
+		// Labels are not unique, so they cannot stand in for the paths
+		output.SortPathsCollated(files, b.cfg.Sort, b.cfg.Collate)
+		return
+	}
+	output.SortPathsCollated(labels, b.cfg.Sort, b.cfg.Collate)
+	for i, label := range labels {
+		files[i] = byLabel[label]
+	}
+}
+
+// capFiles sorts files and splits off those beyond --max-files, warning
+// about what was left out.
+func (b *Bundler) capFiles(files []string) (kept, dropped []string) {
+	b.sortFiles(files)
+	n := b.cfg.MaxFiles
+	if n <= 0 || len(files) <= n {
+		return files, nil
+	}
+	kept, dropped = files[:n], files[n:]
+
+	var names []string
+	for _, file := range dropped[:min(len(dropped), 3)] {
+		names = append(names, b.labelFor(file))
+	}
+	if len(dropped) > len(names) {
+		names = append(names, "...")
+	}
+	fmt.Fprintf(b.warn, "Warning: --max-files %d: left out %d of %d files (%s)\n", n, len(dropped), len(files), strings.Join(names, ", "))
+	return kept, dropped
+}
+
+// dedupe leaves out files whose bytes match a file before them, or one an
+// earlier run copied. Files that cannot be read are kept, for the read to
+// report.
+func (b *Bundler) dedupe(files []string) []string {
+	seen := maps.Clone(b.seen)
+	if seen == nil {
+		seen = make(map[string]string)
+	}
+	return slices.DeleteFunc(files, func(file string) bool {
+		f, err := os.Open(file)
+		if err != nil {
+			return false
+		}
+		defer f.Close()
+		sum := sha256.New()
+		if _, err := io.Copy(sum, f); err != nil {
+			return false
+		}
+		key := hex.EncodeToString(sum.Sum(nil))
+		if first, ok := seen[key]; ok {
+			b.report.skip(b.labelFor(file), "--dedupe-content: same as "+first)
+			return true
+		}
+		seen[key] = b.labelFor(file)
+		return false
+	})
+}
+
+func countLines(content []byte) int {
+	n := bytes.Count(content, []byte("\n"))
+	if len(content) > 0 && content[len(content)-1] != '\n' {
+		n++
+	}
+	return n
+}
+
+// insert puts data into buf at offset and moves the recorded section ends
+// and spans after it along.
+func (doc *document) insert(buf *bytes.Buffer, offset int, data []byte) {
+	rest := slices.Clone(buf.Bytes()[offset:])
+	buf.Truncate(offset)
+	buf.Write(data)
+	buf.Write(rest)
+	for i := range doc.sectionEnds {
+		doc.sectionEnds[i] += len(data)
+	}
+	for i := range doc.spans {
+		doc.spans[i].Start += len(data)
+		doc.spans[i].End += len(data)
+	}
+}
+
+// listing describes files for a --long listing as they were collected;
+// files that could not be stat'd are left out.
+func (b *Bundler) listing(files []string) []output.Entry {
+	var entries []output.Entry
+	for _, file := range files {
+		e, ok := b.entries[file]
+		if !ok || e.ModTime.IsZero() {
+			continue
+		}
+		entries = append(entries, output.Entry{
+			Path:    b.labelFor(file),
+			Mode:    e.Mode,
+			Size:    e.Size,
+			ModTime: e.ModTime,
+		})
+	}
+	return entries
+}
+
+// redaction returns the --redact and --redact-pattern filter, if any. It
+// also runs on --with-diff sections, which the other filters would treat
+// as the file itself.
+func (b *Bundler) redaction() ([]output.Filter, error) {
+	var patterns []string
+	if b.cfg.Redact {
+		patterns = append(patterns, output.SecretPatterns...)
+	}
+	patterns = append(patterns, b.cfg.RedactPatterns...)
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+	res, err := output.CompilePatterns(patterns)
+	if err != nil {
+		return nil, fmt.Errorf("redaction: %w", err)
+	}
+	return []output.Filter{output.Redact(res...)}, nil
+}
+
+// filterChain builds the content filters selected by the config, with any
+// WithFilters filters in the middle.
+func (b *Bundler) filterChain() ([]output.Filter, error) {
+	cfg := &b.cfg
+	var filters []output.Filter
+	// Commands see the content as it is on disk
+	var warnMu sync.Mutex
+	for _, command := range cfg.FilterCmds {
+		run := output.CommandHandler(command)
+		filters = append(filters, func(path string, content []byte) []byte {
+			filtered, err := run(path, content, output.HandlerOptions{})
+			if err != nil {
+				// Files may be secret, so a failing command withholds them
+				warnMu.Lock()
+				fmt.Fprintf(b.warn, "Warning: %s: --filter-cmd: %v; content left out\n", b.labelFor(path), err)
+				warnMu.Unlock()
+				return []byte(output.WithheldPlaceholder + "\n")
+			}
+			return filtered.Data
+		})
+	}
+	// Excerpts come first so their line numbers are the file's
+	excerpted := func(string) bool { return false }
+	if cfg.Excerpt {
+		// Referenced lines win over --grep matches
+		references := output.Excerpt(b.excerpts, cfg.ContextLines)
+		matches := func(path string, content []byte) []byte { return content }
+		if b.matches != nil {
+			re, err := grepPattern(cfg)
+			if err != nil {
+				return nil, err
+			}
+			matches = output.ExcerptMatches(re, cfg.ContextLines)
+		}
+		filters = append(filters, func(path string, content []byte) []byte {
+			if _, ok := b.excerpts[path]; ok {
+				return references(path, content)
+			}
+			return matches(path, content)
+		})
+		excerpted = func(path string) bool {
+			if spans, ok := b.excerpts[path]; ok {
+				return !slices.ContainsFunc(spans, func(r output.LineRange) bool { return r.Start <= 0 })
+			}
+			return b.matches[path] > 0
+		}
+	}
+	if cfg.JSON != "" && cfg.JSON != output.JSONKeep {
+		filters = append(filters, output.ReformatData(cfg.JSON))
+	}
+	if cfg.StripComments {
+		filters = append(filters, output.StripComments)
+	}
+	redact, err := b.redaction()
+	if err != nil {
+		return nil, err
+	}
+	filters = append(filters, redact...)
+	filters = append(filters, b.filters...)
+	if cfg.MaxLines > 0 {
+		filters = append(filters, output.Truncate(cfg.MaxLines))
+	}
+	if cfg.LineNumbers {
+		// Excerpts are numbered already
+		filters = append(filters, func(path string, content []byte) []byte {
+			if excerpted(path) {
+				return content
+			}
+			return output.NumberLines(path, content)
+		})
+	}
+	return filters, nil
+}
\ No newline at end of file
@@ -0,0 +1,185 @@
+package clipcat
+
+import (
+	"clipcat/pkg/config"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Commands lists the subcommands; any other first argument is a path for copy.
+var Commands = []string{"copy", "tree", "gh", "explain", "stats", "llms-txt", "ask", "expand", "pick", "history", "rerun", "diff", "audit", "set", "serve", "unpack", "profiles", "completion", "version", "help"}
+
+// Execute runs the command line args (without the program name).
+func Execute(args []string) error {
+	args, err := ExpandArgFiles(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	if len(args) > 0 {
+		switch args[0] {
+		case "help":
+			fmt.Print(usage)
+			return nil
+		case "unpack":
+			return Unpack(ParseUnpackArgs(args[1:]))
+		case "serve":
+			return Serve(ParseServeArgs(args[1:]))
+		case "bench":
+			// Hidden: times the pipeline on a real tree, for performance work
+			if len(args) == 1 {
+				args = append(args, ".")
+			}
+			return Bench(parseArgs(args[1:]), os.Stdout)
+		case "profiles":
+			return Profiles(args[1:], os.Stdout)
+		case "history":
+			return History(args[1:], os.Stdout)
+		case "rerun":
+			return Rerun(args[1:])
+		case "diff":
+			return Diff(args[1:], os.Stdout)
+		case "audit":
+			return Audit(args[1:], os.Stdout)
+		case "set":
+			return Sets(args[1:], os.Stdout)
+		case "completion":
+			if len(args) != 2 {
+				fmt.Fprintf(os.Stderr, "Error: completion requires a shell: bash, zsh or fish\n")
+				os.Exit(2)
+			}
+			return Completion(args[1], os.Stdout)
+		}
+	}
+	return Run(parseArgs(args))
+}
+
+// Profiles implements `clipcat profiles [list | show NAME]`.
+func Profiles(args []string, w io.Writer) error {
+	conf, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 0 || (len(args) == 1 && args[0] == "list") {
+		names := conf.ProfileNames()
+		if len(names) == 0 {
+			fmt.Fprintf(w, "No profiles defined. Add [profiles.NAME] tables to %s or %s.\n", config.UserPath(), config.ProjectFile)
+			return nil
+		}
+		width := 0
+		for _, name := range names {
+			width = max(width, len(name))
+		}
+		for _, name := range names {
+			p := conf.Profiles[name]
+			desc := p.Description
+			if desc == "" {
+				desc = strings.Join(p.Args, " ")
+			}
+			fmt.Fprintf(w, "%-*s  %s\n", width, name, desc)
+		}
+		return nil
+	}
+
+	if len(args) == 2 && args[0] == "show" {
+		p, ok := conf.Profiles[args[1]]
+		if !ok {
+			return fmt.Errorf("unknown profile %q", args[1])
+		}
+		fmt.Fprintf(w, "name:        %s\n", p.Name)
+		if p.Description != "" {
+			fmt.Fprintf(w, "description: %s\n", p.Description)
+		}
+		fmt.Fprintf(w, "source:      %s\n", p.Source)
+		fmt.Fprintf(w, "args:        %s\n", strings.Join(p.Args, " "))
+		return nil
+	}
+
+	fmt.Fprintf(os.Stderr, "Usage: clipcat profiles [list | show NAME]\n")
+	os.Exit(2)
+	return nil
+}
+
+type flagInfo struct {
+	Short, Long, Arg, Desc string
+}
+
+var usageFlagRe = regexp.MustCompile(`^  (?:-(\w), )?\s*--([\w-]+)(?: ([A-Z]+))?\s+(.*)$`)
+
+// usageFlags extracts the options from the help text so completions stay in
+// sync with it.
+func usageFlags() []flagInfo {
+	var flags []flagInfo
+	_, opts, _ := strings.Cut(usage, "\nOptions:\n")
+	opts, _, _ = strings.Cut(opts, "\n\n")
+	for _, line := range strings.Split(opts, "\n") {
+		if m := usageFlagRe.FindStringSubmatch(line); m != nil {
+			flags = append(flags, flagInfo{Short: m[1], Long: m[2], Arg: m[3], Desc: m[4]})
+		}
+	}
+	return flags
+}
+
+// Completion writes a completion script for shell to w.
+func Completion(shell string, w io.Writer) error {
+	flags := usageFlags()
+	var words []string
+	for _, f := range flags {
+		if f.Short != "" {
+			words = append(words, "-"+f.Short)
+		}
+		words = append(words, "--"+f.Long)
+	}
+	commands := strings.Join(Commands, " ")
+
+	switch shell {
+	case "bash":
+		fmt.Fprintf(w, `# bash completion for clipcat; load with: source <(clipcat completion bash)
+_clipcat() {
+    local cur="${COMP_WORDS[COMP_CWORD]}"
+    if [[ "$cur" == -* ]]; then
+        COMPREPLY=($(compgen -W "%s" -- "$cur"))
+    elif [[ $COMP_CWORD -eq 1 ]]; then
+        COMPREPLY=($(compgen -W "%s" -- "$cur") $(compgen -f -- "$cur"))
+    else
+        COMPREPLY=($(compgen -f -- "$cur"))
+    fi
+}
+complete -o filenames -F _clipcat clipcat
+`, strings.Join(words, " "), commands)
+	case "zsh":
+		fmt.Fprintf(w, `#compdef clipcat
+# zsh completion for clipcat; load with: source <(clipcat completion zsh)
+_clipcat() {
+    if [[ $PREFIX == -* ]]; then
+        compadd -- %s
+        return
+    fi
+    (( CURRENT == 2 )) && compadd -- %s
+    _files
+}
+compdef _clipcat clipcat
+`, strings.Join(words, " "), commands)
+	case "fish":
+		fmt.Fprintf(w, "# fish completion for clipcat; load with: clipcat completion fish | source\n")
+		fmt.Fprintf(w, "complete -c clipcat -n __fish_use_subcommand -a '%s'\n", commands)
+		for _, f := range flags {
+			line := "complete -c clipcat -l " + f.Long
+			if f.Short != "" {
+				line += " -s " + f.Short
+			}
+			if f.Arg != "" {
+				line += " -r"
+			}
+			line += " -d '" + strings.ReplaceAll(f.Desc, "'", `\'`) + "'"
+			fmt.Fprintln(w, line)
+		}
+	default:
+		return fmt.Errorf("unsupported shell %q (expected bash, zsh or fish)", shell)
+	}
+	return nil
+}
\ No newline at end of file
@@ -1,26 +1,241 @@
 package clipcat
 
 import (
+	"clipcat/pkg/config"
+	"clipcat/pkg/crypto"
+	"clipcat/pkg/exclude"
+	"clipcat/pkg/lang"
+	"clipcat/pkg/output"
+	"clipcat/pkg/tokens"
+	"clipcat/pkg/unpack"
+	"cmp"
 	"fmt"
 	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
+	"unicode"
 )
 
 type Config struct {
 	Paths        []string
 	Excludes     []string
 	ExcludeFiles []string
+	ForceInclude []string // files copied whatever the excludes and filters say
+	// DefaultExcludes apply while walking directories; ParseArgs fills them
+	// from the config file or exclude.DefaultExcludes
+	DefaultExcludes   []string
+	NoDefaultExcludes bool
 	ShowTree     bool
 	OnlyTree     bool
+	Long         bool // list mode, size and mtime instead of the tree
+	TreeFormat   string // text (default) or json, nested nodes with sizes
+	TreeShowExcluded bool // add excluded files and directories to the tree
+	Summary      bool // prepend how and when the output was produced
+	Stats        bool // print a size breakdown instead of copying
+	DiffAgainst  string // print what changed since this bundle instead of copying
+	Report       string // run report format ("json"), written after the run
+	ReportFile   string // where the report goes instead of stderr
+	Deterministic bool // byte-identical output for identical trees
+	Manifest     bool // append a SHA-256 and size per file
+	ManifestOnly bool // copy only the manifest
+	PathsOnly    bool // copy the list of paths instead of the bundle
+	Relative     bool // show paths relative to the working directory
 	PrintOut     bool
+	Output       string // write the bundle to this file instead of copying it
+	Clipboard    string // copy with this command, or "auto"; with Output, copy as well
+	AppendFile   bool   // append to Output after a run separator instead of replacing it
+	DedupeContent bool  // leave out files whose bytes are already in the output
+	Compress     string // gzip or zstd the Output file, the upload or the Clipboard copy
+	Encrypt      string // age recipient to encrypt the Output file or the upload to
+	Color        string // auto, always or never; only affects --print
+	Rich         bool   // also put highlighted HTML on the clipboard
+	Hold         bool   // keep serving the clipboard selection until Ctrl-C
+	HoldTimeout  time.Duration // stop holding after this long; 0 means never
 	IgnoreCase   bool
+	IgnoreCaseExcludes bool // case-insensitive excludes only, inputs stay exact
+	Git          bool
+	Root         string // search base for glob inputs (default ".")
+	ChangedSince string
+	Staged       bool
+	Unstaged     bool
+	NewerThan    time.Time // keep files modified after this time
+	Languages    []string  // --ext: languages or extensions to keep
+	Types        []string  // --type: categories of files to keep
+	Preset       string    // --preset: auto, none or ecosystems such as go,node
+	OlderThan    time.Time // keep files modified before this time
+	MaxFiles     int       // keep the first N files in --sort order; 0 means no limit
+	Grep         []string // keep files containing one of these strings...
+	GrepRegexps  []string // ...or matching one of these regular expressions
+	GrepCounts   bool     // note the number of matches in each header
+	FromQuickfix    []string // quickfix files ("path:line: message") naming files to add
+	FromDiagnostics []string // JSON diagnostics files naming files to add
+	GoDeps          []string // Go packages to add with the module packages they import
+	GoDepsDepth     int      // import levels --go-deps follows; 0 means all
+	FollowImports   []string // JS/TS entry files to add with the local files they import
+	WithTests       bool     // add the test file of each source file
+	WithSources     bool     // add the source file of each test file
+	Excerpt         bool     // copy only the lines they reference or --grep matches...
+	ContextLines    int      // ...and this many lines around them
+	WithDiff     string
+	DiffOnly     bool
+	GitMeta      bool
+	Run          []string // shell commands whose output is appended as sections
+	EnvInfo      bool     // append an ENVIRONMENT section for bug reports
+	EnvVars      []string // variables reported besides EnvAllowlist
+	// Content filters, applied to each file in this order
+	FilterCmds     []string // shell commands each file is piped through first
+	StripComments  bool
+	Redact         bool
+	RedactPatterns []string
+	HeadLines      int // keep the first N lines of each file or URL, with --tail-lines
+	TailLines      int // keep the last N lines; the lines between are omitted
+	MaxLines       int
+	MaxLinesPerFile int            // keep the first and last N/2 lines of each file or URL
+	MaxLinesByName  map[string]int // [max_lines_per_file] limits by extension or file name
+	LineNumbers    bool
+	InlineImages   bool   // embed images in markdown output instead of a placeholder
+	JSON           string // keep (default), pretty or minify for .json and .yaml files
+	SummarizeLocks bool   // list the dependencies of lockfiles instead of skipping them
+	IncludeMinified bool  // copy minified JavaScript and CSS instead of a placeholder
+	TablePreview   int    // copy CSV/TSV files as the header and this many rows; 0 copies them whole
+	Notebook       string // render (default), raw or skip for .ipynb files
+	UnreadablePlaceholder string // text for files that could not be read, with {path}, {error} and {size}
+	Jobs           int // concurrent file reads; 0 means runtime.NumCPU()
+	URLTimeout   time.Duration
+	Timeout      time.Duration // abort the whole run after this long; 0 means never
+	URLMaxSize   int64
+	GitHub       string
+	Upload       string
+	SplitSize    int64
+	SplitTokens  int
+	Model        string // tokenizer for token estimates, see tokens.ForModel
+	SplitOutput  string
+	ConfirmOver  int64
+	// ClipboardLimits overrides clipboard.DefaultLimits per backend; 0
+	// means no limit
+	ClipboardLimits map[string]int64
+	PreCopy      string // [hooks] command that can veto a copy
+	PostCopy     string // [hooks] command run after a copy
+	AuditLog     string // file each copy is recorded in, from the [audit] table
+	Force        bool
+	Strict       bool // fail when a file is removed or changed mid-run, and after copying when inputs were skipped or unreadable
+	Explain      []string
+	Ask          string // question for `clipcat ask`; the bundle is sent instead of copied
+	Sort         string
+	Collate      string // how names compare: byte (default), unicode or locale
+	SmartOrder   bool   // READMEs, manifests, entrypoints and imported files first
+	ShowVersion  bool
+	Format       string
+	GroupBy      string // none (default), dir, ext or root
+	IDs          bool     // give each file a short ID for citations
+	Expand       []string // `clipcat expand`: IDs from the last --ids run to copy
+	Pick         bool     // `clipcat pick`: choose the files in a fuzzy finder
+	Args         []string // the command line, remembered by `clipcat history`
+	Template     string // Go template file rendering the whole document, instead of Format
 }
 
+// ParseArgs parses os.Args for the copy-style commands (copy, tree, gh,
+// version); a bare `clipcat PATHS...` is the same as `clipcat copy PATHS...`.
 func ParseArgs() *Config {
-	cfg := &Config{}
+	args, err := ExpandArgFiles(os.Args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	return parseArgs(args)
+}
+
+func parseArgs(args []string) *Config {
+	cfg := &Config{
+		URLTimeout:  30 * time.Second,
+		URLMaxSize:  10 << 20,
+		ConfirmOver: 5 << 20,
+		Args:        slices.Clone(args),
+	}
+
+	if len(args) > 0 {
+		switch args[0] {
+		case "copy":
+			args = args[1:]
+		case "tree":
+			// `clipcat tree` is the same as --only-tree
+			cfg.ShowTree = true
+			cfg.OnlyTree = true
+			args = args[1:]
+		case "version":
+			// `clipcat version` is the same as --version
+			cfg.ShowVersion = true
+			args = args[1:]
+		case "explain":
+			// `clipcat explain PATH [OPTIONS] [INPUTS...]` is the same as --explain PATH
+			if len(args) < 2 || strings.HasPrefix(args[1], "-") {
+				fmt.Fprintf(os.Stderr, "Error: explain requires a path\n")
+				os.Exit(2)
+			}
+			cfg.Explain = append(cfg.Explain, args[1])
+			args = args[2:]
+		case "gh":
+			// `clipcat gh SPEC [PATTERNS...]` collects from a fetched repository
+			if len(args) < 2 || strings.HasPrefix(args[1], "-") {
+				fmt.Fprintf(os.Stderr, "Error: gh requires owner/repo[/path][@ref] or gist:ID\n")
+				os.Exit(2)
+			}
+			cfg.GitHub = args[1]
+			args = args[2:]
+		case "stats":
+			// `clipcat stats` is the same as --stats-only
+			cfg.Stats = true
+			args = args[1:]
+		case "llms-txt":
+			// `clipcat llms-txt` is the same as --format llms-txt
+			cfg.Format = "llms-txt"
+			args = args[1:]
+		case "expand":
+			// `clipcat expand ID... [OPTIONS]` re-copies files of the last --ids run
+			for len(args) > 1 && idRe.MatchString(args[1]) {
+				cfg.Expand = append(cfg.Expand, args[1])
+				args = args[1:]
+			}
+			if len(cfg.Expand) == 0 {
+				fmt.Fprintf(os.Stderr, "Error: expand requires file IDs such as F12\n")
+				os.Exit(2)
+			}
+			args = args[1:]
+		case "pick":
+			// `clipcat pick [DIR] [OPTIONS]` copies the files chosen in a fuzzy finder
+			cfg.Pick = true
+			args = args[1:]
+		case "ask":
+			// `clipcat ask QUESTION [OPTIONS] [PATHS...]` sends the bundle to an LLM API
+			if len(args) < 2 || strings.TrimSpace(args[1]) == "" {
+				fmt.Fprintf(os.Stderr, "Error: ask requires a question\n")
+				os.Exit(2)
+			}
+			cfg.Ask = args[1]
+			args = args[2:]
+		}
+	}
+
+	// Config files are read on first use
+	var conf *config.Config
+	loadConfig := func() *config.Config {
+		if conf == nil {
+			var err error
+			if conf, err = config.Load(); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(2)
+			}
+		}
+		return conf
+	}
+
+	args = expandProfiles(args, loadConfig)
 
 	// Manual argument parsing to allow intermixed flags and paths
-	args := os.Args[1:]
 
 	for i := 0; i < len(args); i++ {
 		arg := args[i]
@@ -29,6 +244,48 @@ func ParseArgs() *Config {
 		case "-h", "--help":
 			printUsage()
 			os.Exit(0)
+		case "--version":
+			cfg.ShowVersion = true
+		case "--format":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --format requires a format\n")
+				os.Exit(2)
+			}
+			cfg.Format = args[i+1]
+			i++
+		case "--group-by":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --group-by requires none, dir, ext or root\n")
+				os.Exit(2)
+			}
+			if !slices.Contains([]string{GroupNone, GroupDir, GroupExt, GroupRoot}, args[i+1]) {
+				fmt.Fprintf(os.Stderr, "Error: invalid --group-by %q: expected none, dir, ext or root\n", args[i+1])
+				os.Exit(2)
+			}
+			cfg.GroupBy = args[i+1]
+			i++
+		case "--ids":
+			cfg.IDs = true
+		case "--template":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --template requires a file\n")
+				os.Exit(2)
+			}
+			cfg.Template = args[i+1]
+			i++
+		case "--paths-from":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --paths-from requires a file (- for stdin)\n")
+				os.Exit(2)
+			}
+			paths, excludes, err := readPathList(args[i+1])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: --paths-from: %v\n", err)
+				os.Exit(2)
+			}
+			cfg.Paths = append(cfg.Paths, paths...)
+			cfg.Excludes = append(cfg.Excludes, excludes...)
+			i++
 		case "-e", "--exclude":
 			if i+1 >= len(args) {
 				fmt.Fprintf(os.Stderr, "Error: %s requires a pattern\n", arg)
@@ -36,6 +293,13 @@ func ParseArgs() *Config {
 			}
 			cfg.Excludes = append(cfg.Excludes, args[i+1])
 			i++
+		case "--force-include":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --force-include requires a file\n")
+				os.Exit(2)
+			}
+			cfg.ForceInclude = append(cfg.ForceInclude, args[i+1])
+			i++
 		case "--exclude-from":
 			if i+1 >= len(args) {
 				fmt.Fprintf(os.Stderr, "Error: --exclude-from requires a file\n")
@@ -43,15 +307,582 @@ func ParseArgs() *Config {
 			}
 			cfg.ExcludeFiles = append(cfg.ExcludeFiles, args[i+1])
 			i++
+		case "--explain":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --explain requires a path\n")
+				os.Exit(2)
+			}
+			cfg.Explain = append(cfg.Explain, args[i+1])
+			i++
+		case "--no-default-excludes":
+			cfg.NoDefaultExcludes = true
+		case "--sort":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --sort requires an order\n")
+				os.Exit(2)
+			}
+			if args[i+1] != output.SortNatural && args[i+1] != output.SortLexical {
+				fmt.Fprintf(os.Stderr, "Error: invalid --sort %q: expected natural or lexical\n", args[i+1])
+				os.Exit(2)
+			}
+			cfg.Sort = args[i+1]
+			i++
+		case "--smart-order":
+			cfg.SmartOrder = true
+		case "--collate":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --collate requires a collation\n")
+				os.Exit(2)
+			}
+			if !slices.Contains(output.Collations, args[i+1]) {
+				fmt.Fprintf(os.Stderr, "Error: invalid --collate %q: expected %s\n", args[i+1], strings.Join(output.Collations, ", "))
+				os.Exit(2)
+			}
+			cfg.Collate = args[i+1]
+			i++
+		case "--tree-format":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --tree-format requires a format\n")
+				os.Exit(2)
+			}
+			if !slices.Contains(output.TreeFormats, args[i+1]) {
+				fmt.Fprintf(os.Stderr, "Error: invalid --tree-format %q: expected %s\n", args[i+1], strings.Join(output.TreeFormats, ", "))
+				os.Exit(2)
+			}
+			cfg.TreeFormat = args[i+1]
+			cfg.ShowTree = cfg.ShowTree || cfg.TreeFormat == output.TreeJSON
+			i++
+		case "--tree-show-excluded":
+			cfg.ShowTree = true
+			cfg.TreeShowExcluded = true
 		case "-t", "--tree":
 			cfg.ShowTree = true
 		case "--only-tree":
 			cfg.ShowTree = true
 			cfg.OnlyTree = true
+		case "--paths-only":
+			cfg.PathsOnly = true
+		case "--relative":
+			cfg.Relative = true
+		case "--summary":
+			cfg.Summary = true
+		case "--stats-only":
+			cfg.Stats = true
+		case "--report":
+			if i+1 >= len(args) || args[i+1] != "json" {
+				fmt.Fprintf(os.Stderr, "Error: --report requires a format: json\n")
+				os.Exit(2)
+			}
+			cfg.Report = args[i+1]
+			i++
+		case "--report-file":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --report-file requires a file\n")
+				os.Exit(2)
+			}
+			cfg.Report, cfg.ReportFile = "json", args[i+1]
+			i++
+		case "--diff-against":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --diff-against requires a bundle file\n")
+				os.Exit(2)
+			}
+			cfg.DiffAgainst = args[i+1]
+			i++
+		case "--deterministic":
+			cfg.Deterministic = true
+		case "--manifest":
+			cfg.Manifest = true
+		case "--manifest-only":
+			cfg.Manifest = true
+			cfg.ManifestOnly = true
+		case "-l", "--long":
+			cfg.ShowTree = true
+			cfg.Long = true
 		case "-p", "--print":
 			cfg.PrintOut = true
+		case "-o", "--output":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --output requires a file\n")
+				os.Exit(2)
+			}
+			cfg.Output = args[i+1]
+			i++
+		case "--compress":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --compress requires %s\n", strings.Join(unpack.Compressions, " or "))
+				os.Exit(2)
+			}
+			if !slices.Contains(unpack.Compressions, args[i+1]) {
+				fmt.Fprintf(os.Stderr, "Error: invalid --compress %q: expected %s\n", args[i+1], strings.Join(unpack.Compressions, " or "))
+				os.Exit(2)
+			}
+			cfg.Compress = args[i+1]
+			i++
+		case "--encrypt":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --encrypt requires age:RECIPIENT\n")
+				os.Exit(2)
+			}
+			recipient, err := crypto.Recipient(args[i+1])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: invalid --encrypt: %v\n", err)
+				os.Exit(2)
+			}
+			cfg.Encrypt = recipient
+			i++
+		case "--append-file":
+			cfg.AppendFile = true
+		case "--dedupe-content":
+			cfg.DedupeContent = true
+		case "--clipboard":
+			if i+1 >= len(args) || strings.TrimSpace(args[i+1]) == "" {
+				fmt.Fprintf(os.Stderr, "Error: --clipboard requires a command, or auto\n")
+				os.Exit(2)
+			}
+			cfg.Clipboard = args[i+1]
+			i++
+		case "--rich":
+			cfg.Rich = true
+		case "--hold":
+			cfg.Hold = true
+		case "--hold-timeout":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --hold-timeout requires a duration\n")
+				os.Exit(2)
+			}
+			d, err := time.ParseDuration(args[i+1])
+			if err != nil || d <= 0 {
+				fmt.Fprintf(os.Stderr, "Error: invalid --hold-timeout %q: expected a positive duration such as 30s or 2m\n", args[i+1])
+				os.Exit(2)
+			}
+			cfg.HoldTimeout = d
+			i++
+		case "--color":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --color requires auto, always or never\n")
+				os.Exit(2)
+			}
+			if !slices.Contains([]string{"auto", "always", "never"}, args[i+1]) {
+				fmt.Fprintf(os.Stderr, "Error: invalid --color %q: expected auto, always or never\n", args[i+1])
+				os.Exit(2)
+			}
+			cfg.Color = args[i+1]
+			i++
 		case "-i", "--ignore-case":
 			cfg.IgnoreCase = true
+		case "--ignore-case-excludes":
+			cfg.IgnoreCaseExcludes = true
+		case "--git":
+			cfg.Git = true
+		case "--root":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --root requires a directory\n")
+				os.Exit(2)
+			}
+			cfg.Root = args[i+1]
+			i++
+		case "--changed-since":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --changed-since requires a git ref\n")
+				os.Exit(2)
+			}
+			cfg.ChangedSince = args[i+1]
+			i++
+		case "--staged":
+			cfg.Staged = true
+		case "--unstaged":
+			cfg.Unstaged = true
+		case "--ext":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --ext requires a list of languages or extensions\n")
+				os.Exit(2)
+			}
+			for _, name := range strings.Split(args[i+1], ",") {
+				if name = strings.TrimSpace(name); name != "" {
+					cfg.Languages = append(cfg.Languages, name)
+				}
+			}
+			i++
+		case "--preset":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --preset requires a name\n")
+				os.Exit(2)
+			}
+			for _, name := range strings.Split(args[i+1], ",") {
+				if !slices.Contains(PresetNames(), name) {
+					fmt.Fprintf(os.Stderr, "Error: invalid --preset %q: expected %s\n", name, strings.Join(PresetNames(), ", "))
+					os.Exit(2)
+				}
+			}
+			cfg.Preset = args[i+1]
+			i++
+		case "--type":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --type requires a list of categories\n")
+				os.Exit(2)
+			}
+			for _, name := range strings.Split(args[i+1], ",") {
+				if name = strings.ToLower(strings.TrimSpace(name)); name == "" {
+					continue
+				}
+				if !slices.Contains(lang.Categories, name) {
+					fmt.Fprintf(os.Stderr, "Error: invalid --type %q: expected %s\n", name, strings.Join(lang.Categories, ", "))
+					os.Exit(2)
+				}
+				cfg.Types = append(cfg.Types, name)
+			}
+			i++
+		case "--grep", "--grep-regexp":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: %s requires a pattern\n", args[i])
+				os.Exit(2)
+			}
+			if args[i] == "--grep" {
+				cfg.Grep = append(cfg.Grep, args[i+1])
+			} else {
+				cfg.GrepRegexps = append(cfg.GrepRegexps, args[i+1])
+			}
+			i++
+		case "--table-preview":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --table-preview requires a row count\n")
+				os.Exit(2)
+			}
+			n, err := strconv.Atoi(args[i+1])
+			if err != nil || n <= 0 {
+				fmt.Fprintf(os.Stderr, "Error: invalid --table-preview %q\n", args[i+1])
+				os.Exit(2)
+			}
+			cfg.TablePreview = n
+			i++
+		case "--summarize-locks":
+			cfg.SummarizeLocks = true
+		case "--include-minified":
+			cfg.IncludeMinified = true
+		case "--grep-counts":
+			cfg.GrepCounts = true
+		case "--from-quickfix", "--from-json-diagnostics":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: %s requires a file (- for stdin)\n", args[i])
+				os.Exit(2)
+			}
+			if args[i] == "--from-quickfix" {
+				cfg.FromQuickfix = append(cfg.FromQuickfix, args[i+1])
+			} else {
+				cfg.FromDiagnostics = append(cfg.FromDiagnostics, args[i+1])
+			}
+			i++
+		case "--go-deps":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --go-deps requires a package\n")
+				os.Exit(2)
+			}
+			cfg.GoDeps = append(cfg.GoDeps, args[i+1])
+			i++
+		case "--follow-imports":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --follow-imports requires an entry file\n")
+				os.Exit(2)
+			}
+			cfg.FollowImports = append(cfg.FollowImports, args[i+1])
+			i++
+		case "--with-tests":
+			cfg.WithTests = true
+		case "--with-sources":
+			cfg.WithSources = true
+		case "--go-deps-depth":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --go-deps-depth requires a depth\n")
+				os.Exit(2)
+			}
+			n, err := strconv.Atoi(args[i+1])
+			if err != nil || n < 0 {
+				fmt.Fprintf(os.Stderr, "Error: invalid --go-deps-depth %q: expected a number of levels\n", args[i+1])
+				os.Exit(2)
+			}
+			cfg.GoDepsDepth = n
+			i++
+		case "--context", "--context-lines":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: %s requires a count\n", args[i])
+				os.Exit(2)
+			}
+			n, err := strconv.Atoi(args[i+1])
+			if err != nil || n < 0 {
+				fmt.Fprintf(os.Stderr, "Error: invalid %s %q\n", args[i], args[i+1])
+				os.Exit(2)
+			}
+			cfg.Excerpt, cfg.ContextLines = true, n
+			i++
+		case "--newer-than", "--older-than":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: %s requires an age or date\n", arg)
+				os.Exit(2)
+			}
+			t, err := parseAge(args[i+1], time.Now())
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: invalid %s %q: %v\n", arg, args[i+1], err)
+				os.Exit(2)
+			}
+			if arg == "--newer-than" {
+				cfg.NewerThan = t
+			} else {
+				cfg.OlderThan = t
+			}
+			i++
+		case "--with-diff":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --with-diff requires a git ref\n")
+				os.Exit(2)
+			}
+			cfg.WithDiff = args[i+1]
+			i++
+		case "--diff-only":
+			cfg.DiffOnly = true
+		case "--git-meta":
+			cfg.GitMeta = true
+		case "--strip-comments":
+			cfg.StripComments = true
+		case "--redact":
+			cfg.Redact = true
+		case "--redact-pattern":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --redact-pattern requires a regular expression\n")
+				os.Exit(2)
+			}
+			if _, err := regexp.Compile(args[i+1]); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: invalid --redact-pattern %q: %v\n", args[i+1], err)
+				os.Exit(2)
+			}
+			cfg.RedactPatterns = append(cfg.RedactPatterns, args[i+1])
+			i++
+		case "--filter-cmd":
+			if i+1 >= len(args) || strings.TrimSpace(args[i+1]) == "" {
+				fmt.Fprintf(os.Stderr, "Error: --filter-cmd requires a command\n")
+				os.Exit(2)
+			}
+			cfg.FilterCmds = append(cfg.FilterCmds, args[i+1])
+			i++
+		case "--run":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --run requires a command\n")
+				os.Exit(2)
+			}
+			cfg.Run = append(cfg.Run, args[i+1])
+			i++
+		case "--env-info":
+			cfg.EnvInfo = true
+		case "--env-var":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --env-var requires a variable name\n")
+				os.Exit(2)
+			}
+			cfg.EnvInfo = true
+			cfg.EnvVars = append(cfg.EnvVars, args[i+1])
+			i++
+		case "--max-files":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --max-files requires a count\n")
+				os.Exit(2)
+			}
+			n, err := strconv.Atoi(args[i+1])
+			if err != nil || n <= 0 {
+				fmt.Fprintf(os.Stderr, "Error: invalid --max-files %q\n", args[i+1])
+				os.Exit(2)
+			}
+			cfg.MaxFiles = n
+			i++
+		case "--head-lines", "--tail-lines":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: %s requires a count\n", args[i])
+				os.Exit(2)
+			}
+			n, err := strconv.Atoi(args[i+1])
+			if err != nil || n < 0 {
+				fmt.Fprintf(os.Stderr, "Error: invalid %s %q\n", args[i], args[i+1])
+				os.Exit(2)
+			}
+			if args[i] == "--head-lines" {
+				cfg.HeadLines = n
+			} else {
+				cfg.TailLines = n
+			}
+			i++
+		case "--max-lines-per-file":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --max-lines-per-file requires a count\n")
+				os.Exit(2)
+			}
+			n, err := strconv.Atoi(args[i+1])
+			if err != nil || n <= 0 {
+				fmt.Fprintf(os.Stderr, "Error: invalid --max-lines-per-file %q\n", args[i+1])
+				os.Exit(2)
+			}
+			cfg.MaxLinesPerFile = n
+			i++
+		case "--max-lines":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --max-lines requires a count\n")
+				os.Exit(2)
+			}
+			n, err := strconv.Atoi(args[i+1])
+			if err != nil || n <= 0 {
+				fmt.Fprintf(os.Stderr, "Error: invalid --max-lines %q\n", args[i+1])
+				os.Exit(2)
+			}
+			cfg.MaxLines = n
+			i++
+		case "-n", "--line-numbers":
+			cfg.LineNumbers = true
+		case "--notebook":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --notebook requires render, raw or skip\n")
+				os.Exit(2)
+			}
+			switch args[i+1] {
+			case output.NotebookRender, output.NotebookRaw, output.NotebookSkip:
+				cfg.Notebook = args[i+1]
+			default:
+				fmt.Fprintf(os.Stderr, "Error: invalid --notebook %q: expected render, raw or skip\n", args[i+1])
+				os.Exit(2)
+			}
+			i++
+		case "--unreadable-placeholder":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --unreadable-placeholder requires a text\n")
+				os.Exit(2)
+			}
+			cfg.UnreadablePlaceholder = args[i+1]
+			i++
+		case "--json":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --json requires pretty, minify or keep\n")
+				os.Exit(2)
+			}
+			switch args[i+1] {
+			case output.JSONPretty, output.JSONMinify, output.JSONKeep:
+				cfg.JSON = args[i+1]
+			default:
+				fmt.Fprintf(os.Stderr, "Error: invalid --json %q: expected pretty, minify or keep\n", args[i+1])
+				os.Exit(2)
+			}
+			i++
+		case "--inline-images":
+			cfg.InlineImages = true
+		case "-j", "--jobs":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --jobs requires a count\n")
+				os.Exit(2)
+			}
+			n, err := strconv.Atoi(args[i+1])
+			if err != nil || n <= 0 {
+				fmt.Fprintf(os.Stderr, "Error: invalid --jobs %q\n", args[i+1])
+				os.Exit(2)
+			}
+			cfg.Jobs = n
+			i++
+		case "--upload":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --upload requires a target\n")
+				os.Exit(2)
+			}
+			cfg.Upload = args[i+1]
+			i++
+		case "--split-size":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --split-size requires a size\n")
+				os.Exit(2)
+			}
+			n, err := parseSize(args[i+1])
+			if err != nil || n == 0 {
+				fmt.Fprintf(os.Stderr, "Error: invalid --split-size %q: expected a size like 500k or 2M\n", args[i+1])
+				os.Exit(2)
+			}
+			cfg.SplitSize = n
+			i++
+		case "--split-tokens":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --split-tokens requires a count\n")
+				os.Exit(2)
+			}
+			n, err := strconv.Atoi(args[i+1])
+			if err != nil || n <= 0 {
+				fmt.Fprintf(os.Stderr, "Error: invalid --split-tokens %q\n", args[i+1])
+				os.Exit(2)
+			}
+			cfg.SplitTokens = n
+			i++
+		case "--model":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --model requires a name\n")
+				os.Exit(2)
+			}
+			if _, err := tokens.ForModel(args[i+1]); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: invalid --model: %v\n", err)
+				os.Exit(2)
+			}
+			cfg.Model = args[i+1]
+			i++
+		case "--split-output":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --split-output requires a file\n")
+				os.Exit(2)
+			}
+			cfg.SplitOutput = args[i+1]
+			i++
+		case "--confirm-over":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --confirm-over requires a size\n")
+				os.Exit(2)
+			}
+			n, err := parseSize(args[i+1])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: invalid --confirm-over %q: %v\n", args[i+1], err)
+				os.Exit(2)
+			}
+			cfg.ConfirmOver = n
+			i++
+		case "--force":
+			cfg.Force = true
+		case "--strict":
+			cfg.Strict = true
+		case "--timeout":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --timeout requires a duration\n")
+				os.Exit(2)
+			}
+			d, err := time.ParseDuration(args[i+1])
+			if err != nil || d <= 0 {
+				fmt.Fprintf(os.Stderr, "Error: invalid --timeout %q: expected a positive duration such as 30s or 2m\n", args[i+1])
+				os.Exit(2)
+			}
+			cfg.Timeout = d
+			i++
+		case "--url-timeout":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --url-timeout requires a duration\n")
+				os.Exit(2)
+			}
+			d, err := time.ParseDuration(args[i+1])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: invalid --url-timeout %q: %v\n", args[i+1], err)
+				os.Exit(2)
+			}
+			cfg.URLTimeout = d
+			i++
+		case "--url-max-size":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --url-max-size requires a size\n")
+				os.Exit(2)
+			}
+			n, err := parseSize(args[i+1])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: invalid --url-max-size %q: %v\n", args[i+1], err)
+				os.Exit(2)
+			}
+			cfg.URLMaxSize = n
+			i++
 		default:
 			if strings.HasPrefix(arg, "-") {
 				fmt.Fprintf(os.Stderr, "Error: unknown option: %s\n", arg)
@@ -62,7 +893,206 @@ func ParseArgs() *Config {
 		}
 	}
 
-	if len(cfg.Paths) == 0 {
+	if cfg.ShowVersion {
+		if cfg.Format != "" && cfg.Format != "text" && cfg.Format != "json" {
+			fmt.Fprintf(os.Stderr, "Error: --version supports --format text or json\n")
+			os.Exit(2)
+		}
+		printVersion(cfg.Format)
+		os.Exit(0)
+	}
+	if cfg.Format != "" && !slices.Contains(output.Formatters(), cfg.Format) {
+		fmt.Fprintf(os.Stderr, "Error: invalid --format %q: expected one of %s\n", cfg.Format, strings.Join(output.Formatters(), ", "))
+		os.Exit(2)
+	}
+
+	if cfg.Template != "" {
+		if cfg.Format != "" {
+			fmt.Fprintf(os.Stderr, "Error: --template and --format cannot be combined\n")
+			os.Exit(2)
+		}
+		if _, err := output.ParseTemplate(cfg.Template); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid --template: %v\n", err)
+			os.Exit(2)
+		}
+	}
+
+	if cfg.TreeFormat == output.TreeJSON && cfg.Long {
+		fmt.Fprintf(os.Stderr, "Error: --tree-format json and --long cannot be combined\n")
+		os.Exit(2)
+	}
+	if cfg.TreeShowExcluded && cfg.Long {
+		fmt.Fprintf(os.Stderr, "Error: --tree-show-excluded and --long cannot be combined\n")
+		os.Exit(2)
+	}
+
+	if cfg.Deterministic && cfg.Collate == output.CollateLocale {
+		fmt.Fprintf(os.Stderr, "Error: --collate locale depends on the environment; use byte or unicode with --deterministic\n")
+		os.Exit(2)
+	}
+
+	if cfg.DiffAgainst != "" && (!slices.Contains([]string{"", "plain", "markdown", "xml", "json", "repomix", "llms-full"}, cfg.Format) || cfg.Template != "") {
+		fmt.Fprintf(os.Stderr, "Error: --diff-against cannot read %s bundles back; use --format plain, markdown, xml, json, repomix or llms-full\n", cmp.Or(cfg.Format, "--template"))
+		os.Exit(2)
+	}
+
+	if cfg.IDs && !slices.Contains([]string{"markdown", "xml", "json"}, cfg.Format) && cfg.Template == "" {
+		fmt.Fprintf(os.Stderr, "Error: --ids requires --format markdown, xml or json\n")
+		os.Exit(2)
+	}
+
+	if cfg.DiffOnly && cfg.WithDiff == "" {
+		fmt.Fprintf(os.Stderr, "Error: --diff-only requires --with-diff\n")
+		os.Exit(2)
+	}
+
+	if (cfg.Output != "" || cfg.Clipboard != "") && (cfg.Upload != "" || cfg.SplitSize > 0 || cfg.SplitTokens > 0) {
+		fmt.Fprintf(os.Stderr, "Error: --output and --clipboard cannot be combined with --upload or --split-size/--split-tokens\n")
+		os.Exit(2)
+	}
+
+	if cfg.Compress != "" && cfg.Output == "" && cfg.Upload == "" && cfg.Clipboard == "" {
+		fmt.Fprintf(os.Stderr, "Error: --compress requires --output, --upload or --clipboard\n")
+		os.Exit(2)
+	}
+
+	if cfg.Encrypt != "" && cfg.Output == "" && cfg.Upload == "" {
+		fmt.Fprintf(os.Stderr, "Error: --encrypt requires --output or --upload\n")
+		os.Exit(2)
+	}
+
+	// An age file cannot be appended to
+	if cfg.Encrypt != "" && cfg.AppendFile {
+		fmt.Fprintf(os.Stderr, "Error: --encrypt cannot be combined with --append-file\n")
+		os.Exit(2)
+	}
+
+	if cfg.AppendFile && cfg.Output == "" {
+		fmt.Fprintf(os.Stderr, "Error: --append-file requires --output\n")
+		os.Exit(2)
+	}
+
+	if cfg.HoldTimeout > 0 && !cfg.Hold {
+		fmt.Fprintf(os.Stderr, "Error: --hold-timeout requires --hold\n")
+		os.Exit(2)
+	}
+
+	// --hold serves the default clipboard copy, not a sink or a link
+	if cfg.Hold && (cfg.Output != "" || cfg.Clipboard != "" || cfg.Upload != "" || cfg.SplitSize > 0 || cfg.SplitTokens > 0) {
+		fmt.Fprintf(os.Stderr, "Error: --hold cannot be combined with --output, --clipboard, --upload or --split-size/--split-tokens\n")
+		os.Exit(2)
+	}
+
+	// The held copy is plain text, which would drop the --rich HTML flavor
+	if cfg.Hold && cfg.Rich {
+		fmt.Fprintf(os.Stderr, "Error: --hold cannot be combined with --rich\n")
+		os.Exit(2)
+	}
+
+	// Later runs read the manifest to know what an earlier run copied
+	if cfg.AppendFile && cfg.DedupeContent {
+		if slices.Contains([]string{"repomix", "llms-txt", "llms-full"}, cfg.Format) {
+			fmt.Fprintf(os.Stderr, "Error: --dedupe-content with --append-file requires a format with a manifest\n")
+			os.Exit(2)
+		}
+		cfg.Manifest = true
+	}
+
+	if cfg.SplitOutput != "" && cfg.SplitSize == 0 && cfg.SplitTokens == 0 {
+		fmt.Fprintf(os.Stderr, "Error: --split-output requires --split-size or --split-tokens\n")
+		os.Exit(2)
+	}
+
+	if !cfg.NoDefaultExcludes {
+		cfg.DefaultExcludes = exclude.DefaultExcludes
+		if patterns := loadConfig().DefaultExcludes; patterns != nil {
+			cfg.DefaultExcludes = patterns
+		}
+	}
+
+	for key, language := range loadConfig().Languages {
+		lang.Register(key, language)
+	}
+
+	for key, h := range loadConfig().Handlers {
+		// A cloned repository must not run commands on its own
+		if filepath.Base(h.Source) == config.ProjectFile {
+			fmt.Fprintf(os.Stderr, "Error: handler %q in %s runs a command; define it in %s instead\n", key, h.Source, config.UserPath())
+			os.Exit(2)
+		}
+		output.RegisterHandler(key, output.CommandHandler(h.Command))
+	}
+
+	hooks := []struct {
+		name string
+		hook *config.Hook
+		dest *string
+	}{{"pre_copy", loadConfig().PreCopy, &cfg.PreCopy}, {"post_copy", loadConfig().PostCopy, &cfg.PostCopy}}
+	for _, h := range hooks {
+		if h.hook == nil {
+			continue
+		}
+		if filepath.Base(h.hook.Source) == config.ProjectFile {
+			fmt.Fprintf(os.Stderr, "Error: hook %s in %s runs a command; define it in %s instead\n", h.name, h.hook.Source, config.UserPath())
+			os.Exit(2)
+		}
+		*h.dest = h.hook.Command
+	}
+
+	if audit := loadConfig().Audit; audit.Source != "" {
+		// The log belongs to the user, not to whichever repository is open
+		if filepath.Base(audit.Source) == config.ProjectFile {
+			fmt.Fprintf(os.Stderr, "Error: [audit] in %s: the audit log is configured in %s only\n", audit.Source, config.UserPath())
+			os.Exit(2)
+		}
+		if audit.Enabled {
+			cfg.AuditLog = cmp.Or(audit.Path, config.AuditPath())
+		}
+	}
+
+	for backend, size := range loadConfig().ClipboardLimits {
+		n, err := parseSize(size)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid [clipboard.limits] %s %q: %v\n", backend, size, err)
+			os.Exit(2)
+		}
+		if cfg.ClipboardLimits == nil {
+			cfg.ClipboardLimits = map[string]int64{}
+		}
+		cfg.ClipboardLimits[backend] = n
+	}
+
+	cfg.MaxLinesByName = loadConfig().MaxLinesPerFile
+
+	// Change selections and explanations default to the whole working tree
+	if len(cfg.Paths) == 0 && (cfg.ChangedSince != "" || cfg.Staged || cfg.Unstaged || len(cfg.Explain) > 0) {
+		cfg.Paths = []string{"."}
+	}
+
+	if _, err := grepPattern(cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	if cfg.GrepCounts && len(cfg.Grep) == 0 && len(cfg.GrepRegexps) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: --grep-counts requires --grep or --grep-regexp\n")
+		os.Exit(2)
+	}
+
+	if cfg.Excerpt && len(cfg.FromQuickfix) == 0 && len(cfg.FromDiagnostics) == 0 && len(cfg.Grep) == 0 && len(cfg.GrepRegexps) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: --context requires --grep, --grep-regexp, --from-quickfix or --from-json-diagnostics\n")
+		os.Exit(2)
+	}
+
+	if cfg.Pick && len(cfg.Paths) == 0 {
+		cfg.Paths = []string{"."}
+	}
+
+	if cfg.GoDepsDepth > 0 && len(cfg.GoDeps) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: --go-deps-depth requires --go-deps\n")
+		os.Exit(2)
+	}
+
+	if len(cfg.Paths) == 0 && cfg.GitHub == "" && len(cfg.Expand) == 0 && len(cfg.FromQuickfix) == 0 && len(cfg.FromDiagnostics) == 0 && len(cfg.GoDeps) == 0 && len(cfg.FollowImports) == 0 && len(cfg.ForceInclude) == 0 {
 		printUsage()
 		os.Exit(2)
 	}
@@ -70,24 +1100,388 @@ func ParseArgs() *Config {
 	return cfg
 }
 
+// readPathList reads a --paths-from file: one path or pattern per line, as
+// it would be given on the command line, and !PATTERN lines for excludes.
+// Blank lines and lines starting with # are skipped.
+func readPathList(path string) (paths, excludes []string, err error) {
+	data, err := readInput(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	for line := range strings.Lines(string(data)) {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+		case strings.HasPrefix(line, "!"):
+			excludes = append(excludes, line[1:])
+		default:
+			paths = append(paths, line)
+		}
+	}
+	if len(paths) == 0 {
+		return nil, nil, fmt.Errorf("%s lists no paths", path)
+	}
+	return paths, excludes, nil
+}
+
+// untrustedFlag returns the first flag in args that runs a command or sends
+// the bundle anywhere but the clipboard, or "". Arguments stored in a
+// checked-out repository may not use them, so cloning a repository cannot
+// make clipcat run its commands, upload the bundle or overwrite files.
+func untrustedFlag(args []string) string {
+	for _, arg := range args {
+		switch arg {
+		case "--run", "--filter-cmd", "--clipboard", "--upload", "-o", "--output", "--split-output", "--report-file":
+			return arg
+		}
+	}
+	return ""
+}
+
+// maxArgFileDepth bounds how deeply argument files may name each other,
+// which also stops a file that names itself.
+const maxArgFileDepth = 8
+
+// ExpandArgFiles replaces each @FILE argument with the arguments in FILE
+// (@- reads standard input), so selections can outgrow the OS limit on
+// command lines and invocations can be kept in files. Arguments are
+// separated by spaces and newlines; '...' quotes literally, "..." quotes
+// with \" and \\ as escapes, and # starts a comment outside quotes.
+// Backslashes are kept elsewhere, so Windows paths need no quoting. Files
+// may name other @FILEs; @@ARG passes @ARG through as it is.
+func ExpandArgFiles(args []string) ([]string, error) {
+	return expandArgFiles(args, 0)
+}
+
+func expandArgFiles(args []string, depth int) ([]string, error) {
+	var out []string
+	for _, arg := range args {
+		name, ok := strings.CutPrefix(arg, "@")
+		switch {
+		case !ok || name == "":
+			out = append(out, arg)
+		case strings.HasPrefix(name, "@"):
+			out = append(out, name)
+		case depth >= maxArgFileDepth:
+			return nil, fmt.Errorf("@%s: argument files nested too deeply", name)
+		default:
+			data, err := readInput(name)
+			if err != nil {
+				return nil, fmt.Errorf("reading @%s: %w", name, err)
+			}
+			words, err := splitArgFile(string(data))
+			if err != nil {
+				return nil, fmt.Errorf("@%s: %w", name, err)
+			}
+			if words, err = expandArgFiles(words, depth+1); err != nil {
+				return nil, err
+			}
+			out = append(out, words...)
+		}
+	}
+	return out, nil
+}
+
+// splitArgFile splits the text of an argument file into arguments.
+func splitArgFile(text string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	r := []rune(text)
+	for i := 0; i < len(r); i++ {
+		switch c := r[i]; {
+		case c == '\'' || c == '"':
+			inWord = true
+			end := i + 1
+			for ; end < len(r) && r[end] != c; end++ {
+				if c == '"' && r[end] == '\\' && end+1 < len(r) && (r[end+1] == '"' || r[end+1] == '\\') {
+					end++
+				}
+				word.WriteRune(r[end])
+			}
+			if end == len(r) {
+				return nil, fmt.Errorf("unterminated %c quote", c)
+			}
+			i = end
+		case c == '#' && !inWord:
+			for i < len(r) && r[i] != '\n' {
+				i++
+			}
+		case unicode.IsSpace(c):
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			inWord = true
+			word.WriteRune(c)
+		}
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// expandProfiles replaces each -P/--profile NAME with the arguments stored for
+// NAME in the config files.
+func expandProfiles(args []string, loadConfig func() *config.Config) []string {
+	var out []string
+	for i := 0; i < len(args); i++ {
+		if args[i] != "-P" && args[i] != "--profile" {
+			out = append(out, args[i])
+			continue
+		}
+		if i+1 >= len(args) {
+			fmt.Fprintf(os.Stderr, "Error: %s requires a name\n", args[i])
+			os.Exit(2)
+		}
+		p, ok := loadConfig().Profiles[args[i+1]]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Error: unknown profile %q (see clipcat profiles)\n", args[i+1])
+			os.Exit(2)
+		}
+		if slices.Contains(p.Args, "-P") || slices.Contains(p.Args, "--profile") {
+			fmt.Fprintf(os.Stderr, "Error: profile %q may not use --profile\n", p.Name)
+			os.Exit(2)
+		}
+		if flag := untrustedFlag(p.Args); filepath.Base(p.Source) == config.ProjectFile && flag != "" {
+			fmt.Fprintf(os.Stderr, "Error: profile %q in %s may not use %s; define it in %s instead\n", p.Name, p.Source, flag, config.UserPath())
+			os.Exit(2)
+		}
+		out = append(out, p.Args...)
+		i++
+	}
+	return out
+}
+
 func printUsage() {
-	fmt.Fprintf(os.Stderr, `Usage: clipcat [OPTIONS] <path1> [<path2> ...]
+	fmt.Fprint(os.Stderr, usage)
+}
+
+const usage = `Usage: clipcat [copy] [OPTIONS] <path1> [<path2> ...]
+       clipcat tree [OPTIONS] <path1> [<path2> ...]
+       clipcat gh <owner/repo[/path][@ref] | gist:ID> [OPTIONS] [<pattern> ...]
+       clipcat explain <path> [OPTIONS] [<path1> ...]
+       clipcat stats [OPTIONS] <path1> [<path2> ...]
+       clipcat llms-txt [OPTIONS] <path1> [<path2> ...]
+       clipcat ask "<question>" [OPTIONS] <path1> [<path2> ...]
+       clipcat expand <ID> [<ID> ...] [OPTIONS]
+       clipcat pick [DIR] [OPTIONS]
+       clipcat history [N]
+       clipcat rerun [N] [OPTIONS] [<path> ...]
+       clipcat diff [--patch] <OLD> <NEW>
+       clipcat audit show [N]
+       clipcat set [list | save NAME [OPTIONS] <path> ... | copy NAME [OPTIONS] | delete NAME]
+       clipcat serve [--http ADDR] [--token TOKEN] [-C DIR]
+       clipcat unpack [OPTIONS] <FILE | - | --from-clipboard>
+       clipcat profiles [list | show NAME]
+       clipcat completion <bash | zsh | fish>
+       clipcat version [--format json]
+
+Commands:
+  copy                      Copy files to the clipboard (the default command)
+  tree                      Copy only the FILE HIERARCHY (same as --only-tree)
+  gh                        Copy files from a GitHub repository or gist
+  explain                   Show which input and exclude rule decide a path
+  stats                     Print files, bytes, lines and tokens per directory and extension
+  llms-txt                  Copy an llms.txt index of the files (same as --format llms-txt)
+  ask                       Send the question and the files to an LLM API and print
+                            the answer (configured in the [ask] config table)
+  expand                    Copy the files with these IDs from the last --ids run again
+  pick                      Choose files in a fuzzy finder and copy them
+  history                   List the last 20 copies, or show copy N with its files
+  rerun                     Repeat copy N (default: the latest) with extra options or paths
+  diff                      List the files added, removed or changed between two bundles
+  audit                     Show the last N (default 20) records of the audit log
+  set                       Save named sets of paths and options for the project, and copy them
+  serve                     Serve bundles over HTTP for editor plugins and extensions
+  unpack                    Recreate files from a clipcat bundle
+  profiles                  List the profiles defined in the config files
+  completion                Print a shell completion script
+  version                   Show version information
 
 Description:
   - If a path is a file: include that file.
   - If a path is a directory: include ALL files recursively.
-  - If a path contains glob patterns (* ? [) and doesn't exist as a literal path,
-    it will be treated as a recursive search pattern.
+  - If a path contains glob patterns (* ? [ {a,b}) and doesn't exist as a literal path,
+    it will be treated as a recursive search pattern. A leading directory such as
+    src/ in src/**/*.go anchors the search there instead of the current directory.
+  - If a path is an http:// or https:// URL: fetch it and include the response body.
   - Output is a single stream: each file is preceded by a header with its path.
   - The final stream is copied to the clipboard.
+  - With gh, the repository (or gist) is shallow-fetched to a temporary directory
+    and the paths/patterns are resolved inside it (default: the spec's path or all files).
+  - An argument @FILE is replaced by the options and paths in FILE, separated by spaces or
+    newlines, with '...' and "..." quoting and # comments (@- reads stdin, @@ARG means @ARG).
 
 Options:
+      --paths-from FILE     Read paths and patterns from FILE, one per line; # starts a comment
+                            and !PATTERN excludes (- for stdin; repeatable)
   -e, --exclude PATTERN     Exclude glob pattern (repeatable)
       --exclude-from FILE   Read patterns from FILE with full .gitignore semantics (repeatable)
-  -i, --ignore-case         Make glob pattern matching case-insensitive
+      --force-include FILE  Copy FILE even if an exclude, .gitignore, default or filter would
+                            skip it; the copy policy still applies (repeatable)
+      --no-default-excludes Don't skip .git/, node_modules/, vendor/, dist/, __pycache__/,
+                            .venv/, target/ and lockfiles when walking directories
+  -i, --ignore-case         Make glob inputs and exclude patterns case-insensitive
+      --ignore-case-excludes Make only exclude patterns (-e, --exclude-from) case-insensitive
+      --git                 Collect only files tracked by git (git ls-files, incl. submodules)
+      --root DIR            Search for glob inputs under DIR instead of the current directory
+      --changed-since REF   Only files changed relative to REF's merge-base with HEAD
+                            (committed, staged, unstaged and untracked changes)
+      --staged              Only files with staged changes
+      --unstaged            Only files with unstaged changes (incl. untracked)
+      --ext LIST            Only files in these languages or with these extensions, e.g.
+                            go,ts or python (repeatable)
+      --type LIST           Only files in these categories: text, code, config, docs, data
+                            (repeatable)
+      --preset NAME         Copy the sources of a go, node, rust or python project, or detect
+                            them from go.mod, package.json, Cargo.toml or pyproject.toml
+                            with auto; none turns a preset off
+      --newer-than AGE      Only files modified within AGE (e.g. 36h, 7d, 2w) or since a date
+                            (2024-05-01 or RFC 3339)
+      --older-than AGE      Only files last modified more than AGE ago or before a date
+      --grep TEXT           Only files containing TEXT (repeatable; any of them matches)
+      --grep-regexp RE      Only files matching regular expression RE (repeatable)
+      --grep-counts         Add the number of --grep matches to each file header
+      --context N           With --grep or --grep-regexp, copy only the matching lines and N
+                            lines around them, numbered as in the file
+      --max-files N         Copy at most N files, the first in --sort order, and report the rest
+      --from-quickfix FILE  Also copy the files named in FILE's "path:line: message" lines
+                            (Vim quickfix, compiler or grep -n output; - for stdin; repeatable)
+      --from-json-diagnostics FILE Also copy the files named in JSON diagnostics (file/line objects,
+                            LSP ranges or ESLint output; - for stdin; repeatable)
+      --context-lines N     With the two above, copy only the referenced lines and N lines
+                            around them, numbered as in the file
+      --go-deps PKG         Also copy the Go package PKG (./pkg/foo or an import path) and the
+                            packages of its module it imports, via go list (repeatable)
+      --go-deps-depth N     Follow --go-deps imports at most N levels deep (default: all)
+      --follow-imports FILE Also copy the JavaScript/TypeScript file FILE and the local files it
+                            imports, directly or not (node_modules excluded; repeatable)
+      --with-tests          Also copy the test file of each source file (foo_test.go,
+                            foo.test.ts, foo.spec.ts, test_foo.py, ...)
+      --with-sources        Also copy the source file of each test file
+      --with-diff REF       Follow each changed file with its diff against REF's merge-base
+      --diff-only           With --with-diff, show changed files as diffs only
+      --git-meta            Add the last commit (hash, author, date) to each file header
+      --run CMD             Run shell command CMD and append its output, headed by the
+                            command line (repeatable)
+      --env-info            Append an ENVIRONMENT section: OS, Go version, git branch and
+                            commit, and toolchain variables such as GOFLAGS or VIRTUAL_ENV
+      --env-var NAME        Also report environment variable NAME (repeatable; implies --env-info)
+      --json MODE           .json files: keep (default), pretty (re-indented) or minify;
+                            minify also drops comments and blank lines from .yaml files
+      --filter-cmd CMD      Pipe each file through shell command CMD, which gets the path in
+                            $CLIPCAT_FILE, and copy its output (repeatable; runs first)
+      --strip-comments      Remove comments from recognised source files
+      --redact              Replace private keys, API tokens and password values with [REDACTED]
+      --redact-pattern RE   Also redact matches of regular expression RE (repeatable)
+      --head-lines N        Keep only the first N lines of each file
+      --tail-lines N        Keep only the last N lines of each file
+      --max-lines N         Keep the first N lines of each file
+      --max-lines-per-file N
+                            Keep the first and last N/2 lines of each file, with a marker
+                            for the lines between; [max_lines_per_file] sets it per extension
+  -n, --line-numbers        Prefix each line with its line number
+      --explain PATH        Report why PATH is included or excluded instead of copying
+      --format NAME         Output format: plain (default), markdown, xml, json, repomix
+                            (Repomix XML layout), llms-txt or llms-full (llms.txt index,
+                            without or with file contents)
+      --template FILE       Render the whole document with Go template FILE instead of a
+                            format (see "Templates" in the README)
+      --ids                 Tag each file with a short ID ([F12]) in its header and the tree,
+                            for markdown, xml and json (see the expand command)
+      --inline-images       Embed images as base64 data URIs (markdown format) instead of an
+                            [image: NAME WxH SIZE] placeholder
+      --notebook MODE       Jupyter notebooks: render (default) as markdown and code cells
+                            without output blobs, raw JSON, or skip them
+      --unreadable-placeholder TEXT
+                            Show TEXT for files that could not be read, with {path}, {error}
+                            and {size} filled in (default "[unreadable: {error}, {size}]")
+      --summarize-locks     Copy lockfiles (go.sum, package-lock.json, yarn.lock, Cargo.lock, ...)
+                            as a list of dependencies and versions instead of skipping them
+      --include-minified    Copy minified JavaScript and CSS instead of a placeholder
+      --table-preview N     Copy CSV and TSV files as an aligned table of the header and the
+                            first N rows, with the total row count
+      --sort ORDER          natural (default): file2 before file10, each directory's files
+                            before its subdirectories; lexical: plain byte order
+      --collate COLLATION   How names compare when sorting: byte (default), unicode (ignore
+                            case and accents) or locale (the alphabet of LC_COLLATE or LANG)
+      --smart-order         Put READMEs, build manifests, entrypoints and the files the others
+                            import (Go, JavaScript/TypeScript, Python) first
+      --group-by KEY        Put a header above each group of files: none (default), dir (top-level
+                            directory), ext (extension) or root (the input that selected them)
   -t, --tree                Prepend a FILE HIERARCHY section
       --only-tree           Copy only the FILE HIERARCHY (no file contents)
+      --tree-format FORMAT  Draw the FILE HIERARCHY as text (default) or json: nested objects
+                            with name, type, size and children (implies --tree)
+      --tree-show-excluded  Also draw the files and directories the excludes left out, each
+                            followed by [excluded: RULE] (implies --tree)
+      --paths-only          Copy only the list of file paths, one per line
+      --relative            Show paths relative to the current directory instead of absolute
+  -l, --long                List files with mode, size and modification time instead of
+                            the tree (combine with --only-tree for an inventory)
+      --summary             Start with a summary: time, clipcat version, inputs, file, line
+                            and token counts, and the excludes applied
+      --stats-only          Print the clipcat stats breakdown instead of copying
+      --diff-against FILE   Print which files changed since the bundle in FILE instead of copying
+      --report json         Write a JSON report of the run to stderr: files copied and left out
+                            with the reason, sizes, time per phase and the clipboard backend
+      --report-file FILE    Write the --report json report to FILE instead
+      --deterministic       Make identical trees produce byte-identical output: relative
+                            paths with / separators, stable order, no timestamps
+      --manifest            Append the size and SHA-256 of every file, as read from disk
+      --manifest-only       Copy only the manifest (no file contents)
   -p, --print               Also print to stdout
+  -o, --output FILE         Write the output to FILE instead of copying it
+      --clipboard CMD       Copy with CMD, e.g. wl-copy or "xclip -selection primary", auto
+                            for the first clipboard command found, or osc52 for the
+                            terminal's clipboard (over SSH); with --output and --print,
+                            one pass feeds every destination
+      --append-file         Append to the --output file after a run separator instead of
+                            replacing it
+      --dedupe-content      Leave out files whose bytes match a file before them, or with
+                            --append-file one in the manifest of an earlier run
+      --compress ALGO       Compress the --output file, the --upload or the --clipboard copy
+                            with gzip or zstd (as base64 text for the clipboard, gist and
+                            paste.rs); clipcat unpack and diff read it back as it is
+      --encrypt age:KEY     Encrypt the --output file or the --upload to the age or SSH public
+                            KEY with age; clipcat unpack --decrypt reads it back
+      --rich                Also copy syntax-highlighted HTML, for pasting into Google Docs,
+                            Confluence and other rich editors (macOS and Windows)
+      --hold                Keep serving the copy until Ctrl-C or another copy replaces it,
+                            for Wayland and X11 setups where it vanishes with wl-copy
+      --hold-timeout DUR    Stop holding after DUR, e.g. 5m
+      --color WHEN          Syntax-highlight --print output: auto (default, when stdout is a
+                            terminal and NO_COLOR is unset), always or never
+      --upload TARGET       Upload instead of copying and copy the link; TARGET is
+                            gist, paste.rs, 0x0.st, or an http(s) URL to POST to
+      --split-size SIZE     Split output into parts of at most SIZE bytes, e.g. 100k
+      --split-tokens N      Split output into parts of at most ~N tokens
+      --model NAME          Estimate tokens for NAME's tokenizer, e.g. gpt-4o, claude-3.5,
+                            llama3, or bytes for ~4 bytes per token (default gpt-4). No
+                            vocabulary is bundled, so counts are estimates: roughly within
+                            15% for code and English, up to 20% off for short or non-Latin
+                            text, and further for models scaled from cl100k
+      --split-output FILE   Write parts to FILE.part1.ext, ... instead of copying them
+                            one at a time (Enter copies the next part)
+      --confirm-over SIZE   Ask before copying output larger than SIZE (default 5M, 0 = never);
+                            without a terminal the copy is refused unless --force is given
+      --force               Copy without asking, whatever the size
+      --strict              Fail if a file is removed or changed while it is read, instead of
+                            copying a [removed during run] placeholder. Also fail after copying
+                            if any input was skipped or unreadable: missing paths, patterns
+                            matching nothing, broken symlinks, and unreadable files, URLs and
+                            --run commands are listed and clipcat exits 1
+  -j, --jobs N              Read up to N files at once (default: number of CPUs)
+      --timeout DUR         Give up on the run after DUR, e.g. on a hung network mount, and
+                            say where it was stuck (exit status 5)
+      --url-timeout DUR     Timeout for fetching URL inputs (default 30s)
+      --url-max-size SIZE   Cap on bytes read per URL input, e.g. 512k, 2M (default 10M)
+  -P, --profile NAME        Insert the arguments of config profile NAME (repeatable)
+      --version             Show version, commit and build date (--format json for scripts)
   -h, --help                Show help
 
 Examples:
@@ -96,5 +1490,92 @@ Examples:
   clipcat . -e go.mod -e go.sum
   clipcat '*checkin*' -i --exclude-from .gitignore
   clipcat '*TEST*' --ignore-case --exclude 'frontend/assets/'
-`)
+  clipcat spec.md https://raw.githubusercontent.com/owner/repo/main/API.md
+  clipcat --changed-since main -t
+  clipcat --changed-since main --with-diff main --diff-only
+  clipcat src/ -t --upload paste.rs
+  clipcat . --split-tokens 8000 --split-output context.txt
+  clipcat gh bmatcuk/doublestar@v4.9.1 '*.go' -e '*_test.go'
+  clipcat tree . -e node_modules/
+  clipcat explain vendor/lib/x.go . --exclude-from .gitignore
+  clipcat ask "Why does the retry loop never stop?" internal/ --ext go
+  clipcat --profile review
+
+Exit status:
+  0  success
+  1  error
+  2  usage error
+  3  no files matched
+  4  clipboard unavailable (output requested with -p or --split-output is still produced)
+`
+
+// parseSize parses a byte count with an optional k/m/g suffix (powers of
+// 1024), e.g. "512", "100k", "2MB".
+func parseSize(s string) (int64, error) {
+	num := strings.ToLower(strings.TrimSpace(s))
+	num = strings.TrimSuffix(num, "b")
+
+	mult := int64(1)
+	switch {
+	case strings.HasSuffix(num, "k"):
+		mult = 1 << 10
+	case strings.HasSuffix(num, "m"):
+		mult = 1 << 20
+	case strings.HasSuffix(num, "g"):
+		mult = 1 << 30
+	}
+	if mult > 1 {
+		num = num[:len(num)-1]
+	}
+
+	n, err := strconv.ParseFloat(num, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("expected a size like 500k or 2M")
+	}
+	return int64(n * float64(mult)), nil
+}
+
+// parseAge turns an age such as "90m", "7d" or "2w" into the time that long
+// before now, and accepts absolute dates as YYYY-MM-DD (local midnight),
+// YYYY-MM-DDTHH:MM:SS or RFC 3339.
+func parseAge(s string, now time.Time) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+
+	num, mult := s, time.Duration(0)
+	switch {
+	case strings.HasSuffix(s, "d"):
+		num, mult = s[:len(s)-1], 24*time.Hour
+	case strings.HasSuffix(s, "w"):
+		num, mult = s[:len(s)-1], 7*24*time.Hour
+	}
+	if mult > 0 {
+		n, err := strconv.ParseFloat(num, 64)
+		if err != nil || n < 0 {
+			return time.Time{}, fmt.Errorf("expected an age like 36h, 7d or 2w, or a date like 2024-05-01")
+		}
+		return now.Add(-time.Duration(n * float64(mult))), nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return time.Time{}, fmt.Errorf("expected an age like 36h, 7d or 2w, or a date like 2024-05-01")
+	}
+	return now.Add(-d), nil
+}
+
+// formatSize renders a byte count for humans, e.g. "512 B", "4.2 MB".
+func formatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGT"[exp])
 }
\ No newline at end of file
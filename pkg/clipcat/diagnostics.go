@@ -0,0 +1,161 @@
+package clipcat
+
+import (
+	"bufio"
+	"bytes"
+	"clipcat/pkg/output"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// quickfixRes match the locations of a quickfix file: compiler and grep -n
+// lines ("path:12:5: message", "path:12: message") and the lines of Vim's
+// quickfix window ("path|12 col 5| message").
+var quickfixRes = []*regexp.Regexp{
+	regexp.MustCompile(`^(.+?):(\d+)(?::\d+)?:`),
+	regexp.MustCompile(`^(.+?)\|(\d+)(?: col \d+)?\|`),
+}
+
+// jsonDiagnostic is one entry of a --from-json-diagnostics file. It accepts
+// flat {"file", "line", "endLine"} objects, LSP-style 0-based ranges and
+// ESLint's per-file "messages" lists.
+type jsonDiagnostic struct {
+	File     string `json:"file"`
+	Path     string `json:"path"`
+	Filename string `json:"filename"`
+	FilePath string `json:"filePath"`
+	URI      string `json:"uri"`
+	Line     int    `json:"line"`
+	EndLine  int    `json:"endLine"`
+	Range    *struct {
+		Start struct{ Line int } `json:"start"`
+		End   struct{ Line int } `json:"end"`
+	} `json:"range"`
+	Messages []jsonDiagnostic `json:"messages"`
+}
+
+// references reads --from-quickfix and --from-json-diagnostics into the
+// referenced files, in order of first mention, and the lines referenced in
+// each, keyed by absolute path. A reference without a line (Start 0)
+// selects the whole file. Missing files are reported and left out.
+func (b *Bundler) references() ([]string, map[string][]output.LineRange, error) {
+	var files []string
+	ranges := make(map[string][]output.LineRange)
+	missing := make(map[string]bool)
+	add := func(source, file string, r output.LineRange) {
+		abs, err := filepath.Abs(file)
+		if err != nil || missing[abs] {
+			return
+		}
+		spans, seen := ranges[abs]
+		if !seen {
+			if _, err := os.Stat(abs); err != nil {
+				fmt.Fprintf(b.warn, "Warning: %s: %s not found; skipped\n", source, file)
+				missing[abs] = true
+				return
+			}
+			files = append(files, file)
+		}
+		ranges[abs] = append(spans, r)
+	}
+
+	for _, source := range b.cfg.FromQuickfix {
+		data, err := readInput(source)
+		if err != nil {
+			return nil, nil, fmt.Errorf("--from-quickfix: %w", err)
+		}
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		scanner.Buffer(nil, 1<<20)
+		for scanner.Scan() {
+			for _, re := range quickfixRes {
+				if m := re.FindStringSubmatch(scanner.Text()); m != nil {
+					line, _ := strconv.Atoi(m[2])
+					add(source, strings.TrimSpace(m[1]), output.LineRange{Start: line, End: line})
+					break
+				}
+			}
+		}
+	}
+
+	for _, source := range b.cfg.FromDiagnostics {
+		data, err := readInput(source)
+		if err != nil {
+			return nil, nil, fmt.Errorf("--from-json-diagnostics: %w", err)
+		}
+		diags, err := decodeDiagnostics(data)
+		if err != nil {
+			return nil, nil, fmt.Errorf("--from-json-diagnostics %s: %w", source, err)
+		}
+		for _, d := range diags {
+			file := cmp.Or(d.File, d.Path, d.Filename, d.FilePath, d.URI)
+			if u, err := url.Parse(file); err == nil && u.Scheme == "file" {
+				file = filepath.FromSlash(u.Path)
+			}
+			if file == "" {
+				continue
+			}
+			r := output.LineRange{Start: d.Line, End: cmp.Or(d.EndLine, d.Line)}
+			if d.Range != nil {
+				r = output.LineRange{Start: d.Range.Start.Line + 1, End: d.Range.End.Line + 1}
+			}
+			add(source, file, r)
+		}
+	}
+
+	return files, ranges, nil
+}
+
+// decodeDiagnostics reads a JSON array of diagnostics, or a stream of
+// diagnostic objects (JSON Lines), flattening ESLint's per-file messages.
+func decodeDiagnostics(data []byte) ([]jsonDiagnostic, error) {
+	var diags []jsonDiagnostic
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		var batch []jsonDiagnostic
+		if bytes.HasPrefix(bytes.TrimSpace(raw), []byte("[")) {
+			if err := json.Unmarshal(raw, &batch); err != nil {
+				return nil, err
+			}
+		} else {
+			var d jsonDiagnostic
+			if err := json.Unmarshal(raw, &d); err != nil {
+				return nil, err
+			}
+			batch = []jsonDiagnostic{d}
+		}
+		for _, d := range batch {
+			if d.Messages == nil {
+				diags = append(diags, d)
+				continue
+			}
+			for _, m := range d.Messages {
+				m.File = cmp.Or(d.File, d.Path, d.Filename, d.FilePath, d.URI)
+				diags = append(diags, m)
+			}
+		}
+	}
+	return diags, nil
+}
+
+// readInput reads a file, or standard input for "-".
+func readInput(path string) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
\ No newline at end of file
@@ -0,0 +1,84 @@
+package clipcat
+
+import (
+	"clipcat/pkg/unpack"
+	"fmt"
+	"io"
+	"os"
+)
+
+// diffContext is how many unchanged lines surround each hunk of
+// `clipcat diff --patch`.
+const diffContext = 3
+
+// Diff implements `clipcat diff [--patch] OLD NEW`: which files of bundle
+// NEW were added, removed or changed since bundle OLD, by header path.
+// Either bundle may be "-" for stdin.
+func Diff(args []string, w io.Writer) error {
+	patch := false
+	var paths []string
+	for _, arg := range args {
+		switch {
+		case arg == "--patch":
+			patch = true
+		case arg == "-h" || arg == "--help":
+			fmt.Print(diffUsage)
+			return nil
+		case len(arg) > 1 && arg[0] == '-':
+			fmt.Fprintf(os.Stderr, "Error: unknown option: %s\n", arg)
+			os.Exit(2)
+		default:
+			paths = append(paths, arg)
+		}
+	}
+	if len(paths) != 2 || (paths[0] == "-" && paths[1] == "-") {
+		fmt.Fprint(os.Stderr, diffUsage)
+		os.Exit(2)
+	}
+
+	var bundles [2][]unpack.File
+	for i, path := range paths {
+		data, err := readInput(path)
+		if err == nil {
+			data, err = unpack.Decompress(data)
+		}
+		if err != nil {
+			return fmt.Errorf("reading bundle: %w", err)
+		}
+		bundles[i] = unpack.Parse(data)
+	}
+	return writeBundleDiff(w, bundles[0], bundles[1], patch)
+}
+
+const diffUsage = `Usage: clipcat diff [--patch] OLD NEW
+
+Compare two clipcat bundles and list the files that were added, removed or
+changed, matched by their header paths. Bundles may be in any format but
+llms-txt and --template, and need not share one. OLD or NEW may be - for
+stdin.
+
+Options:
+      --patch               Follow the list with a unified diff of each file
+`
+
+// writeBundleDiff lists the changes from old to new, one file a line with
+// its added and removed line counts, and with patch their unified diffs.
+func writeBundleDiff(w io.Writer, old, new []unpack.File, patch bool) error {
+	changes, unchanged := unpack.Compare(old, new)
+	counts := map[string]int{}
+	for _, c := range changes {
+		counts[c.Kind]++
+		fmt.Fprintf(w, "%-8s %s (+%d -%d)\n", c.Kind, c.Path, c.Added, c.Removed)
+	}
+	fmt.Fprintf(w, "%d changed, %d added, %d removed, %d unchanged\n", counts["changed"], counts["added"], counts["removed"], unchanged)
+
+	if patch {
+		for _, c := range changes {
+			fmt.Fprintln(w)
+			if _, err := w.Write(c.Unified(diffContext)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
\ No newline at end of file
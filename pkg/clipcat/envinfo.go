@@ -0,0 +1,81 @@
+package clipcat
+
+import (
+	"bufio"
+	"bytes"
+	"clipcat/internal/git"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// EnvInfoLabel heads the --env-info section.
+const EnvInfoLabel = "ENVIRONMENT"
+
+// EnvAllowlist are the environment variables --env-info reports when set.
+// They describe the toolchain, never credentials; --env-var adds more.
+var EnvAllowlist = []string{
+	"SHELL", "TERM", "LANG", "CI",
+	"GOPATH", "GOFLAGS", "GOOS", "GOARCH", "CGO_ENABLED",
+	"NODE_ENV", "VIRTUAL_ENV", "CONDA_DEFAULT_ENV", "JAVA_HOME",
+}
+
+// envInfo renders the --env-info section: OS, Go toolchain, git HEAD of the
+// working directory, clipcat version and the allowlisted variables plus
+// extra. Facts that cannot be determined are left out.
+func envInfo(extra []string) []byte {
+	var buf bytes.Buffer
+	platform := runtime.GOOS + "/" + runtime.GOARCH
+	if name := osName(); name != "" {
+		platform += " (" + name + ")"
+	}
+	fmt.Fprintf(&buf, "os:      %s\n", platform)
+	if out, err := exec.Command("go", "env", "GOVERSION").Output(); err == nil {
+		fmt.Fprintf(&buf, "go:      %s\n", strings.TrimSpace(string(out)))
+	}
+	if branch, commit, dirty, err := git.Head("."); err == nil {
+		fmt.Fprintf(&buf, "git:     %s @ %s", branch, commit)
+		if dirty {
+			buf.WriteString(" (uncommitted changes)")
+		}
+		buf.WriteString("\n")
+	}
+	fmt.Fprintf(&buf, "clipcat: %s\n", Version)
+
+	var vars []string
+	seen := make(map[string]bool)
+	for _, name := range append(EnvAllowlist[:len(EnvAllowlist):len(EnvAllowlist)], extra...) {
+		if value, ok := os.LookupEnv(name); ok && !seen[name] {
+			seen[name] = true
+			vars = append(vars, name+"="+value)
+		}
+	}
+	if len(vars) > 0 {
+		buf.WriteString("env:\n")
+		for _, v := range vars {
+			fmt.Fprintf(&buf, "  %s\n", v)
+		}
+	}
+	return buf.Bytes()
+}
+
+// osName returns the distribution name from /etc/os-release on Linux, or "".
+func osName() string {
+	if runtime.GOOS != "linux" {
+		return ""
+	}
+	f, err := os.Open("/etc/os-release")
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if name, ok := strings.CutPrefix(scanner.Text(), "PRETTY_NAME="); ok {
+			return strings.Trim(name, `"`)
+		}
+	}
+	return ""
+}
\ No newline at end of file
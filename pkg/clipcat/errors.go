@@ -0,0 +1,110 @@
+package clipcat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Exit codes reported by the clipcat command
+const (
+	ExitOK        = 0
+	ExitError     = 1
+	ExitUsage     = 2
+	ExitNoFiles   = 3
+	ExitClipboard = 4
+	ExitTimeout   = 5
+)
+
+// ErrNoFiles is returned when inputs and excludes leave nothing to copy.
+var ErrNoFiles = errors.New("no files matched after applying excludes")
+
+// ErrChanged is reported with --strict when a file was removed or changed
+// between collecting and reading it.
+var ErrChanged = errors.New("removed or changed during the run")
+
+// StrictError reports with --strict what a run worked around: inputs it
+// skipped, patterns that matched nothing, and files, URLs and --run commands
+// it could not read. The output was still produced.
+type StrictError struct {
+	Problems []string
+}
+
+func (e *StrictError) Error() string {
+	noun := "problems"
+	if len(e.Problems) == 1 {
+		noun = "problem"
+	}
+	return fmt.Sprintf("--strict: %d %s:\n  %s", len(e.Problems), noun, strings.Join(e.Problems, "\n  "))
+}
+
+// ClipboardError reports that the output could not be placed on the
+// clipboard. Output requested with -p or written to files is still produced.
+type ClipboardError struct {
+	Err error
+}
+
+func (e *ClipboardError) Error() string {
+	return "copying to clipboard: " + e.Err.Error()
+}
+
+func (e *ClipboardError) Unwrap() error {
+	return e.Err
+}
+
+// TimeoutError reports that --timeout expired, with where the run was:
+// the phase (collect, read or copy), the path or files it was waiting on,
+// and how many files it had read by then.
+type TimeoutError struct {
+	Timeout time.Duration
+	Phase   string
+	Paths   []string
+	Done    int
+}
+
+func (e *TimeoutError) Error() string {
+	var where string
+	switch e.Phase {
+	case "collect":
+		where = "collecting files"
+		if len(e.Paths) > 0 {
+			where = "collecting files at " + e.Paths[0]
+		}
+	case "read":
+		where = fmt.Sprintf("reading files (%d read)", e.Done)
+		if len(e.Paths) > 0 {
+			where = fmt.Sprintf("reading %s (%d files read)", strings.Join(e.Paths, ", "), e.Done)
+		}
+	case "copy":
+		where = "copying to the clipboard"
+		if len(e.Paths) > 0 {
+			where = "copying to the clipboard with " + e.Paths[0]
+		}
+	default:
+		where = "running"
+	}
+	return fmt.Sprintf("timed out after %s while %s", e.Timeout, where)
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return context.DeadlineExceeded
+}
+
+// ExitCode maps an error returned by Execute or Run to a process exit code.
+func ExitCode(err error) int {
+	var clipErr *ClipboardError
+	var timeoutErr *TimeoutError
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.Is(err, ErrNoFiles):
+		return ExitNoFiles
+	case errors.As(err, &timeoutErr):
+		return ExitTimeout
+	case errors.As(err, &clipErr):
+		return ExitClipboard
+	}
+	return ExitError
+}
\ No newline at end of file
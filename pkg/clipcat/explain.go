@@ -0,0 +1,219 @@
+package clipcat
+
+import (
+	"clipcat/pkg/collector"
+	"clipcat/pkg/exclude"
+	"clipcat/pkg/lang"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// explain reports, for each target, whether it would be copied and which
+// input, exclude rule or filter decided it. files is the collected result
+// and dropped the files left out by --max-files.
+func explain(w io.Writer, targets []string, opts collector.Options, files, dropped []string) error {
+	noExcludes, err := exclude.BuildMatcher(nil, nil, false)
+	if err != nil {
+		return err
+	}
+
+	for _, target := range targets {
+		abs, err := filepath.Abs(target)
+		if err != nil {
+			return err
+		}
+		info, err := os.Stat(abs)
+		if err != nil {
+			fmt.Fprintf(w, "%s: does not exist\n", target)
+			continue
+		}
+
+		status := "excluded"
+		if slices.Contains(files, abs) {
+			status = "included"
+		}
+		fmt.Fprintf(w, "%s: %s\n", target, status)
+
+		if info.IsDir() {
+			fmt.Fprintf(w, "  note:    directories are walked, not copied; showing the directory rules\n")
+		} else if collector.HasIgnoreMarker(abs) {
+			fmt.Fprintf(w, "  marker:  first line contains %s\n", collector.IgnoreMarker)
+		}
+
+		if !info.IsDir() && slices.ContainsFunc(opts.Force, func(f string) bool {
+			forced, _ := filepath.Abs(f)
+			return forced == abs
+		}) {
+			fmt.Fprintf(w, "  force:   --force-include overrides the rules below, except the policy\n")
+		}
+
+		// Which inputs select the path before excludes apply
+		var inputs []string
+		for _, input := range opts.Paths {
+			probe := opts
+			probe.Paths = []string{input}
+			probe.Matcher = noExcludes
+			probe.Defaults = nil
+			probe.Only = nil
+			probe.ModifiedAfter, probe.ModifiedBefore = time.Time{}, time.Time{}
+			probe.Languages = nil
+			probe.Types = nil
+			probe.KeepMarked = true
+			probe.Policy = nil
+			probe.Force = nil
+			found, _ := collector.Collect(probe)
+			if slices.Contains(found, abs) || (info.IsDir() && coversDir(input, opts.Root, abs)) {
+				inputs = append(inputs, input)
+			}
+		}
+		switch {
+		case len(inputs) > 0:
+			fmt.Fprintf(w, "  input:   %s\n", strings.Join(inputs, ", "))
+		case opts.Git:
+			fmt.Fprintf(w, "  input:   none of %s selects it among git-tracked files\n", strings.Join(opts.Paths, ", "))
+		default:
+			fmt.Fprintf(w, "  input:   none of %s selects it\n", strings.Join(opts.Paths, ", "))
+		}
+
+		// Excluded directories are pruned, so their rules hide everything below.
+		// Default excludes only apply to walked inputs, not literal files.
+		roots := []string{"."}
+		if len(inputs) > 0 {
+			roots = nil
+			for _, input := range inputs {
+				roots = append(roots, walkRoot(input, opts.Root))
+			}
+		}
+		matcher := opts.Matcher
+		if slices.ContainsFunc(roots, func(root string) bool { return root != "" }) {
+			matcher = matcher.WithDefaults(opts.Defaults)
+		}
+		if dir, rule := excludedAncestor(matcher, roots, abs); rule != nil {
+			fmt.Fprintf(w, "  exclude: %s (directory %s)\n", rule, dir)
+		} else {
+			excluded, rule := matcher.Explain(abs, info.IsDir())
+			switch {
+			case excluded:
+				fmt.Fprintf(w, "  exclude: %s\n", rule)
+			case rule != nil:
+				fmt.Fprintf(w, "  exclude: re-included by %s\n", rule)
+			default:
+				fmt.Fprintf(w, "  exclude: no rule matches\n")
+			}
+		}
+
+		if rule, ok := opts.Policy.Forbids(abs); ok {
+			fmt.Fprintf(w, "  policy:  forbidden by %s\n", rule)
+		}
+		if !info.IsDir() && len(opts.Languages) > 0 && !lang.Match(abs, opts.Languages) {
+			language := lang.DetectFile(abs)
+			if language == "" {
+				language = "unknown"
+			}
+			fmt.Fprintf(w, "  lang:    %s, not among --ext %s\n", language, strings.Join(opts.Languages, ","))
+		}
+		if !info.IsDir() && len(opts.Types) > 0 && !lang.MatchCategory(abs, opts.Types) {
+			category := lang.Category(abs)
+			if category == "" {
+				category = "none"
+			}
+			fmt.Fprintf(w, "  type:    %s, not among --type %s\n", category, strings.Join(opts.Types, ","))
+		}
+		if !info.IsDir() && !opts.InTimeRange(abs) {
+			fmt.Fprintf(w, "  age:     modified %s, outside --newer-than/--older-than\n", info.ModTime().Format("2006-01-02 15:04"))
+		}
+
+		if slices.Contains(dropped, abs) {
+			fmt.Fprintf(w, "  limit:   beyond --max-files %d in --sort order\n", len(files))
+		}
+
+		if opts.Only != nil && !info.IsDir() {
+			resolved, err := filepath.EvalSymlinks(abs)
+			if err != nil {
+				resolved = abs
+			}
+			if !opts.Only[resolved] {
+				fmt.Fprintf(w, "  changes: not among the selected git changes\n")
+			}
+		}
+	}
+	return nil
+}
+
+// displayPath shows path relative to the working directory when it is below it.
+func displayPath(path string) string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return path
+	}
+	rel, err := filepath.Rel(wd, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return path
+	}
+	return rel
+}
+
+// walkRoot returns the directory the collector walks for input: the
+// directory itself, the search root for globs, or "" for a single file.
+func walkRoot(input, globRoot string) string {
+	info, err := os.Stat(input)
+	switch {
+	case err != nil:
+		return collector.GlobRoot(input, globRoot)
+	case info.IsDir():
+		return input
+	}
+	return ""
+}
+
+func coversDir(input, globRoot, dir string) bool {
+	root := walkRoot(input, globRoot)
+	if root == "" {
+		return false
+	}
+	rootAbs, err := filepath.Abs(root)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(rootAbs, dir)
+	return err == nil && !strings.HasPrefix(rel, "..")
+}
+
+// excludedAncestor returns the first directory between a walk root and path
+// (exclusive) that the matcher prunes, along with the rule responsible.
+func excludedAncestor(matcher *exclude.ExcludeMatcher, roots []string, path string) (string, *exclude.Rule) {
+	for _, root := range roots {
+		if root == "" {
+			continue
+		}
+		rootAbs, err := filepath.Abs(root)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(rootAbs, filepath.Dir(path))
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+
+		dir := rootAbs
+		parts := []string{}
+		if rel != "." {
+			parts = strings.Split(rel, string(filepath.Separator))
+		}
+		for i := 0; ; i++ {
+			if excluded, rule := matcher.Explain(dir, true); excluded {
+				return displayPath(dir) + string(filepath.Separator), rule
+			}
+			if i >= len(parts) {
+				break
+			}
+			dir = filepath.Join(dir, parts[i])
+		}
+	}
+	return "", nil
+}
\ No newline at end of file
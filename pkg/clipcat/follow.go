@@ -0,0 +1,52 @@
+package clipcat
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// scriptExts are the files --follow-imports reads import statements from.
+// Vue and Svelte components import the same way in their script blocks.
+var scriptExts = []string{".js", ".mjs", ".cjs", ".jsx", ".ts", ".mts", ".cts", ".tsx", ".vue", ".svelte"}
+
+// followImports returns the --follow-imports entry files and every local
+// file they import, directly or not, with relative specifiers ("./x",
+// "../lib/y"). Bare specifiers name packages and are not followed, nor is
+// anything under node_modules. Files are listed in the order they were
+// reached.
+func (b *Bundler) followImports() ([]string, error) {
+	exists := func(path string) bool {
+		info, err := os.Stat(path)
+		return err == nil && info.Mode().IsRegular() && !inNodeModules(path)
+	}
+
+	var files []string
+	seen := make(map[string]bool)
+	queue := slices.Clone(b.cfg.FollowImports)
+	for _, entry := range queue {
+		if !exists(entry) {
+			return nil, fmt.Errorf("%s is not a file", entry)
+		}
+	}
+	for len(queue) > 0 {
+		file := queue[0]
+		queue = queue[1:]
+		abs, err := filepath.Abs(file)
+		if err != nil || seen[abs] {
+			continue
+		}
+		seen[abs] = true
+		files = append(files, file)
+		if slices.Contains(scriptExts, filepath.Ext(file)) {
+			queue = append(queue, jsImports(file, exists)...)
+		}
+	}
+	return files, nil
+}
+
+func inNodeModules(path string) bool {
+	return slices.Contains(strings.Split(filepath.ToSlash(path), "/"), "node_modules")
+}
\ No newline at end of file
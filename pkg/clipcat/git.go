@@ -0,0 +1,69 @@
+package clipcat
+
+import (
+	"clipcat/internal/git"
+	"fmt"
+	"path/filepath"
+)
+
+// changedFileSet resolves --changed-since/--staged/--unstaged into the set of
+// files the collector may return, keyed by real absolute path.
+func changedFileSet(cfg *Config) (map[string]bool, error) {
+	root, err := git.Root(".")
+	if err != nil {
+		return nil, err
+	}
+	root, _ = filepath.EvalSymlinks(root)
+
+	var changed []string
+	if cfg.ChangedSince != "" {
+		files, err := git.ChangedFiles(".", cfg.ChangedSince)
+		if err != nil {
+			return nil, err
+		}
+		changed = append(changed, files...)
+	}
+	if cfg.Staged {
+		files, err := git.StagedFiles(".")
+		if err != nil {
+			return nil, err
+		}
+		changed = append(changed, files...)
+	}
+	if cfg.Unstaged {
+		files, err := git.UnstagedFiles(".")
+		if err != nil {
+			return nil, err
+		}
+		changed = append(changed, files...)
+	}
+
+	set := make(map[string]bool, len(changed))
+	for _, rel := range changed {
+		set[filepath.Join(root, filepath.FromSlash(rel))] = true
+	}
+	return set, nil
+}
+
+// fileDiffer returns a function producing each file's diff against the
+// merge-base of ref and HEAD. Files outside the repository have no diff.
+func fileDiffer(ref string) func(path string) []byte {
+	base := git.MergeBase(".", ref)
+	return func(path string) []byte {
+		diff, err := git.Diff(filepath.Dir(path), base, path)
+		if err != nil {
+			return nil
+		}
+		return diff
+	}
+}
+
+// gitMeta describes the last commit touching path for --git-meta headers, or
+// returns "" for files without history.
+func gitMeta(path string) string {
+	commit, err := git.LastCommit(filepath.Dir(path), path)
+	if err != nil || commit == nil {
+		return ""
+	}
+	return fmt.Sprintf("commit %s, %s, %s", commit.Hash, commit.Author, commit.Date)
+}
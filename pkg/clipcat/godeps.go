@@ -0,0 +1,96 @@
+package clipcat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// goPackage is the part of `go list -json` output --go-deps uses.
+type goPackage struct {
+	ImportPath string
+	Dir        string
+	GoFiles    []string
+	CgoFiles   []string
+	Imports    []string
+	DepOnly    bool
+	Module     *struct{ Main bool }
+	Error      *struct{ Err string }
+}
+
+// goDeps lists the Go files of the --go-deps packages and of the packages
+// they import from the main module (or workspace), --go-deps-depth levels
+// deep or all the way down. Standard library and third-party packages are
+// left out unless named. It asks the go command, so build tags and the current GOOS and
+// GOARCH apply as they would to go build.
+func (b *Bundler) goDeps(ctx context.Context) ([]string, error) {
+	if _, err := exec.LookPath("go"); err != nil {
+		return nil, errors.New("go not found; --go-deps needs the go command")
+	}
+	args := append([]string{"list", "-e", "-deps", "-json", "--"}, b.cfg.GoDeps...)
+	cmd := exec.CommandContext(ctx, "go", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("go list: %s", msg)
+		}
+		return nil, fmt.Errorf("go list: %w", err)
+	}
+
+	packages := make(map[string]*goPackage)
+	var roots []string
+	dec := json.NewDecoder(bytes.NewReader(out))
+	for {
+		var pkg goPackage
+		if err := dec.Decode(&pkg); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("go list: %w", err)
+		}
+		if !pkg.DepOnly {
+			if pkg.Error != nil {
+				return nil, fmt.Errorf("%s: %s", pkg.ImportPath, pkg.Error.Err)
+			}
+			roots = append(roots, pkg.ImportPath)
+		}
+		packages[pkg.ImportPath] = &pkg
+	}
+
+	if len(roots) == 0 {
+		return nil, fmt.Errorf("%s matched no packages", strings.Join(b.cfg.GoDeps, " "))
+	}
+
+	// Breadth first, so each package is reached at its smallest depth
+	var files []string
+	seen := make(map[string]bool)
+	level := roots
+	for depth := 0; len(level) > 0; depth++ {
+		var next []string
+		for _, path := range level {
+			pkg := packages[path]
+			if seen[path] || pkg == nil {
+				continue
+			}
+			if depth > 0 && (pkg.Module == nil || !pkg.Module.Main) {
+				continue
+			}
+			seen[path] = true
+			for _, name := range append(pkg.GoFiles, pkg.CgoFiles...) {
+				files = append(files, filepath.Join(pkg.Dir, name))
+			}
+			if b.cfg.GoDepsDepth == 0 || depth < b.cfg.GoDepsDepth {
+				next = append(next, pkg.Imports...)
+			}
+		}
+		level = next
+	}
+	return files, nil
+}
\ No newline at end of file
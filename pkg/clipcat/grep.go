@@ -0,0 +1,31 @@
+package clipcat
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// grepPattern combines --grep strings and --grep-regexp expressions into one
+// expression matching any of them.
+func grepPattern(cfg *Config) (*regexp.Regexp, error) {
+	var alts []string
+	for _, s := range cfg.Grep {
+		alts = append(alts, regexp.QuoteMeta(s))
+	}
+	for _, expr := range cfg.GrepRegexps {
+		if _, err := regexp.Compile(expr); err != nil {
+			return nil, fmt.Errorf("invalid --grep-regexp %q: %w", expr, err)
+		}
+		alts = append(alts, "(?:"+expr+")")
+	}
+	return regexp.Compile(strings.Join(alts, "|"))
+}
+
+// matchCount describes n --grep matches for a file header.
+func matchCount(n int) string {
+	if n == 1 {
+		return "1 match"
+	}
+	return fmt.Sprintf("%d matches", n)
+}
\ No newline at end of file
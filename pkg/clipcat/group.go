@@ -0,0 +1,87 @@
+package clipcat
+
+import (
+	"cmp"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Values of --group-by.
+const (
+	GroupNone = "none"
+	GroupDir  = "dir"  // top-level directory below the working directory
+	GroupExt  = "ext"  // file extension
+	GroupRoot = "root" // the input path a file was collected under
+)
+
+// groupFiles returns files reordered so each --group-by group is contiguous,
+// groups in order of first appearance and files in their sorted order
+// within a group, along with the group name of each file.
+func (b *Bundler) groupFiles(inputs []string, files []string) ([]string, []string) {
+	var keyOf func(string) string
+	switch b.cfg.GroupBy {
+	case GroupDir:
+		wd := b.workDir()
+		keyOf = func(file string) string { return topDir(wd, file) }
+	case GroupExt:
+		keyOf = func(file string) string { return cmp.Or(filepath.Ext(file), "(no extension)") }
+	case GroupRoot:
+		keyOf = func(file string) string { return inputOf(inputs, b.cfg.Root, file) }
+	default:
+		return files, nil
+	}
+
+	keys := make(map[string]string, len(files))
+	var order []string
+	for _, file := range files {
+		key := keyOf(file)
+		keys[file] = key
+		if !slices.Contains(order, key) {
+			order = append(order, key)
+		}
+	}
+	grouped := slices.Clone(files)
+	slices.SortStableFunc(grouped, func(a, b string) int {
+		return slices.Index(order, keys[a]) - slices.Index(order, keys[b])
+	})
+	names := make([]string, len(grouped))
+	for i, file := range grouped {
+		names[i] = keys[file]
+	}
+	return grouped, names
+}
+
+// topDir names the first directory of file below wd ("src/"), "./" for
+// files directly in wd, and the file's own directory outside wd.
+func topDir(wd, file string) string {
+	rel, err := filepath.Rel(wd, file)
+	if err != nil || !filepath.IsLocal(rel) {
+		return filepath.ToSlash(filepath.Dir(file)) + "/"
+	}
+	top, _, nested := strings.Cut(filepath.ToSlash(rel), "/")
+	if !nested {
+		return "./"
+	}
+	return top + "/"
+}
+
+// inputOf returns the most specific input that selects file: the file
+// itself, or else the directory or glob whose walk starts deepest among
+// those covering it, the first of them on a tie. In `clipcat . src/` the
+// files below src/ belong to src/.
+func inputOf(inputs []string, globRoot, file string) string {
+	best, depth := "", -1
+	for _, input := range inputs {
+		if abs, err := filepath.Abs(input); err == nil && abs == file {
+			return input
+		}
+		if !coversDir(input, globRoot, file) {
+			continue
+		}
+		if root, err := filepath.Abs(walkRoot(input, globRoot)); err == nil && len(root) > depth {
+			best, depth = input, len(root)
+		}
+	}
+	return best
+}
\ No newline at end of file
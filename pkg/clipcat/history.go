@@ -0,0 +1,161 @@
+package clipcat
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// historySize is how many copies `clipcat history` remembers.
+const historySize = 20
+
+// HistoryEntry is one remembered copy: the command line and where it ran,
+// and what it produced.
+type HistoryEntry struct {
+	Time  time.Time `json:"time"`
+	Dir   string    `json:"dir"`
+	Args  []string  `json:"args"`
+	Files []string  `json:"files"` // resolved files and URLs, in output order
+	Size  int64     `json:"size"`  // bytes copied
+}
+
+// stateFile returns the path of a file in clipcat's state directory, e.g.
+// ~/.cache/clipcat/history.json.
+func stateFile(name string) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "clipcat", name), nil
+}
+
+func loadHistory() ([]HistoryEntry, error) {
+	path, err := stateFile("history.json")
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var history []HistoryEntry
+	if err := json.Unmarshal(data, &history); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	return history, nil
+}
+
+// recordHistory adds a copy made from the command line to the front of the
+// history, dropping the oldest entries beyond historySize.
+func recordHistory(cfg *Config, doc *document) error {
+	history, err := loadHistory()
+	if err != nil {
+		return err
+	}
+	entry := HistoryEntry{
+		Time:  time.Now(),
+		Args:  cfg.Args,
+		Files: append(slices.Clone(doc.files), doc.urls...),
+		Size:  doc.size(),
+	}
+	entry.Dir, _ = os.Getwd()
+	history = append([]HistoryEntry{entry}, history[:min(len(history), historySize-1)]...)
+
+	path, err := stateFile("history.json")
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// History implements `clipcat history [N]`: the remembered copies, newest
+// first, or the details and files of copy N.
+func History(args []string, w io.Writer) error {
+	history, err := loadHistory()
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		if len(history) == 0 {
+			fmt.Fprintln(w, "No copies remembered yet.")
+			return nil
+		}
+		for i, e := range history {
+			fmt.Fprintf(w, "%2d  %s  %4d files  %9s  %s  clipcat %s\n", i+1, e.Time.Format("2006-01-02 15:04"),
+				len(e.Files), formatSize(e.Size), e.Dir, strings.Join(e.Args, " "))
+		}
+		return nil
+	}
+
+	if len(args) > 1 {
+		fmt.Fprintf(os.Stderr, "Usage: clipcat history [N]\n")
+		os.Exit(2)
+	}
+	e, err := historyEntry(history, args[0])
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "time:    %s\n", e.Time.Format(time.RFC3339))
+	fmt.Fprintf(w, "dir:     %s\n", e.Dir)
+	fmt.Fprintf(w, "command: clipcat %s\n", strings.Join(e.Args, " "))
+	fmt.Fprintf(w, "size:    %s\n", formatSize(e.Size))
+	fmt.Fprintf(w, "files:   %d\n", len(e.Files))
+	for _, file := range e.Files {
+		fmt.Fprintf(w, "  %s\n", file)
+	}
+	return nil
+}
+
+// Rerun implements `clipcat rerun [N] [ARGS...]`: copy N (default 1, the
+// latest) is repeated in the directory it ran in, with ARGS appended.
+// Extra paths that exist here are made absolute first, so
+// `clipcat rerun notes.md` adds the notes.md of the current directory.
+func Rerun(args []string) error {
+	history, err := loadHistory()
+	if err != nil {
+		return err
+	}
+	n := "1"
+	if len(args) > 0 {
+		if _, err := strconv.Atoi(args[0]); err == nil {
+			n, args = args[0], args[1:]
+		}
+	}
+	e, err := historyEntry(history, n)
+	if err != nil {
+		return err
+	}
+
+	extra := absPaths(args)
+	if err := os.Chdir(e.Dir); err != nil {
+		return fmt.Errorf("rerun: %w", err)
+	}
+	return Run(parseArgs(append(slices.Clone(e.Args), extra...)))
+}
+
+func historyEntry(history []HistoryEntry, n string) (HistoryEntry, error) {
+	i, err := strconv.Atoi(n)
+	if err != nil || i < 1 {
+		return HistoryEntry{}, fmt.Errorf("invalid history number %q", n)
+	}
+	if i > len(history) {
+		return HistoryEntry{}, fmt.Errorf("no copy %d in the history (%d remembered)", i, len(history))
+	}
+	return history[i-1], nil
+}
\ No newline at end of file
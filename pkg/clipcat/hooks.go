@@ -0,0 +1,44 @@
+package clipcat
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// runHook runs a [hooks] command around a copy. It describes the copy in
+// its environment: CLIPCAT_HOOK (pre_copy or post_copy), CLIPCAT_FILES,
+// CLIPCAT_BYTES, CLIPCAT_TOKENS and CLIPCAT_FORMAT, and gets the copied
+// files and URLs on stdin, one per line. Its output goes to stderr, so it
+// never mixes with --print.
+func runHook(ctx context.Context, name, command string, cfg *Config, doc *document) error {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	var list bytes.Buffer
+	for _, path := range append(append([]string(nil), doc.files...), doc.urls...) {
+		list.WriteString(path + "\n")
+	}
+	cmd.Stdin = &list
+	cmd.Stdout, cmd.Stderr = os.Stderr, os.Stderr
+	cmd.Env = append(os.Environ(),
+		"CLIPCAT_HOOK="+name,
+		"CLIPCAT_FILES="+strconv.Itoa(len(doc.files)+len(doc.urls)),
+		"CLIPCAT_BYTES="+strconv.FormatInt(doc.size(), 10),
+		"CLIPCAT_TOKENS="+strconv.Itoa(doc.tokens(cfg)),
+		"CLIPCAT_FORMAT="+cmp.Or(cfg.Format, "plain"),
+	)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s hook %q: %w", name, strings.TrimSpace(command), err)
+	}
+	return nil
+}
\ No newline at end of file
@@ -0,0 +1,82 @@
+package clipcat
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// idRe matches the file IDs --ids assigns.
+var idRe = regexp.MustCompile(`^F[1-9][0-9]*$`)
+
+// idState is the mapping of the last --ids run, kept for `clipcat expand`.
+type idState struct {
+	Dir   string            `json:"dir"`   // working directory of the run
+	Files map[string]string `json:"files"` // ID -> absolute path
+}
+
+// saveIDs records ids (file -> ID) as the last --ids run.
+func saveIDs(ids map[string]string) error {
+	path, err := stateFile("ids.json")
+	if err != nil {
+		return err
+	}
+	state := idState{Files: make(map[string]string, len(ids))}
+	state.Dir, _ = os.Getwd()
+	for file, id := range ids {
+		state.Files[id] = file
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// expandIDs resolves IDs from the last --ids run into files, and returns
+// the file -> ID mapping so the files keep their IDs.
+func expandIDs(ids []string) ([]string, map[string]string, error) {
+	path, err := stateFile("ids.json")
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil, fmt.Errorf("no --ids run to expand; copy with --ids first")
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	var state idState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+
+	var files []string
+	fixed := make(map[string]string, len(ids))
+	for _, id := range ids {
+		file, ok := state.Files[id]
+		if !ok {
+			return nil, nil, fmt.Errorf("unknown file ID %s: the last --ids run in %s had F1-F%d", id, state.Dir, lastID(state))
+		}
+		files = append(files, file)
+		fixed[file] = id
+	}
+	return files, fixed, nil
+}
+
+func lastID(state idState) int {
+	n := 0
+	for id := range state.Files {
+		i, _ := strconv.Atoi(strings.TrimPrefix(id, "F"))
+		n = max(n, i)
+	}
+	return n
+}
\ No newline at end of file
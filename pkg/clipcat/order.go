@@ -0,0 +1,259 @@
+package clipcat
+
+import (
+	"bufio"
+	"cmp"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Ranks of --smart-order; files of the same rank keep their --sort order.
+const (
+	rankReadme = iota
+	rankManifest
+	rankEntrypoint
+	rankImported
+	rankOther
+)
+
+var (
+	// manifests are the build files that say what a project is and needs
+	manifests = map[string]bool{
+		"go.mod": true, "package.json": true, "tsconfig.json": true, "deno.json": true,
+		"pyproject.toml": true, "setup.py": true, "setup.cfg": true, "requirements.txt": true,
+		"Cargo.toml": true, "pom.xml": true, "build.gradle": true, "build.gradle.kts": true,
+		"Gemfile": true, "composer.json": true, "mix.exs": true, "CMakeLists.txt": true,
+		"Makefile": true, "Dockerfile": true,
+	}
+	// entrypoints are the files a program starts from
+	entrypoints = map[string]bool{
+		"main.go": true, "main.py": true, "__main__.py": true, "app.py": true, "manage.py": true,
+		"index.js": true, "index.mjs": true, "index.ts": true, "index.tsx": true, "index.jsx": true,
+		"main.js": true, "main.ts": true, "main.tsx": true, "server.js": true, "server.ts": true,
+		"main.rs": true, "lib.rs": true, "Main.java": true, "Program.cs": true,
+	}
+
+	goImport     = regexp.MustCompile(`^\s*(?:import\s+)?(?:[\w.]+\s+)?"([^"]+)"`)
+	goModule     = regexp.MustCompile(`(?m)^module\s+(\S+)`)
+	jsImport     = regexp.MustCompile(`(?:\bfrom|\bimport|\brequire\s*\(|\bimport\s*\()\s*['"](\.{1,2}/[^'"]*)['"]`)
+	pyFromImport = regexp.MustCompile(`^\s*from\s+(\.*[\w.]*)\s+import\b`)
+	pyImport     = regexp.MustCompile(`^\s*import\s+([\w.]+(?:\s*,\s*[\w.]+)*)`)
+)
+
+// smartOrder puts files in --smart-order: READMEs, then build manifests,
+// then entrypoints, then the files others in the bundle import, most
+// imported first, then the rest. Models given too long a bundle lose its
+// end, so what explains the project should come first.
+func smartOrder(files []string) {
+	imported := importCounts(files)
+	rank := func(file string) int {
+		base := filepath.Base(file)
+		switch {
+		case strings.HasPrefix(strings.ToLower(base), "readme"):
+			return rankReadme
+		case manifests[base]:
+			return rankManifest
+		case entrypoints[base]:
+			return rankEntrypoint
+		case imported[file] > 0:
+			return rankImported
+		}
+		return rankOther
+	}
+	slices.SortStableFunc(files, func(a, b string) int {
+		ra, rb := rank(a), rank(b)
+		if ra != rb || ra != rankImported {
+			return cmp.Compare(ra, rb)
+		}
+		return cmp.Compare(imported[b], imported[a])
+	})
+}
+
+// importCounts returns how many other files among files import each one,
+// from a look at the imports of Go, JavaScript, TypeScript and Python
+// files. Imports that resolve outside files are ignored.
+func importCounts(files []string) map[string]int {
+	present := make(map[string]bool, len(files))
+	byDir := make(map[string][]string)
+	for _, file := range files {
+		present[file] = true
+		byDir[filepath.Dir(file)] = append(byDir[filepath.Dir(file)], file)
+	}
+	modules := make(map[string]goMod)
+
+	counts := make(map[string]int)
+	for _, file := range files {
+		var targets []string
+		switch ext := filepath.Ext(file); ext {
+		case ".go":
+			targets = goImports(file, byDir, modules)
+		case ".js", ".mjs", ".cjs", ".jsx", ".ts", ".mts", ".tsx":
+			targets = jsImports(file, func(path string) bool { return present[path] })
+		case ".py":
+			targets = pyImports(file, files, present)
+		}
+		seen := map[string]bool{file: true}
+		for _, target := range targets {
+			if !seen[target] {
+				seen[target] = true
+				counts[target]++
+			}
+		}
+	}
+	return counts
+}
+
+// importLines returns the lines of the head of file, where imports are.
+func importLines(file string) []string {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	var lines []string
+	scanner := bufio.NewScanner(io.LimitReader(f, 64<<10))
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines
+}
+
+// goMod is the module a directory belongs to: its path and root directory.
+type goMod struct{ path, root string }
+
+// moduleOf finds the go.mod above dir, caching the answer per directory.
+func moduleOf(dir string, modules map[string]goMod) goMod {
+	if mod, ok := modules[dir]; ok {
+		return mod
+	}
+	var mod goMod
+	if data, err := os.ReadFile(filepath.Join(dir, "go.mod")); err == nil {
+		if m := goModule.FindSubmatch(data); m != nil {
+			mod = goMod{path: string(m[1]), root: dir}
+		}
+	} else if parent := filepath.Dir(dir); parent != dir {
+		mod = moduleOf(parent, modules)
+	}
+	modules[dir] = mod
+	return mod
+}
+
+// goImports returns the non-test files of the packages of its own module
+// that a Go file imports.
+func goImports(file string, byDir map[string][]string, modules map[string]goMod) []string {
+	mod := moduleOf(filepath.Dir(file), modules)
+	if mod.path == "" {
+		return nil
+	}
+	var targets []string
+	block := false
+	for _, line := range importLines(file) {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "import ("):
+			block = true
+			continue
+		case block && trimmed == ")":
+			block = false
+			continue
+		case !block && !strings.HasPrefix(trimmed, "import "):
+			if strings.HasPrefix(trimmed, "func ") || strings.HasPrefix(trimmed, "type ") {
+				return targets
+			}
+			continue
+		}
+		m := goImport.FindStringSubmatch(trimmed)
+		if m == nil {
+			continue
+		}
+		rest, ok := strings.CutPrefix(m[1], mod.path)
+		if !ok || (rest != "" && !strings.HasPrefix(rest, "/")) {
+			continue
+		}
+		for _, target := range byDir[filepath.Join(mod.root, filepath.FromSlash(rest))] {
+			if filepath.Ext(target) == ".go" && !strings.HasSuffix(target, "_test.go") {
+				targets = append(targets, target)
+			}
+		}
+	}
+	return targets
+}
+
+// jsImports returns the files a JavaScript or TypeScript file imports with
+// relative specifiers, trying the usual extensions and index files, among
+// those exists reports.
+func jsImports(file string, exists func(path string) bool) []string {
+	var targets []string
+	for _, line := range importLines(file) {
+		for _, m := range jsImport.FindAllStringSubmatch(line, -1) {
+			base := filepath.Join(filepath.Dir(file), filepath.FromSlash(m[1]))
+			if target := resolveJS(base, exists); target != "" {
+				targets = append(targets, target)
+			}
+		}
+	}
+	return targets
+}
+
+func resolveJS(base string, exists func(path string) bool) string {
+	if exists(base) {
+		return base
+	}
+	// import "./util.js" may name util.ts
+	stem := strings.TrimSuffix(base, filepath.Ext(base))
+	for _, ext := range []string{".ts", ".tsx", ".js", ".jsx", ".mjs", ".cjs", ".mts"} {
+		for _, candidate := range []string{base + ext, stem + ext, filepath.Join(base, "index"+ext)} {
+			if exists(candidate) {
+				return candidate
+			}
+		}
+	}
+	return ""
+}
+
+// pyImports returns the files a Python file imports: relative imports
+// from its own directory, absolute ones from any directory in files.
+func pyImports(file string, files []string, present map[string]bool) []string {
+	var modules []string
+	for _, line := range importLines(file) {
+		if m := pyFromImport.FindStringSubmatch(line); m != nil {
+			modules = append(modules, m[1])
+		} else if m := pyImport.FindStringSubmatch(line); m != nil {
+			for _, name := range strings.Split(m[1], ",") {
+				modules = append(modules, strings.TrimSpace(name))
+			}
+		}
+	}
+
+	var targets []string
+	for _, module := range modules {
+		name := strings.TrimLeft(module, ".")
+		rel := filepath.FromSlash(strings.ReplaceAll(name, ".", "/"))
+		if dots := len(module) - len(name); dots > 0 {
+			dir := filepath.Dir(file)
+			for range dots - 1 {
+				dir = filepath.Dir(dir)
+			}
+			for _, candidate := range []string{filepath.Join(dir, rel+".py"), filepath.Join(dir, rel, "__init__.py")} {
+				if present[candidate] {
+					targets = append(targets, candidate)
+				}
+			}
+			continue
+		}
+		if rel == "" {
+			continue
+		}
+		suffixes := []string{string(filepath.Separator) + rel + ".py", string(filepath.Separator) + filepath.Join(rel, "__init__.py")}
+		for _, candidate := range files {
+			if strings.HasSuffix(candidate, suffixes[0]) || strings.HasSuffix(candidate, suffixes[1]) {
+				targets = append(targets, candidate)
+			}
+		}
+	}
+	return targets
+}
\ No newline at end of file
@@ -0,0 +1,131 @@
+package clipcat
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// jsExts are the extensions whose tests are named foo.test.ts or
+// foo.spec.ts, next to the source or in a __tests__ directory.
+var jsExts = map[string]bool{".js": true, ".jsx": true, ".mjs": true, ".cjs": true, ".ts": true, ".tsx": true, ".mts": true}
+
+// isTestFile reports whether path is named like a test in its language.
+func isTestFile(path string) bool {
+	dir, base := filepath.Split(path)
+	ext := filepath.Ext(base)
+	stem := strings.TrimSuffix(base, ext)
+	switch {
+	case ext == ".go":
+		return strings.HasSuffix(stem, "_test")
+	case jsExts[ext]:
+		return strings.HasSuffix(stem, ".test") || strings.HasSuffix(stem, ".spec") || filepath.Base(dir) == "__tests__"
+	case ext == ".py":
+		return strings.HasPrefix(stem, "test_") || strings.HasSuffix(stem, "_test")
+	case ext == ".rb":
+		return strings.HasSuffix(stem, "_spec") || strings.HasSuffix(stem, "_test")
+	case ext == ".java" || ext == ".kt":
+		return strings.HasSuffix(stem, "Test") || strings.HasSuffix(stem, "Tests")
+	}
+	return false
+}
+
+// testsFor returns where the tests of a source file conventionally live:
+// foo_test.go; foo.test.ts, foo.spec.ts and __tests__/foo.ts; test_foo.py
+// and foo_test.py, also in a tests directory; foo_spec.rb and foo_test.rb;
+// FooTest.java below src/test instead of src/main.
+func testsFor(path string) []string {
+	dir, base := filepath.Split(path)
+	ext := filepath.Ext(base)
+	stem := strings.TrimSuffix(base, ext)
+	join := func(elem ...string) string { return filepath.Join(append([]string{dir}, elem...)...) }
+	switch {
+	case ext == ".go":
+		return []string{join(stem + "_test.go")}
+	case jsExts[ext]:
+		var paths []string
+		for _, sub := range []string{"", "__tests__"} {
+			paths = append(paths, join(sub, stem+".test"+ext), join(sub, stem+".spec"+ext))
+		}
+		return append(paths, join("__tests__", base))
+	case ext == ".py":
+		return []string{join("test_" + base), join(stem + "_test.py"), join("tests", "test_"+base), join("tests", stem+"_test.py")}
+	case ext == ".rb":
+		return []string{join(stem + "_spec.rb"), join(stem + "_test.rb")}
+	case ext == ".java" || ext == ".kt":
+		test := filepath.ToSlash(dir)
+		if main := "/src/main/"; strings.Contains(test, main) {
+			test = strings.Replace(test, main, "/src/test/", 1)
+		}
+		test = filepath.FromSlash(test)
+		return []string{filepath.Join(test, stem+"Test"+ext), filepath.Join(test, stem+"Tests"+ext)}
+	}
+	return nil
+}
+
+// sourcesFor returns where the source file of a test conventionally lives,
+// the reverse of testsFor.
+func sourcesFor(path string) []string {
+	dir, base := filepath.Split(path)
+	ext := filepath.Ext(base)
+	stem := strings.TrimSuffix(base, ext)
+	parent := filepath.Dir(filepath.Clean(dir))
+	switch {
+	case ext == ".go":
+		return []string{filepath.Join(dir, strings.TrimSuffix(stem, "_test")+ext)}
+	case jsExts[ext]:
+		name := strings.TrimSuffix(strings.TrimSuffix(stem, ".test"), ".spec") + ext
+		if filepath.Base(dir) == "__tests__" {
+			return []string{filepath.Join(parent, name)}
+		}
+		return []string{filepath.Join(dir, name)}
+	case ext == ".py":
+		name := strings.TrimSuffix(strings.TrimPrefix(stem, "test_"), "_test") + ext
+		paths := []string{filepath.Join(dir, name)}
+		if filepath.Base(dir) == "tests" {
+			paths = append(paths, filepath.Join(parent, name))
+		}
+		return paths
+	case ext == ".rb":
+		return []string{filepath.Join(dir, strings.TrimSuffix(strings.TrimSuffix(stem, "_spec"), "_test")+ext)}
+	case ext == ".java" || ext == ".kt":
+		src := filepath.ToSlash(dir)
+		if test := "/src/test/"; strings.Contains(src, test) {
+			src = strings.Replace(src, test, "/src/main/", 1)
+		}
+		name := strings.TrimSuffix(strings.TrimSuffix(stem, "Tests"), "Test") + ext
+		return []string{filepath.Join(filepath.FromSlash(src), name)}
+	}
+	return nil
+}
+
+// pairedFiles returns the tests of files with --with-tests and the sources
+// of their tests with --with-sources, those that exist and are not among
+// files already.
+func (b *Bundler) pairedFiles(files []string) []string {
+	have := make(map[string]bool, len(files))
+	for _, file := range files {
+		have[file] = true
+	}
+	var pairs []string
+	for _, file := range files {
+		var candidates []string
+		if isTestFile(file) {
+			if b.cfg.WithSources {
+				candidates = sourcesFor(file)
+			}
+		} else if b.cfg.WithTests {
+			candidates = testsFor(file)
+		}
+		for _, candidate := range candidates {
+			if have[candidate] {
+				continue
+			}
+			if info, err := os.Stat(candidate); err == nil && info.Mode().IsRegular() {
+				have[candidate] = true
+				pairs = append(pairs, candidate)
+			}
+		}
+	}
+	return pairs
+}
\ No newline at end of file
@@ -0,0 +1,30 @@
+package clipcat
+
+import (
+	"clipcat/internal/picker"
+	"context"
+	"fmt"
+)
+
+// pickFiles lets the user choose among the files b would copy in a fuzzy
+// finder on the terminal, for `clipcat pick`.
+func pickFiles(ctx context.Context, b *Bundler) ([]string, error) {
+	files, err := b.Files(ctx)
+	if err != nil {
+		return nil, err
+	}
+	labels := make([]string, len(files))
+	byLabel := make(map[string]string, len(files))
+	for i, file := range files {
+		labels[i] = relativeLabel(file)
+		byLabel[labels[i]] = file
+	}
+	picked, err := picker.RunTerminal(labels)
+	if err != nil {
+		return nil, fmt.Errorf("pick: %w", err)
+	}
+	for i, label := range picked {
+		picked[i] = byLabel[label]
+	}
+	return picked, nil
+}
\ No newline at end of file
@@ -0,0 +1,105 @@
+package clipcat
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// preset is the source files of one ecosystem: its languages, and the
+// build and cache directories to leave out on top of the default excludes.
+type preset struct {
+	name      string
+	marker    string // the manifest that gives the ecosystem away
+	languages []string
+	excludes  []string
+}
+
+// Preset values besides the names in presets
+const (
+	PresetAuto = "auto"
+	PresetNone = "none"
+)
+
+var presets = []preset{
+	{
+		name:      "go",
+		marker:    "go.mod",
+		languages: []string{"go", "go-mod", "makefile"},
+		excludes:  []string{"bin/"},
+	},
+	{
+		name:      "node",
+		marker:    "package.json",
+		languages: []string{"javascript", "jsx", "typescript", "tsx", "json", "jsonc", "css", "scss", "sass", "less", "html", "vue", "svelte"},
+		excludes:  []string{"build/", "coverage/", "out/", ".next/", ".nuxt/", ".svelte-kit/", ".turbo/", ".cache/", "*.map"},
+	},
+	{
+		name:      "rust",
+		marker:    "Cargo.toml",
+		languages: []string{"rust", "toml"},
+	},
+	{
+		name:      "python",
+		marker:    "pyproject.toml",
+		languages: []string{"python", "toml", "ini"},
+		excludes:  []string{"build/", "venv/", "htmlcov/", "*.egg-info/", ".tox/", ".nox/", ".mypy_cache/", ".pytest_cache/", ".ruff_cache/"},
+	},
+}
+
+// PresetNames lists the values --preset accepts.
+func PresetNames() []string {
+	names := []string{PresetAuto, PresetNone}
+	for _, p := range presets {
+		names = append(names, p.name)
+	}
+	return names
+}
+
+// activePresets resolves --preset for the inputs in paths. Auto picks the
+// presets whose manifest is in one of the input directories, or in the
+// current directory when no input is one, and warns when there is none.
+func (b *Bundler) activePresets(paths []string) []preset {
+	var active []preset
+	for _, name := range strings.Split(b.cfg.Preset, ",") {
+		switch name {
+		case "", PresetNone:
+		case PresetAuto:
+			var dirs []string
+			for _, path := range paths {
+				if info, err := os.Stat(path); err == nil && info.IsDir() {
+					dirs = append(dirs, path)
+				}
+			}
+			if len(dirs) == 0 {
+				dirs = []string{"."}
+			}
+			found := false
+			for _, p := range presets {
+				if slices.ContainsFunc(dirs, func(dir string) bool {
+					_, err := os.Stat(filepath.Join(dir, p.marker))
+					return err == nil
+				}) {
+					active, found = appendPreset(active, p), true
+				}
+			}
+			if !found {
+				fmt.Fprintf(b.warn, "Warning: --preset auto: no go.mod, package.json, Cargo.toml or pyproject.toml found; no preset applied\n")
+			}
+		default:
+			if i := slices.IndexFunc(presets, func(p preset) bool { return p.name == name }); i >= 0 {
+				active = appendPreset(active, presets[i])
+			}
+		}
+	}
+	return active
+}
+
+func appendPreset(active []preset, p preset) []preset {
+	if slices.ContainsFunc(active, func(q preset) bool { return q.name == p.name }) {
+		return active
+	}
+	return append(active, p)
+}
\ No newline at end of file
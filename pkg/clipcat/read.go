@@ -0,0 +1,211 @@
+package clipcat
+
+import (
+	"clipcat/pkg/output"
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// streamSize is the size from which --head-lines/--tail-lines files are
+// streamed in chunks instead of read whole, so a giant log costs only the
+// lines that are kept.
+const streamSize = 4 << 20
+
+// fileContent is one file as read by readFiles; err is set when the file
+// could not be read, and is ErrChanged when it went away or changed while
+// being read. size and sum describe the bytes on disk and are only set for
+// --manifest.
+type fileContent struct {
+	data    []byte
+	image   *output.Image
+	err     error
+	warning string // why a content handler was skipped
+	size    int64
+	sum     string
+}
+
+// readFiles reads and filters files on up to cfg.Jobs goroutines (NumCPU
+// when 0), after passing them through their output.Handle content
+// handler: images and binary files are described rather than filtered,
+// notebooks are rendered as markdown unless --notebook raw, and lockfiles
+// are summarized with --summarize-locks. Result i arrives on the i-th
+// channel, so the caller can render in order while later files are
+// still being read. Cancelling ctx stops handing out new files. p, which
+// may be nil, learns which files are being read.
+func readFiles(ctx context.Context, files []string, cfg *Config, filters []output.Filter, p *progress) []chan fileContent {
+	results := make([]chan fileContent, len(files))
+	for i := range results {
+		results[i] = make(chan fileContent, 1)
+	}
+	jobs := cfg.Jobs
+	if jobs <= 0 {
+		jobs = runtime.NumCPU()
+	}
+	jobs = max(1, min(jobs, len(files)))
+
+	next := make(chan int)
+	go func() {
+		defer close(next)
+		for i := range files {
+			select {
+			case next <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for range jobs {
+		go func() {
+			for i := range next {
+				var content fileContent
+				finish := p.read(files[i])
+				data, err := readFile(files[i], cfg, &content)
+				finish()
+				var image *output.Image
+				if err == nil {
+					handled, herr := output.Handle(files[i], data, output.HandlerOptions{
+						InlineImages:    cfg.InlineImages,
+						Notebook:        cfg.Notebook,
+						SummarizeLocks:  cfg.SummarizeLocks,
+						IncludeMinified: cfg.IncludeMinified,
+					})
+					switch {
+					case herr != nil:
+						// A failing handler leaves the file as it is
+						content.warning = herr.Error()
+					case handled != nil:
+						data, image = handled.Data, handled.Image
+					}
+					if handled == nil || !handled.Placeholder {
+						data = output.ApplyFilters(filters, files[i], data)
+					}
+				}
+				content.data, content.image, content.err = data, image, err
+				results[i] <- content
+			}
+		}()
+	}
+	return results
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// readFile reads path, cut to --head-lines/--tail-lines when set (images,
+// notebooks and lockfiles to summarize are kept whole), and records its size and checksum in
+// content for --manifest. A file that was deleted since it was collected,
+// or whose size or modification time moved while it was read, reports
+// ErrChanged rather than partial content.
+func readFile(path string, cfg *Config, content *fileContent) ([]byte, error) {
+	before, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrChanged
+	}
+	if err != nil {
+		return nil, err
+	}
+	data, err := readContent(path, cfg, content, before.Size())
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrChanged
+	}
+	if err != nil {
+		return nil, err
+	}
+	after, err := os.Stat(path)
+	if err != nil || after.Size() != before.Size() || !after.ModTime().Equal(before.ModTime()) {
+		return nil, ErrChanged
+	}
+	return data, nil
+}
+
+func readContent(path string, cfg *Config, content *fileContent, size int64) ([]byte, error) {
+	if cfg.TablePreview > 0 && output.IsTable(path) {
+		data, err := streamFile(path, cfg, content, size, func(r io.Reader) ([]byte, error) {
+			return output.ReadTablePreview(path, r, cfg.TablePreview)
+		})
+		// Tables that fail to parse are copied as text
+		var parseErr *csv.ParseError
+		if !errors.As(err, &parseErr) {
+			return data, err
+		}
+	}
+
+	head, tail := cfg.headTail(path)
+	cut := (head > 0 || tail > 0) && output.ImageMIME(path) == "" && !output.IsNotebook(path) &&
+		!(cfg.SummarizeLocks && output.IsLockfile(path))
+	if cut && size >= streamSize {
+		return streamFile(path, cfg, content, size, func(r io.Reader) ([]byte, error) {
+			return output.ReadHeadTail(r, head, tail)
+		})
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Manifest {
+		content.size, content.sum = int64(len(data)), sha256Hex(data)
+	}
+	if cut {
+		data = output.HeadTail(head, tail)(path, data)
+	}
+	return data, nil
+}
+
+// headTail returns how many lines of path to keep from its start and end:
+// --head-lines and --tail-lines when given, or else half each of its
+// [max_lines_per_file] or --max-lines-per-file limit. 0, 0 keeps it all.
+func (cfg *Config) headTail(path string) (head, tail int) {
+	if cfg.HeadLines > 0 || cfg.TailLines > 0 {
+		return cfg.HeadLines, cfg.TailLines
+	}
+	limit := cfg.MaxLinesPerFile
+	base := filepath.Base(path)
+	if n, ok := cfg.MaxLinesByName[base]; ok {
+		limit = n
+	} else if n, ok := cfg.MaxLinesByName[strings.ToLower(filepath.Ext(base))]; ok {
+		limit = n
+	}
+	if limit <= 0 {
+		return 0, 0
+	}
+	return (limit + 1) / 2, limit / 2
+}
+
+// streamFile passes a file to read in chunks, for readers that keep only
+// part of it; the checksum is computed on the way through.
+func streamFile(path string, cfg *Config, content *fileContent, size int64, read func(io.Reader) ([]byte, error)) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	var sum hash.Hash
+	if cfg.Manifest {
+		sum = sha256.New()
+		r = io.TeeReader(f, sum)
+	}
+	data, err := read(r)
+	if err != nil {
+		return nil, err
+	}
+	if sum != nil {
+		content.size, content.sum = size, hex.EncodeToString(sum.Sum(nil))
+	}
+	return data, nil
+}
\ No newline at end of file
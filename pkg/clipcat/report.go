@@ -0,0 +1,138 @@
+package clipcat
+
+import (
+	"clipcat/pkg/collector"
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+	"time"
+)
+
+// Report is what --report json writes after a run: the files copied and
+// left out with the reason, how long each phase took and where the output
+// went. A failed run still has a report, with Error set.
+type Report struct {
+	// A run left behind at --timeout may still be recording while Run
+	// writes the report, so every access after newReport holds mu
+	mu sync.Mutex
+
+	Version     string              `json:"version"`
+	Started     time.Time           `json:"started"`
+	Dir         string              `json:"dir"`
+	Inputs      []string            `json:"inputs"`
+	Files       []ReportFile        `json:"files"`
+	Skipped     []ReportSkip        `json:"skipped"`
+	Warnings    []collector.Warning `json:"warnings"`
+	Bytes       int                 `json:"bytes"`  // size of the output
+	Tokens      int                 `json:"tokens"` // estimated, of the output
+	Durations   map[string]float64  `json:"durations_ms"`
+	Destination string              `json:"destination,omitempty"` // clipboard, file:PATH, stdout, the upload link, or a list with --output
+	Backend     string              `json:"backend,omitempty"`     // the clipboard command
+	Error       string              `json:"error,omitempty"`
+}
+
+// ReportFile is a file or URL in the output. Size is on disk and Bytes in
+// the output, after the content filters.
+type ReportFile struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	Bytes  int    `json:"bytes"`
+	Status string `json:"status"` // copied, unreadable or removed
+}
+
+// ReportSkip is a file left out, or an excluded directory that was not
+// walked, and the rule or option that decided it.
+type ReportSkip struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+func newReport(inputs []string) *Report {
+	r := &Report{
+		Version:   Version,
+		Started:   time.Now().UTC(),
+		Inputs:    inputs,
+		Files:     []ReportFile{},
+		Skipped:   []ReportSkip{},
+		Warnings:  []collector.Warning{},
+		Durations: map[string]float64{},
+	}
+	r.Dir, _ = os.Getwd()
+	return r
+}
+
+// set changes fields of the report under its lock. Like phase, skip and
+// file, it does nothing on a nil report.
+func (r *Report) set(f func(r *Report)) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	f(r)
+}
+
+// phase records how long the phase that began at start took.
+func (r *Report) phase(name string, start time.Time) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Durations[name] += float64(time.Since(start).Microseconds()) / 1000
+}
+
+func (r *Report) skip(path, reason string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Skipped = append(r.Skipped, ReportSkip{Path: path, Reason: reason})
+}
+
+func (r *Report) warn(warnings []collector.Warning) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Warnings = append(r.Warnings, warnings...)
+}
+
+func (r *Report) file(path string, size int64, content []byte, err error) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	status := "copied"
+	switch {
+	case errors.Is(err, ErrChanged):
+		status = "removed"
+	case err != nil:
+		status = "unreadable"
+	}
+	r.Files = append(r.Files, ReportFile{Path: path, Size: size, Bytes: len(content), Status: status})
+}
+
+// writeReport writes the report as indented JSON to cfg.ReportFile, or to
+// stderr when it is empty.
+func writeReport(cfg *Config, r *Report, runErr error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if runErr != nil {
+		r.Error = runErr.Error()
+	}
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if cfg.ReportFile == "" {
+		_, err = os.Stderr.Write(data)
+		return err
+	}
+	return os.WriteFile(cfg.ReportFile, data, 0644)
+}
\ No newline at end of file
@@ -0,0 +1,40 @@
+package clipcat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// RunPrefix starts the header of a --run section, followed by the command
+// line, e.g. "$ go test ./...".
+const RunPrefix = "$ "
+
+// runCommand runs command through the shell in the working directory and
+// returns what it printed, stdout and stderr interleaved as a terminal
+// would show them. A failing command is not an error, since failing test
+// output is usually why it was included; its exit status is appended
+// instead. err is only set when the command could not be run at all.
+func runCommand(ctx context.Context, command string) ([]byte, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	out, err := cmd.CombinedOutput()
+	var exitErr *exec.ExitError
+	switch {
+	case errors.As(err, &exitErr) && exitErr.Exited():
+		if len(out) > 0 && !strings.HasSuffix(string(out), "\n") {
+			out = append(out, '\n')
+		}
+		return fmt.Appendf(out, "[exit status %d]\n", exitErr.ExitCode()), nil
+	case err != nil:
+		return nil, err
+	}
+	return out, nil
+}
\ No newline at end of file
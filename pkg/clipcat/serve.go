@@ -0,0 +1,273 @@
+package clipcat
+
+import (
+	"clipcat/pkg/output"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type ServeConfig struct {
+	Addr  string
+	Token   string   // required from clients; generated when empty
+	Dir     string   // root that request paths are resolved against
+	Origins []string // web origins allowed to call from a browser
+}
+
+func ParseServeArgs(args []string) *ServeConfig {
+	cfg := &ServeConfig{Addr: "127.0.0.1:8090", Dir: "."}
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		switch arg {
+		case "-h", "--help":
+			printServeUsage()
+			os.Exit(0)
+		case "--http", "--token", "-C", "--dir", "--allow-origin":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: %s requires a value\n", arg)
+				os.Exit(2)
+			}
+			switch arg {
+			case "--http":
+				cfg.Addr = args[i+1]
+			case "--token":
+				cfg.Token = args[i+1]
+			case "--allow-origin":
+				cfg.Origins = append(cfg.Origins, args[i+1])
+			default:
+				cfg.Dir = args[i+1]
+			}
+			i++
+		default:
+			fmt.Fprintf(os.Stderr, "Error: unknown option: %s\n", arg)
+			printServeUsage()
+			os.Exit(2)
+		}
+	}
+
+	return cfg
+}
+
+func printServeUsage() {
+	fmt.Fprintf(os.Stderr, `Usage: clipcat serve [OPTIONS]
+
+Description:
+  Serve bundles over HTTP for editor plugins and browser extensions. Every
+  request must carry the token as "Authorization: Bearer TOKEN".
+  Browsers may only call from the --allow-origin origins.
+
+Endpoints:
+  GET /bundle?path=P&exclude=E&ext=go&format=markdown&tree=1
+                            The rendered bundle; path and exclude are repeatable
+  GET /tree?path=P&exclude=E
+                            The files that /bundle would include, as JSON
+
+Options:
+      --http ADDR           Listen address (default 127.0.0.1:8090)
+      --token TOKEN         Token clients must send (default: a random token,
+                            printed at startup)
+  -C, --dir DIR             Resolve request paths under DIR (default .)
+      --allow-origin ORIGIN Let pages and extensions from ORIGIN call the
+                            server (e.g. chrome-extension://ID); repeatable
+  -h, --help                Show help
+
+Examples:
+  clipcat serve --http :8090 -C ~/src/project
+  curl -H "Authorization: Bearer $TOKEN" 'localhost:8090/bundle?path=src&ext=go'
+`)
+}
+
+// Serve runs the HTTP server until it fails.
+func Serve(cfg *ServeConfig) error {
+	root, err := filepath.Abs(cfg.Dir)
+	if err != nil {
+		return err
+	}
+	if info, err := os.Stat(root); err != nil || !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", cfg.Dir)
+	}
+
+	token := cfg.Token
+	if token == "" {
+		buf := make([]byte, 16)
+		rand.Read(buf)
+		token = hex.EncodeToString(buf)
+	}
+
+	fmt.Fprintf(os.Stderr, "Serving %s on http://%s\n", root, cfg.Addr)
+	if cfg.Token == "" {
+		fmt.Fprintf(os.Stderr, "Token: %s\n", token)
+	}
+	server := &http.Server{
+		Addr:              cfg.Addr,
+		Handler:           ServeHandler(root, token, cfg.Origins...),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return server.ListenAndServe()
+}
+
+// ServeHandler serves /bundle and /tree for the files under root. Requests
+// without token are rejected, as are paths that leave root and URLs.
+// Browsers may call from the given origins only.
+func ServeHandler(root, token string, origins ...string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /bundle", func(w http.ResponseWriter, r *http.Request) {
+		b, err := serveBundler(root, r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		body := &responseBody{w: w, contentType: contentType(b.cfg.Format)}
+		if err := b.Write(r.Context(), body); err != nil {
+			if body.started {
+				// The status is sent; cut the response short so the client
+				// does not take a partial bundle for a whole one
+				panic(http.ErrAbortHandler)
+			}
+			status := http.StatusInternalServerError
+			if errors.Is(err, ErrNoFiles) {
+				status = http.StatusNotFound
+			}
+			http.Error(w, err.Error(), status)
+		}
+	})
+	mux.HandleFunc("GET /tree", func(w http.ResponseWriter, r *http.Request) {
+		b, err := serveBundler(root, r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		files, err := b.Files(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		type entry struct {
+			Path     string `json:"path"`
+			Size     int64  `json:"size"`
+			Modified string `json:"modified"`
+		}
+		result := struct {
+			Root  string  `json:"root"`
+			Files []entry `json:"files"`
+		}{Root: root, Files: []entry{}}
+		for _, e := range b.listing(files) {
+			result.Files = append(result.Files, entry{Path: e.Path, Size: e.Size, Modified: e.ModTime.Format(time.RFC3339)})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Browser extensions and web-based editors call from other origins;
+		// only the allowed ones may read the responses
+		w.Header().Add("Vary", "Origin")
+		if origin := r.Header.Get("Origin"); origin != "" && slices.Contains(origins, origin) {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Headers", "Authorization")
+		}
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		// Only a header: a token in the URL ends up in logs and history
+		got, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if got == "" || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			http.Error(w, "missing or wrong token", http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// responseBody sends the headers with the first part of the bundle, so an
+// error before anything is written can still be reported with its status.
+type responseBody struct {
+	w           http.ResponseWriter
+	contentType string
+	started     bool
+}
+
+func (b *responseBody) Write(p []byte) (int, error) {
+	if !b.started {
+		b.w.Header().Set("Content-Type", b.contentType)
+		b.started = true
+	}
+	return b.w.Write(p)
+}
+
+func contentType(format string) string {
+	switch format {
+	case "markdown":
+		return "text/markdown; charset=utf-8"
+	case "xml":
+		return "application/xml; charset=utf-8"
+	case "json":
+		return "application/json"
+	}
+	return "text/plain; charset=utf-8"
+}
+
+// serveBundler builds a Bundler from the query: path, exclude and ext are
+// repeatable, format and tree select the rendering. Paths default to root.
+func serveBundler(root string, r *http.Request) (*Bundler, error) {
+	q := r.URL.Query()
+
+	paths := q["path"]
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+	for i, path := range paths {
+		if strings.Contains(path, "://") || !filepath.IsLocal(filepath.FromSlash(path)) {
+			return nil, fmt.Errorf("path %q must be relative and inside the served directory", path)
+		}
+		paths[i] = filepath.Join(root, filepath.FromSlash(path))
+	}
+
+	format := q.Get("format")
+	if format != "" && !slices.Contains(output.Formatters(), format) {
+		return nil, fmt.Errorf("unknown format %q (expected %s)", format, strings.Join(output.Formatters(), ", "))
+	}
+
+	tree := false
+	if s := q.Get("tree"); s != "" {
+		var err error
+		if tree, err = strconv.ParseBool(s); err != nil {
+			return nil, fmt.Errorf("invalid tree %q", s)
+		}
+	}
+
+	var exts []string
+	for _, ext := range q["ext"] {
+		exts = append(exts, strings.Split(ext, ",")...)
+	}
+
+	label := func(path string) string {
+		if rel, err := filepath.Rel(root, path); err == nil && filepath.IsLocal(rel) {
+			return filepath.ToSlash(rel)
+		}
+		return path
+	}
+	return New(
+		WithPaths(paths...),
+		WithExcludes(q["exclude"]...),
+		WithLanguages(exts...),
+		WithFormat(format),
+		WithTree(tree),
+		WithLabel(label),
+		withDir(root),
+	), nil
+}
\ No newline at end of file
@@ -0,0 +1,136 @@
+package clipcat
+
+import (
+	"clipcat/pkg/config"
+	"clipcat/pkg/remote"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// setNameRe matches the names `clipcat set save` accepts.
+var setNameRe = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Sets implements `clipcat set`: saving named bundles (paths and options) to
+// the project's sets file, listing and deleting them, and copying them.
+func Sets(args []string, w io.Writer) error {
+	path := config.SetsPath(".")
+	sets, err := config.LoadSets(path)
+	if err != nil {
+		return err
+	}
+
+	sub := ""
+	if len(args) > 0 {
+		sub = args[0]
+	}
+	switch {
+	case sub == "" || (sub == "list" && len(args) == 1):
+		if len(sets) == 0 {
+			fmt.Fprintf(w, "No sets saved in %s. Save one with clipcat set save NAME PATHS...\n", path)
+			return nil
+		}
+		names := make([]string, 0, len(sets))
+		width := 0
+		for name := range sets {
+			names = append(names, name)
+			width = max(width, len(name))
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			fmt.Fprintf(w, "%-*s  %s\n", width, name, strings.Join(sets[name].Args, " "))
+		}
+		return nil
+
+	case sub == "save" && len(args) >= 3:
+		name := args[1]
+		if !setNameRe.MatchString(name) {
+			return fmt.Errorf("invalid set name %q: use letters, digits, - and _", name)
+		}
+		saved, err := setArgs(args[2:], filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+		_, replaced := sets[name]
+		sets[name] = &config.Set{Name: name, Args: saved}
+		if err := config.SaveSets(path, sets); err != nil {
+			return err
+		}
+		verb := "Saved"
+		if replaced {
+			verb = "Replaced"
+		}
+		fmt.Fprintf(w, "%s set %q in %s: %s\n", verb, name, path, strings.Join(saved, " "))
+		return nil
+
+	case sub == "copy" && len(args) >= 2:
+		s, ok := sets[args[1]]
+		if !ok {
+			return fmt.Errorf("unknown set %q in %s (see clipcat set list)", args[1], path)
+		}
+		if flag := untrustedFlag(s.Args); flag != "" {
+			return fmt.Errorf("set %q in %s may not use %s", s.Name, path, flag)
+		}
+		extra := absPaths(args[2:])
+		if err := os.Chdir(filepath.Dir(path)); err != nil {
+			return fmt.Errorf("set copy: %w", err)
+		}
+		return Run(parseArgs(append(slices.Clone(s.Args), extra...)))
+
+	case sub == "delete" && len(args) == 2:
+		if _, ok := sets[args[1]]; !ok {
+			return fmt.Errorf("unknown set %q in %s", args[1], path)
+		}
+		delete(sets, args[1])
+		if err := config.SaveSets(path, sets); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "Deleted set %q from %s\n", args[1], path)
+		return nil
+	}
+
+	fmt.Fprintf(os.Stderr, "Usage: clipcat set [list | save NAME [OPTIONS] <path> ... | copy NAME [OPTIONS] | delete NAME]\n")
+	os.Exit(2)
+	return nil
+}
+
+// setArgs validates the arguments of `clipcat set save` and rewrites their
+// paths relative to dir, where the set will be copied from, so a committed
+// sets file works in every checkout.
+func setArgs(args []string, dir string) ([]string, error) {
+	if flag := untrustedFlag(args); flag != "" {
+		return nil, fmt.Errorf("sets may not use %s; use a profile in %s instead", flag, config.UserPath())
+	}
+	cfg := parseArgs(args)
+
+	saved := slices.Clone(args)
+	for i, arg := range saved {
+		if !slices.Contains(cfg.Paths, arg) || remote.IsURL(arg) {
+			continue
+		}
+		abs, err := filepath.Abs(arg)
+		if err != nil {
+			continue
+		}
+		if rel, err := filepath.Rel(dir, abs); err == nil {
+			saved[i] = filepath.ToSlash(rel)
+		}
+	}
+	return saved, nil
+}
+
+// absPaths returns args with those that exist as paths made absolute, so they
+// still name the same files after a change of directory.
+func absPaths(args []string) []string {
+	out := slices.Clone(args)
+	for i, arg := range out {
+		if _, err := os.Stat(arg); err == nil && !strings.HasPrefix(arg, "-") {
+			out[i], _ = filepath.Abs(arg)
+		}
+	}
+	return out
+}
\ No newline at end of file
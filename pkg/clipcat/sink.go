@@ -0,0 +1,319 @@
+package clipcat
+
+import (
+	"clipcat/internal/clipboard"
+	"clipcat/pkg/crypto"
+	"clipcat/pkg/output"
+	"clipcat/pkg/unpack"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// sink is one destination of --output, --clipboard and --print. A sink
+// that fails keeps its error and is skipped from then on.
+type sink struct {
+	name  string // for the success message
+	dest  string // for the report and audit log
+	w     io.Writer
+	close func() error
+	abort func() // instead of close when the bundle is incomplete
+	err   error
+}
+
+// tee writes to every sink that has not failed, so a full disk or a dead
+// clipboard command does not cost the others their copy.
+type tee []*sink
+
+// errNoSinks is returned by tee.Write once every sink has failed.
+var errNoSinks = errors.New("no output left to write to")
+
+func (t tee) Write(p []byte) (int, error) {
+	live := 0
+	for _, s := range t {
+		if s.err != nil {
+			continue
+		}
+		if _, err := s.w.Write(p); err != nil {
+			s.err = err
+			continue
+		}
+		live++
+	}
+	if live == 0 {
+		return 0, errNoSinks
+	}
+	return len(p), nil
+}
+
+// openSinks opens the destinations cfg asks for, in the order --output,
+// --clipboard, --print. Highlighted --print output is not a copy of the
+// bundle, so printDocument writes it after the others instead.
+func openSinks(ctx context.Context, cfg *Config) tee {
+	var sinks tee
+	if cfg.Output != "" {
+		s := &sink{name: cfg.Output, dest: "output:" + cfg.Output}
+		if f, err := openOutput(ctx, cfg); err != nil {
+			s.err = err
+		} else {
+			s.w, s.close, s.abort = f, f.Close, f.abort
+		}
+		sinks = append(sinks, s)
+	}
+	if cfg.Clipboard != "" {
+		command := cfg.Clipboard
+		if command == "auto" {
+			command = ""
+		}
+		s := &sink{name: "the clipboard", dest: "clipboard"}
+		if w, err := clipboard.Open(ctx, command); err != nil {
+			s.err = err
+		} else if cfg.Compress != "" {
+			// Clipboards hold text, so a compressed bundle goes as base64
+			enc, err := unpack.NewWriter(w, cfg.Compress, true)
+			if err != nil {
+				w.Close()
+				s.err = err
+			} else {
+				s.w, s.close = enc, func() error { return errors.Join(enc.Close(), w.Close()) }
+			}
+		} else {
+			s.w, s.close = w, w.Close
+		}
+		sinks = append(sinks, s)
+	}
+	if cfg.PrintOut && !useColor(cfg.Color) {
+		sinks = append(sinks, &sink{dest: "stdout", w: os.Stdout})
+	}
+	return sinks
+}
+
+// openOutput opens the --output file. A new bundle goes to a temp file
+// next to it that replaces the file once the bundle is complete, so a run
+// that fails leaves the old file as it was. With --append-file the file is
+// opened for appending, with the run separator when it has content, and a
+// failed run is cut off again. The bundle is compressed with --compress,
+// then encrypted with --encrypt. With --compress each appended run is a
+// stream of its own, which gunzip and zstd -d read as one.
+func openOutput(ctx context.Context, cfg *Config) (*layered, error) {
+	if cfg.Encrypt != "" {
+		if err := crypto.Available(); err != nil {
+			return nil, err
+		}
+	}
+
+	var f *os.File
+	var err error
+	w := &layered{}
+	if cfg.AppendFile {
+		if f, err = os.OpenFile(cfg.Output, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666); err != nil {
+			return nil, err
+		}
+		info, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		size := info.Size()
+		w.discard = func() { os.Truncate(cfg.Output, size) }
+	} else {
+		if f, err = os.CreateTemp(filepath.Dir(cfg.Output), "."+filepath.Base(cfg.Output)+".*"); err != nil {
+			return nil, err
+		}
+		mode := fs.FileMode(0644)
+		if info, err := os.Stat(cfg.Output); err == nil {
+			mode = info.Mode().Perm()
+		}
+		f.Chmod(mode)
+		w.commit = func() error { return os.Rename(f.Name(), cfg.Output) }
+		w.discard = func() { os.Remove(f.Name()) }
+	}
+	w.Writer, w.closers = f, []io.Closer{f}
+
+	if cfg.Encrypt != "" {
+		e, err := crypto.Encrypter(ctx, cfg.Encrypt, false, f)
+		if err != nil {
+			w.abort()
+			return nil, err
+		}
+		w.push(e, e)
+	}
+	if cfg.Compress != "" {
+		enc, err := unpack.NewWriter(w.Writer, cfg.Compress, false)
+		if err != nil {
+			w.abort()
+			return nil, err
+		}
+		w.push(enc, enc)
+	}
+	if info, err := f.Stat(); err == nil && cfg.AppendFile && info.Size() > 0 {
+		if _, err := io.WriteString(w, runSeparator(cfg, time.Now())); err != nil {
+			w.abort()
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+// layered writes through encoders stacked on a file, and closes them from
+// the top down so each flushes into the one below.
+type layered struct {
+	io.Writer
+	closers []io.Closer // the top first
+	commit  func() error // once all are closed, e.g. renaming the file into place
+	discard func()       // undoes what was written
+}
+
+func (l *layered) push(w io.Writer, c io.Closer) {
+	l.Writer = w
+	l.closers = append([]io.Closer{c}, l.closers...)
+}
+
+func (l *layered) Close() error {
+	var errs []error
+	for _, c := range l.closers {
+		errs = append(errs, c.Close())
+	}
+	if err := errors.Join(errs...); err != nil {
+		l.discard()
+		return err
+	}
+	if l.commit != nil {
+		return l.commit()
+	}
+	return nil
+}
+
+// abort closes the layers and undoes what was written, for a bundle that
+// was not completed.
+func (l *layered) abort() {
+	for _, c := range l.closers {
+		c.Close()
+	}
+	l.discard()
+}
+
+// runSeparator marks where a run appended with --append-file begins: a
+// header that unpack skips in plain output, a comment in the others. JSON
+// documents simply follow each other, which jq and other streaming parsers
+// read as they are.
+func runSeparator(cfg *Config, at time.Time) string {
+	stamp := ""
+	if !cfg.Deterministic {
+		stamp = at.UTC().Format(time.RFC3339)
+	}
+	run := strings.TrimSpace("clipcat run " + stamp)
+	switch cfg.Format {
+	case "json":
+		return ""
+	case "markdown":
+		return fmt.Sprintf("\n---\n\n<!-- %s -->\n\n", run)
+	case "xml", "repomix":
+		return fmt.Sprintf("\n<!-- %s -->\n", run)
+	case "", "plain":
+		var header strings.Builder
+		output.WriteHeader(&header, output.RunLabel(stamp))
+		return header.String()
+	}
+	return fmt.Sprintf("\n<!-- %s -->\n\n", run)
+}
+
+// manifestSum matches the SHA-256 of a manifest entry in the plain and
+// markdown ("SUM  SIZE  PATH"), xml and json formats.
+var manifestSum = regexp.MustCompile(`(?m)^([0-9a-f]{64})  +[0-9]+  |sha256="([0-9a-f]{64})"|"sha256": "([0-9a-f]{64})"`)
+
+// previousSums returns the SHA-256 sums in the manifests of the runs
+// appended to path, which need not exist yet.
+func previousSums(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err == nil {
+		data, err = unpack.Decompress(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var sums []string
+	for _, m := range manifestSum.FindAllStringSubmatch(string(data), -1) {
+		sums = append(sums, cmp.Or(m[1], m[2], m[3]))
+	}
+	return sums, nil
+}
+
+// writeSinks opens the sinks and has render write the bundle to all of them
+// in one pass, then closes them. When rendering fails, the clipboard command
+// is stopped before it can take the partial bundle. The error joins those of
+// the sinks that failed; a clipboard failure is a *ClipboardError.
+func writeSinks(ctx context.Context, cfg *Config, p *progress, render func(io.Writer) (*document, error)) (*document, tee, error) {
+	p.start("copy")
+	if cfg.Clipboard != "" && cfg.Clipboard != "auto" {
+		p.visit(strings.Fields(cfg.Clipboard)[0])
+	} else if backend, err := clipboard.Backend(); err == nil && cfg.Clipboard != "" {
+		p.visit(backend)
+	}
+
+	sinkCtx, abort := context.WithCancel(ctx)
+	defer abort()
+	sinks := openSinks(sinkCtx, cfg)
+	doc, renderErr := render(sinks)
+	failed := renderErr != nil && !errors.Is(renderErr, errNoSinks)
+	if failed {
+		abort()
+	}
+	for _, s := range sinks {
+		switch {
+		case (failed || s.err != nil) && s.abort != nil:
+			s.abort()
+		case s.close != nil:
+			if err := s.close(); err != nil && s.err == nil {
+				s.err = err
+			}
+		}
+	}
+	if failed {
+		return nil, sinks, renderErr
+	}
+	if doc != nil && cfg.PrintOut && useColor(cfg.Color) {
+		printDocument(cfg, doc)
+	}
+	if err := ctx.Err(); err != nil {
+		return doc, sinks, err
+	}
+
+	var errs []error
+	for _, s := range sinks {
+		switch {
+		case s.err == nil:
+		case s.dest == "clipboard":
+			errs = append(errs, &ClipboardError{Err: s.err})
+		default:
+			errs = append(errs, fmt.Errorf("writing %s: %w", s.name, s.err))
+		}
+	}
+	return doc, sinks, errors.Join(errs...)
+}
+
+// written lists the sinks that got the whole bundle: their names for the
+// success message and their destinations for the report.
+func (t tee) written() (names, dests []string) {
+	for _, s := range t {
+		if s.err != nil {
+			continue
+		}
+		if s.name != "" {
+			names = append(names, s.name)
+		}
+		dests = append(dests, s.dest)
+	}
+	return names, dests
+}
\ No newline at end of file
@@ -0,0 +1,130 @@
+package clipcat
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// statsShown is how many directories and extensions `clipcat stats` lists;
+// the rest are counted in one line.
+const statsShown = 20
+
+// statsRow totals the files below a directory or with an extension.
+type statsRow struct {
+	name                 string
+	files, lines, tokens int
+	bytes                int64
+}
+
+func (r *statsRow) add(o statsRow) {
+	r.files += o.files
+	r.lines += o.lines
+	r.tokens += o.tokens
+	r.bytes += o.bytes
+}
+
+// WriteStats writes what Write would copy, broken down by directory and by
+// extension: files, bytes, lines and estimated tokens of each, largest
+// first. Directories include their subdirectories. Sizes are measured
+// after the content filters, as they would be copied.
+func (b *Bundler) WriteStats(ctx context.Context, w io.Writer) error {
+	cfg := &b.cfg
+	_, files, _, err := b.collect(ctx)
+	if err != nil {
+		return err
+	}
+	files, _ = b.capFiles(files)
+	if len(files) == 0 {
+		return ErrNoFiles
+	}
+	filters, err := b.filterChain()
+	if err != nil {
+		return err
+	}
+
+	// Directories are totalled up to the deepest one holding every file
+	root := filepath.Dir(files[0])
+	for _, file := range files[1:] {
+		for !strings.HasPrefix(file, root+string(filepath.Separator)) && filepath.Dir(root) != root {
+			root = filepath.Dir(root)
+		}
+	}
+
+	count := tokenCounter(cfg)
+	dirs := map[string]*statsRow{}
+	exts := map[string]*statsRow{}
+	var total statsRow
+	tally := func(rows map[string]*statsRow, name string, row statsRow) {
+		if rows[name] == nil {
+			rows[name] = &statsRow{name: name}
+		}
+		rows[name].add(row)
+	}
+
+	b.progress.start("read")
+	contents := readFiles(ctx, files, cfg, filters, b.progress)
+	for i, file := range files {
+		var content fileContent
+		select {
+		case content = <-contents[i]:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if content.err != nil {
+			continue
+		}
+		data := content.data
+		row := statsRow{files: 1, bytes: int64(len(data)), lines: bytes.Count(data, []byte("\n")), tokens: count(data)}
+		if len(data) > 0 && data[len(data)-1] != '\n' {
+			row.lines++
+		}
+		total.add(row)
+		for dir := filepath.Dir(file); ; dir = filepath.Dir(dir) {
+			tally(dirs, b.labelFor(dir), row)
+			if dir == root || filepath.Dir(dir) == dir {
+				break
+			}
+		}
+		ext := strings.ToLower(filepath.Ext(file))
+		if ext == "" {
+			ext = "(none)"
+		}
+		tally(exts, ext, row)
+	}
+
+	writeStatsTable(w, "DIRECTORY", dirs, total.tokens)
+	fmt.Fprintln(w)
+	writeStatsTable(w, "EXTENSION", exts, total.tokens)
+	fmt.Fprintf(w, "\n%d files, %s, %d lines, ~%d tokens\n", total.files, formatSize(total.bytes), total.lines, total.tokens)
+	return nil
+}
+
+// writeStatsTable lists rows by tokens, largest first, with their share of
+// all tokens.
+func writeStatsTable(w io.Writer, title string, rows map[string]*statsRow, tokens int) {
+	sorted := make([]*statsRow, 0, len(rows))
+	for _, row := range rows {
+		sorted = append(sorted, row)
+	}
+	slices.SortFunc(sorted, func(a, b *statsRow) int {
+		return cmp.Or(cmp.Compare(b.tokens, a.tokens), cmp.Compare(b.bytes, a.bytes), strings.Compare(a.name, b.name))
+	})
+
+	fmt.Fprintf(w, "%6s %10s %8s %9s %6s  %s\n", "FILES", "BYTES", "LINES", "TOKENS", "SHARE", title)
+	for _, row := range sorted[:min(len(sorted), statsShown)] {
+		share := 0
+		if tokens > 0 {
+			share = row.tokens * 100 / tokens
+		}
+		fmt.Fprintf(w, "%6d %10s %8d %9d %5d%%  %s\n", row.files, formatSize(row.bytes), row.lines, row.tokens, share, row.name)
+	}
+	if n := len(sorted) - statsShown; n > 0 {
+		fmt.Fprintf(w, "%43s  ... %d more\n", "", n)
+	}
+}
\ No newline at end of file
@@ -0,0 +1,115 @@
+package clipcat
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+// timeoutGrace is how long a run that hit --timeout gets to stop on its
+// own before Run gives up on it, e.g. while it hangs in a read from a dead
+// NFS mount, which nothing can interrupt.
+const timeoutGrace = 500 * time.Millisecond
+
+// progress tracks where a run is, so a --timeout can say what it was
+// waiting for. Its methods do nothing on a nil progress.
+type progress struct {
+	mu      sync.Mutex
+	phase   string
+	path    string              // the directory or file being collected
+	reading map[string]struct{} // files being read
+	done    int                 // files read
+}
+
+func (p *progress) start(phase string) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.phase, p.path, p.done = phase, "", 0
+}
+
+// visit records the path being collected, or the clipboard command for
+// the copy phase.
+func (p *progress) visit(path string) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.path = path
+}
+
+// read records that reading path began, and the returned func that it
+// ended.
+func (p *progress) read(path string) func() {
+	if p == nil {
+		return func() {}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.reading == nil {
+		p.reading = make(map[string]struct{})
+	}
+	p.reading[path] = struct{}{}
+	return func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		delete(p.reading, path)
+		p.done++
+	}
+}
+
+// timeout describes where the run was when --timeout expired.
+func (p *progress) timeout(after time.Duration, label func(string) string) *TimeoutError {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	e := &TimeoutError{Timeout: after, Phase: p.phase, Done: p.done}
+	if p.path != "" {
+		e.Paths = []string{label(p.path)}
+	}
+	if p.phase == "read" {
+		e.Paths = nil
+		for _, path := range slices.Sorted(maps.Keys(p.reading)) {
+			e.Paths = append(e.Paths, label(path))
+		}
+	}
+	return e
+}
+
+// runWithin runs run under cfg.Timeout. A run that does not return soon
+// after the deadline is left behind, and its progress reported instead.
+func runWithin(cfg *Config, report *Report) error {
+	if cfg.Timeout <= 0 {
+		return run(context.Background(), cfg, report, nil)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
+	defer cancel()
+
+	p := &progress{}
+	done := make(chan error, 1)
+	go func() { done <- run(ctx, cfg, report, p) }()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		select {
+		case err = <-done:
+		case <-time.After(timeoutGrace):
+			err = ctx.Err()
+		}
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		label := func(path string) string { return path }
+		if cfg.Relative {
+			label = relativeLabel
+		}
+		return p.timeout(cfg.Timeout, label)
+	}
+	return err
+}
\ No newline at end of file
@@ -0,0 +1,198 @@
+package clipcat
+
+import (
+	"bufio"
+	"bytes"
+	"clipcat/internal/clipboard"
+	"clipcat/pkg/crypto"
+	"clipcat/pkg/unpack"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type UnpackConfig struct {
+	Source        string // bundle file, or "-" for stdin
+	FromClipboard bool
+	Dir           string
+	DryRun        bool
+	Force         bool
+	Decrypt       bool   // decrypt an --encrypt bundle...
+	Identity      string // ...with the age key in this file
+}
+
+func ParseUnpackArgs(args []string) *UnpackConfig {
+	cfg := &UnpackConfig{Dir: "."}
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		switch arg {
+		case "-h", "--help":
+			printUnpackUsage()
+			os.Exit(0)
+		case "--from-clipboard":
+			cfg.FromClipboard = true
+		case "-n", "--dry-run":
+			cfg.DryRun = true
+		case "-f", "--force":
+			cfg.Force = true
+		case "--decrypt":
+			cfg.Decrypt = true
+		case "-i", "--identity":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: %s requires a file\n", arg)
+				os.Exit(2)
+			}
+			cfg.Identity = args[i+1]
+			i++
+		case "-C", "--dir":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: %s requires a directory\n", arg)
+				os.Exit(2)
+			}
+			cfg.Dir = args[i+1]
+			i++
+		default:
+			if strings.HasPrefix(arg, "-") && arg != "-" {
+				fmt.Fprintf(os.Stderr, "Error: unknown option: %s\n", arg)
+				printUnpackUsage()
+				os.Exit(2)
+			}
+			if cfg.Source != "" {
+				fmt.Fprintf(os.Stderr, "Error: unpack takes a single bundle file\n")
+				os.Exit(2)
+			}
+			cfg.Source = arg
+		}
+	}
+
+	if (cfg.Source == "") == !cfg.FromClipboard {
+		printUnpackUsage()
+		os.Exit(2)
+	}
+	if cfg.Decrypt && cfg.Identity == "" {
+		fmt.Fprintf(os.Stderr, "Error: --decrypt requires --identity FILE\n")
+		os.Exit(2)
+	}
+
+	return cfg
+}
+
+func printUnpackUsage() {
+	fmt.Fprintf(os.Stderr, `Usage: clipcat unpack [OPTIONS] <FILE | - | --from-clipboard>
+
+Description:
+  Recreate the files contained in a clipcat bundle in any format but llms-txt
+  and --template. Absolute header paths are written relative to their common
+  parent directory.
+
+Options:
+      --from-clipboard      Read the bundle from the clipboard
+  -C, --dir DIR             Write files under DIR (default .)
+      --decrypt             Decrypt a bundle copied with --encrypt...
+  -i, --identity FILE       ...with the age key in FILE
+  -n, --dry-run             List what would be written without touching disk
+  -f, --force               Overwrite existing files without asking
+  -h, --help                Show help
+
+Examples:
+  clipcat unpack bundle.txt -C restored/
+  clipcat unpack --from-clipboard --dry-run
+`)
+}
+
+func Unpack(cfg *UnpackConfig) error {
+	var data []byte
+	var err error
+	switch {
+	case cfg.FromClipboard:
+		data, err = clipboard.ReadFromClipboard()
+	case cfg.Source == "-":
+		data, err = io.ReadAll(os.Stdin)
+	default:
+		data, err = os.ReadFile(cfg.Source)
+	}
+	if err == nil && crypto.IsEncrypted(data) {
+		if !cfg.Decrypt {
+			return fmt.Errorf("bundle is encrypted; use --decrypt --identity FILE")
+		}
+		data, err = crypto.Decrypt(context.Background(), cfg.Identity, data)
+	}
+	if err == nil {
+		data, err = unpack.Decompress(data)
+	}
+	if err != nil {
+		return fmt.Errorf("reading bundle: %w", err)
+	}
+
+	files := unpack.Parse(data)
+	if len(files) == 0 {
+		return fmt.Errorf("no file sections found in bundle")
+	}
+
+	rels, err := unpack.Relativize(files)
+	if err != nil {
+		return err
+	}
+
+	// Prompts read answers from stdin, which is unavailable when it carries the bundle
+	canPrompt := cfg.Source != "-"
+	stdin := bufio.NewReader(os.Stdin)
+	overwriteAll := cfg.Force
+	written := 0
+
+	for i, file := range files {
+		dest := filepath.Join(cfg.Dir, rels[i])
+
+		existing, err := os.ReadFile(dest)
+		exists := err == nil
+		if exists && bytes.Equal(existing, file.Content) {
+			fmt.Printf("unchanged  %s\n", dest)
+			continue
+		}
+
+		if cfg.DryRun {
+			action := "create"
+			if exists {
+				action = "overwrite"
+			}
+			fmt.Printf("%-10s %s (%d bytes)\n", action, dest, len(file.Content))
+			continue
+		}
+
+		if exists && !overwriteAll {
+			if !canPrompt {
+				fmt.Printf("skipped    %s (exists; use --force to overwrite)\n", dest)
+				continue
+			}
+			fmt.Printf("Overwrite %s? [y/N/a] ", dest)
+			answer, _ := stdin.ReadString('\n')
+			switch strings.ToLower(strings.TrimSpace(answer)) {
+			case "y", "yes":
+			case "a", "all":
+				overwriteAll = true
+			default:
+				fmt.Printf("skipped    %s\n", dest)
+				continue
+			}
+		}
+
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(dest, file.Content, 0644); err != nil {
+			return fmt.Errorf("writing %s: %w", dest, err)
+		}
+		fmt.Printf("wrote      %s\n", dest)
+		written++
+	}
+
+	if !cfg.DryRun {
+		fmt.Printf("Unpacked %d of %d files into %s.\n", written, len(files), cfg.Dir)
+	}
+	return nil
+}
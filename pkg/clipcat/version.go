@@ -0,0 +1,74 @@
+package clipcat
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/debug"
+)
+
+// Build metadata, injected at build time:
+//
+//	go build -ldflags "-X clipcat/pkg/clipcat.Version=v1.2.0 -X clipcat/pkg/clipcat.Commit=... -X clipcat/pkg/clipcat.Date=..."
+//
+// When they are not set, Commit and Date fall back to the VCS stamp Go embeds
+// in binaries built from a checkout.
+var (
+	Version = "dev"
+	Commit  = ""
+	Date    = ""
+)
+
+type VersionInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	Date      string `json:"date"`
+	GoVersion string `json:"go_version"`
+	Platform  string `json:"platform"`
+}
+
+func GetVersionInfo() VersionInfo {
+	info := VersionInfo{
+		Version:   Version,
+		Commit:    Commit,
+		Date:      Date,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+
+	if build, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range build.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = setting.Value
+			case setting.Key == "vcs.time" && info.Date == "":
+				info.Date = setting.Value
+			}
+		}
+	}
+	if info.Commit == "" {
+		info.Commit = "unknown"
+	}
+	if info.Date == "" {
+		info.Date = "unknown"
+	}
+	return info
+}
+
+func printVersion(format string) {
+	info := GetVersionInfo()
+
+	if format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(info)
+		return
+	}
+
+	commit := info.Commit
+	if len(commit) > 12 {
+		commit = commit[:12]
+	}
+	fmt.Printf("clipcat %s (commit %s, built %s, %s %s)\n", info.Version, commit, info.Date, info.GoVersion, info.Platform)
+}
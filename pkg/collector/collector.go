@@ -1,66 +1,343 @@
 package collector
 
 import (
+	"bufio"
 	"clipcat/pkg/exclude"
+	"clipcat/pkg/lang"
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
+)
+
+// IgnoreMarker opts a file out of collection when it appears on the file's
+// first line (or the line after a shebang), e.g. "// clipcat:ignore".
+const IgnoreMarker = "clipcat:ignore"
 
-	"github.com/bmatcuk/doublestar/v4"
+func containsAnySep(s string) bool {
+	return strings.Contains(s, "/") || strings.Contains(s, string(filepath.Separator))
+}
+
+// HasIgnoreMarker reports whether the file carries IgnoreMarker in its header.
+// Only the first line is inspected, or the first two when the file starts
+// with a shebang, so markers deeper in the file are not honored.
+func HasIgnoreMarker(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(io.LimitReader(f, 4096))
+	line, _ := reader.ReadString('\n')
+	if strings.HasPrefix(line, "#!") {
+		line, _ = reader.ReadString('\n')
+	}
+	return strings.Contains(line, IgnoreMarker)
+}
+
+// Options controls how Collect resolves input paths into files.
+type Options struct {
+	Paths      []string
+	Matcher    *exclude.ExcludeMatcher
+	IgnoreCase bool
+	// Git lists tracked files with `git ls-files` instead of walking the
+	// filesystem, so untracked and gitignored files never appear.
+	Git bool
+	// Only, when non-nil, restricts results to these files (keyed by their
+	// symlink-resolved absolute path), e.g. the files changed since a git ref.
+	Only map[string]bool
+	// Defaults are extra exclude patterns applied only while walking
+	// directories and matching globs, never to files named literally.
+	Defaults []string
+	// ModifiedAfter and ModifiedBefore, when non-zero, keep only files whose
+	// modification time falls between them.
+	ModifiedAfter  time.Time
+	ModifiedBefore time.Time
+	// Languages, when set, keeps only files whose language or extension is
+	// listed (see lang.Match).
+	Languages []string
+	// Types, when set, keeps only files in one of these categories (see
+	// lang.Category).
+	Types []string
+	// Policy drops the files it forbids whatever the other options say;
+	// naming one literally is a *exclude.PolicyError.
+	Policy *exclude.Policy
+	// Force names files to collect whatever the matcher, defaults, Git,
+	// Only, time range, languages, types and ignore markers say; only the
+	// policy still applies.
+	Force []string
+	// KeepMarked skips the IgnoreMarker check.
+	KeepMarked bool
+	// Skipped, when set, is called with every file left out and why, and
+	// with excluded directories, which are not walked.
+	Skipped func(path, reason string)
+	// Visit, when set, is called with each directory before it is read and
+	// each file before it is stat'd, to tell where a hung walk is stuck.
+	Visit func(path string)
+	// Warnings receives notices about skipped inputs from Collect and
+	// CollectContext (default os.Stderr); CollectEntries returns them.
+	Warnings io.Writer
+	// Root is where glob inputs without a directory prefix are searched
+	// (default "."). Prefixed globs such as "src/**/*.go" are searched
+	// under their prefix, resolved against Root when relative.
+	Root string
+
+	warnings *[]Warning // where CollectEntries gathers them
+}
+
+// Entry is a collected file with what the walk already knew about it, so
+// callers need not stat it again.
+type Entry struct {
+	Path    string // absolute
+	Rel     string // relative to Root
+	Root    string // absolute directory the input selected the file from
+	Input   string // that input, as given
+	Match   string // how the input selected it: MatchLiteral, MatchDir, MatchGlob or MatchForce
+	Size    int64
+	Mode    fs.FileMode
+	ModTime time.Time
+}
+
+// How an Entry's input selected it
+const (
+	MatchLiteral = "literal" // named directly; Root is its directory
+	MatchDir     = "dir"     // found under a directory input, the Root
+	MatchGlob    = "glob"    // matched by a glob input searched from Root
+	MatchForce   = "force"   // named by Options.Force; Root is its directory
 )
 
-func isGlobPattern(path string) bool {
-	return strings.ContainsAny(path, "*?[")
+// Warning is a problem that left an input or file out without failing the
+// collection.
+type Warning struct {
+	Kind    string `json:"kind"` // WarnNotExist, WarnNoMatch, WarnPermission, WarnBrokenLink, WarnUnreadable, WarnForceDir or WarnPolicy
+	Path    string `json:"path"` // the input or file; the policy file for WarnPolicy
+	Message string `json:"message"`
 }
 
-func isDoublestarPattern(pattern string) bool {
-	return strings.Contains(pattern, "**")
+// Kinds of Warning
+const (
+	WarnNotExist   = "not-exist"   // an input that does not exist
+	WarnNoMatch    = "no-match"    // a glob pattern that matched no files
+	WarnPermission = "permission"  // a file or directory that may not be read
+	WarnBrokenLink = "broken-link" // a symlink to nothing
+	WarnUnreadable = "unreadable"  // a file or directory the walk could not read otherwise
+	WarnForceDir   = "force-dir"   // a directory given to Options.Force
+	WarnPolicy     = "policy"      // files the policy left out of walks
+)
+
+// String is the warning as the command line prints it.
+func (w Warning) String() string {
+	return "Warning: " + w.Message
 }
 
-func hasBraceExpansion(pattern string) bool {
-	return strings.Contains(pattern, "{") && strings.Contains(pattern, "}")
+// origin is the input a file was found through.
+type origin struct {
+	input, root, match string
 }
 
-func containsAnySep(s string) bool {
-	return strings.Contains(s, "/") || strings.Contains(s, string(filepath.Separator))
+// InTimeRange reports whether path's modification time is within
+// ModifiedAfter and ModifiedBefore.
+func (opts Options) InTimeRange(path string) bool {
+	if opts.ModifiedAfter.IsZero() && opts.ModifiedBefore.IsZero() {
+		return true
+	}
+	info, err := os.Stat(path)
+	return err == nil && opts.inTimeRange(info)
 }
 
-func matchPath(pattern, target string) bool {
-	if isDoublestarPattern(pattern) || hasBraceExpansion(pattern) {
-		// Use doublestar for complex patterns with ** or brace expansion
-		matched, err := doublestar.Match(pattern, target)
-		if err != nil {
-			return false
-		}
-		return matched
-	} else {
-		// Use filepath.Match for simple patterns
-		ok, _ := filepath.Match(pattern, target)
-		return ok
+func (opts Options) inTimeRange(info os.FileInfo) bool {
+	if opts.ModifiedAfter.IsZero() && opts.ModifiedBefore.IsZero() {
+		return true
+	}
+	if info == nil {
+		return false
+	}
+	mtime := info.ModTime()
+	if !opts.ModifiedAfter.IsZero() && !mtime.After(opts.ModifiedAfter) {
+		return false
 	}
+	return opts.ModifiedBefore.IsZero() || mtime.Before(opts.ModifiedBefore)
 }
 
 func CollectFiles(paths []string, matcher *exclude.ExcludeMatcher, ignoreCase bool) ([]string, error) {
+	return Collect(Options{Paths: paths, Matcher: matcher, IgnoreCase: ignoreCase})
+}
+
+func Collect(opts Options) ([]string, error) {
+	return CollectContext(context.Background(), opts)
+}
+
+// CollectContext is Collect with cancellation: walks stop with ctx.Err()
+// once ctx is done.
+func CollectContext(ctx context.Context, opts Options) ([]string, error) {
+	entries, warnings, err := CollectEntries(ctx, opts)
+	out := opts.Warnings
+	if out == nil {
+		out = os.Stderr
+	}
+	for _, w := range warnings {
+		fmt.Fprintln(out, w)
+	}
+	if err != nil {
+		return nil, err
+	}
+	paths := make([]string, len(entries))
+	for i, e := range entries {
+		paths[i] = e.Path
+	}
+	return paths, nil
+}
+
+// CollectEntries is CollectContext returning an Entry per file: its input,
+// root and the size, mode and modification time seen while collecting.
+// Instead of printing warnings it returns them, for the caller to show.
+func CollectEntries(ctx context.Context, opts Options) ([]Entry, []Warning, error) {
+	var warnings []Warning
+	opts.warnings = &warnings
+	entries, err := collectEntries(ctx, opts)
+	return entries, warnings, err
+}
+
+func collectEntries(ctx context.Context, opts Options) ([]Entry, error) {
+	matcher := opts.Matcher
+	walk := matcher.WithDefaults(opts.Defaults)
 	seen := make(map[string]bool)
-	var result []string
+	kept := make(map[string]bool)
+	found := make(map[string]bool) // inputs that brought in at least one file
+	var result []Entry
+	forbidden := 0
 
-	for _, path := range paths {
+	// Files are keyed by their symlink-resolved path, so a file reached
+	// through several inputs or symlinked aliases is collected once, under
+	// the first path it was found by.
+	keep := func(absPath string, from origin) {
+		opts.visit(absPath)
+		force := from.match == MatchForce
+		canonical := canonicalPath(absPath)
+		if seen[canonical] {
+			found[from.input] = found[from.input] || kept[canonical]
+			return
+		}
+		seen[canonical] = true
+		// Symlinks must not smuggle forbidden files in under another name
+		for _, p := range []string{absPath, canonical} {
+			if rule, ok := opts.Policy.Forbids(p); ok {
+				forbidden++
+				opts.skip(absPath, "policy: "+rule.String())
+				return
+			}
+		}
+		// Files that cannot be stat'd are still collected, and reported
+		// when read
+		info, err := os.Stat(absPath)
+		if err != nil {
+			if link, lerr := os.Lstat(absPath); lerr == nil && link.Mode()&fs.ModeSymlink != 0 {
+				opts.warn(WarnBrokenLink, absPath, "Skipping broken symlink: %s", absPath)
+				return
+			}
+			info = nil
+		}
+		if !force {
+			if opts.Only != nil && !opts.Only[canonical] {
+				opts.skip(absPath, "not among the selected git changes")
+				return
+			}
+			if !opts.inTimeRange(info) {
+				opts.skip(absPath, "modified outside --newer-than/--older-than")
+				return
+			}
+			if len(opts.Languages) > 0 && !lang.Match(absPath, opts.Languages) {
+				opts.skip(absPath, "not among --ext "+strings.Join(opts.Languages, ","))
+				return
+			}
+			if len(opts.Types) > 0 && !lang.MatchCategory(absPath, opts.Types) {
+				opts.skip(absPath, "not among --type "+strings.Join(opts.Types, ","))
+				return
+			}
+			if !opts.KeepMarked && HasIgnoreMarker(absPath) {
+				opts.skip(absPath, "opt-out marker "+IgnoreMarker)
+				return
+			}
+		}
+		entry := Entry{Path: absPath, Root: from.root, Input: from.input, Match: from.match}
+		entry.Rel, _ = filepath.Rel(from.root, absPath)
+		if info != nil {
+			entry.Size, entry.Mode, entry.ModTime = info.Size(), info.Mode(), info.ModTime()
+		}
+		result = append(result, entry)
+		kept[canonical], found[from.input] = true, true
+	}
+
+	// Each glob pattern that brought in nothing is reported by itself, so a
+	// mistyped one among several does not go unnoticed
+	warnEmpty := func() {
+		for _, path := range opts.Paths {
+			if _, err := os.Stat(path); err != nil && exclude.IsGlobPattern(path) && !found[path] {
+				opts.warn(WarnNoMatch, path, "Pattern '%s' matched no files", path)
+			}
+		}
+	}
+
+	// Forced files go first, so no input can mark them seen and filter them
+	for _, path := range opts.Force {
+		info, err := os.Stat(path)
+		if err != nil {
+			opts.warnMissing(path)
+			continue
+		}
+		if info.IsDir() {
+			opts.warn(WarnForceDir, path, "Skipping directory %s: only files can be forced in", path)
+			continue
+		}
+		absPath, _ := filepath.Abs(path)
+		for _, p := range []string{absPath, canonicalPath(absPath)} {
+			if rule, ok := opts.Policy.Forbids(p); ok {
+				return nil, &exclude.PolicyError{Path: path, Rule: rule}
+			}
+		}
+		keep(absPath, origin{path, filepath.Dir(absPath), MatchForce})
+	}
+
+	if opts.Git {
+		if err := collectGit(ctx, opts, keep); err != nil {
+			return nil, err
+		}
+		warnEmpty()
+		opts.warnForbidden(forbidden)
+		return result, nil
+	}
+
+	for _, path := range opts.Paths {
 		// Check if it's a literal path
 		info, err := os.Stat(path)
 		if err == nil {
 			// Literal path exists
 			if info.IsDir() {
 				// Walk directory
+				root, _ := filepath.Abs(path)
+				from := origin{path, root, MatchDir}
 				err := filepath.Walk(path, func(p string, fi os.FileInfo, err error) error {
+					if ctxErr := ctx.Err(); ctxErr != nil {
+						return ctxErr
+					}
 					if err != nil {
-						return nil // Skip errors
+						opts.warnUnreadable(p, err)
+						return nil
 					}
 
 					absPath, _ := filepath.Abs(p)
+					if fi.IsDir() {
+						opts.visit(absPath)
+					}
 
 					// Exclude?
-					if matcher.ShouldExclude(absPath, fi.IsDir()) {
+					if opts.excludes(walk, absPath, fi.IsDir()) {
 						if fi.IsDir() {
 							return filepath.SkipDir
 						}
@@ -68,10 +345,7 @@ func CollectFiles(paths []string, matcher *exclude.ExcludeMatcher, ignoreCase bo
 					}
 
 					if !fi.IsDir() {
-						if !seen[absPath] {
-							result = append(result, absPath)
-							seen[absPath] = true
-						}
+						keep(absPath, from)
 					}
 					return nil
 				})
@@ -80,23 +354,39 @@ func CollectFiles(paths []string, matcher *exclude.ExcludeMatcher, ignoreCase bo
 				}
 			} else {
 				absPath, _ := filepath.Abs(path)
-				if !matcher.ShouldExclude(absPath, false) && !seen[absPath] {
-					result = append(result, absPath)
-					seen[absPath] = true
+				for _, p := range []string{absPath, canonicalPath(absPath)} {
+					if rule, ok := opts.Policy.Forbids(p); ok {
+						return nil, &exclude.PolicyError{Path: path, Rule: rule}
+					}
 				}
+				if !opts.excludes(matcher, absPath, false) {
+					keep(absPath, origin{path, filepath.Dir(absPath), MatchLiteral})
+				}
+			}
+		} else if exclude.IsGlobPattern(path) {
+			// Glob pattern - search from its directory prefix or the root
+			dir, match := opts.globMatcher(path)
+			if _, err := os.Stat(dir); err != nil {
+				continue // reported as matching no files
 			}
-		} else if isGlobPattern(path) {
-			// Glob pattern - search from current directory
-			pattern := path
-			err := filepath.Walk(".", func(p string, fi os.FileInfo, err error) error {
+			root, _ := filepath.Abs(dir)
+			from := origin{path, root, MatchGlob}
+			err := filepath.Walk(dir, func(p string, fi os.FileInfo, err error) error {
+				if ctxErr := ctx.Err(); ctxErr != nil {
+					return ctxErr
+				}
 				if err != nil {
+					opts.warnUnreadable(p, err)
 					return nil
 				}
 
 				absPath, _ := filepath.Abs(p)
+				if fi.IsDir() {
+					opts.visit(absPath)
+				}
 
 				// Exclude?
-				if matcher.ShouldExclude(absPath, fi.IsDir()) {
+				if opts.excludes(walk, absPath, fi.IsDir()) {
 					if fi.IsDir() {
 						return filepath.SkipDir
 					}
@@ -107,36 +397,9 @@ func CollectFiles(paths []string, matcher *exclude.ExcludeMatcher, ignoreCase bo
 					return nil
 				}
 
-				rel, _ := filepath.Rel(".", p)
-				sep := string(filepath.Separator)
-
-				// Normalize both sides for matching
-				patNorm := strings.ReplaceAll(pattern, "/", sep)
-				target := rel
-
-				var matched bool
-				if containsAnySep(patNorm) || isDoublestarPattern(patNorm) {
-					// Match against the relative path when the pattern has a separator or is a doublestar pattern
-					if ignoreCase {
-						matched = matchPath(strings.ToLower(patNorm), strings.ToLower(target))
-					} else {
-						matched = matchPath(patNorm, target)
-					}
-				} else {
-					// Match against basename when there's no separator and not a doublestar pattern
-					name := filepath.Base(rel)
-					if ignoreCase {
-						matched = matchPath(strings.ToLower(patNorm), strings.ToLower(name))
-					} else {
-						matched = matchPath(patNorm, name)
-					}
-				}
-
-				if matched {
-					if !seen[absPath] {
-						result = append(result, absPath)
-						seen[absPath] = true
-					}
+				rel, _ := filepath.Rel(dir, p)
+				if match(rel) {
+					keep(absPath, from)
 				}
 				return nil
 			})
@@ -144,9 +407,157 @@ func CollectFiles(paths []string, matcher *exclude.ExcludeMatcher, ignoreCase bo
 				return nil, err
 			}
 		} else {
-			fmt.Fprintf(os.Stderr, "Warning: Skipping non-existent path: %s\n", path)
+			opts.warnMissing(path)
 		}
 	}
 
+	warnEmpty()
+	opts.warnForbidden(forbidden)
 	return result, nil
+}
+
+// visit reports where the walk is to opts.Visit.
+func (opts Options) visit(path string) {
+	if opts.Visit != nil {
+		opts.Visit(path)
+	}
+}
+
+// skip reports a path left out to opts.Skipped.
+func (opts Options) skip(path, reason string) {
+	if opts.Skipped != nil {
+		opts.Skipped(path, reason)
+	}
+}
+
+// excludes reports whether m excludes path, telling opts.Skipped which rule
+// did.
+func (opts Options) excludes(m *exclude.ExcludeMatcher, path string, isDir bool) bool {
+	excluded, rule := m.Explain(path, isDir)
+	if excluded && opts.Skipped != nil {
+		reason := "excluded"
+		if rule != nil {
+			reason = "exclude: " + rule.String()
+		}
+		opts.Skipped(path, reason)
+	}
+	return excluded
+}
+
+// warn records a Warning for CollectEntries to return.
+func (opts Options) warn(kind, path, format string, args ...any) {
+	if opts.warnings != nil {
+		*opts.warnings = append(*opts.warnings, Warning{Kind: kind, Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+}
+
+// warnMissing reports an input that does not exist, or a symlink to
+// nothing.
+func (opts Options) warnMissing(path string) {
+	if link, err := os.Lstat(path); err == nil && link.Mode()&fs.ModeSymlink != 0 {
+		opts.warn(WarnBrokenLink, path, "Skipping broken symlink: %s", path)
+		return
+	}
+	opts.warn(WarnNotExist, path, "Skipping non-existent path: %s", path)
+}
+
+// warnUnreadable reports a file or directory a walk could not read.
+func (opts Options) warnUnreadable(path string, err error) {
+	if errors.Is(err, fs.ErrPermission) {
+		opts.warn(WarnPermission, path, "Skipping %s: permission denied", path)
+		return
+	}
+	opts.warn(WarnUnreadable, path, "Skipping %s: %v", path, err)
+}
+
+// warnForbidden reports the files the policy kept out of a walk.
+func (opts Options) warnForbidden(n int) {
+	switch {
+	case n == 1:
+		opts.warn(WarnPolicy, opts.Policy.Source(), "1 file left out by policy %s", opts.Policy.Source())
+	case n > 1:
+		opts.warn(WarnPolicy, opts.Policy.Source(), "%d files left out by policy %s", n, opts.Policy.Source())
+	}
+}
+
+// canonicalPath resolves symlinks so paths reported by external tools (git
+// prints real paths) compare equal to walked paths.
+func canonicalPath(absPath string) string {
+	if resolved, err := filepath.EvalSymlinks(absPath); err == nil {
+		return resolved
+	}
+	return absPath
+}
+
+// GlobBase splits a glob input into its leading literal directories and the
+// pattern below them, e.g. "src/**/*.go" into "src" and "**/*.go". dir is ""
+// when the pattern starts with a wildcard.
+func GlobBase(pattern string) (dir, rest string) {
+	parts := strings.Split(filepath.ToSlash(pattern), "/")
+	n := 0
+	for n < len(parts)-1 && !exclude.IsGlobPattern(parts[n]) {
+		n++
+	}
+	if n == 0 {
+		return "", pattern
+	}
+	dir = strings.Join(parts[:n], "/")
+	if dir == "" {
+		dir = "/"
+	}
+	return filepath.FromSlash(dir), strings.Join(parts[n:], "/")
+}
+
+// GlobRoot returns the directory searched for a glob input: its literal
+// prefix resolved against root, or root itself ("." when empty).
+func GlobRoot(pattern, root string) string {
+	if root == "" {
+		root = "."
+	}
+	dir, _ := GlobBase(pattern)
+	if filepath.IsAbs(dir) {
+		return dir
+	}
+	return filepath.Join(root, dir)
+}
+
+// globMatcher returns the directory to search for a glob input and a match
+// func for paths relative to it. Prefixed patterns match the whole relative
+// path; others keep matchGlob's basename semantics.
+func (opts Options) globMatcher(pattern string) (string, func(rel string) bool) {
+	dir := GlobRoot(pattern, opts.Root)
+	base, rest := GlobBase(pattern)
+	if base == "" {
+		return dir, func(rel string) bool { return matchGlob(pattern, rel, opts.IgnoreCase) }
+	}
+	rest = filepath.FromSlash(rest)
+	if opts.IgnoreCase {
+		rest = strings.ToLower(rest)
+	}
+	return dir, func(rel string) bool {
+		if opts.IgnoreCase {
+			rel = strings.ToLower(rel)
+		}
+		return exclude.MatchPath(rest, rel)
+	}
+}
+
+// matchGlob matches a positional glob input against a path relative to the
+// search root.
+func matchGlob(pattern, rel string, ignoreCase bool) bool {
+	sep := string(filepath.Separator)
+
+	// Normalize both sides for matching
+	patNorm := strings.ReplaceAll(pattern, "/", sep)
+	target := rel
+
+	if !containsAnySep(patNorm) && !exclude.IsDoublestarPattern(patNorm) {
+		// Match against basename when there's no separator and not a doublestar pattern
+		target = filepath.Base(rel)
+	}
+	// Otherwise match against the relative path
+	if ignoreCase {
+		return exclude.MatchPath(strings.ToLower(patNorm), strings.ToLower(target))
+	}
+	return exclude.MatchPath(patNorm, target)
 }
\ No newline at end of file
@@ -0,0 +1,117 @@
+package collector
+
+import (
+	"clipcat/internal/git"
+	"clipcat/pkg/exclude"
+	"context"
+	"os"
+	"path/filepath"
+)
+
+// collectGit resolves inputs against `git ls-files` output. Literal files are
+// taken as given, directories expand to their tracked files, and glob inputs
+// match the tracked files under their directory prefix or Root.
+func collectGit(ctx context.Context, opts Options, add func(string, origin)) error {
+	excluded := ancestorExcluder(opts.Matcher.WithDefaults(opts.Defaults))
+
+	for _, path := range opts.Paths {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		info, err := os.Stat(path)
+		switch {
+		case err == nil && !info.IsDir():
+			absPath, _ := filepath.Abs(path)
+			if !opts.excludes(opts.Matcher, absPath, false) {
+				add(absPath, origin{path, filepath.Dir(absPath), MatchLiteral})
+			}
+
+		case err == nil:
+			tracked, err := git.LsFiles(path)
+			if err != nil {
+				return err
+			}
+			root, _ := filepath.Abs(path)
+			for _, rel := range tracked {
+				absPath := filepath.Join(root, filepath.FromSlash(rel))
+				if isRegularFile(absPath) && !opts.excludedBelow(excluded, root, absPath) {
+					add(absPath, origin{path, root, MatchDir})
+				}
+			}
+
+		case exclude.IsGlobPattern(path):
+			dir, match := opts.globMatcher(path)
+			if _, err := os.Stat(dir); err != nil {
+				continue // reported as matching no files
+			}
+			tracked, err := git.LsFiles(dir)
+			if err != nil {
+				return err
+			}
+			root, _ := filepath.Abs(dir)
+			for _, rel := range tracked {
+				rel = filepath.FromSlash(rel)
+				if !match(rel) {
+					continue
+				}
+				absPath := filepath.Join(root, rel)
+				if isRegularFile(absPath) && !opts.excludedBelow(excluded, root, absPath) {
+					add(absPath, origin{path, root, MatchGlob})
+				}
+			}
+
+		default:
+			opts.warnMissing(path)
+		}
+	}
+	return nil
+}
+
+// excludedBelow runs an ancestorExcluder check and tells opts.Skipped about
+// the files it excludes.
+func (opts Options) excludedBelow(excluded func(root, absPath string) bool, root, absPath string) bool {
+	if !excluded(root, absPath) {
+		return false
+	}
+	if opts.Skipped != nil {
+		if self, rule := opts.Matcher.WithDefaults(opts.Defaults).Explain(absPath, false); self && rule != nil {
+			opts.Skipped(absPath, "exclude: "+rule.String())
+		} else {
+			opts.Skipped(absPath, "exclude: in an excluded directory")
+		}
+	}
+	return true
+}
+
+// ancestorExcluder returns a check equivalent to the pruning a directory walk
+// performs: a file is excluded if it, or any directory between it and root,
+// is excluded. Directory decisions are cached since many files share them.
+func ancestorExcluder(matcher *exclude.ExcludeMatcher) func(root, absPath string) bool {
+	dirs := make(map[string]bool)
+
+	return func(root, absPath string) bool {
+		if matcher.ShouldExclude(absPath, false) {
+			return true
+		}
+		for dir := filepath.Dir(absPath); ; dir = filepath.Dir(dir) {
+			excluded, ok := dirs[dir]
+			if !ok {
+				excluded = matcher.ShouldExclude(dir, true)
+				dirs[dir] = excluded
+			}
+			if excluded {
+				return true
+			}
+			if dir == root || dir == filepath.Dir(dir) {
+				return false
+			}
+		}
+	}
+}
+
+// isRegularFile skips index entries that were deleted from the worktree and
+// submodule gitlinks that were not checked out.
+func isRegularFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
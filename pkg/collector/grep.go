@@ -0,0 +1,61 @@
+package collector
+
+import (
+	"context"
+	"os"
+	"regexp"
+	"runtime"
+)
+
+// Grep keeps the files whose content matches re, scanning up to jobs files
+// at once (NumCPU when 0). It returns the matching files in their original
+// order and the number of matches in each. Unreadable files don't match.
+func Grep(ctx context.Context, files []string, re *regexp.Regexp, jobs int) ([]string, []int, error) {
+	if jobs <= 0 {
+		jobs = runtime.NumCPU()
+	}
+	jobs = max(1, min(jobs, len(files)))
+
+	counts := make([]int, len(files))
+	next := make(chan int)
+	done := make(chan struct{})
+	for range jobs {
+		go func() {
+			defer func() { done <- struct{}{} }()
+			for i := range next {
+				data, err := os.ReadFile(files[i])
+				if err == nil {
+					counts[i] = len(re.FindAllIndex(data, -1))
+				}
+			}
+		}()
+	}
+
+	var err error
+feed:
+	for i := range files {
+		select {
+		case next <- i:
+		case <-ctx.Done():
+			err = ctx.Err()
+			break feed
+		}
+	}
+	close(next)
+	for range jobs {
+		<-done
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var matched []string
+	var matchCounts []int
+	for i, file := range files {
+		if counts[i] > 0 {
+			matched = append(matched, file)
+			matchCounts = append(matchCounts, counts[i])
+		}
+	}
+	return matched, matchCounts, nil
+}
\ No newline at end of file
@@ -0,0 +1,597 @@
+package config
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ProjectFile is looked up in the working directory and its parents
+const ProjectFile = ".clipcat.toml"
+
+// Profile is a named set of arguments, expanded in place by --profile NAME
+type Profile struct {
+	Name        string
+	Description string
+	Args        []string
+	Source      string
+}
+
+type Config struct {
+	Profiles map[string]*Profile
+	// DefaultExcludes replaces the built-in default exclude set when non-nil
+	DefaultExcludes []string
+	// Languages maps extensions (".tpl") or file names ("Justfile") to
+	// language identifiers, from the [languages] table
+	Languages map[string]string
+	// Handlers maps registry keys (".json", "go.sum", "image/*") to
+	// external commands, from [handlers.KEY] tables
+	Handlers map[string]*Handler
+	// PreCopy and PostCopy run around each clipboard copy, from the [hooks]
+	// table's pre_copy and post_copy keys
+	PreCopy  *Hook
+	PostCopy *Hook
+	// Audit turns on the audit log of copies, from the [audit] table
+	Audit Audit
+	// Ask configures the API used by `clipcat ask`, from the [ask] table
+	Ask Ask
+	// ClipboardLimits maps clipboard backends ("xclip", "clip.exe") to the
+	// largest payload to copy, as sizes like "16M", from [clipboard.limits]
+	ClipboardLimits map[string]string
+	// MaxLinesPerFile maps extensions (".log") or file names to the lines
+	// kept of such files, from the [max_lines_per_file] table
+	MaxLinesPerFile map[string]int
+	Files           []string // config files that were read, lowest precedence first
+}
+
+// Handler is a [handlers.KEY] table: the command whose output replaces the
+// content of files matching Key.
+type Handler struct {
+	Key     string
+	Command string
+	Source  string
+}
+
+// Hook is a shell command from the [hooks] table and the file that set it.
+type Hook struct {
+	Command string
+	Source  string
+}
+
+// Audit is the [audit] table. Path defaults to AuditPath.
+type Audit struct {
+	Enabled bool
+	Path    string
+	Source  string // the file that last set a key
+}
+
+// AuditPath returns the default audit log, next to the user config.
+func AuditPath() string {
+	path := UserPath()
+	if path == "" {
+		return ""
+	}
+	return filepath.Join(filepath.Dir(path), "audit.jsonl")
+}
+
+// Ask is the [ask] table: which chat completion API `clipcat ask` sends to.
+// Empty fields fall back to the provider's defaults.
+type Ask struct {
+	Provider  string // anthropic or openai (OpenAI-compatible)
+	URL       string
+	Model     string
+	APIKeyEnv string // environment variable holding the API key
+	MaxTokens int
+}
+
+// PolicyPath is the organization policy file, whose deny patterns no flag
+// or config file can override. A missing file means no policy.
+var PolicyPath = defaultPolicyPath()
+
+func defaultPolicyPath() string {
+	if runtime.GOOS == "windows" {
+		return filepath.Join(cmp.Or(os.Getenv("ProgramData"), `C:\ProgramData`), "clipcat", "policy.toml")
+	}
+	return "/etc/clipcat/policy.toml"
+}
+
+// LoadPolicy reads the deny array of the policy file at path. A missing
+// file has no patterns; an unreadable or invalid one is an error, so a
+// broken policy never allows everything.
+func LoadPolicy(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading policy: %w", err)
+	}
+	tables, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for key := range tables[""] {
+		if key != "deny" {
+			return nil, fmt.Errorf("%s: unknown key %q", path, key)
+		}
+	}
+	value, ok := tables[""]["deny"]
+	if !ok {
+		return nil, nil
+	}
+	deny, ok := value.([]string)
+	if !ok {
+		return nil, fmt.Errorf("%s: deny must be an array of strings", path)
+	}
+	return deny, nil
+}
+
+// UserPath returns the per-user config file, e.g. ~/.config/clipcat/config.toml
+func UserPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "clipcat", "config.toml")
+}
+
+// ProjectPath returns the nearest .clipcat.toml at or above dir, or "".
+func ProjectPath(dir string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	for {
+		path := filepath.Join(dir, ProjectFile)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// Load reads the user config and then the project config, so project
+// settings win. Missing files are not an error.
+func Load() (*Config, error) {
+	cfg := &Config{Profiles: map[string]*Profile{}}
+	for _, path := range []string{UserPath(), ProjectPath(".")} {
+		if path == "" {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading config: %w", err)
+		}
+		if err := cfg.Merge(data, path); err != nil {
+			return nil, err
+		}
+	}
+	return cfg, nil
+}
+
+// Merge parses data and layers it over cfg.
+func (cfg *Config) Merge(data []byte, source string) error {
+	tables, err := Parse(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", source, err)
+	}
+	if cfg.Profiles == nil {
+		cfg.Profiles = map[string]*Profile{}
+	}
+
+	if value, ok := tables[""]["default_excludes"]; ok {
+		patterns, ok := value.([]string)
+		if !ok {
+			return fmt.Errorf("%s: default_excludes must be an array of strings", source)
+		}
+		cfg.DefaultExcludes = patterns
+	}
+
+	for key, value := range tables["languages"] {
+		language, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s: [languages] %s must be a string", source, key)
+		}
+		if cfg.Languages == nil {
+			cfg.Languages = map[string]string{}
+		}
+		cfg.Languages[key] = language
+	}
+
+	for backend, value := range tables["clipboard.limits"] {
+		var size string
+		switch v := value.(type) {
+		case string:
+			size = v
+		case int64:
+			size = strconv.FormatInt(v, 10)
+		default:
+			return fmt.Errorf("%s: [clipboard.limits] %s must be a size such as \"16M\"", source, backend)
+		}
+		if cfg.ClipboardLimits == nil {
+			cfg.ClipboardLimits = map[string]string{}
+		}
+		cfg.ClipboardLimits[backend] = size
+	}
+
+	for key, value := range tables["max_lines_per_file"] {
+		n, ok := value.(int64)
+		if !ok || n < 0 {
+			return fmt.Errorf("%s: [max_lines_per_file] %s must be a line count", source, key)
+		}
+		if cfg.MaxLinesPerFile == nil {
+			cfg.MaxLinesPerFile = map[string]int{}
+		}
+		cfg.MaxLinesPerFile[key] = int(n)
+	}
+
+	for key, value := range tables["hooks"] {
+		command, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s: [hooks] %s must be a command string", source, key)
+		}
+		switch key {
+		case "pre_copy":
+			cfg.PreCopy = &Hook{Command: command, Source: source}
+		case "post_copy":
+			cfg.PostCopy = &Hook{Command: command, Source: source}
+		default:
+			return fmt.Errorf("%s: [hooks] unknown key %q", source, key)
+		}
+	}
+
+	for key, value := range tables["audit"] {
+		switch key {
+		case "enabled":
+			enabled, ok := value.(bool)
+			if !ok {
+				return fmt.Errorf("%s: [audit] enabled must be true or false", source)
+			}
+			cfg.Audit.Enabled = enabled
+		case "path":
+			path, ok := value.(string)
+			if !ok || path == "" {
+				return fmt.Errorf("%s: [audit] path must be a file path", source)
+			}
+			cfg.Audit.Path = path
+		default:
+			return fmt.Errorf("%s: [audit] unknown key %q", source, key)
+		}
+		cfg.Audit.Source = source
+	}
+
+	for key, value := range tables["ask"] {
+		if key == "max_tokens" {
+			n, ok := value.(int64)
+			if !ok || n <= 0 {
+				return fmt.Errorf("%s: [ask] max_tokens must be a positive integer", source)
+			}
+			cfg.Ask.MaxTokens = int(n)
+			continue
+		}
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s: [ask] %s must be a string", source, key)
+		}
+		switch key {
+		case "provider":
+			cfg.Ask.Provider = s
+		case "url":
+			cfg.Ask.URL = s
+		case "model":
+			cfg.Ask.Model = s
+		case "api_key_env":
+			cfg.Ask.APIKeyEnv = s
+		default:
+			return fmt.Errorf("%s: [ask] unknown key %q", source, key)
+		}
+	}
+
+	for table, values := range tables {
+		key, ok := strings.CutPrefix(table, "handlers.")
+		if !ok {
+			continue
+		}
+		key = strings.Trim(key, `"`)
+		h := &Handler{Key: key, Source: source}
+		for name, value := range values {
+			command, ok := value.(string)
+			if name != "handler" {
+				return fmt.Errorf("%s: [%s] unknown key %q", source, table, name)
+			}
+			if !ok || strings.TrimSpace(command) == "" {
+				return fmt.Errorf("%s: [%s] handler must be a command string", source, table)
+			}
+			h.Command = command
+		}
+		if h.Command == "" {
+			return fmt.Errorf("%s: [%s] is missing handler", source, table)
+		}
+		if cfg.Handlers == nil {
+			cfg.Handlers = map[string]*Handler{}
+		}
+		cfg.Handlers[key] = h
+	}
+
+	for table, values := range tables {
+		name, ok := strings.CutPrefix(table, "profiles.")
+		if !ok {
+			continue
+		}
+		p := &Profile{Name: name, Source: source}
+		for key, value := range values {
+			switch key {
+			case "description":
+				s, ok := value.(string)
+				if !ok {
+					return fmt.Errorf("%s: [%s] description must be a string", source, table)
+				}
+				p.Description = s
+			case "args":
+				args, ok := value.([]string)
+				if !ok {
+					return fmt.Errorf("%s: [%s] args must be an array of strings", source, table)
+				}
+				p.Args = args
+			default:
+				return fmt.Errorf("%s: [%s] unknown key %q", source, table, key)
+			}
+		}
+		cfg.Profiles[name] = p
+	}
+
+	cfg.Files = append(cfg.Files, source)
+	return nil
+}
+
+// ProfileNames returns the profile names in sorted order.
+func (cfg *Config) ProfileNames() []string {
+	var names []string
+	for name := range cfg.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Parse reads the TOML subset used by clipcat config files: [table] headers,
+// and key = value pairs whose values are strings, booleans, integers or
+// arrays of strings. Top-level keys are stored under the "" table.
+func Parse(data []byte) (map[string]map[string]any, error) {
+	tables := map[string]map[string]any{"": {}}
+	current := ""
+
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		lineNo := i + 1
+		line := strings.TrimSpace(stripComment(lines[i]))
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") || strings.HasPrefix(line, "[[") {
+				return nil, fmt.Errorf("line %d: invalid table header %q", lineNo, line)
+			}
+			current = strings.TrimSpace(line[1 : len(line)-1])
+			if current == "" {
+				return nil, fmt.Errorf("line %d: empty table name", lineNo)
+			}
+			if tables[current] == nil {
+				tables[current] = map[string]any{}
+			}
+			continue
+		}
+
+		key, raw, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key = value", lineNo)
+		}
+		key = strings.Trim(strings.TrimSpace(key), `"`)
+		raw = strings.TrimSpace(raw)
+
+		// Arrays may span several lines
+		for strings.HasPrefix(raw, "[") && !closedArray(raw) && i+1 < len(lines) {
+			i++
+			raw += " " + strings.TrimSpace(stripComment(lines[i]))
+		}
+
+		value, err := parseValue(raw)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s: %w", lineNo, key, err)
+		}
+		tables[current][key] = value
+	}
+
+	return tables, nil
+}
+
+func parseValue(raw string) (any, error) {
+	switch {
+	case raw == "true":
+		return true, nil
+	case raw == "false":
+		return false, nil
+	case strings.HasPrefix(raw, `"`), strings.HasPrefix(raw, "'"):
+		s, rest, err := parseString(raw)
+		if err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(rest) != "" {
+			return nil, fmt.Errorf("unexpected %q after string", rest)
+		}
+		return s, nil
+	case strings.HasPrefix(raw, "["):
+		if !closedArray(raw) {
+			return nil, fmt.Errorf("unterminated array")
+		}
+		items := []string{}
+		rest := strings.TrimSpace(raw[1:])
+		for {
+			rest = strings.TrimLeft(rest, " \t,")
+			if strings.HasPrefix(rest, "]") {
+				break
+			}
+			s, tail, err := parseString(rest)
+			if err != nil {
+				return nil, fmt.Errorf("arrays may only hold strings")
+			}
+			items = append(items, s)
+			rest = strings.TrimSpace(tail)
+		}
+		return items, nil
+	}
+
+	n, err := strconv.ParseInt(strings.ReplaceAll(raw, "_", ""), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported value %q", raw)
+	}
+	return n, nil
+}
+
+// parseString reads a leading basic ("...") or literal ('...') string and
+// returns the remainder of the input.
+func parseString(s string) (string, string, error) {
+	if strings.HasPrefix(s, "'") {
+		end := strings.Index(s[1:], "'")
+		if end < 0 {
+			return "", "", fmt.Errorf("unterminated string")
+		}
+		return s[1 : end+1], s[end+2:], nil
+	}
+	if !strings.HasPrefix(s, `"`) {
+		return "", "", fmt.Errorf("expected a string")
+	}
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			str, err := strconv.Unquote(s[:i+1])
+			if err != nil {
+				return "", "", fmt.Errorf("invalid string %s", s[:i+1])
+			}
+			return str, s[i+1:], nil
+		}
+	}
+	return "", "", fmt.Errorf("unterminated string")
+}
+
+// stripComment removes a trailing # comment that is not inside a string.
+func stripComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
+
+func closedArray(raw string) bool {
+	s := strings.TrimSpace(raw)
+	return strings.HasSuffix(s, "]")
+}
+
+// SetsFile holds the named file sets of `clipcat set`. It lives next to
+// ProjectFile so it can be committed with the project.
+const SetsFile = ".clipcat-sets.toml"
+
+// Set is a named list of arguments saved by `clipcat set save`. Paths in
+// Args are relative to the directory of the sets file.
+type Set struct {
+	Name string
+	Args []string
+}
+
+// SetsPath returns the sets file for dir: the nearest SetsFile at or above
+// dir, else one next to the nearest ProjectFile, else one in dir.
+func SetsPath(dir string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return SetsFile
+	}
+	for d := dir; ; d = filepath.Dir(d) {
+		if info, err := os.Stat(filepath.Join(d, SetsFile)); err == nil && !info.IsDir() {
+			return filepath.Join(d, SetsFile)
+		}
+		if filepath.Dir(d) == d {
+			break
+		}
+	}
+	if project := ProjectPath(dir); project != "" {
+		return filepath.Join(filepath.Dir(project), SetsFile)
+	}
+	return filepath.Join(dir, SetsFile)
+}
+
+// LoadSets reads the [sets.NAME] tables of a sets file. A missing file has
+// no sets.
+func LoadSets(path string) (map[string]*Set, error) {
+	sets := map[string]*Set{}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return sets, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	tables, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for table, values := range tables {
+		name, ok := strings.CutPrefix(table, "sets.")
+		if !ok {
+			continue
+		}
+		args, ok := values["args"].([]string)
+		if !ok {
+			return nil, fmt.Errorf("%s: [%s] args must be an array of strings", path, table)
+		}
+		sets[name] = &Set{Name: name, Args: args}
+	}
+	return sets, nil
+}
+
+// SaveSets writes sets to path, in name order, replacing its contents.
+func SaveSets(path string, sets map[string]*Set) error {
+	var names []string
+	for name := range sets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString("# Named file sets, see clipcat set\n")
+	for _, name := range names {
+		quoted := make([]string, len(sets[name].Args))
+		for i, arg := range sets[name].Args {
+			quoted[i] = strconv.Quote(arg)
+		}
+		fmt.Fprintf(&b, "\n[sets.%s]\nargs = [%s]\n", name, strings.Join(quoted, ", "))
+	}
+	return os.WriteFile(path, []byte(b.String()), 0644)
+}
\ No newline at end of file
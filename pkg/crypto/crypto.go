@@ -0,0 +1,136 @@
+// Package crypto encrypts bundles for --encrypt and decrypts them for
+// `clipcat unpack --decrypt`. The work is done by the age command
+// (https://github.com/FiloSottile/age), so bundles interoperate with age
+// and rage, and clipcat carries no cryptography of its own.
+package crypto
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+var (
+	// header begins a binary age file, armor one encrypted with --armor
+	header      = []byte("age-encryption.org/v1\n")
+	armorHeader = []byte("-----BEGIN AGE ENCRYPTED FILE-----")
+)
+
+// Recipient returns the public key of an --encrypt value written as
+// age:RECIPIENT, where RECIPIENT is an age public key (age1...) or an SSH
+// public key.
+func Recipient(spec string) (string, error) {
+	scheme, key, ok := strings.Cut(spec, ":")
+	if !ok || scheme != "age" {
+		return "", fmt.Errorf("expected age:RECIPIENT, got %q", spec)
+	}
+	if key = strings.TrimSpace(key); key == "" {
+		return "", fmt.Errorf("missing recipient in %q", spec)
+	}
+	return key, nil
+}
+
+// IsEncrypted reports whether data is an age file, binary or armored.
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, header) || bytes.HasPrefix(bytes.TrimSpace(data), armorHeader)
+}
+
+// Writer encrypts what is written to it into the writer it was opened on.
+type Writer struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stderr bytes.Buffer
+}
+
+// Encrypter starts age encrypting to recipient into w, as PEM text with
+// armor for destinations that hold text only.
+func Encrypter(ctx context.Context, recipient string, armor bool, w io.Writer) (*Writer, error) {
+	args := []string{"--encrypt", "--recipient", recipient}
+	if armor {
+		args = append(args, "--armor")
+	}
+	cmd, err := command(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+	e := &Writer{cmd: cmd}
+	cmd.Stdout, cmd.Stderr = w, &e.stderr
+	if e.stdin, err = cmd.StdinPipe(); err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("age: %w", err)
+	}
+	return e, nil
+}
+
+func (e *Writer) Write(p []byte) (int, error) {
+	return e.stdin.Write(p)
+}
+
+// Close ends the input and waits for age to finish the file.
+func (e *Writer) Close() error {
+	e.stdin.Close()
+	return failed(e.cmd.Wait(), &e.stderr)
+}
+
+// Encrypt returns data encrypted to recipient.
+func Encrypt(ctx context.Context, recipient string, armor bool, data []byte) ([]byte, error) {
+	var out bytes.Buffer
+	w, err := Encrypter(ctx, recipient, armor, &out)
+	if err != nil {
+		return nil, err
+	}
+	// A write fails only once age has exited, which Close reports
+	w.Write(data)
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// Decrypt returns the plaintext of an age file with the key in the
+// identity file.
+func Decrypt(ctx context.Context, identity string, data []byte) ([]byte, error) {
+	cmd, err := command(ctx, "--decrypt", "--identity", identity)
+	if err != nil {
+		return nil, err
+	}
+	var out, stderr bytes.Buffer
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = bytes.NewReader(data), &out, &stderr
+	if err := failed(cmd.Run(), &stderr); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// Available reports an error when the age command cannot be found, so
+// callers can fail before they touch their destination.
+func Available() error {
+	if _, err := exec.LookPath("age"); err != nil {
+		return fmt.Errorf("age not found; install it from https://github.com/FiloSottile/age")
+	}
+	return nil
+}
+
+func command(ctx context.Context, args ...string) (*exec.Cmd, error) {
+	if err := Available(); err != nil {
+		return nil, err
+	}
+	return exec.CommandContext(ctx, "age", args...), nil
+}
+
+// failed passes on what age printed when it exits with an error, since
+// "exit status 1" says nothing.
+func failed(err error, stderr *bytes.Buffer) error {
+	if err == nil {
+		return nil
+	}
+	if msg := strings.TrimSpace(stderr.String()); msg != "" {
+		return fmt.Errorf("age: %s", msg)
+	}
+	return fmt.Errorf("age: %w", err)
+}
\ No newline at end of file
@@ -6,22 +6,52 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 
-	gitignore "github.com/sabhiram/go-gitignore"
 	"github.com/bmatcuk/doublestar/v4"
+	gitignore "github.com/sabhiram/go-gitignore"
 )
 
 type ExcludeMatcher struct {
 	gitignoreMatcher *gitignore.GitIgnore
-	globPatterns     []string
+	gitignoreLines   []Rule // one per compiled line, for provenance
+	globPatterns     []pattern
+	defaults         []pattern
 	ignoreCase       bool
+	base             string // working directory at BuildMatcher time, see WithBase
+	dirs             *dirCache
+}
+
+// DefaultExcludes are skipped when walking directories, so that explicitly
+// named files (e.g. `clipcat go.sum`) are still copied.
+var DefaultExcludes = []string{
+	".git/", "node_modules/", "vendor/", "dist/", "__pycache__/", ".venv/", "target/",
+	"package-lock.json", "yarn.lock", "pnpm-lock.yaml", "go.sum", "Cargo.lock",
+	"poetry.lock", "Gemfile.lock", "composer.lock",
+}
+
+// Rule identifies the exclude pattern that decided a path.
+type Rule struct {
+	Source  string // exclude file, "-e" for command-line patterns, or "default"
+	Line    int    // 1-based line within Source; 0 for -e and default patterns
+	Pattern string
+	Negated bool // a "!" gitignore line that re-included the path
+}
+
+func (r *Rule) String() string {
+	if r.Line == 0 {
+		return fmt.Sprintf("%s %s", r.Source, r.Pattern)
+	}
+	return fmt.Sprintf("%s:%d: %s", r.Source, r.Line, r.Pattern)
 }
 
 func BuildMatcher(files []string, globPatterns []string, ignoreCase bool) (*ExcludeMatcher, error) {
 	matcher := &ExcludeMatcher{
-		globPatterns: globPatterns,
+		globPatterns: compilePatterns("-e", globPatterns, ignoreCase),
 		ignoreCase:   ignoreCase,
+		dirs:         newDirCache(),
 	}
+	matcher.base, _ = os.Getwd()
 
 	// Collect all patterns from files
 	var allPatterns []string
@@ -32,16 +62,46 @@ func BuildMatcher(files []string, globPatterns []string, ignoreCase bool) (*Excl
 			return nil, fmt.Errorf("cannot read exclude file %s: %w", file, err)
 		}
 		allPatterns = append(allPatterns, patterns...)
+		for i, pattern := range patterns {
+			matcher.gitignoreLines = append(matcher.gitignoreLines, Rule{Source: file, Line: i + 1, Pattern: pattern})
+		}
 	}
 
-	// Build gitignore matcher if we have patterns
+	// Build gitignore matcher if we have patterns; with ignoreCase the lines
+	// are lowercased here and paths in Explain
 	if len(allPatterns) > 0 {
+		if ignoreCase {
+			for i, pattern := range allPatterns {
+				allPatterns[i] = strings.ToLower(pattern)
+			}
+		}
 		matcher.gitignoreMatcher = gitignore.CompileIgnoreLines(allPatterns...)
 	}
 
 	return matcher, nil
 }
 
+// WithDefaults returns a matcher that additionally excludes the default
+// patterns, matched like -e patterns. The collector uses it while walking.
+func (m *ExcludeMatcher) WithDefaults(patterns []string) *ExcludeMatcher {
+	if len(patterns) == 0 {
+		return m
+	}
+	walk := *m
+	walk.defaults = compilePatterns("default", patterns, m.ignoreCase)
+	walk.dirs = newDirCache()
+	return &walk
+}
+
+// WithBase returns a matcher that matches paths relative to dir instead of
+// the working directory it was built in.
+func (m *ExcludeMatcher) WithBase(dir string) *ExcludeMatcher {
+	moved := *m
+	moved.base = dir
+	moved.dirs = newDirCache()
+	return &moved
+}
+
 func readPatternsFromFile(path string) ([]string, error) {
 	f, err := os.Open(path)
 	if err != nil {
@@ -60,21 +120,42 @@ func readPatternsFromFile(path string) ([]string, error) {
 	return patterns, scanner.Err()
 }
 
-func hasGlobChars(s string) bool {
-	return strings.ContainsAny(s, "*?[")
-}
-
-func isDoublestarPattern(pattern string) bool {
-	return strings.Contains(pattern, "**")
+func (m *ExcludeMatcher) ShouldExclude(path string, isDir bool) bool {
+	excluded, _ := m.Explain(path, isDir)
+	return excluded
 }
 
-func hasBraceExpansion(pattern string) bool {
-	return strings.Contains(pattern, "{") && strings.Contains(pattern, "}")
+// Explain reports whether path is excluded and which rule decided it. The
+// rule is nil when no pattern matched; a kept path may still carry the
+// negated gitignore line that re-included it. Decisions for directories are
+// cached, since every file below a directory shares its ancestors.
+func (m *ExcludeMatcher) Explain(path string, isDir bool) (bool, *Rule) {
+	if !isDir || m.dirs == nil {
+		return m.explain(path, isDir)
+	}
+	m.dirs.mu.Lock()
+	d, ok := m.dirs.m[path]
+	m.dirs.mu.Unlock()
+	if !ok {
+		d.excluded, d.rule = m.explain(path, true)
+		m.dirs.mu.Lock()
+		m.dirs.m[path] = d
+		m.dirs.mu.Unlock()
+	}
+	return d.excluded, d.rule
 }
 
-func (m *ExcludeMatcher) ShouldExclude(path string, isDir bool) bool {
-	// Convert to relative path for gitignore matching
+func (m *ExcludeMatcher) explain(path string, isDir bool) (bool, *Rule) {
+	// Match relative to the directory the matcher was built in, so path
+	// patterns like "src/*.go" apply to walked (absolute) paths too; paths
+	// outside it keep their absolute form
 	relPath, err := filepath.Rel(".", path)
+	if filepath.IsAbs(path) {
+		relPath, err = filepath.Rel(m.base, path)
+		if err == nil && !filepath.IsLocal(relPath) {
+			relPath = path
+		}
+	}
 	if err != nil {
 		relPath = path
 	}
@@ -84,95 +165,229 @@ func (m *ExcludeMatcher) ShouldExclude(path string, isDir bool) bool {
 	relNorm := strings.ReplaceAll(relPath, "/", osSep)
 	base := filepath.Base(relNorm)
 
-	lower := func(s string) string {
-		if m.ignoreCase {
-			return strings.ToLower(s)
-		}
-		return s
+	relCmp, baseCmp := relNorm, base
+	if m.ignoreCase {
+		relCmp, baseCmp = strings.ToLower(relNorm), strings.ToLower(base)
 	}
-	relCmp := lower(relNorm)
-	baseCmp := lower(base)
 
 	// 1) Check gitignore matcher (if any)
-	if m.gitignoreMatcher != nil && m.gitignoreMatcher.MatchesPath(relNorm) {
-		return true
+	var negated *Rule
+	if m.gitignoreMatcher != nil {
+		matched, how := m.gitignoreMatcher.MatchesPathHow(relCmp)
+		if matched {
+			return true, m.gitignoreRule(how.LineNo)
+		}
+		if how != nil {
+			negated = m.negatedRule(relCmp, how.LineNo)
+		}
 	}
 
-	// 2) Check our -e/--exclude glob patterns
-	for _, raw := range m.globPatterns {
-		pat := strings.TrimSpace(raw)
+	// 2) Check our -e/--exclude glob patterns, then the default excludes
+	for _, patterns := range [...][]pattern{m.globPatterns, m.defaults} {
+		for i := range patterns {
+			if patterns[i].matches(relCmp, baseCmp, isDir) {
+				rule := patterns[i].rule
+				return true, &rule
+			}
+		}
+	}
+
+	return false, negated
+}
+
+// patternKind says how a compiled -e or default pattern is matched.
+type patternKind int
+
+const (
+	dirName     patternKind = iota // "__pycache__/": a directory name anywhere, and its contents
+	dirGlob                        // "*cache*/", "{build,dist}/": a glob over directory names
+	dirPrefix                      // "src/gen*/": everything under a matching directory path
+	pathPattern                    // "src/*.go": files by relative path
+	basePattern                    // "*.log": files by basename
+)
+
+// pattern is an -e or default pattern, normalized once by compilePatterns
+// rather than for every path visited.
+type pattern struct {
+	kind patternKind
+	expr string // separator-normalized, lowercased with ignoreCase
+	rule Rule
+}
+
+// compilePatterns classifies raw patterns from source ("-e" or "default").
+// Directory patterns MUST end with a separator to affect directories;
+// others match files only, by relative path if they contain a separator
+// and by basename otherwise.
+func compilePatterns(source string, raw []string, ignoreCase bool) []pattern {
+	osSep := string(filepath.Separator)
+	var compiled []pattern
+	for _, r := range raw {
+		pat := strings.TrimSpace(r)
 		if pat == "" {
 			continue
 		}
-
 		// Normalize separators in the pattern so user-written "/" also works on Windows
 		pat = strings.ReplaceAll(pat, "/", osSep)
-		patCmp := lower(pat)
-
-		// Directory patterns MUST end with a separator to affect directories.
-		if strings.HasSuffix(patCmp, osSep) {
-			dirPat := strings.TrimSuffix(patCmp, osSep)
-
-			// Simple dir name (no globs/seps) like "__pycache__/"
-			if !hasGlobChars(dirPat) && !strings.Contains(dirPat, osSep) {
-				// Directory itself
-				if isDir && (relCmp == dirPat || relCmp == dirPat+osSep) {
-					return true
-				}
-				// Any content at root under that dir
-				if strings.HasPrefix(relCmp, dirPat+osSep) {
-					return true
-				}
-				// Nested segment anywhere
-				if strings.Contains(relCmp, osSep+dirPat+osSep) {
-					return true
-				}
-				continue
-			}
+		if ignoreCase {
+			pat = strings.ToLower(pat)
+		}
+
+		p := pattern{expr: pat, rule: Rule{Source: source, Pattern: r}}
+		switch dirPat, isDirPat := strings.CutSuffix(pat, osSep); {
+		case isDirPat && !IsGlobPattern(dirPat) && !strings.Contains(dirPat, osSep):
+			p.kind, p.expr = dirName, dirPat
+		case isDirPat && !strings.Contains(dirPat, osSep):
+			p.kind, p.expr = dirGlob, dirPat
+		case isDirPat:
+			// Complex dir pattern (globs or seps): the directory and anything under it
+			p.kind, p.expr = dirPrefix, dirPat
+		case strings.Contains(pat, osSep):
+			p.kind = pathPattern
+		default:
+			p.kind = basePattern
+		}
+		compiled = append(compiled, p)
+	}
+	return compiled
+}
 
-			// Complex dir pattern (globs or seps): treat as prefix for anything under it
-			dirAny := dirPat + osSep + "*"
-			if matchPath(dirAny, relCmp) {
+// matches reports whether p excludes a path, given its relative path and
+// basename normalized like p.expr.
+func (p *pattern) matches(rel, base string, isDir bool) bool {
+	osSep := string(filepath.Separator)
+	switch p.kind {
+	case dirName:
+		// The directory itself at any depth, so walks prune it before
+		// entering, or content under the name as a segment anywhere
+		return (isDir && (rel == p.expr || strings.HasSuffix(rel, osSep+p.expr))) ||
+			strings.HasPrefix(rel, p.expr+osSep) ||
+			strings.Contains(rel, osSep+p.expr+osSep)
+	case dirGlob:
+		dirs := filepath.Dir(rel)
+		if isDir {
+			dirs = rel
+		}
+		for _, seg := range strings.Split(dirs, osSep) {
+			if seg != "" && MatchPath(p.expr, seg) {
 				return true
 			}
-			continue
 		}
+		return false
+	case dirPrefix:
+		return (isDir && MatchPath(p.expr, rel)) || MatchPath(p.expr+osSep+"*", rel)
+	case pathPattern:
+		// Path patterns are intended for files; directories keep being walked
+		return !isDir && MatchPath(p.expr, rel)
+	default:
+		// Basename-only pattern: applies to FILES only (require '/' for directories)
+		return !isDir && MatchPath(p.expr, base)
+	}
+}
 
-		// Non-slash patterns WITHOUT trailing slash:
-		// - If they contain a separator → path-aware file match on full rel path
-		// - If they do NOT contain a separator → match FILE BASENAME ONLY
-		if strings.Contains(patCmp, osSep) {
-			// Path-aware pattern; only meaningful for files (but matching against full path is fine)
-			if matchPath(patCmp, relCmp) {
-				// If the path matches and we're visiting a directory, don't exclude the directory
-				// (these patterns are intended for files). For directories, keep walking.
-				if !isDir {
-					return true
-				}
-			}
+// dirCache holds Explain results for directories.
+type dirCache struct {
+	mu sync.Mutex
+	m  map[string]decision
+}
+
+type decision struct {
+	excluded bool
+	rule     *Rule
+}
+
+func newDirCache() *dirCache {
+	return &dirCache{m: make(map[string]decision)}
+}
+
+func (m *ExcludeMatcher) gitignoreRule(lineNo int) *Rule {
+	rule := m.gitignoreLines[lineNo-1]
+	return &rule
+}
+
+// negatedRule finds the "!" line after lineNo that re-included relPath.
+func (m *ExcludeMatcher) negatedRule(relPath string, lineNo int) *Rule {
+	for i := len(m.gitignoreLines) - 1; i >= lineNo; i-- {
+		line := strings.TrimSpace(m.gitignoreLines[i].Pattern)
+		if m.ignoreCase {
+			line = strings.ToLower(line)
+		}
+		if !strings.HasPrefix(line, "!") {
 			continue
 		}
-
-		// Basename-only pattern: applies to FILES only (require '/' for directories)
-		if !isDir && matchPath(patCmp, baseCmp) {
-			return true
+		if gitignore.CompileIgnoreLines(line[1:]).MatchesPath(relPath) {
+			rule := m.gitignoreLines[i]
+			rule.Negated = true
+			return &rule
 		}
 	}
+	return nil
+}
 
-	return false
+// Policy holds an organization's never-copy patterns. Unlike exclude
+// patterns, no flag turns them off: they apply to walked and named files
+// alike, anywhere in the absolute path, ignoring case.
+type Policy struct {
+	source string
+	deny   []string // slash-separated and lowercased
+	raw    []string
 }
 
-func matchPath(pattern, target string) bool {
-	if isDoublestarPattern(pattern) || hasBraceExpansion(pattern) {
-		// Use doublestar for complex patterns with ** or brace expansion
-		matched, err := doublestar.Match(pattern, target)
-		if err != nil {
-			return false
+// PolicyError reports a file that was named explicitly but is forbidden
+// by the policy.
+type PolicyError struct {
+	Path string
+	Rule *Rule
+}
+
+func (e *PolicyError) Error() string {
+	return fmt.Sprintf("refusing to copy %s: forbidden by policy %s", e.Path, e.Rule)
+}
+
+// NewPolicy compiles the deny patterns read from source. A pattern without
+// a slash matches a file or directory name ("*.pem", "id_rsa"); one ending
+// in a slash matches a directory and everything below it ("secrets/");
+// others match path suffixes ("config/*.key" also forbids a/b/config/x.key).
+func NewPolicy(source string, patterns []string) *Policy {
+	p := &Policy{source: source}
+	for _, raw := range patterns {
+		pat := strings.ToLower(strings.Trim(strings.ReplaceAll(strings.TrimSpace(raw), `\`, "/"), "/"))
+		if pat == "" || pat == "**" {
+			continue
+		}
+		switch {
+		case strings.HasSuffix(strings.TrimSpace(raw), "/"):
+			pat = "**/" + strings.TrimPrefix(pat, "**/") + "/**"
+		case strings.Contains(pat, "/"):
+			pat = "**/" + strings.TrimPrefix(pat, "**/")
+		default:
+			// A name matches the file itself or any directory above it
+			pat = "**/" + pat + "{,/**}"
+		}
+		p.deny = append(p.deny, pat)
+		p.raw = append(p.raw, raw)
+	}
+	return p
+}
+
+// Source returns the file the policy was read from.
+func (p *Policy) Source() string { return p.source }
+
+// Forbids reports whether the policy forbids copying path, and the pattern
+// that does. A nil Policy forbids nothing. path is matched as an absolute
+// path, so the answer does not depend on the working directory.
+func (p *Policy) Forbids(path string) (*Rule, bool) {
+	if p == nil {
+		return nil, false
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		abs = path
+	}
+	target := strings.ToLower(strings.TrimPrefix(filepath.ToSlash(strings.TrimPrefix(abs, filepath.VolumeName(abs))), "/"))
+	for i, pat := range p.deny {
+		if matched, _ := doublestar.Match(pat, target); matched {
+			return &Rule{Source: p.source, Pattern: p.raw[i]}, true
 		}
-		return matched
-	} else {
-		// Use filepath.Match for simple patterns
-		ok, _ := filepath.Match(pattern, target)
-		return ok
 	}
+	return nil, false
 }
\ No newline at end of file
@@ -0,0 +1,43 @@
+package exclude
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// Pattern helpers shared by the matcher, the collector's glob inputs, and the
+// tree renderer, so every package agrees on what counts as a pattern and how
+// it matches.
+
+// IsGlobPattern reports whether s contains glob metacharacters or a
+// {a,b} brace expansion.
+func IsGlobPattern(s string) bool {
+	return strings.ContainsAny(s, "*?[") || hasBraceExpansion(s)
+}
+
+// IsDoublestarPattern reports whether the pattern spans directories with "**".
+func IsDoublestarPattern(pattern string) bool {
+	return strings.Contains(pattern, "**")
+}
+
+func hasBraceExpansion(pattern string) bool {
+	return strings.Contains(pattern, "{") && strings.Contains(pattern, "}")
+}
+
+// MatchPath matches target against pattern, using doublestar for patterns
+// with ** or brace expansion and filepath.Match for everything else.
+func MatchPath(pattern, target string) bool {
+	if IsDoublestarPattern(pattern) || hasBraceExpansion(pattern) {
+		// Use doublestar for complex patterns with ** or brace expansion
+		matched, err := doublestar.Match(pattern, target)
+		if err != nil {
+			return false
+		}
+		return matched
+	}
+	// Use filepath.Match for simple patterns
+	ok, _ := filepath.Match(pattern, target)
+	return ok
+}
@@ -0,0 +1,112 @@
+package lang
+
+import (
+	"mime"
+	"path/filepath"
+	"strings"
+)
+
+// Categories are the file categories of --type, in the order they are
+// listed in help and errors.
+var Categories = []string{"text", "code", "config", "docs", "data"}
+
+var (
+	// languageCategories classifies the languages Detect returns; a language
+	// missing here, such as one added with Register, counts as code.
+	languageCategories = map[string]string{
+		"dockerfile": "config",
+		"go-mod":     "config",
+		"gitignore":  "config",
+		"json":       "config",
+		"jsonc":      "config",
+		"yaml":       "config",
+		"toml":       "config",
+		"ini":        "config",
+		"hcl":        "config",
+		"nix":        "config",
+		"xml":        "data",
+		"markdown":   "docs",
+		"rst":        "docs",
+		"latex":      "docs",
+		"diff":       "text",
+	}
+
+	// extensionCategories classifies files no language covers.
+	extensionCategories = map[string]string{
+		".csv":        "data",
+		".tsv":        "data",
+		".jsonl":      "data",
+		".ndjson":     "data",
+		".geojson":    "data",
+		".parquet":    "data",
+		".sqlite":     "data",
+		".env":        "config",
+		".conf":       "config",
+		".properties": "config",
+		".lock":       "config",
+		".adoc":       "docs",
+		".asciidoc":   "docs",
+		".org":        "docs",
+		".txt":        "text",
+		".log":        "text",
+	}
+
+	// nameCategories classifies files by name, with or without the
+	// extension (README, README.md), ignoring case.
+	nameCategories = map[string]string{
+		"readme":         "docs",
+		"license":        "docs",
+		"licence":        "docs",
+		"copying":        "docs",
+		"notice":         "docs",
+		"authors":        "docs",
+		"changelog":      "docs",
+		"contributing":   "docs",
+		".editorconfig":  "config",
+		".env":           "config",
+		".npmrc":         "config",
+		".dockerignore":  "config",
+		".gitattributes": "config",
+	}
+)
+
+// Category returns the --type category of path, looking at its name
+// (README, LICENSE), then its language, then its extension, and settling on
+// text for a MIME type of text/* or no known MIME type at all. It returns
+// "" for files of another MIME type, such as images.
+func Category(path string) string {
+	base := filepath.Base(path)
+	stem := strings.ToLower(strings.TrimSuffix(base, filepath.Ext(base)))
+	if category, ok := nameCategories[strings.ToLower(base)]; ok {
+		return category
+	}
+	if category, ok := nameCategories[stem]; ok && stem != "" {
+		return category
+	}
+	if language := DetectFile(path); language != "" {
+		if category, ok := languageCategories[language]; ok {
+			return category
+		}
+		return "code"
+	}
+	ext := strings.ToLower(filepath.Ext(base))
+	if category, ok := extensionCategories[ext]; ok {
+		return category
+	}
+	typ, _, _ := strings.Cut(mime.TypeByExtension(ext), ";")
+	if typ == "" || strings.HasPrefix(typ, "text/") {
+		return "text"
+	}
+	return ""
+}
+
+// MatchCategory reports whether path is in one of the categories.
+func MatchCategory(path string, categories []string) bool {
+	category := Category(path)
+	for _, name := range categories {
+		if category != "" && strings.EqualFold(name, category) {
+			return true
+		}
+	}
+	return false
+}
\ No newline at end of file
@@ -0,0 +1,227 @@
+// Package lang maps file names, extensions and shebang lines to language
+// identifiers as used in markdown code fences, e.g. "go" or "python".
+package lang
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+var (
+	mu sync.RWMutex
+
+	extensions = map[string]string{
+		".go":      "go",
+		".py":      "python",
+		".pyi":     "python",
+		".js":      "javascript",
+		".mjs":     "javascript",
+		".cjs":     "javascript",
+		".jsx":     "jsx",
+		".ts":      "typescript",
+		".mts":     "typescript",
+		".tsx":     "tsx",
+		".rs":      "rust",
+		".c":       "c",
+		".h":       "c",
+		".cc":      "cpp",
+		".cpp":     "cpp",
+		".cxx":     "cpp",
+		".hpp":     "cpp",
+		".hh":      "cpp",
+		".java":    "java",
+		".kt":      "kotlin",
+		".kts":     "kotlin",
+		".scala":   "scala",
+		".cs":      "csharp",
+		".fs":      "fsharp",
+		".swift":   "swift",
+		".m":       "objectivec",
+		".rb":      "ruby",
+		".php":     "php",
+		".pl":      "perl",
+		".lua":     "lua",
+		".r":       "r",
+		".dart":    "dart",
+		".ex":      "elixir",
+		".exs":     "elixir",
+		".erl":     "erlang",
+		".hs":      "haskell",
+		".ml":      "ocaml",
+		".clj":     "clojure",
+		".zig":     "zig",
+		".nim":     "nim",
+		".sh":      "bash",
+		".bash":    "bash",
+		".zsh":     "zsh",
+		".fish":    "fish",
+		".ps1":     "powershell",
+		".bat":     "batch",
+		".cmd":     "batch",
+		".sql":     "sql",
+		".html":    "html",
+		".htm":     "html",
+		".css":     "css",
+		".scss":    "scss",
+		".sass":    "sass",
+		".less":    "less",
+		".vue":     "vue",
+		".svelte":  "svelte",
+		".json":    "json",
+		".jsonc":   "jsonc",
+		".yaml":    "yaml",
+		".yml":     "yaml",
+		".toml":    "toml",
+		".ini":     "ini",
+		".cfg":     "ini",
+		".xml":     "xml",
+		".svg":     "xml",
+		".md":      "markdown",
+		".rst":     "rst",
+		".tex":     "latex",
+		".proto":   "protobuf",
+		".graphql": "graphql",
+		".gql":     "graphql",
+		".tf":      "hcl",
+		".hcl":     "hcl",
+		".nix":     "nix",
+		".cmake":   "cmake",
+		".diff":    "diff",
+		".patch":   "diff",
+	}
+
+	filenames = map[string]string{
+		"Dockerfile":     "dockerfile",
+		"Containerfile":  "dockerfile",
+		"Makefile":       "makefile",
+		"GNUmakefile":    "makefile",
+		"makefile":       "makefile",
+		"CMakeLists.txt": "cmake",
+		"go.mod":         "go-mod",
+		"Gemfile":        "ruby",
+		"Rakefile":       "ruby",
+		"Vagrantfile":    "ruby",
+		"Jenkinsfile":    "groovy",
+		".bashrc":        "bash",
+		".zshrc":         "zsh",
+		".gitignore":     "gitignore",
+	}
+
+	interpreters = map[string]string{
+		"sh":      "bash",
+		"bash":    "bash",
+		"zsh":     "zsh",
+		"fish":    "fish",
+		"python":  "python",
+		"python3": "python",
+		"node":    "javascript",
+		"deno":    "typescript",
+		"ruby":    "ruby",
+		"perl":    "perl",
+		"php":     "php",
+		"lua":     "lua",
+	}
+)
+
+// Register maps a file extension (starting with ".") or an exact file name
+// to language, overriding the built-in mapping. It backs the [languages]
+// config table.
+func Register(key, language string) {
+	mu.Lock()
+	defer mu.Unlock()
+	if strings.HasPrefix(key, ".") && filepath.Ext(key) == key {
+		extensions[strings.ToLower(key)] = language
+	} else {
+		filenames[key] = language
+	}
+}
+
+// Detect returns the language for path, using its file name, then its
+// extension, then a shebang on the first line of content. It returns ""
+// when nothing matches.
+func Detect(path string, content []byte) string {
+	if language := byName(path); language != "" {
+		return language
+	}
+	line, _, _ := strings.Cut(string(content[:min(len(content), 256)]), "\n")
+	return FromShebang(line)
+}
+
+// DetectFile is Detect for a file on disk; it reads only the first line,
+// and only when the name alone is not enough.
+func DetectFile(path string) string {
+	if language := byName(path); language != "" {
+		return language
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	line, _ := bufio.NewReader(f).ReadString('\n')
+	return FromShebang(line)
+}
+
+func byName(path string) string {
+	mu.RLock()
+	defer mu.RUnlock()
+	base := filepath.Base(path)
+	if language, ok := filenames[base]; ok {
+		return language
+	}
+	return extensions[strings.ToLower(filepath.Ext(base))]
+}
+
+// FromShebang returns the language of a "#!" line, e.g. python for
+// "#!/usr/bin/env python3".
+func FromShebang(line string) string {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(line), "#!")
+	if !ok {
+		return ""
+	}
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return ""
+	}
+	interp := filepath.Base(fields[0])
+	if interp == "env" {
+		// Skip env options such as -S
+		for _, field := range fields[1:] {
+			if !strings.HasPrefix(field, "-") {
+				interp = field
+				break
+			}
+		}
+	}
+	mu.RLock()
+	defer mu.RUnlock()
+	if language, ok := interpreters[interp]; ok {
+		return language
+	}
+	// python3.12, ruby2.7 and the like
+	return interpreters[strings.TrimRight(interp, "0123456789.")]
+}
+
+// Match reports whether path is in one of the languages or has one of the
+// extensions in names, e.g. "go", "python" or "ts". A leading dot on a name
+// is optional.
+func Match(path string, names []string) bool {
+	ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	language, detected := "", false
+	for _, name := range names {
+		name = strings.TrimPrefix(strings.ToLower(name), ".")
+		if ext != "" && name == ext {
+			return true
+		}
+		if !detected {
+			language, detected = DetectFile(path), true
+		}
+		if language != "" && name == language {
+			return true
+		}
+	}
+	return false
+}
\ No newline at end of file
@@ -0,0 +1,142 @@
+package output
+
+import (
+	"os"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Collations accepted by SortPathsCollated
+const (
+	CollateByte    = "byte"
+	CollateUnicode = "unicode"
+	CollateLocale  = "locale"
+)
+
+// Collations lists the accepted collations, the default first.
+var Collations = []string{CollateByte, CollateUnicode, CollateLocale}
+
+// latinFolds maps accented Latin letters to the letters they sort with.
+var latinFolds = map[rune]string{
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a", 'ā': "a", 'ă': "a", 'ą': "a",
+	'ç': "c", 'ć': "c", 'ĉ': "c", 'ċ': "c", 'č': "c", 'ď': "d", 'đ': "d", 'ð': "d",
+	'è': "e", 'é': "e", 'ê': "e", 'ë': "e", 'ē': "e", 'ĕ': "e", 'ė': "e", 'ę': "e", 'ě': "e",
+	'ĝ': "g", 'ğ': "g", 'ġ': "g", 'ģ': "g", 'ĥ': "h", 'ħ': "h",
+	'ì': "i", 'í': "i", 'î': "i", 'ï': "i", 'ĩ': "i", 'ī': "i", 'ĭ': "i", 'į': "i", 'ı': "i",
+	'ĵ': "j", 'ķ': "k", 'ĺ': "l", 'ļ': "l", 'ľ': "l", 'ŀ': "l", 'ł': "l",
+	'ñ': "n", 'ń': "n", 'ņ': "n", 'ň': "n",
+	'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "o", 'ø': "o", 'ō': "o", 'ŏ': "o", 'ő': "o",
+	'ŕ': "r", 'ŗ': "r", 'ř': "r", 'ś': "s", 'ŝ': "s", 'ş': "s", 'š': "s", 'ţ': "t", 'ť': "t", 'ŧ': "t",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "u", 'ũ': "u", 'ū': "u", 'ŭ': "u", 'ů': "u", 'ű': "u", 'ų': "u",
+	'ŵ': "w", 'ý': "y", 'ÿ': "y", 'ŷ': "y", 'ź': "z", 'ż': "z", 'ž': "z",
+	'ß': "ss", 'æ': "ae", 'œ': "oe", 'þ': "th",
+}
+
+// tailorings are the letters a language sorts as letters of their own:
+// each group's letters follow its first letter, in order.
+var tailorings = map[string][]string{
+	"da": {"zæøå"},
+	"nb": {"zæøå"},
+	"nn": {"zæøå"},
+	"no": {"zæøå"},
+	"sv": {"zåäö"},
+	"fi": {"zåäö"},
+	"es": {"nñ"},
+	"tr": {"cç", "gğ", "hı", "oö", "sş", "uü"},
+	"az": {"cç", "gğ", "hı", "oö", "sş", "uü"},
+	"pl": {"aą", "cć", "eę", "lł", "nń", "oó", "sś", "zźż"},
+	"cs": {"cč", "rř", "sš", "zž"},
+	"sk": {"aä", "cč", "oô", "rř", "sš", "zž"},
+}
+
+// collator orders names by letter, ignoring case and accents, before
+// breaking ties by accents, then case (lowercase first).
+type collator struct {
+	weights map[rune]int // tailored letters
+}
+
+// newCollator returns the comparison for names under collation, or
+// strings.Compare for byte order.
+func newCollator(collation string) func(a, b string) int {
+	c := &collator{}
+	switch collation {
+	case CollateUnicode:
+	case CollateLocale:
+		language := LocaleLanguage()
+		if language == "" {
+			return strings.Compare
+		}
+		c.weights = make(map[rune]int)
+		for _, group := range tailorings[language] {
+			letters := []rune(group)
+			for i, r := range letters[1:] {
+				c.weights[r] = weight(letters[0]) + i + 1
+			}
+		}
+	default:
+		return strings.Compare
+	}
+	return c.compare
+}
+
+// weight spaces code points out so tailored letters fit between them.
+func weight(r rune) int {
+	return int(r) * 16
+}
+
+func (c *collator) key(s string) []int {
+	key := make([]int, 0, len(s))
+	for _, r := range s {
+		r = unicode.ToLower(r)
+		if w, ok := c.weights[r]; ok {
+			key = append(key, w)
+		} else if fold, ok := latinFolds[r]; ok {
+			for _, f := range fold {
+				key = append(key, weight(f))
+			}
+		} else {
+			key = append(key, weight(r))
+		}
+	}
+	return key
+}
+
+func (c *collator) compare(a, b string) int {
+	if n := slices.Compare(c.key(a), c.key(b)); n != 0 {
+		return n
+	}
+	if n := strings.Compare(strings.ToLower(a), strings.ToLower(b)); n != 0 {
+		return n
+	}
+	return strings.Compare(swapCase(a), swapCase(b))
+}
+
+func swapCase(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsUpper(r) {
+			return unicode.ToLower(r)
+		}
+		return unicode.ToUpper(r)
+	}, s)
+}
+
+// LocaleLanguage returns the language of the collation locale, taken from
+// LC_ALL, LC_COLLATE or LANG like the C library does, e.g. "sv" for
+// sv_SE.UTF-8. It is "" for the C and POSIX locales and when none is set.
+func LocaleLanguage() string {
+	for _, name := range []string{"LC_ALL", "LC_COLLATE", "LANG"} {
+		locale := os.Getenv(name)
+		if locale == "" {
+			continue
+		}
+		language, _, _ := strings.Cut(locale, "_")
+		language, _, _ = strings.Cut(language, ".")
+		language, _, _ = strings.Cut(language, "@")
+		if language == "C" || language == "POSIX" {
+			return ""
+		}
+		return strings.ToLower(language)
+	}
+	return ""
+}
\ No newline at end of file
@@ -0,0 +1,386 @@
+package output
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Filter transforms a file's content between reading and formatting. path
+// is the file's absolute path or URL, so filters can decide by extension.
+// Files are read concurrently, so a Filter must be safe for concurrent use.
+type Filter func(path string, content []byte) []byte
+
+// ApplyFilters runs content through filters in order.
+func ApplyFilters(filters []Filter, path string, content []byte) []byte {
+	for _, filter := range filters {
+		content = filter(path, content)
+	}
+	return content
+}
+
+// SecretPatterns are redacted by --redact: private keys, common API token
+// formats and values assigned to password, secret, token or api_key names.
+var SecretPatterns = []string{
+	`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`,
+	`AKIA[0-9A-Z]{16}`,
+	`gh[pousr]_[A-Za-z0-9]{36,}`,
+	`xox[abprs]-[A-Za-z0-9-]{10,}`,
+	`sk-[A-Za-z0-9_-]{20,}`,
+	`(?i)((?:password|passwd|secret|token|api[_-]?key)["']?\s*[:=]\s*["']?)[^\s"']{8,}`,
+}
+
+// Redacted replaces each redacted value.
+const Redacted = "[REDACTED]"
+
+// Redact replaces matches of patterns with [REDACTED]. When a pattern has a
+// capture group, the first group is kept, so `(password=)\S+` hides only
+// the value.
+func Redact(patterns ...*regexp.Regexp) Filter {
+	return func(path string, content []byte) []byte {
+		for _, re := range patterns {
+			repl := []byte(Redacted)
+			if re.NumSubexp() > 0 {
+				repl = []byte("${1}" + Redacted)
+			}
+			content = re.ReplaceAll(content, repl)
+		}
+		return content
+	}
+}
+
+// CompilePatterns compiles regular expressions for Redact.
+func CompilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	var res []*regexp.Regexp
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
+
+// Truncate keeps the first maxLines lines and notes how many were dropped.
+func Truncate(maxLines int) Filter {
+	return func(path string, content []byte) []byte {
+		lines := bytes.SplitAfter(content, []byte("\n"))
+		if len(lines) > 0 && len(lines[len(lines)-1]) == 0 {
+			lines = lines[:len(lines)-1]
+		}
+		if len(lines) <= maxLines {
+			return content
+		}
+		out := bytes.Join(lines[:maxLines], nil)
+		if len(out) > 0 && out[len(out)-1] != '\n' {
+			out = append(out, '\n')
+		}
+		return fmt.Appendf(out, "[truncated: %d more lines]\n", len(lines)-maxLines)
+	}
+}
+
+// HeadTail keeps the first head and last tail lines, replacing the lines
+// between them with an OmittedMarker.
+func HeadTail(head, tail int) Filter {
+	return func(path string, content []byte) []byte {
+		out, _ := ReadHeadTail(bytes.NewReader(content), head, tail)
+		return out
+	}
+}
+
+// ReadHeadTail reads r line by line and returns its first head and last
+// tail lines, with an OmittedMarker between them when lines were dropped.
+// Only the kept lines are held in memory, so giant logs can be streamed.
+func ReadHeadTail(r io.Reader, head, tail int) ([]byte, error) {
+	br := bufio.NewReaderSize(r, 64<<10)
+	var out []byte
+	ring := make([][]byte, tail) // the last tail lines after the head
+	n := 0
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(line) > 0 {
+			if n < head {
+				out = append(out, line...)
+			} else if tail > 0 {
+				ring[(n-head)%tail] = line
+			}
+			n++
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	rest := n - head
+	if rest <= 0 {
+		return out, nil
+	}
+	kept := min(rest, tail)
+	if omitted := rest - kept; omitted > 0 {
+		out = append(out, OmittedMarker(omitted)...)
+	}
+	for i := rest - kept; i < rest; i++ {
+		out = append(out, ring[i%tail]...)
+	}
+	return out, nil
+}
+
+// OmittedMarker is the line that stands in for n dropped lines, e.g.
+// "[... 12,000 lines omitted ...]".
+func OmittedMarker(n int) string {
+	unit := "lines"
+	if n == 1 {
+		unit = "line"
+	}
+	return fmt.Sprintf("[... %s %s omitted ...]\n", groupDigits(n), unit)
+}
+
+// groupDigits formats n with thousands separators, e.g. 12,000.
+func groupDigits(n int) string {
+	digits := fmt.Sprint(n)
+	var grouped strings.Builder
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			grouped.WriteByte(',')
+		}
+		grouped.WriteRune(d)
+	}
+	return grouped.String()
+}
+
+// LineRange is a span of 1-based lines, Start through End.
+type LineRange struct {
+	Start, End int
+}
+
+// Excerpt keeps only the lines of ranges[path] with context lines around
+// them, prefixed with their line numbers in the file, and an OmittedMarker
+// for each gap. Files without ranges, or with a range starting at line 0,
+// are left whole.
+func Excerpt(ranges map[string][]LineRange, context int) Filter {
+	return func(path string, content []byte) []byte {
+		spans := ranges[path]
+		if slices.ContainsFunc(spans, func(r LineRange) bool { return r.Start <= 0 }) {
+			return content
+		}
+		return excerpt(content, spans, context)
+	}
+}
+
+// ExcerptMatches is Excerpt for the lines matching re. Content without a
+// match is left whole.
+func ExcerptMatches(re *regexp.Regexp, context int) Filter {
+	return func(path string, content []byte) []byte {
+		return excerpt(content, MatchLines(re, content), context)
+	}
+}
+
+// MatchLines returns the lines of content spanned by each match of re.
+func MatchLines(re *regexp.Regexp, content []byte) []LineRange {
+	var spans []LineRange
+	line, offset := 1, 0
+	for _, m := range re.FindAllIndex(content, -1) {
+		line += bytes.Count(content[offset:m[0]], []byte("\n"))
+		end := line + bytes.Count(content[m[0]:max(m[0], m[1]-1)], []byte("\n"))
+		spans = append(spans, LineRange{Start: line, End: end})
+		offset = m[0]
+	}
+	return spans
+}
+
+func excerpt(content []byte, spans []LineRange, context int) []byte {
+	if len(spans) == 0 || len(content) == 0 {
+		return content
+	}
+	lines := strings.SplitAfter(string(content), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	keep := make([]bool, len(lines))
+	for _, r := range spans {
+		for i := max(r.Start-context, 1); i <= min(max(r.End, r.Start)+context, len(lines)); i++ {
+			keep[i-1] = true
+		}
+	}
+
+	width := len(fmt.Sprint(len(lines)))
+	var buf bytes.Buffer
+	omitted := 0
+	for i, line := range lines {
+		if !keep[i] {
+			omitted++
+			continue
+		}
+		if omitted > 0 {
+			buf.WriteString(OmittedMarker(omitted))
+			omitted = 0
+		}
+		fmt.Fprintf(&buf, "%*d | %s", width, i+1, line)
+		if !strings.HasSuffix(line, "\n") {
+			buf.WriteByte('\n')
+		}
+	}
+	if omitted > 0 {
+		buf.WriteString(OmittedMarker(omitted))
+	}
+	return buf.Bytes()
+}
+
+// NumberLines prefixes each line with its line number.
+func NumberLines(path string, content []byte) []byte {
+	if len(content) == 0 {
+		return content
+	}
+	lines := strings.SplitAfter(string(content), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	width := len(fmt.Sprint(len(lines)))
+	var buf bytes.Buffer
+	for i, line := range lines {
+		fmt.Fprintf(&buf, "%*d | %s", width, i+1, line)
+	}
+	return buf.Bytes()
+}
+
+type commentSyntax struct {
+	line       []string
+	blockStart string
+	blockEnd   string
+	quotes     string
+}
+
+var (
+	cStyle     = commentSyntax{line: []string{"//"}, blockStart: "/*", blockEnd: "*/", quotes: `"'`}
+	hashStyle  = commentSyntax{line: []string{"#"}, quotes: `"'`}
+	dashStyle  = commentSyntax{line: []string{"--"}, quotes: `"'`}
+	commentsBy = map[string]commentSyntax{
+		".go":    {line: []string{"//"}, blockStart: "/*", blockEnd: "*/", quotes: "\"'`"},
+		".js":    {line: []string{"//"}, blockStart: "/*", blockEnd: "*/", quotes: "\"'`"},
+		".jsx":   {line: []string{"//"}, blockStart: "/*", blockEnd: "*/", quotes: "\"'`"},
+		".ts":    {line: []string{"//"}, blockStart: "/*", blockEnd: "*/", quotes: "\"'`"},
+		".tsx":   {line: []string{"//"}, blockStart: "/*", blockEnd: "*/", quotes: "\"'`"},
+		".rs":    {line: []string{"//"}, blockStart: "/*", blockEnd: "*/", quotes: `"`},
+		".c":     cStyle,
+		".h":     cStyle,
+		".cc":    cStyle,
+		".cpp":   cStyle,
+		".hpp":   cStyle,
+		".java":  cStyle,
+		".kt":    cStyle,
+		".cs":    cStyle,
+		".swift": cStyle,
+		".scala": cStyle,
+		".css":   {blockStart: "/*", blockEnd: "*/", quotes: `"'`},
+		".php":   {line: []string{"//", "#"}, blockStart: "/*", blockEnd: "*/", quotes: `"'`},
+		".py":    hashStyle,
+		".rb":    hashStyle,
+		".sh":    hashStyle,
+		".bash":  hashStyle,
+		".zsh":   hashStyle,
+		".pl":    hashStyle,
+		".r":     hashStyle,
+		".yaml":  hashStyle,
+		".yml":   hashStyle,
+		".toml":  hashStyle,
+		".sql":   dashStyle,
+		".lua":   dashStyle,
+		".hs":    dashStyle,
+	}
+)
+
+// StripComments removes comments from source files it recognises by
+// extension and drops lines that held only a comment. Comment markers inside
+// string literals are kept; a leading #! line is kept too.
+func StripComments(path string, content []byte) []byte {
+	syntax, ok := commentsBy[strings.ToLower(filepath.Ext(path))]
+	if !ok {
+		return content
+	}
+
+	var out bytes.Buffer
+	var quote byte
+	inBlock := false
+	lines := strings.SplitAfter(string(content), "\n")
+	for n, line := range lines {
+		if n == 0 && strings.HasPrefix(line, "#!") {
+			out.WriteString(line)
+			continue
+		}
+		body, eol := strings.CutSuffix(line, "\n")
+
+		var kept strings.Builder
+		commented := false
+	scan:
+		for i := 0; i < len(body); {
+			switch {
+			case inBlock:
+				end := strings.Index(body[i:], syntax.blockEnd)
+				commented = true
+				if end < 0 {
+					break scan
+				}
+				i += end + len(syntax.blockEnd)
+				inBlock = false
+				continue
+			case quote != 0:
+				c := body[i]
+				kept.WriteByte(c)
+				i++
+				if c == '\\' && quote != '`' && i < len(body) {
+					kept.WriteByte(body[i])
+					i++
+				} else if c == quote {
+					quote = 0
+				}
+				continue
+			case strings.IndexByte(syntax.quotes, body[i]) >= 0:
+				quote = body[i]
+				kept.WriteByte(body[i])
+				i++
+				continue
+			case syntax.blockStart != "" && strings.HasPrefix(body[i:], syntax.blockStart):
+				inBlock = true
+				commented = true
+				i += len(syntax.blockStart)
+				continue
+			}
+			for _, marker := range syntax.line {
+				// A # only starts a comment at the start of a word, so $# and
+				// ${#var} survive in shell scripts
+				if strings.HasPrefix(body[i:], marker) && (marker != "#" || i == 0 || body[i-1] == ' ' || body[i-1] == '\t') {
+					commented = true
+					break scan
+				}
+			}
+			kept.WriteByte(body[i])
+			i++
+		}
+		// Only backtick strings span lines
+		if quote != '`' {
+			quote = 0
+		}
+
+		text := kept.String()
+		if commented {
+			text = strings.TrimRight(text, " \t")
+			if strings.TrimSpace(text) == "" {
+				continue
+			}
+		}
+		out.WriteString(text)
+		if eol {
+			out.WriteByte('\n')
+		}
+	}
+	return out.Bytes()
+}
\ No newline at end of file
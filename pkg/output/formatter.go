@@ -0,0 +1,339 @@
+package output
+
+import (
+	"bytes"
+	"clipcat/pkg/collector"
+	"clipcat/pkg/lang"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// File is one section of a document: a collected file, a fetched URL, or a
+// file's diff.
+type File struct {
+	Path        string // label shown in the header
+	Meta        string // optional extra header line, e.g. git provenance
+	Content     []byte
+	Unreadable  bool
+	Error       string // why the file is Unreadable, e.g. "permission denied"
+	Size        int64  // size on disk of an Unreadable file, when known
+	Placeholder string // replaces UnreadableText's default, with {path}, {error} and {size} filled in
+	Removed     bool   // deleted or changed while being read; Content is empty
+	DiffRef     string // set when Content is a diff against DiffRef
+	Image       *Image // set for image files; Content is then a placeholder
+	ID          string // short --ids reference such as "F12"; plain output leaves it out
+}
+
+// UnreadablePrefix starts the text UnreadableText shows by default.
+const UnreadablePrefix = "[unreadable"
+
+// UnreadableText is the text that stands in for the content of an
+// Unreadable file: its Placeholder with the fields filled in, or by default
+// the error and size that are known, e.g. "[unreadable: permission denied,
+// 4.2MB]".
+func (f File) UnreadableText() string {
+	if f.Placeholder != "" {
+		return strings.NewReplacer("{path}", f.Path, "{error}", f.Error, "{size}", compactSize(int(f.Size))).Replace(f.Placeholder)
+	}
+	var details []string
+	if f.Error != "" {
+		details = append(details, f.Error)
+	}
+	if f.Size > 0 {
+		details = append(details, compactSize(int(f.Size)))
+	}
+	if len(details) == 0 {
+		return UnreadablePrefix + "]"
+	}
+	return UnreadablePrefix + ": " + strings.Join(details, ", ") + "]"
+}
+
+// RemovedPlaceholder stands in for the content of a File that was Removed.
+const RemovedPlaceholder = "[removed during run]"
+
+// WithheldPlaceholder stands in for content a failing --filter-cmd was
+// meant to transform.
+const WithheldPlaceholder = "[withheld: --filter-cmd failed]"
+
+// Formatter renders a document. The renderer calls BeginDocument, then
+// WriteTree if a tree was requested, WriteFile once per section, and
+// finally EndDocument. A Formatter is used for a single document.
+type Formatter interface {
+	BeginDocument(w io.Writer) error
+	WriteTree(w io.Writer, roots []string, files []string) error
+	WriteFile(w io.Writer, f File) error
+	EndDocument(w io.Writer) error
+}
+
+var (
+	formattersMu sync.RWMutex
+	formatters   = map[string]func() Formatter{
+		"plain":     func() Formatter { return plainFormatter{} },
+		"markdown":  func() Formatter { return markdownFormatter{} },
+		"xml":       func() Formatter { return &xmlFormatter{} },
+		"json":      func() Formatter { return &jsonFormatter{} },
+		"repomix":   func() Formatter { return &repomixFormatter{} },
+		"llms-txt":  func() Formatter { return &llmsTxtFormatter{} },
+		"llms-full": func() Formatter { return &llmsTxtFormatter{full: true} },
+	}
+)
+
+// RegisterFormatter makes a formatter available by name to --format and
+// the library API, replacing any formatter of the same name.
+func RegisterFormatter(name string, factory func() Formatter) {
+	formattersMu.Lock()
+	defer formattersMu.Unlock()
+	formatters[name] = factory
+}
+
+// NewFormatter returns a fresh formatter registered under name.
+func NewFormatter(name string) (Formatter, error) {
+	formattersMu.RLock()
+	defer formattersMu.RUnlock()
+	factory, ok := formatters[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown format %q (available: %s)", name, strings.Join(formatterNames(), ", "))
+	}
+	return factory(), nil
+}
+
+// Formatters lists the registered formatter names.
+func Formatters() []string {
+	formattersMu.RLock()
+	defer formattersMu.RUnlock()
+	return formatterNames()
+}
+
+func formatterNames() []string {
+	var names []string
+	for name := range formatters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// plainFormatter is the original format: "=" bars around each path, which
+// `clipcat unpack` can parse back.
+type plainFormatter struct{}
+
+func (plainFormatter) BeginDocument(w io.Writer) error { return nil }
+
+func (plainFormatter) WriteTree(w io.Writer, roots []string, files []string) error {
+	WriteHeader(w, "FILE HIERARCHY")
+	WriteTree(w, roots, files)
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func (plainFormatter) WriteFile(w io.Writer, f File) error {
+	if f.DiffRef != "" {
+		WriteHeader(w, DiffLabel(f.Path, f.DiffRef))
+	} else {
+		WriteHeaderMeta(w, f.Path, f.Meta)
+	}
+	if f.Unreadable {
+		io.WriteString(w, f.UnreadableText()+"\n")
+	} else if f.Removed {
+		io.WriteString(w, RemovedPlaceholder+"\n")
+	} else {
+		w.Write(f.Content)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func (plainFormatter) EndDocument(w io.Writer) error { return nil }
+
+// markdownFormatter renders a heading and a fenced code block per file.
+type markdownFormatter struct{}
+
+func (markdownFormatter) BeginDocument(w io.Writer) error { return nil }
+
+func (f markdownFormatter) WriteTree(w io.Writer, roots []string, files []string) error {
+	return f.WriteTreeIDs(w, roots, files, nil)
+}
+
+func (markdownFormatter) WriteFile(w io.Writer, f File) error {
+	title := f.Path
+	if f.DiffRef != "" {
+		title = DiffLabel(f.Path, f.DiffRef)
+	}
+	if f.ID != "" {
+		title = "[" + f.ID + "] " + title
+	}
+	fmt.Fprintf(w, "## %s\n\n", title)
+	if f.Meta != "" {
+		fmt.Fprintf(w, "_%s_\n\n", f.Meta)
+	}
+	if f.Unreadable {
+		_, err := fmt.Fprintf(w, "_%s_\n\n", f.UnreadableText())
+		return err
+	}
+	if f.Removed {
+		_, err := fmt.Fprintf(w, "_%s_\n\n", RemovedPlaceholder)
+		return err
+	}
+
+	if f.Image != nil && f.Image.Data != nil {
+		_, err := fmt.Fprintf(w, "![%s](data:%s;base64,%s)\n\n", filepath.Base(f.Path), f.Image.MIME, base64.StdEncoding.EncodeToString(f.Image.Data))
+		return err
+	}
+
+	info := lang.Detect(f.Path, f.Content)
+	if f.DiffRef != "" {
+		info = "diff"
+	}
+	fence := codeFence(f.Content)
+	content := f.Content
+	if len(content) > 0 && content[len(content)-1] != '\n' {
+		content = append(content[:len(content):len(content)], '\n')
+	}
+	_, err := fmt.Fprintf(w, "%s%s\n%s%s\n\n", fence, info, content, fence)
+	return err
+}
+
+func (markdownFormatter) EndDocument(w io.Writer) error { return nil }
+
+// codeFence returns a backtick fence longer than any backtick run in content.
+func codeFence(content []byte) string {
+	longest, run := 0, 0
+	for _, c := range content {
+		if c == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return strings.Repeat("`", max(3, longest+1))
+}
+
+// xmlFormatter wraps each file in a <file> element with escaped content.
+type xmlFormatter struct {
+	group bool // inside a --group-by <group> element
+}
+
+// xmlEscaper escapes text and attribute values but keeps newlines and tabs
+// readable, unlike xml.EscapeText.
+var xmlEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+
+func (*xmlFormatter) BeginDocument(w io.Writer) error {
+	_, err := io.WriteString(w, "<documents>\n")
+	return err
+}
+
+func (f *xmlFormatter) WriteTree(w io.Writer, roots []string, files []string) error {
+	return f.WriteTreeIDs(w, roots, files, nil)
+}
+
+func (*xmlFormatter) WriteFile(w io.Writer, f File) error {
+	io.WriteString(w, "<file")
+	if f.ID != "" {
+		fmt.Fprintf(w, " id=\"%s\"", f.ID)
+	}
+	fmt.Fprintf(w, " path=\"%s\"", xmlEscaper.Replace(f.Path))
+	if f.Meta != "" {
+		fmt.Fprintf(w, " meta=\"%s\"", xmlEscaper.Replace(f.Meta))
+	}
+	if f.DiffRef != "" {
+		fmt.Fprintf(w, " diff=\"%s\"", xmlEscaper.Replace(f.DiffRef))
+	}
+	if f.Unreadable {
+		io.WriteString(w, " unreadable=\"true\"")
+		if f.Error != "" {
+			fmt.Fprintf(w, " error=\"%s\"", xmlEscaper.Replace(f.Error))
+		}
+		if f.Size > 0 {
+			fmt.Fprintf(w, " size=\"%d\"", f.Size)
+		}
+		_, err := io.WriteString(w, "/>\n")
+		return err
+	}
+	if f.Removed {
+		_, err := io.WriteString(w, " removed=\"true\"/>\n")
+		return err
+	}
+	io.WriteString(w, ">\n")
+	io.WriteString(w, xmlEscaper.Replace(string(f.Content)))
+	if len(f.Content) > 0 && f.Content[len(f.Content)-1] != '\n' {
+		io.WriteString(w, "\n")
+	}
+	_, err := io.WriteString(w, "</file>\n")
+	return err
+}
+
+func (*xmlFormatter) EndDocument(w io.Writer) error {
+	_, err := io.WriteString(w, "</documents>\n")
+	return err
+}
+
+// jsonFormatter collects the document and writes it as one JSON object in
+// EndDocument.
+type jsonFormatter struct {
+	group string // current --group-by group
+	doc   struct {
+		Summary  *jsonSummary        `json:"summary,omitempty"`
+		Tree     any                 `json:"tree,omitempty"` // text, or []*TreeNode for --tree-format json
+		Listing  []jsonEntry         `json:"listing,omitempty"`
+		Files    []jsonFile          `json:"files"`
+		Manifest []jsonManifestEntry `json:"manifest,omitempty"`
+		Warnings []collector.Warning `json:"warnings,omitempty"`
+	}
+}
+
+type jsonFile struct {
+	ID         string `json:"id,omitempty"`
+	Path       string `json:"path"`
+	Meta       string `json:"meta,omitempty"`
+	DiffRef    string `json:"diff_ref,omitempty"`
+	Unreadable bool   `json:"unreadable,omitempty"`
+	Error      string `json:"error,omitempty"`
+	Size       int64  `json:"size,omitempty"`
+	Removed    bool   `json:"removed,omitempty"`
+	Group      string `json:"group,omitempty"`
+	Content    string `json:"content"`
+}
+
+func (f *jsonFormatter) BeginDocument(w io.Writer) error {
+	f.doc.Files = []jsonFile{}
+	return nil
+}
+
+func (f *jsonFormatter) WriteTree(w io.Writer, roots []string, files []string) error {
+	var tree bytes.Buffer
+	WriteTree(&tree, roots, files)
+	if tree.Len() > 0 {
+		f.doc.Tree = tree.String()
+	}
+	return nil
+}
+
+func (f *jsonFormatter) WriteFile(w io.Writer, file File) error {
+	f.doc.Files = append(f.doc.Files, jsonFile{
+		ID:         file.ID,
+		Path:       file.Path,
+		Meta:       file.Meta,
+		DiffRef:    file.DiffRef,
+		Unreadable: file.Unreadable,
+		Error:      file.Error,
+		Size:       file.Size,
+		Removed:    file.Removed,
+		Group:      f.group,
+		Content:    string(file.Content),
+	})
+	return nil
+}
+
+func (f *jsonFormatter) EndDocument(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(f.doc)
+}
\ No newline at end of file
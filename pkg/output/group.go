@@ -0,0 +1,52 @@
+package output
+
+import (
+	"fmt"
+	"io"
+)
+
+// GroupWriter is implemented by formatters that can mark --group-by groups.
+// WriteGroup is called before the first file of each group, and with an
+// empty name after the last one; formatters without it render the files
+// ungrouped.
+type GroupWriter interface {
+	WriteGroup(w io.Writer, name string) error
+}
+
+// GroupLabel is the header plain output puts above a group, e.g. "### src/".
+// unpack skips such sections.
+func GroupLabel(name string) string {
+	return "### " + name
+}
+
+func (plainFormatter) WriteGroup(w io.Writer, name string) error {
+	if name != "" {
+		WriteHeader(w, GroupLabel(name))
+	}
+	return nil
+}
+
+func (markdownFormatter) WriteGroup(w io.Writer, name string) error {
+	if name == "" {
+		return nil
+	}
+	_, err := fmt.Fprintf(w, "# %s\n\n", name)
+	return err
+}
+
+func (f *xmlFormatter) WriteGroup(w io.Writer, name string) error {
+	if f.group {
+		io.WriteString(w, "</group>\n")
+	}
+	f.group = name != ""
+	if !f.group {
+		return nil
+	}
+	_, err := fmt.Fprintf(w, "<group name=\"%s\">\n", xmlEscaper.Replace(name))
+	return err
+}
+
+func (f *jsonFormatter) WriteGroup(w io.Writer, name string) error {
+	f.group = name
+	return nil
+}
\ No newline at end of file
@@ -0,0 +1,196 @@
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// BinaryPrefix starts the placeholder that replaces a binary file's bytes.
+const BinaryPrefix = "[binary: "
+
+// Content is what a Handler makes of a file.
+type Content struct {
+	Data []byte
+	// Image is set for images; Data is then their placeholder
+	Image *Image
+	// Placeholder marks Data as a description of the file rather than its
+	// content, so content filters are not applied
+	Placeholder bool
+}
+
+// HandlerOptions are the settings the built-in handlers depend on.
+type HandlerOptions struct {
+	InlineImages    bool   // keep image bytes for embedding
+	Notebook        string // NotebookRaw leaves notebooks as JSON
+	SummarizeLocks  bool   // list lockfile dependencies
+	IncludeMinified bool   // copy minified JavaScript and CSS as they are
+}
+
+// Handler turns the raw bytes of a file into the content to copy. It
+// returns nil when it does not apply to the file, and the file goes on to
+// the next handler.
+type Handler func(path string, data []byte, opts HandlerOptions) (*Content, error)
+
+var (
+	handlersMu sync.RWMutex
+	handlers   = map[string]Handler{
+		"image/*": handleImage,
+		".ipynb":  handleNotebook,
+		"*":       handleOpaque,
+	}
+)
+
+func init() {
+	for name := range lockParsers {
+		handlers[name] = handleLockfile
+	}
+}
+
+// RegisterHandler sets the handler for key, replacing any built-in one. A
+// key is an exact file name ("go.sum"), an extension (".ipynb"), a MIME type
+// ("image/png") or family ("image/*"), or "*" for every file. It backs the
+// [handlers] config table.
+func RegisterHandler(key string, h Handler) {
+	handlersMu.Lock()
+	defer handlersMu.Unlock()
+	if strings.HasPrefix(key, ".") {
+		key = strings.ToLower(key)
+	}
+	handlers[key] = h
+}
+
+// Handle runs the handlers for path, most specific first: its file name,
+// extension, MIME type, MIME family and finally "*". It returns nil when
+// none applies and the file is copied as text.
+func Handle(path string, data []byte, opts HandlerOptions) (*Content, error) {
+	name := filepath.Base(path)
+	ext := strings.ToLower(filepath.Ext(path))
+	keys := []string{name, ext}
+	if mime := MIMEType(path); mime != "" {
+		family, _, _ := strings.Cut(mime, "/")
+		keys = append(keys, mime, family+"/*")
+	}
+	keys = append(keys, "*")
+
+	for _, key := range keys {
+		handlersMu.RLock()
+		h := handlers[key]
+		handlersMu.RUnlock()
+		if h == nil || key == "" {
+			continue
+		}
+		content, err := h(path, data, opts)
+		if content != nil || err != nil {
+			return content, err
+		}
+	}
+	return nil, nil
+}
+
+// mimeTypes complements imageTypes for MIME-keyed handlers; the system
+// tables are not used, so lookups are the same everywhere.
+var mimeTypes = map[string]string{
+	".pdf":  "application/pdf",
+	".zip":  "application/zip",
+	".gz":   "application/gzip",
+	".tar":  "application/x-tar",
+	".json": "application/json",
+	".xml":  "application/xml",
+	".csv":  "text/csv",
+	".html": "text/html",
+	".md":   "text/markdown",
+	".txt":  "text/plain",
+	".mp3":  "audio/mpeg",
+	".wav":  "audio/wav",
+	".mp4":  "video/mp4",
+	".webm": "video/webm",
+}
+
+// MIMEType returns the MIME type of path by its extension, or "".
+func MIMEType(path string) string {
+	if mime := ImageMIME(path); mime != "" {
+		return mime
+	}
+	return mimeTypes[strings.ToLower(filepath.Ext(path))]
+}
+
+// IsBinary reports whether data looks binary: a NUL byte in its first 8000
+// bytes, the test git uses.
+func IsBinary(data []byte) bool {
+	return bytes.IndexByte(data[:min(len(data), 8000)], 0) >= 0
+}
+
+func handleImage(path string, data []byte, opts HandlerOptions) (*Content, error) {
+	// Image bytes would corrupt the text, so describe them instead
+	image := NewImage(path, data, opts.InlineImages)
+	return &Content{Data: []byte(image.Placeholder(path)), Image: image, Placeholder: true}, nil
+}
+
+func handleNotebook(path string, data []byte, opts HandlerOptions) (*Content, error) {
+	if opts.Notebook == NotebookRaw {
+		return nil, nil
+	}
+	rendered, err := RenderNotebook(data)
+	if err != nil {
+		return nil, nil // copied as JSON
+	}
+	return &Content{Data: rendered}, nil
+}
+
+func handleLockfile(path string, data []byte, opts HandlerOptions) (*Content, error) {
+	if !opts.SummarizeLocks || !IsLockfile(path) {
+		return nil, nil
+	}
+	summary, err := SummarizeLockfile(path, data)
+	if err != nil {
+		return nil, nil // copied as it is
+	}
+	return &Content{Data: summary}, nil
+}
+
+// handleOpaque describes the files whose bytes are no use as context:
+// binary files, and minified ones unless opts.IncludeMinified is set.
+func handleOpaque(path string, data []byte, opts HandlerOptions) (*Content, error) {
+	prefix := BinaryPrefix
+	switch {
+	case IsBinary(data):
+	case !opts.IncludeMinified && IsMinified(path, data):
+		prefix = MinifiedPrefix
+	default:
+		return nil, nil
+	}
+	placeholder := prefix + filepath.Base(path) + " " + compactSize(len(data)) + "]\n"
+	return &Content{Data: []byte(placeholder), Placeholder: true}, nil
+}
+
+// CommandHandler returns a Handler that pipes a file through a shell
+// command (sh -c, or cmd /C on Windows) and copies what it prints. The
+// file's path is in $CLIPCAT_FILE.
+func CommandHandler(command string) Handler {
+	return func(path string, data []byte, opts HandlerOptions) (*Content, error) {
+		var cmd *exec.Cmd
+		if runtime.GOOS == "windows" {
+			cmd = exec.Command("cmd", "/C", command)
+		} else {
+			cmd = exec.Command("sh", "-c", command)
+		}
+		cmd.Stdin = bytes.NewReader(data)
+		cmd.Env = append(os.Environ(), "CLIPCAT_FILE="+path)
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil {
+			if msg := strings.TrimSpace(stderr.String()); msg != "" {
+				err = fmt.Errorf("%w: %s", err, msg)
+			}
+			return nil, fmt.Errorf("handler %q: %w", command, err)
+		}
+		return &Content{Data: out}, nil
+	}
+}
\ No newline at end of file
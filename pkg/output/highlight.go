@@ -0,0 +1,92 @@
+package output
+
+import (
+	"bytes"
+	"html"
+	"io"
+	"net/url"
+	"path"
+	"path/filepath"
+
+	"github.com/alecthomas/chroma/v2"
+	chromaformatters "github.com/alecthomas/chroma/v2/formatters"
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+)
+
+// HighlightStyle is the chroma style used for terminal output, and
+// HTMLStyle the one for --rich, which usually lands on a white page.
+const (
+	HighlightStyle = "monokai"
+	HTMLStyle      = "github"
+)
+
+// Span locates one file's content inside a rendered document, so printing
+// can colorize it without touching the copy that goes to the clipboard.
+type Span struct {
+	Start, End int
+	Path       string
+	Diff       bool
+}
+
+// Lexer picks a chroma lexer for a file path or URL, falling back to plain
+// text. diff selects the diff lexer.
+func Lexer(name string, diff bool) chroma.Lexer {
+	if diff {
+		return lexers.Get("diff")
+	}
+	if u, err := url.Parse(name); err == nil && u.Scheme != "" && u.Host != "" {
+		name = path.Base(u.Path)
+	}
+	lexer := lexers.Match(filepath.Base(name))
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+	return chroma.Coalesce(lexer)
+}
+
+// Highlight writes data with each span's content colorized with 256-color
+// ANSI escapes. Spans must be sorted and must not overlap.
+func Highlight(w io.Writer, data []byte, spans []Span) error {
+	formatter := chromaformatters.Get("terminal256")
+	style := styles.Get(HighlightStyle)
+	last := 0
+	for _, s := range spans {
+		if _, err := w.Write(data[last:s.Start]); err != nil {
+			return err
+		}
+		tokens, err := Lexer(s.Path, s.Diff).Tokenise(nil, string(data[s.Start:s.End]))
+		if err != nil {
+			w.Write(data[s.Start:s.End])
+		} else if err := formatter.Format(w, style, tokens); err != nil {
+			return err
+		}
+		last = s.End
+	}
+	_, err := w.Write(data[last:])
+	return err
+}
+
+// HTML renders data as a monospace HTML fragment with each span's content
+// highlighted using inline styles, for pasting into rich-text editors.
+func HTML(data []byte, spans []Span) []byte {
+	formatter := chromahtml.New(chromahtml.WithClasses(false), chromahtml.PreventSurroundingPre(true))
+	style := styles.Get(HTMLStyle)
+
+	var buf bytes.Buffer
+	buf.WriteString(`<pre style="font-family: Menlo, Consolas, 'DejaVu Sans Mono', monospace; font-size: 12px; white-space: pre-wrap;">`)
+	last := 0
+	for _, s := range spans {
+		buf.WriteString(html.EscapeString(string(data[last:s.Start])))
+		content := string(data[s.Start:s.End])
+		tokens, err := Lexer(s.Path, s.Diff).Tokenise(nil, content)
+		if err != nil || formatter.Format(&buf, style, tokens) != nil {
+			buf.WriteString(html.EscapeString(content))
+		}
+		last = s.End
+	}
+	buf.WriteString(html.EscapeString(string(data[last:])))
+	buf.WriteString("</pre>\n")
+	return buf.Bytes()
+}
\ No newline at end of file
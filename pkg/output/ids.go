@@ -0,0 +1,28 @@
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// IDTreeWriter is implemented by formatters that can show --ids in the
+// tree. ids maps each file, as passed to WriteTree, to its ID; formatters
+// without it get a plain WriteTree.
+type IDTreeWriter interface {
+	WriteTreeIDs(w io.Writer, roots []string, files []string, ids map[string]string) error
+}
+
+func (markdownFormatter) WriteTreeIDs(w io.Writer, roots []string, files []string, ids map[string]string) error {
+	var tree bytes.Buffer
+	WriteTreeIDs(&tree, roots, files, ids)
+	_, err := fmt.Fprintf(w, "## File hierarchy\n\n```\n%s```\n\n", tree.String())
+	return err
+}
+
+func (*xmlFormatter) WriteTreeIDs(w io.Writer, roots []string, files []string, ids map[string]string) error {
+	var tree bytes.Buffer
+	WriteTreeIDs(&tree, roots, files, ids)
+	_, err := fmt.Fprintf(w, "<tree>\n%s</tree>\n", xmlEscaper.Replace(tree.String()))
+	return err
+}
\ No newline at end of file
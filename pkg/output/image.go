@@ -0,0 +1,75 @@
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"path/filepath"
+	"strings"
+)
+
+var imageTypes = map[string]string{
+	".png":  "image/png",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".gif":  "image/gif",
+	".webp": "image/webp",
+	".bmp":  "image/bmp",
+	".ico":  "image/x-icon",
+	".tif":  "image/tiff",
+	".tiff": "image/tiff",
+}
+
+// ImagePrefix starts the placeholder that replaces an image's bytes.
+const ImagePrefix = "[image: "
+
+// Image describes a binary image file. Data holds the raw bytes only when
+// the image is to be embedded (--inline-images).
+type Image struct {
+	MIME          string
+	Width, Height int // 0 when the format can't be decoded
+	Size          int
+	Data          []byte
+}
+
+// ImageMIME returns the MIME type for an image path, or "" for other files.
+// SVG is text and is left alone.
+func ImageMIME(path string) string {
+	return imageTypes[strings.ToLower(filepath.Ext(path))]
+}
+
+// NewImage describes data read from an image file, keeping the bytes when
+// inline is set.
+func NewImage(path string, data []byte, inline bool) *Image {
+	img := &Image{MIME: ImageMIME(path), Size: len(data)}
+	if cfg, _, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+		img.Width, img.Height = cfg.Width, cfg.Height
+	}
+	if inline {
+		img.Data = data
+	}
+	return img
+}
+
+// Placeholder is the text shown instead of the image bytes, e.g.
+// "[image: logo.png 320x200 45KB]".
+func (img *Image) Placeholder(path string) string {
+	desc := filepath.Base(path)
+	if img.Width > 0 {
+		desc += fmt.Sprintf(" %dx%d", img.Width, img.Height)
+	}
+	return ImagePrefix + desc + " " + compactSize(img.Size) + "]\n"
+}
+
+func compactSize(n int) string {
+	switch {
+	case n < 1<<10:
+		return fmt.Sprintf("%dB", n)
+	case n < 1<<20:
+		return fmt.Sprintf("%dKB", (n+1<<9)>>10)
+	}
+	return fmt.Sprintf("%.1fMB", float64(n)/(1<<20))
+}
\ No newline at end of file
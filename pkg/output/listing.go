@@ -0,0 +1,79 @@
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"time"
+)
+
+// Entry is one line of a --long listing.
+type Entry struct {
+	Path    string
+	Mode    fs.FileMode
+	Size    int64
+	ModTime time.Time
+}
+
+// ListingWriter is implemented by formatters that can render a --long
+// listing. Formatters without it get WriteTree instead.
+type ListingWriter interface {
+	WriteListing(w io.Writer, entries []Entry) error
+}
+
+// WriteListing writes entries like `ls -l`: mode, size in bytes, modification
+// time and path, with the size column right-aligned.
+func WriteListing(w io.Writer, entries []Entry) {
+	width := 0
+	for _, e := range entries {
+		width = max(width, len(fmt.Sprint(e.Size)))
+	}
+	for _, e := range entries {
+		fmt.Fprintf(w, "%s  %*d  %s  %s\n", e.Mode, width, e.Size, e.ModTime.Format("2006-01-02 15:04"), e.Path)
+	}
+}
+
+func (plainFormatter) WriteListing(w io.Writer, entries []Entry) error {
+	WriteHeader(w, "FILE LISTING")
+	WriteListing(w, entries)
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func (markdownFormatter) WriteListing(w io.Writer, entries []Entry) error {
+	var listing bytes.Buffer
+	WriteListing(&listing, entries)
+	_, err := fmt.Fprintf(w, "## File listing\n\n```\n%s```\n\n", listing.String())
+	return err
+}
+
+func (*xmlFormatter) WriteListing(w io.Writer, entries []Entry) error {
+	io.WriteString(w, "<listing>\n")
+	for _, e := range entries {
+		fmt.Fprintf(w, "<entry path=\"%s\" mode=\"%s\" size=\"%d\" modified=\"%s\"/>\n",
+			xmlEscaper.Replace(e.Path), e.Mode, e.Size, e.ModTime.Format(time.RFC3339))
+	}
+	_, err := io.WriteString(w, "</listing>\n")
+	return err
+}
+
+func (f *jsonFormatter) WriteListing(w io.Writer, entries []Entry) error {
+	f.doc.Listing = []jsonEntry{}
+	for _, e := range entries {
+		f.doc.Listing = append(f.doc.Listing, jsonEntry{
+			Path:     e.Path,
+			Mode:     e.Mode.String(),
+			Size:     e.Size,
+			Modified: e.ModTime.Format(time.RFC3339),
+		})
+	}
+	return nil
+}
+
+type jsonEntry struct {
+	Path     string `json:"path"`
+	Mode     string `json:"mode"`
+	Size     int64  `json:"size"`
+	Modified string `json:"modified"`
+}
\ No newline at end of file
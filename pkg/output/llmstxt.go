@@ -0,0 +1,131 @@
+package output
+
+import (
+	"bytes"
+	"clipcat/pkg/lang"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// llmsTxtFormatter writes an llms.txt index (https://llmstxt.org): a title,
+// a one-paragraph summary taken from the README, and a section of links per
+// top-level directory. With full set it is llms-full.txt, which also holds
+// every file's contents.
+type llmsTxtFormatter struct {
+	full  bool
+	files []File
+}
+
+func (*llmsTxtFormatter) BeginDocument(w io.Writer) error { return nil }
+
+func (*llmsTxtFormatter) WriteTree(w io.Writer, roots []string, files []string) error { return nil }
+
+func (f *llmsTxtFormatter) WriteFile(w io.Writer, file File) error {
+	if !file.Unreadable && !file.Removed && file.DiffRef == "" {
+		f.files = append(f.files, file)
+	}
+	return nil
+}
+
+func (f *llmsTxtFormatter) EndDocument(w io.Writer) error {
+	title := "Project"
+	if wd, err := os.Getwd(); err == nil {
+		title = filepath.Base(wd)
+	}
+	fmt.Fprintf(w, "# %s\n\n", title)
+
+	summary := ""
+	for _, file := range f.files {
+		if strings.HasPrefix(strings.ToLower(filepath.Base(file.Path)), "readme") {
+			summary = firstParagraph(file.Content)
+			break
+		}
+	}
+	if summary == "" {
+		summary = fmt.Sprintf("%d files from %s.", len(f.files), title)
+	}
+	fmt.Fprintf(w, "> %s\n", summary)
+
+	// Top-level files first, then one section per directory in first-seen order
+	paths := make([]string, len(f.files))
+	sections := map[string][]int{}
+	var order []string
+	for i, file := range f.files {
+		paths[i] = displayPath(file.Path)
+		section := "Files"
+		if dir, _, ok := strings.Cut(paths[i], "/"); ok && !strings.Contains(paths[i], "://") {
+			section = dir
+		}
+		if _, ok := sections[section]; !ok {
+			if section == "Files" {
+				order = append([]string{section}, order...)
+			} else {
+				order = append(order, section)
+			}
+		}
+		sections[section] = append(sections[section], i)
+	}
+	for _, section := range order {
+		fmt.Fprintf(w, "\n## %s\n\n", section)
+		for _, i := range sections[section] {
+			fmt.Fprintf(w, "- [%s](%s)%s\n", paths[i], paths[i], linkNote(f.files[i]))
+		}
+	}
+
+	if !f.full {
+		return nil
+	}
+	for i, file := range f.files {
+		fence := codeFence(file.Content)
+		content := file.Content
+		if len(content) > 0 && content[len(content)-1] != '\n' {
+			content = append(content[:len(content):len(content)], '\n')
+		}
+		fmt.Fprintf(w, "\n---\n\n## %s\n\n%s%s\n%s%s\n", paths[i], fence, lang.Detect(file.Path, file.Content), content, fence)
+	}
+	return nil
+}
+
+// linkNote describes a file after its link, e.g. ": go, 120 lines".
+func linkNote(file File) string {
+	if file.Image != nil {
+		return ": image"
+	}
+	lines := bytes.Count(file.Content, []byte("\n"))
+	if len(file.Content) > 0 && file.Content[len(file.Content)-1] != '\n' {
+		lines++
+	}
+	note := fmt.Sprintf("%d lines", lines)
+	if lines == 1 {
+		note = "1 line"
+	}
+	if language := lang.Detect(file.Path, file.Content); language != "" {
+		note = language + ", " + note
+	}
+	return ": " + note
+}
+
+// firstParagraph returns the first prose paragraph of a README as one line,
+// skipping headings, badges, HTML and other markup-only lines.
+func firstParagraph(content []byte) string {
+	var para []string
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+			if len(para) > 0 {
+				return strings.Join(para, " ")
+			}
+		case strings.ContainsAny(line[:1], "#![<=-|`>*"):
+			if len(para) > 0 {
+				return strings.Join(para, " ")
+			}
+		default:
+			para = append(para, line)
+		}
+	}
+	return strings.Join(para, " ")
+}
\ No newline at end of file
@@ -0,0 +1,279 @@
+package output
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Dependency is one package pinned by a lockfile.
+type Dependency struct {
+	Name, Version string
+}
+
+// lockParsers read the dependencies of each known lockfile, by file name.
+var lockParsers = map[string]func([]byte) ([]Dependency, error){
+	"go.sum":            parseGoSum,
+	"modules.txt":       parseVendorModules,
+	"package-lock.json": parsePackageLock,
+	"yarn.lock":         parseYarnLock,
+	"pnpm-lock.yaml":    parsePnpmLock,
+	"Cargo.lock":        parseTOMLPackages,
+	"poetry.lock":       parseTOMLPackages,
+	"Gemfile.lock":      parseGemfileLock,
+	"composer.lock":     parseComposerLock,
+}
+
+// IsLockfile reports whether path is a lockfile --summarize-locks can
+// summarize: go.sum, vendor/modules.txt, package-lock.json, yarn.lock,
+// pnpm-lock.yaml, Cargo.lock, poetry.lock, Gemfile.lock or composer.lock.
+func IsLockfile(path string) bool {
+	name := filepath.Base(path)
+	if name == "modules.txt" {
+		return filepath.Base(filepath.Dir(path)) == "vendor"
+	}
+	return lockParsers[name] != nil
+}
+
+// SummarizeLockfile replaces a lockfile with its dependencies, one
+// "name version" line each in name order, under a line naming the file and
+// the count.
+func SummarizeLockfile(path string, data []byte) ([]byte, error) {
+	parse := lockParsers[filepath.Base(path)]
+	if parse == nil {
+		return nil, fmt.Errorf("%s is not a known lockfile", path)
+	}
+	deps, err := parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("summarizing %s: %w", filepath.Base(path), err)
+	}
+	slices.SortFunc(deps, func(a, b Dependency) int {
+		return cmpDependency(a, b)
+	})
+	deps = slices.Compact(deps)
+
+	var buf bytes.Buffer
+	unit := "dependencies"
+	if len(deps) == 1 {
+		unit = "dependency"
+	}
+	fmt.Fprintf(&buf, "[lockfile %s: %d %s]\n", filepath.Base(path), len(deps), unit)
+	for _, d := range deps {
+		fmt.Fprintf(&buf, "%s %s\n", d.Name, d.Version)
+	}
+	return buf.Bytes(), nil
+}
+
+func cmpDependency(a, b Dependency) int {
+	if c := strings.Compare(a.Name, b.Name); c != 0 {
+		return c
+	}
+	return strings.Compare(a.Version, b.Version)
+}
+
+// parseGoSum lists the modules whose source is pinned; modules with only a
+// go.mod hash were needed for version selection but never built.
+func parseGoSum(data []byte) ([]Dependency, error) {
+	var deps, modOnly []Dependency
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			continue
+		}
+		if version, ok := strings.CutSuffix(fields[1], "/go.mod"); ok {
+			modOnly = append(modOnly, Dependency{fields[0], version})
+		} else {
+			deps = append(deps, Dependency{fields[0], fields[1]})
+		}
+	}
+	if len(deps) == 0 {
+		return modOnly, nil
+	}
+	return deps, nil
+}
+
+// parseVendorModules reads the "# module version" lines of vendor/modules.txt.
+func parseVendorModules(data []byte) ([]Dependency, error) {
+	var deps []Dependency
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 3 && fields[0] == "#" && !strings.HasPrefix(fields[1], "explicit") {
+			deps = append(deps, Dependency{fields[1], fields[2]})
+		}
+	}
+	return deps, nil
+}
+
+// parsePackageLock reads npm's "packages" map (lockfile v2 and v3), or the
+// nested "dependencies" of v1.
+func parsePackageLock(data []byte) ([]Dependency, error) {
+	type v1Dependency struct {
+		Version      string                     `json:"version"`
+		Dependencies map[string]json.RawMessage `json:"dependencies"`
+	}
+	var lock struct {
+		Packages map[string]struct {
+			Version string `json:"version"`
+		} `json:"packages"`
+		Dependencies map[string]json.RawMessage `json:"dependencies"`
+	}
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return nil, err
+	}
+
+	var deps []Dependency
+	for path, pkg := range lock.Packages {
+		_, name, ok := cutLast(path, "node_modules/")
+		if ok && pkg.Version != "" {
+			deps = append(deps, Dependency{name, pkg.Version})
+		}
+	}
+	if lock.Packages != nil {
+		return deps, nil
+	}
+
+	var walk func(map[string]json.RawMessage) error
+	walk = func(m map[string]json.RawMessage) error {
+		for name, raw := range m {
+			var d v1Dependency
+			if err := json.Unmarshal(raw, &d); err != nil {
+				return err
+			}
+			deps = append(deps, Dependency{name, d.Version})
+			if err := walk(d.Dependencies); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return deps, walk(lock.Dependencies)
+}
+
+// parseYarnLock reads classic (v1) and Berry yarn.lock entries: an unindented
+// "name@range, name@range:" header followed by an indented version line.
+func parseYarnLock(data []byte) ([]Dependency, error) {
+	var deps []Dependency
+	name := ""
+	for _, line := range strings.Split(string(data), "\n") {
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+		case !strings.HasPrefix(line, " "):
+			spec, _, _ := strings.Cut(strings.TrimSuffix(line, ":"), ",")
+			spec = strings.Trim(spec, `"`)
+			name = ""
+			if at := strings.LastIndex(spec, "@"); at > 0 {
+				name = spec[:at]
+			}
+		case name != "" && strings.HasPrefix(strings.TrimSpace(line), "version"):
+			version := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "version"))
+			version = strings.Trim(strings.TrimPrefix(version, ":"), ` "`)
+			deps = append(deps, Dependency{name, version})
+			name = ""
+		}
+	}
+	return deps, nil
+}
+
+// parsePnpmLock reads the keys of the "packages:" section: "/name@1.0.0"
+// (v6), "name@1.0.0" (v9) or "/name/1.0.0" (v5), with any peer suffix.
+func parsePnpmLock(data []byte) ([]Dependency, error) {
+	var deps []Dependency
+	inPackages := false
+	for _, line := range strings.Split(string(data), "\n") {
+		if !strings.HasPrefix(line, " ") && line != "" {
+			inPackages = strings.TrimSpace(line) == "packages:"
+			continue
+		}
+		if !inPackages || !strings.HasPrefix(line, "  ") || strings.HasPrefix(line, "   ") {
+			continue
+		}
+		key := strings.Trim(strings.TrimSuffix(strings.TrimSpace(line), ":"), `'"`)
+		key, _, _ = strings.Cut(key, "(")
+		key = strings.TrimPrefix(key, "/")
+		if at := strings.LastIndex(key, "@"); at > 0 {
+			deps = append(deps, Dependency{key[:at], key[at+1:]})
+		} else if name, version, ok := cutLast(key, "/"); ok {
+			deps = append(deps, Dependency{name, version})
+		}
+	}
+	return deps, nil
+}
+
+// parseTOMLPackages reads the name and version of each [[package]] table,
+// as in Cargo.lock and poetry.lock.
+func parseTOMLPackages(data []byte) ([]Dependency, error) {
+	var deps []Dependency
+	var current *Dependency
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") {
+			current = nil
+			if line == "[[package]]" {
+				deps = append(deps, Dependency{})
+				current = &deps[len(deps)-1]
+			}
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if current == nil || !ok {
+			continue
+		}
+		value = strings.Trim(strings.TrimSpace(value), `"`)
+		switch strings.TrimSpace(key) {
+		case "name":
+			current.Name = value
+		case "version":
+			current.Version = value
+		}
+	}
+	return slices.DeleteFunc(deps, func(d Dependency) bool { return d.Name == "" }), nil
+}
+
+// parseGemfileLock reads the "    name (version)" lines of the specs lists.
+func parseGemfileLock(data []byte) ([]Dependency, error) {
+	var deps []Dependency
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "    ") || strings.HasPrefix(line, "     ") {
+			continue
+		}
+		name, version, ok := strings.Cut(strings.TrimSpace(line), " (")
+		if ok {
+			deps = append(deps, Dependency{name, strings.TrimSuffix(version, ")")})
+		}
+	}
+	return deps, scanner.Err()
+}
+
+// parseComposerLock reads the "packages" and "packages-dev" lists.
+func parseComposerLock(data []byte) ([]Dependency, error) {
+	type pkg struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	}
+	var lock struct {
+		Packages    []pkg `json:"packages"`
+		PackagesDev []pkg `json:"packages-dev"`
+	}
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return nil, err
+	}
+	var deps []Dependency
+	for _, p := range append(lock.Packages, lock.PackagesDev...) {
+		deps = append(deps, Dependency{p.Name, p.Version})
+	}
+	return deps, nil
+}
+
+// cutLast slices s around the last instance of sep.
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
\ No newline at end of file
@@ -0,0 +1,71 @@
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// ManifestEntry is one line of a --manifest: a file's size and the SHA-256
+// of its bytes on disk (or of a URL's body), before any content filter.
+type ManifestEntry struct {
+	Path   string
+	Size   int64
+	SHA256 string // hex-encoded
+}
+
+// ManifestWriter is implemented by formatters that can render a --manifest.
+// It is written after the last file, before EndDocument; formatters without
+// it leave the manifest out.
+type ManifestWriter interface {
+	WriteManifest(w io.Writer, entries []ManifestEntry) error
+}
+
+// WriteManifest writes entries like sha256sum, with the size in bytes
+// between the hash and the path, right-aligned.
+func WriteManifest(w io.Writer, entries []ManifestEntry) {
+	width := 0
+	for _, e := range entries {
+		width = max(width, len(fmt.Sprint(e.Size)))
+	}
+	for _, e := range entries {
+		fmt.Fprintf(w, "%s  %*d  %s\n", e.SHA256, width, e.Size, e.Path)
+	}
+}
+
+func (plainFormatter) WriteManifest(w io.Writer, entries []ManifestEntry) error {
+	WriteHeader(w, "MANIFEST")
+	WriteManifest(w, entries)
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func (markdownFormatter) WriteManifest(w io.Writer, entries []ManifestEntry) error {
+	var manifest bytes.Buffer
+	WriteManifest(&manifest, entries)
+	_, err := fmt.Fprintf(w, "## Manifest\n\n```\n%s```\n\n", manifest.String())
+	return err
+}
+
+func (*xmlFormatter) WriteManifest(w io.Writer, entries []ManifestEntry) error {
+	io.WriteString(w, "<manifest>\n")
+	for _, e := range entries {
+		fmt.Fprintf(w, "<entry path=\"%s\" size=\"%d\" sha256=\"%s\"/>\n", xmlEscaper.Replace(e.Path), e.Size, e.SHA256)
+	}
+	_, err := io.WriteString(w, "</manifest>\n")
+	return err
+}
+
+func (f *jsonFormatter) WriteManifest(w io.Writer, entries []ManifestEntry) error {
+	f.doc.Manifest = []jsonManifestEntry{}
+	for _, e := range entries {
+		f.doc.Manifest = append(f.doc.Manifest, jsonManifestEntry{Path: e.Path, Size: e.Size, SHA256: e.SHA256})
+	}
+	return nil
+}
+
+type jsonManifestEntry struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
\ No newline at end of file
@@ -0,0 +1,45 @@
+package output
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+)
+
+// MinifiedPrefix starts the placeholder that replaces a minified file.
+const MinifiedPrefix = "[minified: "
+
+// minifiable are the extensions of files that get minified for the web.
+var minifiable = map[string]bool{".js": true, ".mjs": true, ".cjs": true, ".css": true}
+
+// IsMinified reports whether the JavaScript or CSS file at path is
+// minified: named like app.min.js, or with lines that average over 200
+// bytes, or with almost no whitespace. Files under 1KB are too small to
+// tell, or to matter.
+func IsMinified(path string, data []byte) bool {
+	name := strings.ToLower(filepath.Base(path))
+	ext := filepath.Ext(name)
+	if !minifiable[ext] {
+		return false
+	}
+	if strings.HasSuffix(name, ".min"+ext) {
+		return true
+	}
+	if len(data) < 1024 {
+		return false
+	}
+	lines := bytes.Count(data, []byte("\n"))
+	if data[len(data)-1] != '\n' {
+		lines++
+	}
+	if len(data)/lines > 200 {
+		return true
+	}
+	space := 0
+	for _, c := range data {
+		if c == ' ' || c == '\t' || c == '\n' || c == '\r' {
+			space++
+		}
+	}
+	return space*100 < len(data)*3
+}
\ No newline at end of file
@@ -0,0 +1,130 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Notebook modes for --notebook.
+const (
+	NotebookRender = "render"
+	NotebookRaw    = "raw"
+	NotebookSkip   = "skip"
+)
+
+// IsNotebook reports whether path is a Jupyter notebook.
+func IsNotebook(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".ipynb")
+}
+
+type notebook struct {
+	Metadata struct {
+		Kernelspec struct {
+			Language string `json:"language"`
+		} `json:"kernelspec"`
+		LanguageInfo struct {
+			Name string `json:"name"`
+		} `json:"language_info"`
+	} `json:"metadata"`
+	Cells []struct {
+		CellType string          `json:"cell_type"`
+		Source   notebookText    `json:"source"`
+		Outputs  []notebookOutput `json:"outputs"`
+	} `json:"cells"`
+}
+
+type notebookOutput struct {
+	OutputType string                  `json:"output_type"`
+	Text       notebookText            `json:"text"`
+	Data       map[string]notebookText `json:"data"`
+	Ename      string                  `json:"ename"`
+	Evalue     string                  `json:"evalue"`
+}
+
+// notebookText is a multiline string, stored either as one string or as a
+// list of lines.
+type notebookText string
+
+func (t *notebookText) UnmarshalJSON(data []byte) error {
+	var lines []string
+	if err := json.Unmarshal(data, &lines); err == nil {
+		*t = notebookText(strings.Join(lines, ""))
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		// Non-text output data such as application/json objects
+		return nil
+	}
+	*t = notebookText(s)
+	return nil
+}
+
+// RenderNotebook turns notebook JSON into markdown: markdown cells as they
+// are, code cells as fenced blocks, and text outputs below them. Images and
+// other binary outputs become a short placeholder.
+func RenderNotebook(data []byte) ([]byte, error) {
+	var nb notebook
+	if err := json.Unmarshal(data, &nb); err != nil {
+		return nil, fmt.Errorf("parsing notebook: %w", err)
+	}
+	lang := nb.Metadata.LanguageInfo.Name
+	if lang == "" {
+		lang = nb.Metadata.Kernelspec.Language
+	}
+
+	var buf bytes.Buffer
+	for i, cell := range nb.Cells {
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+		source := strings.TrimRight(string(cell.Source), "\n")
+		switch cell.CellType {
+		case "markdown":
+			buf.WriteString(source + "\n")
+		case "code":
+			fence := codeFence([]byte(source))
+			fmt.Fprintf(&buf, "%s%s\n%s\n%s\n", fence, lang, source, fence)
+			if out := notebookOutputs(cell.Outputs); out != "" {
+				fence = codeFence([]byte(out))
+				fmt.Fprintf(&buf, "\nOutput:\n\n%s\n%s\n%s\n", fence, out, fence)
+			}
+		default:
+			fence := codeFence([]byte(source))
+			fmt.Fprintf(&buf, "%s\n%s\n%s\n", fence, source, fence)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func notebookOutputs(outputs []notebookOutput) string {
+	var parts []string
+	for _, out := range outputs {
+		var text string
+		switch out.OutputType {
+		case "stream":
+			text = string(out.Text)
+		case "error":
+			text = out.Ename + ": " + out.Evalue
+		case "execute_result", "display_data":
+			if plain, ok := out.Data["text/plain"]; ok {
+				text = string(plain)
+			}
+			for _, mime := range slices.Sorted(maps.Keys(out.Data)) {
+				if strings.HasPrefix(mime, "image/") {
+					text = "[" + mime + " output]"
+					break
+				}
+			}
+		}
+		if text = strings.TrimRight(text, "\n"); text != "" {
+			parts = append(parts, text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
\ No newline at end of file
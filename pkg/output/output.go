@@ -1,6 +1,7 @@
 package output
 
 import (
+	"clipcat/pkg/exclude"
 	"fmt"
 	"io"
 	"os"
@@ -13,6 +14,17 @@ func WriteHeader(w io.Writer, path string) {
 	fmt.Fprintf(w, "%s\n%s\n%s\n\n", bar, path, bar)
 }
 
+// WriteHeaderMeta writes a header with an extra metadata line under the path,
+// e.g. git provenance. The bars span the longer of the two lines.
+func WriteHeaderMeta(w io.Writer, path, meta string) {
+	if meta == "" {
+		WriteHeader(w, path)
+		return
+	}
+	bar := strings.Repeat("=", max(len(path), len(meta)))
+	fmt.Fprintf(w, "%s\n%s\n%s\n%s\n\n", bar, path, meta, bar)
+}
+
 func WriteFileContent(w io.Writer, path string) error {
 	f, err := os.Open(path)
 	if err != nil {
@@ -24,94 +36,93 @@ func WriteFileContent(w io.Writer, path string) error {
 	return err
 }
 
-func getRelativePath(file string, roots []string) string {
-	// Find the best matching root
-	var bestRoot string
-	var bestLabel string
-
+// treeRoot returns the directory root file is drawn under and its path
+// relative to it. Overlapping roots merge: a file goes under the outermost
+// directory root holding it, so `clipcat . src/` draws one tree in which
+// src/ is a directory. Files under no directory root (globs, literal files)
+// are drawn relative to the working directory.
+func treeRoot(file string, roots []string) (label, abs, rel string) {
 	for _, root := range roots {
-		if isGlobPattern(root) {
+		if exclude.IsGlobPattern(root) {
 			continue
 		}
-
-		absRoot, _ := filepath.Abs(root)
-		if strings.HasPrefix(file, absRoot) && len(absRoot) > len(bestRoot) {
-			bestRoot = absRoot
-			bestLabel = root
+		absRoot, err := filepath.Abs(root)
+		if err != nil || (abs != "" && len(absRoot) >= len(abs)) {
+			continue
+		}
+		if info, err := os.Stat(absRoot); err != nil || !info.IsDir() {
+			continue
+		}
+		if r, err := filepath.Rel(absRoot, file); err == nil && filepath.IsLocal(r) {
+			label, abs, rel = root, absRoot, r
 		}
 	}
-
-	if bestRoot != "" {
-		rel, _ := filepath.Rel(bestRoot, file)
-		return bestLabel + ":" + rel
+	if abs != "" {
+		return label, abs, rel
 	}
-
-	rel, _ := filepath.Rel(".", file)
-	return ".:" + rel
-}
-
-func isGlobPattern(path string) bool {
-	return strings.ContainsAny(path, "*?[")
+	abs, _ = filepath.Abs(".")
+	rel, _ = filepath.Rel(abs, file)
+	return ".", abs, rel
 }
 
 func WriteTree(w io.Writer, roots []string, files []string) {
-	// Group files by root
-	type rootGroup struct {
-		label string
-		files []string
-	}
-
-	groups := make(map[string]*rootGroup)
-	order := []string{}
-
-	for _, file := range files {
-		relPath := getRelativePath(file, roots)
-		parts := strings.SplitN(relPath, ":", 2)
-		root := parts[0]
-		rel := parts[1]
+	WriteTreeIDs(w, roots, files, nil)
+}
 
-		if _, exists := groups[root]; !exists {
-			groups[root] = &rootGroup{label: root, files: []string{}}
-			order = append(order, root)
-		}
-		groups[root].files = append(groups[root].files, rel)
-	}
+// WriteTreeIDs writes the tree like WriteTree, with each file's --ids ID
+// after its name ("-app.go [F1]").
+func WriteTreeIDs(w io.Writer, roots []string, files []string, ids map[string]string) {
+	DrawTree(w, buildTree(roots, files, ids, false))
+}
 
-	// Print tree for each root
-	for i, rootKey := range order {
+// DrawTree writes nodes as WriteTree does, one tree per root separated by a
+// blank line, with a dash per level.
+func DrawTree(w io.Writer, roots []*TreeNode) {
+	for i, root := range roots {
 		if i > 0 {
 			fmt.Fprintln(w)
 		}
+		fmt.Fprintf(w, "%s/\n", root.Name)
+		drawNodes(w, root.Children, 1)
+	}
+}
 
-		group := groups[rootKey]
-		label := filepath.Base(group.label)
-		if group.label == "." {
-			label = "."
+func drawNodes(w io.Writer, nodes []*TreeNode, depth int) {
+	for _, node := range nodes {
+		name := node.Name
+		if node.Type == "directory" {
+			name += "/"
+		}
+		if node.ID != "" {
+			name += " [" + node.ID + "]"
+		}
+		if node.Excluded != "" {
+			name += " [excluded: " + node.Excluded + "]"
 		}
-		fmt.Fprintf(w, "%s/\n", label)
+		fmt.Fprintf(w, "%s%s\n", strings.Repeat("-", depth), name)
+		drawNodes(w, node.Children, depth+1)
+	}
+}
 
-		seenDirs := make(map[string]bool)
+// DiffLabel is the header for a file's diff section. The bracketed suffix
+// lets unpack tell diff sections apart from file contents.
+func DiffLabel(label, ref string) string {
+	return label + " [diff vs " + ref + "]"
+}
 
-		for _, relPath := range group.files {
-			// Print directory hierarchy
-			parts := strings.Split(relPath, string(filepath.Separator))
-			accum := ""
-			for i := 0; i < len(parts)-1; i++ {
-				if accum != "" {
-					accum += string(filepath.Separator)
-				}
-				accum += parts[i]
+// RunLabel is the header plain output puts between runs appended to one
+// file with --append-file, with the time the run began unless it is "".
+// unpack skips such sections.
+func RunLabel(at string) string {
+	return strings.TrimSpace("CLIPCAT RUN " + at)
+}
 
-				if !seenDirs[accum] {
-					seenDirs[accum] = true
-					depth := i + 1
-					fmt.Fprintf(w, "%s%s/\n", strings.Repeat("-", depth), parts[i])
-				}
-			}
+// IsRunLabel reports whether a header was produced by RunLabel.
+func IsRunLabel(header string) bool {
+	return header == "CLIPCAT RUN" || strings.HasPrefix(header, "CLIPCAT RUN ")
+}
 
-			// Print file
-			depth := len(parts)
-			fmt.Fprintf(w, "%s%s\n", strings.Repeat("-", depth), parts[len(parts)-1])
-		}
-	}
-}
\ No newline at end of file
+// IsDiffLabel reports whether a header was produced by DiffLabel.
+func IsDiffLabel(header string) bool {
+	return strings.HasSuffix(header, "]") && strings.Contains(header, " [diff vs ")
+}
@@ -0,0 +1,74 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Modes for --json.
+const (
+	JSONKeep   = "keep"
+	JSONPretty = "pretty"
+	JSONMinify = "minify"
+)
+
+// yamlBlockRe matches a line opening a block scalar ("key: |", "- >-").
+var yamlBlockRe = regexp.MustCompile(`(^|[\s:-])[|>][-+0-9]*$`)
+
+// ReformatData returns a Filter that re-indents (JSONPretty) or compacts
+// (JSONMinify) .json files, and drops comment and blank lines from .yaml
+// and .yml files with JSONMinify. Files that are not valid JSON, and YAML
+// with JSONPretty, are left unchanged.
+func ReformatData(mode string) Filter {
+	return func(path string, content []byte) []byte {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".json":
+			if !json.Valid(content) {
+				return content
+			}
+			var buf bytes.Buffer
+			switch mode {
+			case JSONPretty:
+				json.Indent(&buf, content, "", "  ")
+			case JSONMinify:
+				json.Compact(&buf, content)
+			default:
+				return content
+			}
+			buf.WriteByte('\n')
+			return buf.Bytes()
+		case ".yaml", ".yml":
+			if mode == JSONMinify {
+				return minifyYAML(content)
+			}
+		}
+		return content
+	}
+}
+
+// minifyYAML removes blank lines, comment lines and trailing whitespace,
+// keeping the lines of block scalars, where they are content.
+func minifyYAML(content []byte) []byte {
+	var buf bytes.Buffer
+	block := -1 // indentation of the line opening the current block scalar
+	for _, line := range strings.SplitAfter(string(content), "\n") {
+		trimmed := strings.TrimSpace(line)
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		if block >= 0 && (trimmed == "" || indent > block) {
+			buf.WriteString(line)
+			continue
+		}
+		block = -1
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		buf.WriteString(strings.TrimRight(line, " \t\r\n") + "\n")
+		if yamlBlockRe.MatchString(trimmed) {
+			block = indent
+		}
+	}
+	return buf.Bytes()
+}
\ No newline at end of file
@@ -0,0 +1,155 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// repomixFormatter follows the default (XML) layout of Repomix: a file
+// summary, the directory structure and a <file path="..."> block per file.
+// Like Repomix, it does not escape file contents. The directory structure
+// is always written, so the document is built in EndDocument.
+type repomixFormatter struct {
+	files []File
+}
+
+const repomixSummary = `This file is a merged representation of the selected files, combined into a single document by clipcat in the Repomix layout.
+
+<file_summary>
+This section contains a summary of this file.
+
+<purpose>
+This file contains a packed representation of the repository's contents.
+It is designed to be easily consumable by AI systems for analysis, code review,
+or other automated processes.
+</purpose>
+
+<file_format>
+The content is organized as follows:
+1. This summary section
+2. Directory structure
+3. Repository files, each consisting of:
+  - File path as an attribute
+  - Full contents of the file
+</file_format>
+
+<usage_guidelines>
+- This file should be treated as read-only. Any changes should be made to the
+  original repository files, not this packed version.
+- When processing this file, use the file path to distinguish
+  between different files in the repository.
+- Be aware that this file may contain sensitive information. Handle it with
+  the same level of security as you would the original repository.
+</usage_guidelines>
+
+<notes>
+- Some files may have been excluded based on exclude patterns and .gitignore rules
+- Binary files are not included in this packed representation
+</notes>
+
+</file_summary>
+
+`
+
+func (*repomixFormatter) BeginDocument(w io.Writer) error { return nil }
+
+func (*repomixFormatter) WriteTree(w io.Writer, roots []string, files []string) error { return nil }
+
+func (f *repomixFormatter) WriteFile(w io.Writer, file File) error {
+	f.files = append(f.files, file)
+	return nil
+}
+
+func (f *repomixFormatter) EndDocument(w io.Writer) error {
+	io.WriteString(w, repomixSummary)
+
+	paths := make([]string, len(f.files))
+	for i, file := range f.files {
+		paths[i] = displayPath(file.Path)
+	}
+	fmt.Fprintf(w, "<directory_structure>\n%s</directory_structure>\n\n", indentedTree(paths))
+
+	io.WriteString(w, "<files>\nThis section contains the contents of the repository's files.\n\n")
+	for i, file := range f.files {
+		if file.Unreadable || file.Removed || file.DiffRef != "" {
+			continue
+		}
+		fmt.Fprintf(w, "<file path=\"%s\">\n", xmlEscaper.Replace(paths[i]))
+		w.Write(file.Content)
+		if len(file.Content) > 0 && file.Content[len(file.Content)-1] != '\n' {
+			io.WriteString(w, "\n")
+		}
+		io.WriteString(w, "</file>\n\n")
+	}
+	_, err := io.WriteString(w, "</files>\n")
+	return err
+}
+
+// displayPath shows absolute paths under the working directory relative to
+// it, as repo-packing tools do; URLs and other paths are kept.
+func displayPath(path string) string {
+	if !filepath.IsAbs(path) {
+		return filepath.ToSlash(path)
+	}
+	if wd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(wd, path); err == nil && filepath.IsLocal(rel) {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return filepath.ToSlash(path)
+}
+
+// indentedTree renders slash-separated paths as a tree indented by two
+// spaces per level, directories first and then by name ignoring case, e.g.
+// "src/\n  app.go\n".
+func indentedTree(paths []string) string {
+	type node struct {
+		children map[string]*node
+	}
+	root := &node{children: map[string]*node{}}
+	for _, path := range paths {
+		n := root
+		for _, part := range strings.Split(path, "/") {
+			if part == "" {
+				continue
+			}
+			child, ok := n.children[part]
+			if !ok {
+				child = &node{children: map[string]*node{}}
+				n.children[part] = child
+			}
+			n = child
+		}
+	}
+
+	var sb strings.Builder
+	var walk func(n *node, depth int)
+	walk = func(n *node, depth int) {
+		names := make([]string, 0, len(n.children))
+		for name := range n.children {
+			names = append(names, name)
+		}
+		sort.Slice(names, func(i, j int) bool {
+			di, dj := len(n.children[names[i]].children) > 0, len(n.children[names[j]].children) > 0
+			if di != dj {
+				return di
+			}
+			return strings.ToLower(names[i]) < strings.ToLower(names[j])
+		})
+		for _, name := range names {
+			child := n.children[name]
+			if len(child.children) > 0 {
+				fmt.Fprintf(&sb, "%s%s/\n", strings.Repeat("  ", depth), name)
+				walk(child, depth+1)
+			} else {
+				fmt.Fprintf(&sb, "%s%s\n", strings.Repeat("  ", depth), name)
+			}
+		}
+	}
+	walk(root, 0)
+	return sb.String()
+}
\ No newline at end of file
@@ -0,0 +1,108 @@
+package output
+
+import (
+	"cmp"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Sort orders accepted by SortPaths
+const (
+	SortNatural = "natural"
+	SortLexical = "lexical"
+)
+
+// SortPaths orders paths in place. The natural order (the default) compares
+// paths segment by segment with numbers compared by value, so file2 sorts
+// before file10, and lists a directory's files before its subdirectories,
+// keeping each directory's files together. SortLexical is plain byte order.
+func SortPaths(paths []string, mode string) {
+	SortPathsCollated(paths, mode, CollateByte)
+}
+
+// SortPathsCollated is SortPaths with names compared under collation:
+// CollateByte compares bytes, CollateUnicode compares letters regardless of
+// case and accents, and CollateLocale also follows the alphabet of the
+// locale's language, e.g. å after z in Swedish.
+func SortPathsCollated(paths []string, mode, collation string) {
+	compare := newCollator(collation)
+	if mode == SortLexical {
+		sort.SliceStable(paths, func(i, j int) bool {
+			return compare(paths[i], paths[j]) < 0
+		})
+		return
+	}
+	sort.SliceStable(paths, func(i, j int) bool {
+		return pathLess(paths[i], paths[j], compare)
+	})
+}
+
+func pathLess(a, b string, compare func(a, b string) int) bool {
+	as := strings.Split(filepath.ToSlash(a), "/")
+	bs := strings.Split(filepath.ToSlash(b), "/")
+
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		aFile, bFile := i == len(as)-1, i == len(bs)-1
+		if aFile != bFile {
+			// Files come before the subdirectories next to them
+			return aFile
+		}
+		if as[i] != bs[i] {
+			return naturalCompare(as[i], bs[i], compare) < 0
+		}
+	}
+	return len(as) < len(bs)
+}
+
+// NaturalLess compares strings with runs of digits compared by numeric value.
+func NaturalLess(a, b string) bool {
+	return naturalCompare(a, b, strings.Compare) < 0
+}
+
+// naturalCompare is NaturalLess as a comparison, with the runs between
+// numbers compared by compare.
+func naturalCompare(a, b string, compare func(a, b string) int) int {
+	for a != "" && b != "" {
+		aNum, bNum := isDigit(a[0]), isDigit(b[0])
+		if aNum != bNum {
+			return strings.Compare(a, b)
+		}
+
+		var aRun, bRun string
+		aRun, a = splitRun(a, aNum)
+		bRun, b = splitRun(b, bNum)
+		if aRun == bRun {
+			continue
+		}
+		if !aNum {
+			if n := compare(aRun, bRun); n != 0 {
+				return n
+			}
+			continue
+		}
+
+		// Compare numbers by value, then prefer fewer leading zeros
+		aTrim, bTrim := strings.TrimLeft(aRun, "0"), strings.TrimLeft(bRun, "0")
+		if len(aTrim) != len(bTrim) {
+			return cmp.Compare(len(aTrim), len(bTrim))
+		}
+		if aTrim != bTrim {
+			return strings.Compare(aTrim, bTrim)
+		}
+		return cmp.Compare(len(aRun), len(bRun))
+	}
+	return cmp.Compare(len(a), len(b))
+}
+
+func splitRun(s string, digits bool) (string, string) {
+	i := 0
+	for i < len(s) && isDigit(s[i]) == digits {
+		i++
+	}
+	return s[:i], s[i:]
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
\ No newline at end of file
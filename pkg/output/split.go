@@ -0,0 +1,75 @@
+package output
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// EstimateTokens approximates the LLM token count of data at ~4 bytes per token.
+// Package tokens has closer, per-model estimates.
+func EstimateTokens(data []byte) int {
+	return (len(data) + 3) / 4
+}
+
+// Split packs sections (the tree and each file block) into chunks whose
+// measure stays within limit. Sections are kept whole where possible; a
+// section that is too large on its own is broken at line boundaries, and a
+// single oversized line is broken wherever the limit falls.
+func Split(sections [][]byte, limit int, measure func([]byte) int) [][]byte {
+	var chunks [][]byte
+	var current []byte
+
+	flush := func() {
+		if len(current) > 0 {
+			chunks = append(chunks, current)
+			current = nil
+		}
+	}
+
+	appendPiece := func(piece []byte) {
+		if len(current) > 0 && measure(current)+measure(piece) > limit {
+			flush()
+		}
+		current = append(current, piece...)
+	}
+
+	for _, section := range sections {
+		if measure(section) <= limit {
+			appendPiece(section)
+			continue
+		}
+
+		for _, line := range bytes.SplitAfter(section, []byte("\n")) {
+			for measure(line) > limit {
+				cut := cutIndex(line, limit, measure)
+				appendPiece(line[:cut])
+				line = line[cut:]
+			}
+			if len(line) > 0 {
+				appendPiece(line)
+			}
+		}
+	}
+	flush()
+
+	return chunks
+}
+
+// cutIndex finds the longest prefix of line whose measure fits within limit.
+func cutIndex(line []byte, limit int, measure func([]byte) int) int {
+	lo, hi := 1, len(line)
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if measure(line[:mid]) <= limit {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	return lo
+}
+
+// PartBanner is written at the top of each chunk produced by Split.
+func PartBanner(part, total int) string {
+	return fmt.Sprintf("[part %d/%d]\n\n", part, total)
+}
@@ -0,0 +1,106 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Summary describes how a document was produced, for --summary.
+type Summary struct {
+	Generated time.Time // left out when zero, e.g. for --deterministic
+	Version   string
+	Roots     []string
+	Files     int
+	Lines     int
+	Tokens    int
+	Model     string // tokenizer the token count was estimated for
+	Excludes  []string
+}
+
+// SummaryWriter is implemented by formatters that can render a --summary
+// block. It is written right after BeginDocument; formatters without it
+// leave the summary out.
+type SummaryWriter interface {
+	WriteSummary(w io.Writer, s Summary) error
+}
+
+// fields lists the summary as key/value pairs in display order.
+func (s Summary) fields() [][2]string {
+	excludes := "none"
+	if len(s.Excludes) > 0 {
+		excludes = strings.Join(s.Excludes, ", ")
+	}
+	var fields [][2]string
+	if !s.Generated.IsZero() {
+		fields = append(fields, [2]string{"generated", s.Generated.Format(time.RFC3339)})
+	}
+	return append(fields, [][2]string{
+		{"clipcat", s.Version},
+		{"roots", strings.Join(s.Roots, ", ")},
+		{"files", fmt.Sprint(s.Files)},
+		{"lines", fmt.Sprint(s.Lines)},
+		{"tokens", fmt.Sprintf("~%d (%s)", s.Tokens, s.Model)},
+		{"excludes", excludes},
+	}...)
+}
+
+func (plainFormatter) WriteSummary(w io.Writer, s Summary) error {
+	WriteHeader(w, "SUMMARY")
+	for _, kv := range s.fields() {
+		fmt.Fprintf(w, "%-10s %s\n", kv[0]+":", kv[1])
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// WriteSummary writes YAML front matter, which markdown tools understand.
+func (markdownFormatter) WriteSummary(w io.Writer, s Summary) error {
+	io.WriteString(w, "---\n")
+	for _, kv := range s.fields() {
+		value := kv[1]
+		if kv[0] != "files" && kv[0] != "lines" {
+			value = fmt.Sprintf("%q", value)
+		}
+		fmt.Fprintf(w, "%s: %s\n", kv[0], value)
+	}
+	_, err := io.WriteString(w, "---\n\n")
+	return err
+}
+
+func (*xmlFormatter) WriteSummary(w io.Writer, s Summary) error {
+	io.WriteString(w, "<summary")
+	for _, kv := range s.fields() {
+		fmt.Fprintf(w, " %s=\"%s\"", kv[0], xmlEscaper.Replace(kv[1]))
+	}
+	_, err := io.WriteString(w, "/>\n")
+	return err
+}
+
+func (f *jsonFormatter) WriteSummary(w io.Writer, s Summary) error {
+	f.doc.Summary = &jsonSummary{
+		Version:   s.Version,
+		Roots:     s.Roots,
+		Files:     s.Files,
+		Lines:     s.Lines,
+		Tokens:    s.Tokens,
+		Model:     s.Model,
+		Excludes:  s.Excludes,
+	}
+	if !s.Generated.IsZero() {
+		f.doc.Summary.Generated = s.Generated.Format(time.RFC3339)
+	}
+	return nil
+}
+
+type jsonSummary struct {
+	Generated string   `json:"generated,omitempty"`
+	Version   string   `json:"clipcat"`
+	Roots     []string `json:"roots"`
+	Files     int      `json:"files"`
+	Lines     int      `json:"lines"`
+	Tokens    int      `json:"tokens"`
+	Model     string   `json:"model"`
+	Excludes  []string `json:"excludes"`
+}
\ No newline at end of file
@@ -0,0 +1,114 @@
+package output
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// tableCellWidth caps the width of a --table-preview column; longer cells
+// are cut with an ellipsis.
+const tableCellWidth = 40
+
+// IsTable reports whether path is a CSV or TSV file.
+func IsTable(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv", ".tsv":
+		return true
+	}
+	return false
+}
+
+// ReadTablePreview reads the CSV (or TSV, by path's extension) from r and
+// returns its header and first rows as an aligned table, followed by the
+// total row count. The remaining rows are counted, not kept, so huge files
+// can be streamed.
+func ReadTablePreview(path string, r io.Reader, rows int) ([]byte, error) {
+	cr := csv.NewReader(r)
+	if strings.EqualFold(filepath.Ext(path), ".tsv") {
+		cr.Comma = '\t'
+	}
+	cr.FieldsPerRecord = -1
+	cr.ReuseRecord = true
+
+	var kept [][]string
+	total := -1 // the header is not a row
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if total < rows {
+			kept = append(kept, append([]string(nil), record...))
+		}
+		total++
+	}
+	if len(kept) == 0 {
+		return nil, nil
+	}
+
+	columns := 0
+	for _, record := range kept {
+		columns = max(columns, len(record))
+	}
+	widths := make([]int, columns)
+	for _, record := range kept {
+		for i, cell := range record {
+			record[i] = tableCell(cell)
+			widths[i] = max(widths[i], utf8.RuneCountInString(record[i]))
+		}
+	}
+
+	var buf bytes.Buffer
+	for n, record := range kept {
+		var line strings.Builder
+		for i := range columns {
+			cell := ""
+			if i < len(record) {
+				cell = record[i]
+			}
+			if i > 0 {
+				line.WriteString(" | ")
+			}
+			line.WriteString(cell + strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell)))
+		}
+		buf.WriteString(strings.TrimRight(line.String(), " ") + "\n")
+		if n == 0 {
+			for i, w := range widths {
+				if i > 0 {
+					buf.WriteString("-+-")
+				}
+				buf.WriteString(strings.Repeat("-", w))
+			}
+			buf.WriteByte('\n')
+		}
+	}
+
+	unit := "rows"
+	if total == 1 {
+		unit = "row"
+	}
+	if shown := len(kept) - 1; shown < total {
+		fmt.Fprintf(&buf, "[table: %s %s, first %d shown]\n", groupDigits(total), unit, shown)
+	} else {
+		fmt.Fprintf(&buf, "[table: %s %s]\n", groupDigits(total), unit)
+	}
+	return buf.Bytes(), nil
+}
+
+// tableCell flattens a cell to one line of at most tableCellWidth runes.
+func tableCell(cell string) string {
+	cell = strings.Join(strings.Fields(cell), " ")
+	if utf8.RuneCountInString(cell) <= tableCellWidth {
+		return cell
+	}
+	return string([]rune(cell)[:tableCellWidth-1]) + "…"
+}
\ No newline at end of file
@@ -0,0 +1,162 @@
+package output
+
+import (
+	"bytes"
+	"clipcat/pkg/lang"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// TemplateDocument is the data a --template is executed with.
+type TemplateDocument struct {
+	Tree     string // the FILE HIERARCHY, when -t was given
+	Roots    []string
+	Files    []TemplateFile
+	Summary  *Summary // set with --summary
+	Manifest []ManifestEntry
+}
+
+// TemplateFile is one section of a TemplateDocument.
+type TemplateFile struct {
+	ID         string // with --ids, e.g. "F12"
+	Path       string
+	Dir        string // directory part of Path, "." for top-level files
+	Ext        string // extension without the dot, e.g. "go"
+	Lang       string // fence language, as the markdown format would use
+	Meta       string
+	DiffRef    string
+	Content    string
+	Unreadable bool
+	Error      string // why the file is Unreadable
+	Removed    bool
+}
+
+// TemplateGroup is a run of files sharing a key, as returned by groupBy.
+type TemplateGroup struct {
+	Key   string
+	Files []TemplateFile
+}
+
+// templateFuncs are available to every --template besides the text/template
+// builtins.
+var templateFuncs = template.FuncMap{
+	"groupBy": groupBy,
+	"fence":   func(content string) string { return codeFence([]byte(content)) },
+	"indent":  indent,
+	"join":    strings.Join,
+	"lower":   strings.ToLower,
+	"upper":   strings.ToUpper,
+	"trim":    strings.TrimSpace,
+}
+
+// ParseTemplate reads and parses a --template file.
+func ParseTemplate(path string) (*template.Template, error) {
+	text, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return template.New(filepath.Base(path)).Funcs(templateFuncs).Parse(string(text))
+}
+
+// templateFormatter collects the document and executes a template over it
+// in EndDocument, so the template decides where everything goes.
+type templateFormatter struct {
+	tmpl *template.Template
+	doc  TemplateDocument
+}
+
+// NewTemplateFormatter returns a Formatter that renders the whole document
+// with tmpl, which is executed with a TemplateDocument.
+func NewTemplateFormatter(tmpl *template.Template) Formatter {
+	return &templateFormatter{tmpl: tmpl}
+}
+
+func (f *templateFormatter) BeginDocument(w io.Writer) error { return nil }
+
+func (f *templateFormatter) WriteTree(w io.Writer, roots []string, files []string) error {
+	var tree bytes.Buffer
+	WriteTree(&tree, roots, files)
+	f.doc.Tree, f.doc.Roots = tree.String(), roots
+	return nil
+}
+
+func (f *templateFormatter) WriteFile(w io.Writer, file File) error {
+	info := lang.Detect(file.Path, file.Content)
+	if file.DiffRef != "" {
+		info = "diff"
+	}
+	f.doc.Files = append(f.doc.Files, TemplateFile{
+		ID:         file.ID,
+		Path:       file.Path,
+		Dir:        filepath.Dir(file.Path),
+		Ext:        strings.TrimPrefix(filepath.Ext(file.Path), "."),
+		Lang:       info,
+		Meta:       file.Meta,
+		DiffRef:    file.DiffRef,
+		Content:    string(file.Content),
+		Unreadable: file.Unreadable,
+		Error:      file.Error,
+		Removed:    file.Removed,
+	})
+	return nil
+}
+
+func (f *templateFormatter) WriteSummary(w io.Writer, s Summary) error {
+	f.doc.Summary = &s
+	return nil
+}
+
+func (f *templateFormatter) WriteManifest(w io.Writer, entries []ManifestEntry) error {
+	f.doc.Manifest = entries
+	return nil
+}
+
+func (f *templateFormatter) EndDocument(w io.Writer) error {
+	return f.tmpl.Execute(w, f.doc)
+}
+
+// groupBy splits files into runs sharing a directory ("dir"), extension
+// ("ext") or language ("lang"), in order of first appearance.
+func groupBy(key string, files []TemplateFile) ([]TemplateGroup, error) {
+	var keyOf func(TemplateFile) string
+	switch key {
+	case "dir":
+		keyOf = func(f TemplateFile) string { return f.Dir }
+	case "ext":
+		keyOf = func(f TemplateFile) string { return f.Ext }
+	case "lang":
+		keyOf = func(f TemplateFile) string { return f.Lang }
+	default:
+		return nil, fmt.Errorf("groupBy: unknown key %q (expected dir, ext or lang)", key)
+	}
+
+	var groups []TemplateGroup
+	index := make(map[string]int)
+	for _, file := range files {
+		k := keyOf(file)
+		i, ok := index[k]
+		if !ok {
+			i = len(groups)
+			index[k] = i
+			groups = append(groups, TemplateGroup{Key: k})
+		}
+		groups[i].Files = append(groups[i].Files, file)
+	}
+	return groups, nil
+}
+
+// indent prefixes every non-empty line of s with n spaces.
+func indent(n int, s string) string {
+	pad := strings.Repeat(" ", n)
+	lines := strings.SplitAfter(s, "\n")
+	for i, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			lines[i] = pad + line
+		}
+	}
+	return strings.Join(lines, "")
+}
\ No newline at end of file
@@ -0,0 +1,220 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Tree formats accepted by --tree-format
+const (
+	TreeText = "text"
+	TreeJSON = "json"
+)
+
+// TreeFormats lists the accepted tree formats, the default first.
+var TreeFormats = []string{TreeText, TreeJSON}
+
+// TreeNode is a file or directory of the hierarchy for --tree-format json.
+// A directory's size is the total of the files under it.
+type TreeNode struct {
+	Name     string      `json:"name"`
+	Type     string      `json:"type"` // file or directory
+	Size     int64       `json:"size"`
+	ID       string      `json:"id,omitempty"`       // with --ids
+	Excluded string      `json:"excluded,omitempty"` // the rule, with --tree-show-excluded
+	Children []*TreeNode `json:"children,omitempty"`
+}
+
+// Exclusion is a file, or a directory that was not walked, which an
+// exclude rule kept out of the output.
+type Exclusion struct {
+	Path string
+	Dir  bool
+	Rule string
+}
+
+// NodeTreeWriter is implemented by formatters that can render the tree
+// from nodes, drawn as text or as JSON for --tree-format json.
+type NodeTreeWriter interface {
+	WriteTreeNodes(w io.Writer, roots []*TreeNode, format string) error
+}
+
+// BuildTree returns the hierarchy WriteTreeIDs draws, one node per root,
+// with sizes read from disk. ids may be nil. The excluded entries follow
+// the files of their directory and add nothing to its size.
+func BuildTree(roots []string, files []string, ids map[string]string, excluded []Exclusion) []*TreeNode {
+	t := &treeBuilder{paths: roots, dirs: make(map[string]*TreeNode)}
+	for _, file := range files {
+		t.add(file, true, ids[file])
+	}
+	for _, e := range excluded {
+		node, _ := t.node(e.Path, e.Dir)
+		if node == nil {
+			continue
+		}
+		node.Excluded = e.Rule
+		if info, err := os.Stat(e.Path); err == nil && !e.Dir {
+			node.Size = info.Size()
+		}
+	}
+	return t.roots
+}
+
+// buildTree is BuildTree without exclusions, and without sizes unless
+// sized is set.
+func buildTree(roots []string, files []string, ids map[string]string, sized bool) []*TreeNode {
+	t := &treeBuilder{paths: roots, dirs: make(map[string]*TreeNode)}
+	for _, file := range files {
+		t.add(file, sized, ids[file])
+	}
+	return t.roots
+}
+
+type treeBuilder struct {
+	paths []string             // the inputs, for treeRoot
+	roots []*TreeNode          // one per tree, in order
+	dirs  map[string]*TreeNode // by absolute path, roots included
+}
+
+// add puts file in the tree, adding its size to every directory above it.
+func (t *treeBuilder) add(file string, sized bool, id string) {
+	node, above := t.node(file, false)
+	if node == nil {
+		return
+	}
+	node.ID = id
+	if !sized {
+		return
+	}
+	if info, err := os.Stat(file); err == nil {
+		node.Size = info.Size()
+		for _, dir := range above {
+			dir.Size += node.Size
+		}
+	}
+}
+
+// node returns the node for path, creating it and the directories above
+// it, which it also returns from the root down. The node is nil when path
+// is the root of its tree.
+func (t *treeBuilder) node(path string, isDir bool) (*TreeNode, []*TreeNode) {
+	label, root, rel := treeRoot(path, t.paths)
+	if rel == "." {
+		return nil, nil
+	}
+	parent, ok := t.dirs[root]
+	if !ok {
+		name := filepath.Base(label)
+		if label == "." {
+			name = "."
+		}
+		parent = &TreeNode{Name: name, Type: "directory"}
+		t.dirs[root] = parent
+		t.roots = append(t.roots, parent)
+	}
+	above := []*TreeNode{parent}
+
+	parts := strings.Split(rel, string(filepath.Separator))
+	dir := root
+	for i, part := range parts {
+		dir = filepath.Join(dir, part)
+		last := i == len(parts)-1
+		if last && !isDir {
+			node := &TreeNode{Name: part, Type: "file"}
+			parent.Children = append(parent.Children, node)
+			return node, above
+		}
+		node, ok := t.dirs[dir]
+		if !ok {
+			node = &TreeNode{Name: part, Type: "directory"}
+			t.dirs[dir] = node
+			parent.Children = append(parent.Children, node)
+		}
+		if last {
+			return node, above
+		}
+		above = append(above, node)
+		parent = node
+	}
+	return nil, nil
+}
+
+// WriteTreeJSON writes the nodes as an indented JSON array.
+func WriteTreeJSON(w io.Writer, roots []*TreeNode) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if roots == nil {
+		roots = []*TreeNode{}
+	}
+	return enc.Encode(roots)
+}
+
+// drawTreeAs renders roots in format, TreeText or TreeJSON.
+func drawTreeAs(roots []*TreeNode, format string) ([]byte, error) {
+	var tree bytes.Buffer
+	if format == TreeJSON {
+		err := WriteTreeJSON(&tree, roots)
+		return tree.Bytes(), err
+	}
+	DrawTree(&tree, roots)
+	return tree.Bytes(), nil
+}
+
+func (plainFormatter) WriteTreeNodes(w io.Writer, roots []*TreeNode, format string) error {
+	tree, err := drawTreeAs(roots, format)
+	if err != nil {
+		return err
+	}
+	WriteHeader(w, "FILE HIERARCHY")
+	w.Write(tree)
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+func (markdownFormatter) WriteTreeNodes(w io.Writer, roots []*TreeNode, format string) error {
+	tree, err := drawTreeAs(roots, format)
+	if err != nil {
+		return err
+	}
+	fence := ""
+	if format == TreeJSON {
+		fence = "json"
+	}
+	_, err = fmt.Fprintf(w, "## File hierarchy\n\n```%s\n%s```\n\n", fence, tree)
+	return err
+}
+
+func (*xmlFormatter) WriteTreeNodes(w io.Writer, roots []*TreeNode, format string) error {
+	tree, err := drawTreeAs(roots, format)
+	if err != nil {
+		return err
+	}
+	attr := ""
+	if format == TreeJSON {
+		attr = ` format="json"`
+	}
+	_, err = fmt.Fprintf(w, "<tree%s>\n%s</tree>\n", attr, xmlEscaper.Replace(string(tree)))
+	return err
+}
+
+func (f *jsonFormatter) WriteTreeNodes(w io.Writer, roots []*TreeNode, format string) error {
+	if format != TreeJSON {
+		var tree bytes.Buffer
+		DrawTree(&tree, roots)
+		if tree.Len() > 0 {
+			f.doc.Tree = tree.String()
+		}
+		return nil
+	}
+	if roots == nil {
+		roots = []*TreeNode{}
+	}
+	f.doc.Tree = roots
+	return nil
+}
\ No newline at end of file
@@ -0,0 +1,18 @@
+package output
+
+import (
+	"clipcat/pkg/collector"
+	"io"
+)
+
+// WarningWriter is implemented by formatters that keep the collection
+// warnings in the document, for programs reading it. The others leave them
+// to stderr.
+type WarningWriter interface {
+	WriteWarnings(w io.Writer, warnings []collector.Warning) error
+}
+
+func (f *jsonFormatter) WriteWarnings(w io.Writer, warnings []collector.Warning) error {
+	f.doc.Warnings = warnings
+	return nil
+}
\ No newline at end of file
@@ -0,0 +1,98 @@
+package remote
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// GitHubSpec identifies what to fetch for `clipcat gh`: either a repository
+// subtree written as owner/repo[/path][@ref], or a gist written as gist:ID.
+type GitHubSpec struct {
+	Owner string
+	Repo  string
+	Path  string
+	Ref   string
+	Gist  string
+}
+
+func ParseGitHubSpec(s string) (*GitHubSpec, error) {
+	spec := &GitHubSpec{}
+
+	if id, ok := strings.CutPrefix(s, "gist:"); ok {
+		if id == "" {
+			return nil, fmt.Errorf("missing gist ID in %q", s)
+		}
+		spec.Gist = id
+		return spec, nil
+	}
+
+	rest := s
+	if i := strings.LastIndex(rest, "@"); i >= 0 {
+		spec.Ref = rest[i+1:]
+		rest = rest[:i]
+		if spec.Ref == "" {
+			return nil, fmt.Errorf("empty ref in %q", s)
+		}
+	}
+
+	parts := strings.SplitN(strings.Trim(rest, "/"), "/", 3)
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("expected owner/repo[/path][@ref] or gist:ID, got %q", s)
+	}
+	spec.Owner = parts[0]
+	spec.Repo = strings.TrimSuffix(parts[1], ".git")
+	if len(parts) == 3 {
+		spec.Path = parts[2]
+	}
+	return spec, nil
+}
+
+func (s *GitHubSpec) CloneURL() string {
+	if s.Gist != "" {
+		return "https://gist.github.com/" + s.Gist + ".git"
+	}
+	return "https://github.com/" + s.Owner + "/" + s.Repo + ".git"
+}
+
+// String returns the label used in place of the temporary checkout directory
+// in file headers.
+func (s *GitHubSpec) String() string {
+	label := "gist:" + s.Gist
+	if s.Gist == "" {
+		label = s.Owner + "/" + s.Repo
+	}
+	if s.Ref != "" {
+		label += "@" + s.Ref
+	}
+	return label
+}
+
+// Clone shallow-fetches the spec's ref (or the default branch) into dir.
+// Fetching by ref instead of `git clone --branch` also works for commit SHAs.
+func Clone(spec *GitHubSpec, dir string) error {
+	ref := spec.Ref
+	if ref == "" {
+		ref = "HEAD"
+	}
+
+	steps := [][]string{
+		{"init", "-q", dir},
+		{"-C", dir, "remote", "add", "origin", spec.CloneURL()},
+		{"-C", dir, "fetch", "-q", "--depth", "1", "origin", ref},
+		{"-C", dir, "checkout", "-q", "FETCH_HEAD"},
+	}
+	for _, args := range steps {
+		cmd := exec.Command("git", args...)
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			if msg := strings.TrimSpace(stderr.String()); msg != "" {
+				return fmt.Errorf("fetching %s: %s", spec, msg)
+			}
+			return fmt.Errorf("fetching %s: %w", spec, err)
+		}
+	}
+	return nil
+}
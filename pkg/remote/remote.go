@@ -0,0 +1,53 @@
+package remote
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// IsURL reports whether an input path should be fetched over HTTP instead of
+// being resolved on the local filesystem.
+func IsURL(path string) bool {
+	return strings.HasPrefix(path, "https://") || strings.HasPrefix(path, "http://")
+}
+
+// Fetch downloads url and returns at most maxSize bytes of its body. When the
+// body is larger, the returned data is cut at maxSize and truncated is true.
+// A maxSize of zero or less disables the cap. The fetch stops when ctx is
+// done or timeout passes, whichever comes first.
+func Fetch(ctx context.Context, url string, timeout time.Duration, maxSize int64) (data []byte, truncated bool, err error) {
+	client := &http.Client{Timeout: timeout}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, false, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	if maxSize <= 0 {
+		data, err = io.ReadAll(resp.Body)
+		return data, false, err
+	}
+
+	// Read one byte past the cap so we can tell whether anything was cut
+	data, err = io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return nil, false, err
+	}
+	if int64(len(data)) > maxSize {
+		return data[:maxSize], true, nil
+	}
+	return data, false, nil
+}
@@ -0,0 +1,186 @@
+// Package tokens estimates how many LLM tokens a text takes up, for the
+// size summaries and --split-tokens budgets. The counts are estimates: no
+// vocabulary is bundled, but the text is split the way cl100k-family BPE
+// tokenizers pre-tokenize it, which tracks real counts far better than a
+// flat bytes-per-token ratio, especially for code.
+package tokens
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Counter returns the estimated token count of data.
+type Counter func(data []byte) int
+
+// DefaultModel is used when --model is not given.
+const DefaultModel = "gpt-4"
+
+// models maps a model name to how its tokenizer compares with cl100k on
+// typical source code. "bytes" keeps the old 4-bytes-per-token rule.
+var models = map[string]float64{
+	"gpt-4":      1.0,
+	"gpt-3.5":    1.0,
+	"gpt-4o":     0.92,
+	"gpt-4.1":    0.92,
+	"o1":         0.92,
+	"o3":         0.92,
+	"claude-3":   1.15,
+	"claude-3.5": 1.15,
+	"claude-4":   1.15,
+	"llama3":     0.97,
+	"llama2":     1.25,
+	"mistral":    1.25,
+	"gemini":     1.0,
+	"bytes":      0,
+}
+
+// Models lists the names accepted by ForModel.
+func Models() []string {
+	var names []string
+	for name := range models {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ForModel returns the counter for a model name; "" means DefaultModel.
+func ForModel(name string) (Counter, error) {
+	if name == "" {
+		name = DefaultModel
+	}
+	scale, ok := models[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown model %q (known: %s)", name, strings.Join(Models(), ", "))
+	}
+	if scale == 0 {
+		return Bytes, nil
+	}
+	return func(data []byte) int {
+		return int(math.Ceil(float64(Estimate(data)) * scale))
+	}, nil
+}
+
+// Bytes is the flat estimate of ~4 bytes per token.
+func Bytes(data []byte) int {
+	return (len(data) + 3) / 4
+}
+
+// Estimate approximates the cl100k token count of data. It splits the text
+// into the same kinds of pieces as the cl100k pre-tokenizer (words with
+// their leading space, digit groups of up to three, punctuation runs and
+// whitespace runs) and charges each piece what BPE typically spends on it.
+func Estimate(data []byte) int {
+	s := string(data)
+	count := 0
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		start := i
+
+		switch {
+		case r == '\n' || r == '\r':
+			// A run of line breaks and the indentation after it
+			for i < len(s) && (s[i] == '\n' || s[i] == '\r') {
+				i++
+			}
+			i = skipSpaces(s, i)
+			count++
+		case r == ' ' || r == '\t':
+			j := skipSpaces(s, i)
+			if j < len(s) && j-i == 1 && joinsSpace(s[j:]) {
+				// A single space joins the following word or punctuation
+				i = j
+				continue
+			}
+			i = j
+			count++
+		case unicode.IsLetter(r):
+			for i < len(s) {
+				r, size := utf8.DecodeRuneInString(s[i:])
+				if !unicode.IsLetter(r) {
+					break
+				}
+				i += size
+			}
+			count += wordCost(s[start:i])
+		case unicode.IsDigit(r):
+			n := 0
+			for i < len(s) {
+				r, size := utf8.DecodeRuneInString(s[i:])
+				if !unicode.IsDigit(r) {
+					break
+				}
+				i += size
+				n++
+			}
+			count += (n + 2) / 3
+		default:
+			n := 0
+			for i < len(s) {
+				r, size := utf8.DecodeRuneInString(s[i:])
+				if unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsSpace(r) {
+					break
+				}
+				i += size
+				n++
+			}
+			if n == 0 {
+				// Other whitespace such as form feeds
+				i += size
+				n = 1
+			}
+			count += (n + 1) / 2
+		}
+	}
+	return count
+}
+
+func skipSpaces(s string, i int) int {
+	for i < len(s) && (s[i] == ' ' || s[i] == '\t') {
+		i++
+	}
+	return i
+}
+
+func joinsSpace(s string) bool {
+	r, _ := utf8.DecodeRuneInString(s)
+	return !unicode.IsDigit(r) && !unicode.IsSpace(r)
+}
+
+// wordCost charges a run of letters: camelCase parts count separately,
+// common-length parts are one token and longer ones are split every eight
+// letters. Letters outside Latin scripts cost about one token each.
+func wordCost(word string) int {
+	cost := 0
+	part := 0
+	flush := func() {
+		if part > 0 {
+			cost += (part + 7) / 8
+			part = 0
+		}
+	}
+	prevLower := false
+	for _, r := range word {
+		switch {
+		case r >= 0x2E80:
+			// CJK and other large scripts
+			flush()
+			cost++
+		case r > unicode.MaxLatin1:
+			part += 2
+		default:
+			if prevLower && unicode.IsUpper(r) {
+				flush()
+			}
+			part++
+		}
+		prevLower = unicode.IsLower(r)
+	}
+	flush()
+	return cost
+}
\ No newline at end of file
@@ -0,0 +1,123 @@
+package unpack
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// The encodings accepted by --compress.
+const (
+	Gzip = "gzip"
+	Zstd = "zstd"
+)
+
+// Compressions lists the encodings accepted by --compress.
+var Compressions = []string{Gzip, Zstd}
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	// How base64 text of a gzip or zstd stream begins
+	gzipBase64 = []byte("H4sI")
+	zstdBase64 = []byte("KLUv/")
+)
+
+// NewWriter returns a writer that compresses into w with method, as base64
+// text when the destination holds text only, such as a gist or a
+// clipboard. Closing it flushes the stream; w itself is left open.
+func NewWriter(w io.Writer, method string, text bool) (io.WriteCloser, error) {
+	var layers []io.WriteCloser // the top first
+	if text {
+		enc := base64.NewEncoder(base64.StdEncoding, w)
+		layers, w = append(layers, enc), enc
+	}
+	switch method {
+	case Gzip:
+		layers = append([]io.WriteCloser{gzip.NewWriter(w)}, layers...)
+	case Zstd:
+		zw, err := zstd.NewWriter(w)
+		if err != nil {
+			return nil, err
+		}
+		layers = append([]io.WriteCloser{zw}, layers...)
+	default:
+		return nil, fmt.Errorf("unknown compression %q", method)
+	}
+	return stack(layers), nil
+}
+
+// stack writes to its first layer and closes them all in order, so each
+// flushes into the one below.
+type stack []io.WriteCloser
+
+func (s stack) Write(p []byte) (int, error) { return s[0].Write(p) }
+
+func (s stack) Close() error {
+	var errs []error
+	for _, l := range s {
+		errs = append(errs, l.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// Compress compresses data with method, as base64 text when the
+// destination holds text only.
+func Compress(data []byte, method string, text bool) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, method, text)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decompress returns the bundle in data written with --compress: gzip or
+// zstd, as they are or as base64 text, including streams appended to each
+// other. Anything else is returned as it is.
+func Decompress(data []byte) ([]byte, error) {
+	var r io.Reader
+	trimmed := bytes.TrimSpace(data)
+	switch {
+	case bytes.HasPrefix(data, gzipMagic), bytes.HasPrefix(data, zstdMagic):
+		r = bytes.NewReader(data)
+	case bytes.HasPrefix(trimmed, gzipBase64), bytes.HasPrefix(trimmed, zstdBase64):
+		r = base64.NewDecoder(base64.StdEncoding, bytes.NewReader(trimmed))
+	default:
+		return data, nil
+	}
+
+	// Both formats start with their magic, so peek at the decoded stream
+	var head [4]byte
+	n, _ := io.ReadFull(r, head[:])
+	r = io.MultiReader(bytes.NewReader(head[:n]), r)
+	var out []byte
+	var err error
+	if bytes.HasPrefix(head[:n], zstdMagic) {
+		var zr *zstd.Decoder
+		if zr, err = zstd.NewReader(r); err == nil {
+			out, err = io.ReadAll(zr)
+			zr.Close()
+		}
+	} else {
+		var gz *gzip.Reader
+		if gz, err = gzip.NewReader(r); err == nil {
+			out, err = io.ReadAll(gz)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("decompressing bundle: %w", err)
+	}
+	return out, nil
+}
\ No newline at end of file
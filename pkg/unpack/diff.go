@@ -0,0 +1,252 @@
+package unpack
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// maxEdits bounds the line diff; files that differ in more lines than this
+// are reported as entirely replaced rather than diffed line by line.
+const maxEdits = 4000
+
+// Change is a file that differs between two bundles.
+type Change struct {
+	Path     string
+	Kind     string // "added", "removed" or "changed"
+	Old, New []byte
+	Added    int // lines
+	Removed  int
+}
+
+// Compare matches the files of two bundles by path. It returns the
+// changed and added files in new's order, then the removed files in old's
+// order, and the number of files that are identical in both.
+func Compare(old, new []File) (changes []Change, unchanged int) {
+	before := make(map[string][]byte, len(old))
+	for _, f := range old {
+		before[f.Path] = f.Content
+	}
+	after := make(map[string]bool, len(new))
+	for _, f := range new {
+		after[f.Path] = true
+		content, ok := before[f.Path]
+		switch {
+		case !ok:
+			changes = append(changes, Change{Path: f.Path, Kind: "added", New: f.Content, Added: countLines(f.Content)})
+		case bytes.Equal(content, f.Content):
+			unchanged++
+		default:
+			c := Change{Path: f.Path, Kind: "changed", Old: content, New: f.Content}
+			for _, op := range diffLines(splitLines(content), splitLines(f.Content)) {
+				switch op.kind {
+				case '+':
+					c.Added++
+				case '-':
+					c.Removed++
+				}
+			}
+			changes = append(changes, c)
+		}
+	}
+	for _, f := range old {
+		if !after[f.Path] {
+			changes = append(changes, Change{Path: f.Path, Kind: "removed", Old: f.Content, Removed: countLines(f.Content)})
+		}
+	}
+	return changes, unchanged
+}
+
+// Unified returns the change as a unified diff with context lines around
+// each hunk, headed by --- and +++ lines naming the path.
+func (c Change) Unified(context int) []byte {
+	var buf bytes.Buffer
+	oldName, newName := "a/"+strings.TrimPrefix(c.Path, "/"), "b/"+strings.TrimPrefix(c.Path, "/")
+	switch c.Kind {
+	case "added":
+		oldName = "/dev/null"
+	case "removed":
+		newName = "/dev/null"
+	}
+	fmt.Fprintf(&buf, "--- %s\n+++ %s\n", oldName, newName)
+
+	ops := diffLines(splitLines(c.Old), splitLines(c.New))
+	for start := 0; start < len(ops); {
+		// A hunk runs from the first change until a gap of more than
+		// twice the context
+		first := slices.IndexFunc(ops[start:], func(o lineOp) bool { return o.kind != ' ' })
+		if first < 0 {
+			break
+		}
+		first += start
+		last := first
+		for i := first + 1; i < len(ops) && i-last <= 2*context; i++ {
+			if ops[i].kind != ' ' {
+				last = i
+			}
+		}
+		from, to := max(0, first-context), min(len(ops), last+1+context)
+
+		oldLine, newLine := 1, 1
+		for _, o := range ops[:from] {
+			if o.kind != '+' {
+				oldLine++
+			}
+			if o.kind != '-' {
+				newLine++
+			}
+		}
+		oldCount, newCount := 0, 0
+		for _, o := range ops[from:to] {
+			if o.kind != '+' {
+				oldCount++
+			}
+			if o.kind != '-' {
+				newCount++
+			}
+		}
+		fmt.Fprintf(&buf, "@@ -%s +%s @@\n", hunkRange(oldLine, oldCount), hunkRange(newLine, newCount))
+		for _, o := range ops[from:to] {
+			buf.WriteByte(o.kind)
+			buf.WriteString(o.line)
+			if !strings.HasSuffix(o.line, "\n") {
+				buf.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		start = to
+	}
+	return buf.Bytes()
+}
+
+// hunkRange formats a unified diff range; an empty range names the line
+// before it.
+func hunkRange(line, count int) string {
+	if count == 0 {
+		line--
+	}
+	if count == 1 {
+		return fmt.Sprint(line)
+	}
+	return fmt.Sprintf("%d,%d", line, count)
+}
+
+// lineOp is one line of an edit script: ' ' kept, '-' removed, '+' added.
+type lineOp struct {
+	kind byte
+	line string
+}
+
+func splitLines(content []byte) []string {
+	if len(content) == 0 {
+		return nil
+	}
+	lines := strings.SplitAfter(string(content), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+func countLines(content []byte) int {
+	n := bytes.Count(content, []byte("\n"))
+	if len(content) > 0 && content[len(content)-1] != '\n' {
+		n++
+	}
+	return n
+}
+
+// diffLines returns a shortest edit script from a to b (Myers' algorithm),
+// after setting aside their common prefix and suffix.
+func diffLines(a, b []string) []lineOp {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var ops []lineOp
+	for _, line := range a[:prefix] {
+		ops = append(ops, lineOp{' ', line})
+	}
+	ops = append(ops, myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, lineOp{' ', line})
+	}
+	return ops
+}
+
+func myers(a, b []string) []lineOp {
+	n, m := len(a), len(b)
+	offset := n + m
+	v := make([]int, 2*offset+2)
+	// trace[d] holds v for diagonals -d..d before step d
+	var trace [][]int
+	for d := 0; d <= min(offset, maxEdits); d++ {
+		trace = append(trace, slices.Clone(v[offset-d:offset+d+1]))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(trace, a, b)
+			}
+		}
+	}
+
+	// Too different to be worth diffing
+	ops := make([]lineOp, 0, n+m)
+	for _, line := range a {
+		ops = append(ops, lineOp{'-', line})
+	}
+	for _, line := range b {
+		ops = append(ops, lineOp{'+', line})
+	}
+	return ops
+}
+
+func backtrack(trace [][]int, a, b []string) []lineOp {
+	var ops []lineOp
+	x, y := len(a), len(b)
+	for d := len(trace) - 1; d > 0; d-- {
+		v := func(k int) int { return trace[d][k+d] }
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && v(k-1) < v(k+1)) {
+			prevK = k + 1
+		}
+		prevX := v(prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			ops = append(ops, lineOp{' ', a[x-1]})
+			x--
+			y--
+		}
+		if x == prevX {
+			ops = append(ops, lineOp{'+', b[y-1]})
+			y--
+		} else {
+			ops = append(ops, lineOp{'-', a[x-1]})
+			x--
+		}
+	}
+	for x > 0 && y > 0 {
+		ops = append(ops, lineOp{' ', a[x-1]})
+		x--
+		y--
+	}
+	slices.Reverse(ops)
+	return ops
+}
\ No newline at end of file
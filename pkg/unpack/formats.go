@@ -0,0 +1,129 @@
+package unpack
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// repomixPreamble is how clipcat's repomix output begins.
+const repomixPreamble = "This file is a merged representation of the selected files"
+
+var (
+	// A markdown file heading, with the --ids reference left out of the path
+	markdownHeading = regexp.MustCompile(`^## (?:\[F\d+\] )?(.+)$`)
+	markdownFence   = regexp.MustCompile("^(`{3,})[^`]*$")
+	// An xml or repomix <file> element with content; unreadable and removed
+	// files are empty elements
+	xmlFileOpen = regexp.MustCompile(`^<file((?: [a-z]+="[^"]*")*)>$`)
+	xmlAttr     = regexp.MustCompile(` ([a-z]+)="([^"]*)"`)
+)
+
+// xmlUnescaper reverses the output package's xmlEscaper.
+var xmlUnescaper = strings.NewReplacer("&lt;", "<", "&gt;", ">", "&quot;", `"`, "&amp;", "&")
+
+// parseMarkdown reads markdown and llms-full bundles: a "## path" heading,
+// an optional _metadata_ line, and the content in a fenced code block.
+// Headings not followed by a fence, such as placeholders and images, are
+// skipped along with the tree, listing and manifest blocks.
+func parseMarkdown(data []byte) []File {
+	lines := strings.SplitAfter(string(data), "\n")
+	line := func(j int) string { return strings.TrimSuffix(lines[j], "\n") }
+
+	var files []File
+	for i := 0; i+2 < len(lines); i++ {
+		m := markdownHeading.FindStringSubmatch(line(i))
+		if m == nil || line(i+1) != "" {
+			continue
+		}
+		start := i + 2
+		if meta := line(start); len(meta) > 1 && strings.HasPrefix(meta, "_") && strings.HasSuffix(meta, "_") && start+2 < len(lines) && line(start+1) == "" {
+			start += 2
+		}
+		fence := markdownFence.FindStringSubmatch(line(start))
+		if fence == nil {
+			continue
+		}
+		end := start + 1
+		for end < len(lines) && line(end) != fence[1] {
+			end++
+		}
+		if end == len(lines) {
+			break
+		}
+		content := []byte(strings.Join(lines[start+1:end], ""))
+		path := m[1]
+		i = end
+		if path == "File hierarchy" || path == "File listing" || path == "Manifest" || skipped(path, content) {
+			continue
+		}
+		files = append(files, File{Path: path, Content: content})
+	}
+	return files
+}
+
+// parseXML reads xml bundles, whose content is escaped, and repomix ones,
+// whose content is not: each <file path="..."> element holds the content
+// on the lines up to its closing </file>.
+func parseXML(data []byte, escaped bool) []File {
+	lines := strings.SplitAfter(string(data), "\n")
+
+	var files []File
+	for i := 0; i < len(lines); i++ {
+		m := xmlFileOpen.FindStringSubmatch(strings.TrimSuffix(lines[i], "\n"))
+		if m == nil {
+			continue
+		}
+		attrs := map[string]string{}
+		for _, a := range xmlAttr.FindAllStringSubmatch(m[1], -1) {
+			attrs[a[1]] = xmlUnescaper.Replace(a[2])
+		}
+		end := i + 1
+		for end < len(lines) && lines[end] != "</file>\n" && lines[end] != "</file>" {
+			end++
+		}
+		if end == len(lines) {
+			break
+		}
+		content := strings.Join(lines[i+1:end], "")
+		if escaped {
+			content = xmlUnescaper.Replace(content)
+		}
+		i = end
+		path, ok := attrs["path"]
+		if !ok || attrs["diff"] != "" || skipped(path, []byte(content)) {
+			continue
+		}
+		files = append(files, File{Path: path, Content: []byte(content)})
+	}
+	return files
+}
+
+// parseJSON reads json bundles, including several appended to one file
+// with --append-file. Reading stops at the end or at the first document
+// that does not decode.
+func parseJSON(data []byte) []File {
+	var files []File
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for {
+		var doc struct {
+			Files []struct {
+				Path       string `json:"path"`
+				DiffRef    string `json:"diff_ref"`
+				Unreadable bool   `json:"unreadable"`
+				Removed    bool   `json:"removed"`
+				Content    string `json:"content"`
+			} `json:"files"`
+		}
+		if err := dec.Decode(&doc); err != nil {
+			return files
+		}
+		for _, f := range doc.Files {
+			if f.DiffRef != "" || f.Unreadable || f.Removed || skipped(f.Path, []byte(f.Content)) {
+				continue
+			}
+			files = append(files, File{Path: f.Path, Content: []byte(f.Content)})
+		}
+	}
+}
\ No newline at end of file
@@ -0,0 +1,166 @@
+package unpack
+
+import (
+	"bytes"
+	"clipcat/pkg/output"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// File is one file section recovered from a clipcat bundle.
+type File struct {
+	Path    string
+	Content []byte
+}
+
+// Parse splits a bundle in any of clipcat's built-in formats back into
+// files. The format is told by how the bundle begins: a bar of '=' for
+// plain, '{' for json, <documents> for xml and the Repomix preamble for
+// repomix; anything else is read as markdown, which covers llms-full too.
+// Trees, listings, manifests, URL, command and diff sections, and
+// unreadable, removed and placeholder files are skipped. llms-txt bundles
+// only link to files and yield none.
+func Parse(data []byte) []File {
+	trimmed := bytes.TrimLeft(data, " \t\r\n")
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		return parseJSON(trimmed)
+	case bytes.HasPrefix(trimmed, []byte("<documents>")):
+		return parseXML(data, true)
+	case bytes.HasPrefix(trimmed, []byte(repomixPreamble)):
+		return parseXML(data, false)
+	case bytes.HasPrefix(trimmed, []byte("=")):
+		return parsePlain(data)
+	}
+	if files := parseMarkdown(data); len(files) > 0 {
+		return files
+	}
+	return parsePlain(data)
+}
+
+// skipped reports whether a section holds nothing to restore: a special
+// section of clipcat's, a URL, a command, a diff, or a placeholder for an
+// unreadable, removed, withheld, image, binary or minified file.
+func skipped(path string, content []byte) bool {
+	if path == "FILE HIERARCHY" || path == "FILE LISTING" || path == "SUMMARY" || path == "MANIFEST" || path == "ENVIRONMENT" || strings.Contains(path, "://") || strings.HasPrefix(path, "$ ") || strings.HasPrefix(path, output.GroupLabel("")) || output.IsRunLabel(path) || output.IsDiffLabel(path) {
+		return true
+	}
+	return bytes.HasPrefix(content, []byte(output.UnreadablePrefix)) || string(content) == output.RemovedPlaceholder+"\n" || string(content) == output.WithheldPlaceholder+"\n" || bytes.HasPrefix(content, []byte(output.ImagePrefix)) || bytes.HasPrefix(content, []byte(output.BinaryPrefix)) || bytes.HasPrefix(content, []byte(output.MinifiedPrefix))
+}
+
+// parsePlain reads clipcat's header format:
+//
+//	====
+//	path
+//	====
+//
+//	content
+//
+// Headers may carry a metadata line under the path (--git-meta).
+func parsePlain(data []byte) []File {
+	lines := bytes.SplitAfter(data, []byte("\n"))
+
+	type section struct {
+		path  string
+		start int // index of the first content line
+		end   int // index of the header's first bar line
+	}
+	var sections []section
+
+	for i := 0; i+3 < len(lines); i++ {
+		if p, n, ok := headerAt(lines, i); ok {
+			if len(sections) > 0 {
+				sections[len(sections)-1].end = i
+			}
+			sections = append(sections, section{path: p, start: i + n, end: len(lines)})
+			i += n - 1
+		}
+	}
+
+	var files []File
+	for _, s := range sections {
+		content := bytes.Join(lines[s.start:s.end], nil)
+		// Every section is followed by one separator newline
+		content = bytes.TrimSuffix(content, []byte("\n"))
+		if skipped(s.path, content) {
+			continue
+		}
+		files = append(files, File{Path: s.path, Content: content})
+	}
+	return files
+}
+
+// headerAt reports whether a header starts at lines[i]: a bar of '=', the
+// path, an optional metadata line, the same bar again, and a blank line. The
+// bar is exactly as long as the longest of the enclosed lines. It returns the
+// path and the number of header lines.
+func headerAt(lines [][]byte, i int) (string, int, bool) {
+	line := func(j int) string { return strings.TrimSuffix(string(lines[j]), "\n") }
+
+	bar := line(i)
+	if bar == "" || strings.Trim(bar, "=") != "" {
+		return "", 0, false
+	}
+	for inner := 1; inner <= 2 && i+inner+2 < len(lines); inner++ {
+		closing := i + inner + 1
+		if line(closing) != bar || string(lines[closing+1]) != "\n" {
+			continue
+		}
+		longest := 0
+		for j := i + 1; j < closing; j++ {
+			longest = max(longest, len(line(j)))
+		}
+		if longest == len(bar) {
+			return line(i + 1), inner + 3, true
+		}
+	}
+	return "", 0, false
+}
+
+// Relativize maps header paths to paths relative to the destination directory.
+// Absolute paths (clipcat's default headers) are made relative to their
+// deepest common directory so a bundle from /home/a/proj unpacks as proj's
+// contents. Paths that would escape the destination are rejected.
+func Relativize(files []File) ([]string, error) {
+	var common string
+	first := true
+	for _, f := range files {
+		p := slashPath(f.Path)
+		if !path.IsAbs(p) {
+			continue
+		}
+		dir := path.Dir(p)
+		if first {
+			common, first = dir, false
+			continue
+		}
+		for common != "/" && dir != common && !strings.HasPrefix(dir, common+"/") {
+			common = path.Dir(common)
+		}
+	}
+
+	rels := make([]string, len(files))
+	for i, f := range files {
+		p := slashPath(f.Path)
+		if path.IsAbs(p) {
+			p = strings.TrimPrefix(strings.TrimPrefix(p, common), "/")
+		}
+		p = path.Clean(p)
+		if p == "." || p == ".." || strings.HasPrefix(p, "../") || path.IsAbs(p) {
+			return nil, fmt.Errorf("refusing to write outside the destination: %s", f.Path)
+		}
+		rels[i] = filepath.FromSlash(p)
+	}
+	return rels, nil
+}
+
+// slashPath normalizes a header path to forward slashes. Bundles may come
+// from another OS, so Windows drive paths are recognized everywhere.
+func slashPath(p string) string {
+	if len(p) > 2 && p[1] == ':' && (p[2] == '\\' || p[2] == '/') {
+		return strings.ReplaceAll(p[2:], "\\", "/")
+	}
+	return filepath.ToSlash(p)
+}
@@ -0,0 +1,633 @@
+package integration_test
+
+import (
+	"bytes"
+	"clipcat/internal/clipboard"
+	"clipcat/pkg/clipcat"
+	"clipcat/pkg/unpack"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// fakeXclip puts an xclip script first on PATH that saves its input to the
+// returned file. It fails with a message the first failures times it runs,
+// or always when failures is negative.
+func fakeXclip(t *testing.T, failures int) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as the clipboard command")
+	}
+	dir := t.TempDir()
+	saved := filepath.Join(dir, "clipboard")
+	runs := filepath.Join(dir, "runs")
+	script := fmt.Sprintf(`#!/bin/sh
+echo run >> %s
+if [ %d -lt 0 ] || [ "$(wc -l < %s)" -le %d ]; then
+	echo 'Error: target STRING not available' >&2
+	exit 1
+fi
+cat > %s
+`, runs, failures, runs, failures, saved)
+	if err := os.WriteFile(filepath.Join(dir, "xclip"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return saved
+}
+
+func TestClipboard_OverLimitCopiesFilePath(t *testing.T) {
+	tmpDir := setupTestDirectory(t)
+	defer os.RemoveAll(tmpDir)
+	saved := fakeXclip(t, 0)
+
+	if backend, err := clipboard.Backend(); err != nil || backend != "xclip" {
+		t.Fatalf("Backend() = %q, %v; want xclip", backend, err)
+	}
+
+	devNull, _ := os.Open(os.DevNull)
+	defer devNull.Close()
+	stdout, stderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = devNull, devNull
+	err := clipcat.Run(&clipcat.Config{
+		Paths:           []string{filepath.Join(tmpDir, "src")},
+		Format:          "markdown",
+		ClipboardLimits: map[string]int64{"xclip": 10},
+	})
+	os.Stdout, os.Stderr = stdout, stderr
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	copied, err := os.ReadFile(saved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := string(copied)
+	if !strings.HasSuffix(path, ".md") {
+		t.Fatalf("Expected a .md file path on the clipboard, got %q", path)
+	}
+	defer os.Remove(path)
+	bundle, err := os.ReadFile(path)
+	if err != nil || !strings.Contains(string(bundle), "package src") {
+		t.Errorf("Expected the bundle in %s, got %q (%v)", path, bundle, err)
+	}
+}
+
+func TestClipboard_ErrorIncludesCommandOutput(t *testing.T) {
+	fakeXclip(t, -1)
+	err := clipboard.CopyToClipboard([]byte("data"))
+	if err == nil || !strings.Contains(err.Error(), "target STRING not available") || !strings.Contains(err.Error(), "after 3 attempts") {
+		t.Errorf("Expected xclip's message and the attempt count in the error, got %v", err)
+	}
+}
+
+func TestClipboard_RetriesTransientFailures(t *testing.T) {
+	saved := fakeXclip(t, 2)
+	if err := clipboard.CopyToClipboard([]byte("data")); err != nil {
+		t.Fatalf("Expected the third attempt to succeed, got %v", err)
+	}
+	if copied, _ := os.ReadFile(saved); string(copied) != "data" {
+		t.Errorf("Clipboard holds %q, want %q", copied, "data")
+	}
+}
+
+func TestClipboard_IDsAndExpand(t *testing.T) {
+	tmpDir := setupTestDirectory(t)
+	defer os.RemoveAll(tmpDir)
+	saved := fakeXclip(t, 0)
+	// The --ids mapping is kept in the user cache directory
+	cache := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", cache)
+	t.Setenv("HOME", cache)
+
+	devNull, _ := os.Open(os.DevNull)
+	defer devNull.Close()
+	stdout, stderr := os.Stdout, os.Stderr
+	defer func() { os.Stdout, os.Stderr = stdout, stderr }()
+	os.Stdout, os.Stderr = devNull, devNull
+
+	src := filepath.Join(tmpDir, "src")
+	if err := clipcat.Run(&clipcat.Config{Paths: []string{src}, Format: "markdown", IDs: true, ShowTree: true}); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	copied, _ := os.ReadFile(saved)
+	button := filepath.Join(src, "components", "button.go")
+	if !strings.Contains(string(copied), "--button.go [F2]\n") || !strings.Contains(string(copied), "## [F2] "+button+"\n") {
+		t.Fatalf("Expected F2 in the tree and the header, got:\n%s", copied)
+	}
+
+	if err := clipcat.Run(&clipcat.Config{Expand: []string{"F2"}, Format: "xml"}); err != nil {
+		t.Fatalf("expand failed: %v", err)
+	}
+	copied, _ = os.ReadFile(saved)
+	if want := "<documents>\n<file id=\"F2\" path=\"" + button + "\">\npackage components\n</file>\n</documents>\n"; string(copied) != want {
+		t.Errorf("expand F2:\n got %q\nwant %q", copied, want)
+	}
+
+	if err := clipcat.Run(&clipcat.Config{Expand: []string{"F9"}}); err == nil || !strings.Contains(err.Error(), "unknown file ID F9") {
+		t.Errorf("Expected an unknown ID error, got %v", err)
+	}
+}
+
+func TestClipboard_HistoryAndRerun(t *testing.T) {
+	tmpDir := setupTestDirectory(t)
+	defer os.RemoveAll(tmpDir)
+	saved := fakeXclip(t, 0)
+	cache := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", cache)
+	t.Setenv("HOME", cache)
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+
+	devNull, _ := os.Open(os.DevNull)
+	defer devNull.Close()
+	stdout, stderr := os.Stdout, os.Stderr
+	defer func() { os.Stdout, os.Stderr = stdout, stderr }()
+	os.Stdout, os.Stderr = devNull, devNull
+
+	var out strings.Builder
+	if err := clipcat.History(nil, &out); err != nil || out.String() != "No copies remembered yet.\n" {
+		t.Fatalf("Expected an empty history, got %q (%v)", out.String(), err)
+	}
+
+	src := filepath.Join(tmpDir, "src")
+	if err := clipcat.Run(&clipcat.Config{Paths: []string{src}, Args: []string{src}}); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	// Copies made through the library are not remembered
+	if err := clipcat.Run(&clipcat.Config{Paths: []string{filepath.Join(tmpDir, "README.md")}}); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	out.Reset()
+	if err := clipcat.History([]string{"1"}, &out); err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	button := filepath.Join(src, "components", "button.go")
+	if !strings.Contains(out.String(), "command: clipcat "+src+"\n") || !strings.Contains(out.String(), "  "+button+"\n") {
+		t.Errorf("Expected the command and its files, got:\n%s", out.String())
+	}
+
+	os.WriteFile(filepath.Join(src, "added.go"), []byte("package src\n"), 0644)
+	if err := clipcat.Rerun(nil); err != nil {
+		t.Fatalf("Rerun failed: %v", err)
+	}
+	copied, _ := os.ReadFile(saved)
+	if !strings.Contains(string(copied), "package src\n") || !strings.Contains(string(copied), "package components\n") {
+		t.Errorf("Expected the rerun to copy the directory again, got:\n%s", copied)
+	}
+
+	if err := clipcat.Rerun([]string{"5"}); err == nil || !strings.Contains(err.Error(), "no copy 5 in the history (2 remembered)") {
+		t.Errorf("Expected a missing copy error, got %v", err)
+	}
+}
+
+func TestClipboard_SavedSets(t *testing.T) {
+	tmpDir := setupTestDirectory(t)
+	defer os.RemoveAll(tmpDir)
+	saved := fakeXclip(t, 0)
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	os.Chdir(tmpDir)
+
+	devNull, _ := os.Open(os.DevNull)
+	defer devNull.Close()
+	stdout, stderr := os.Stdout, os.Stderr
+	defer func() { os.Stdout, os.Stderr = stdout, stderr }()
+	os.Stdout, os.Stderr = devNull, devNull
+
+	var out strings.Builder
+	if err := clipcat.Sets([]string{"save", "ui", filepath.Join(tmpDir, "src", "components"), "--format", "xml"}, &out); err != nil {
+		t.Fatalf("set save failed: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(tmpDir, ".clipcat-sets.toml"))
+	if err != nil || !strings.Contains(string(data), "[sets.ui]\nargs = [\"src/components\", \"--format\", \"xml\"]\n") {
+		t.Fatalf("Expected the set with a relative path, got %q (%v)", data, err)
+	}
+
+	// Sets are found from subdirectories and copied from the sets file's directory
+	os.Chdir(filepath.Join(tmpDir, "src"))
+	if err := clipcat.Sets([]string{"copy", "ui"}, &out); err != nil {
+		t.Fatalf("set copy failed: %v", err)
+	}
+	copied, _ := os.ReadFile(saved)
+	if !strings.Contains(string(copied), "<file path=\"") || !strings.Contains(string(copied), "package components\n") {
+		t.Errorf("Expected the set copied as xml, got:\n%s", copied)
+	}
+
+	if err := clipcat.Sets([]string{"save", "cmd", "--run", "make", "."}, &out); err == nil || !strings.Contains(err.Error(), "may not use --run") {
+		t.Errorf("Expected --run to be refused, got %v", err)
+	}
+	for _, flag := range []string{"--clipboard", "--upload", "--output"} {
+		if err := clipcat.Sets([]string{"save", "out", flag, "x", "."}, &out); err == nil || !strings.Contains(err.Error(), "may not use "+flag) {
+			t.Errorf("Expected %s to be refused, got %v", flag, err)
+		}
+	}
+	if err := clipcat.Sets([]string{"copy", "nope"}, &out); err == nil || !strings.Contains(err.Error(), "unknown set \"nope\"") {
+		t.Errorf("Expected an unknown set error, got %v", err)
+	}
+}
+func TestClipboard_CopyHooks(t *testing.T) {
+	tmpDir := setupTestDirectory(t)
+	defer os.RemoveAll(tmpDir)
+	saved := fakeXclip(t, 0)
+	log := filepath.Join(t.TempDir(), "audit.log")
+
+	devNull, _ := os.Open(os.DevNull)
+	defer devNull.Close()
+	stdout, stderr := os.Stdout, os.Stderr
+	defer func() { os.Stdout, os.Stderr = stdout, stderr }()
+	os.Stdout, os.Stderr = devNull, devNull
+
+	readme := filepath.Join(tmpDir, "README.md")
+	err := clipcat.Run(&clipcat.Config{
+		Paths:    []string{readme},
+		PreCopy:  "test $CLIPCAT_FILES -eq 1",
+		PostCopy: fmt.Sprintf(`echo "$CLIPCAT_HOOK $CLIPCAT_FILES $CLIPCAT_BYTES" > %s; cat >> %s`, log, log),
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	copied, _ := os.ReadFile(saved)
+	audit, _ := os.ReadFile(log)
+	if want := fmt.Sprintf("post_copy 1 %d\n%s\n", len(copied), readme); string(audit) != want {
+		t.Errorf("post_copy logged %q, want %q", audit, want)
+	}
+
+	os.Remove(saved)
+	err = clipcat.Run(&clipcat.Config{Paths: []string{filepath.Join(tmpDir, "src")}, PreCopy: "test $CLIPCAT_FILES -eq 1"})
+	if err == nil || !strings.Contains(err.Error(), "pre_copy hook") {
+		t.Errorf("Expected pre_copy to veto the copy, got %v", err)
+	}
+	if _, err := os.Stat(saved); err == nil {
+		t.Error("Expected nothing copied after a vetoed copy")
+	}
+
+	// Split parts go through the same hooks and audit log
+	parts := filepath.Join(t.TempDir(), "bundle.txt")
+	split := clipcat.Config{Paths: []string{readme}, SplitSize: 4096, SplitOutput: parts, PreCopy: "false"}
+	if err := clipcat.Run(&split); err == nil || !strings.Contains(err.Error(), "pre_copy hook") {
+		t.Errorf("Expected pre_copy to veto the split, got %v", err)
+	}
+	if _, err := os.Stat(strings.TrimSuffix(parts, ".txt") + ".part1.txt"); err == nil {
+		t.Error("Expected no parts written after a vetoed copy")
+	}
+	split.PreCopy, split.AuditLog = "", log
+	if err := clipcat.Run(&split); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if audit, _ := os.ReadFile(log); !strings.Contains(string(audit), `"destination":"file:`+strings.TrimSuffix(parts, ".txt")+`.part*.txt"`) {
+		t.Errorf("Expected the split parts in the audit log, got %s", audit)
+	}
+}
+func TestClipboard_AuditLog(t *testing.T) {
+	tmpDir := setupTestDirectory(t)
+	defer os.RemoveAll(tmpDir)
+	fakeXclip(t, 0)
+	home := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", home)
+	t.Setenv("HOME", home)
+	log := filepath.Join(home, "logs", "audit.jsonl")
+	os.MkdirAll(filepath.Join(home, "clipcat"), 0755)
+	os.WriteFile(filepath.Join(home, "clipcat", "config.toml"), []byte(fmt.Sprintf("[audit]\nenabled = true\npath = %q\n", log)), 0644)
+
+	devNull, _ := os.Open(os.DevNull)
+	defer devNull.Close()
+	stdout, stderr := os.Stdout, os.Stderr
+	defer func() { os.Stdout, os.Stderr = stdout, stderr }()
+	os.Stdout, os.Stderr = devNull, devNull
+
+	var out strings.Builder
+	if err := clipcat.Audit([]string{"show"}, &out); err != nil || !strings.HasPrefix(out.String(), "No copies recorded") {
+		t.Fatalf("Expected an empty log, got %q (%v)", out.String(), err)
+	}
+
+	readme := filepath.Join(tmpDir, "README.md")
+	for _, paths := range [][]string{{filepath.Join(tmpDir, "src")}, {readme}} {
+		if err := clipcat.Run(&clipcat.Config{Paths: paths, AuditLog: log}); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+	}
+
+	out.Reset()
+	if err := clipcat.Audit([]string{"show", "1"}, &out); err != nil {
+		t.Fatalf("Audit failed: %v", err)
+	}
+	info, _ := os.Stat(readme)
+	if got := out.String(); !strings.Contains(got, "  clipboard  1 files  ") || !strings.Contains(got, fmt.Sprintf("  %s (%d B)\n", readme, info.Size())) ||
+		strings.Contains(got, "button.go") {
+		t.Errorf("Expected only the last copy, got:\n%s", got)
+	}
+	if info, err := os.Stat(log); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected a private log file, got %v, %v", info, err)
+	}
+}
+
+func TestClipboard_PipedWithoutClipboardPrints(t *testing.T) {
+	tmpDir := setupTestDirectory(t)
+	defer os.RemoveAll(tmpDir)
+	// No clipboard command on PATH, and stdout a file rather than a terminal
+	t.Setenv("PATH", t.TempDir())
+	out, err := os.Create(filepath.Join(t.TempDir(), "out.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	devNull, _ := os.Open(os.DevNull)
+	defer devNull.Close()
+	stdout, stderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = out, devNull
+	err = clipcat.Run(&clipcat.Config{Paths: []string{filepath.Join(tmpDir, "README.md")}})
+	os.Stdout, os.Stderr = stdout, stderr
+	if err != nil {
+		t.Fatalf("Expected the bundle on stdout instead of an error, got %v", err)
+	}
+
+	written, _ := os.ReadFile(out.Name())
+	if !strings.Contains(string(written), filepath.Join(tmpDir, "README.md")+"\n") || strings.Contains(string(written), "Copied") {
+		t.Errorf("Expected only the bundle on stdout, got:\n%s", written)
+	}
+}
+
+func TestClipboard_RunReport(t *testing.T) {
+	tmpDir := setupTestDirectory(t)
+	defer os.RemoveAll(tmpDir)
+	fakeXclip(t, 0)
+	reportFile := filepath.Join(t.TempDir(), "report.json")
+
+	devNull, _ := os.Open(os.DevNull)
+	defer devNull.Close()
+	stdout, stderr := os.Stdout, os.Stderr
+	defer func() { os.Stdout, os.Stderr = stdout, stderr }()
+	os.Stdout, os.Stderr = devNull, devNull
+
+	src := filepath.Join(tmpDir, "src")
+	err := clipcat.Run(&clipcat.Config{
+		Paths:      []string{src},
+		Excludes:   []string{"utils/"},
+		MaxFiles:   1,
+		Report:     "json",
+		ReportFile: reportFile,
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	var report clipcat.Report
+	data, _ := os.ReadFile(reportFile)
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("Invalid report: %v\n%s", err, data)
+	}
+	if len(report.Files) != 1 || report.Files[0].Path != filepath.Join(src, "app.go") || report.Files[0].Status != "copied" || report.Files[0].Size != int64(len("package src")) {
+		t.Errorf("Unexpected files: %+v", report.Files)
+	}
+	want := []clipcat.ReportSkip{
+		{Path: filepath.Join(src, "utils"), Reason: "exclude: -e utils/"},
+		{Path: filepath.Join(src, "components", "button.go"), Reason: "beyond --max-files 1"},
+	}
+	if fmt.Sprint(report.Skipped) != fmt.Sprint(want) {
+		t.Errorf("Skipped %+v, want %+v", report.Skipped, want)
+	}
+	if report.Destination != "clipboard" || report.Backend != "xclip" || report.Bytes == 0 || report.Error != "" {
+		t.Errorf("Unexpected report: %s", data)
+	}
+	for _, phase := range []string{"collect", "render", "copy"} {
+		if _, ok := report.Durations[phase]; !ok {
+			t.Errorf("No duration for %s: %v", phase, report.Durations)
+		}
+	}
+
+	// A failed run still reports, with the error
+	err = clipcat.Run(&clipcat.Config{Paths: []string{src}, Excludes: []string{"*.go"}, Report: "json", ReportFile: reportFile})
+	data, _ = os.ReadFile(reportFile)
+	if err == nil || !strings.Contains(string(data), `"error": "no files matched`) {
+		t.Errorf("Expected the error in the report, got %v:\n%s", err, data)
+	}
+}
+
+func TestRun_Timeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a FIFO and a shell script as the clipboard command")
+	}
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	devNull, _ := os.Open(os.DevNull)
+	defer devNull.Close()
+	stdout, stderr := os.Stdout, os.Stderr
+	defer func() { os.Stdout, os.Stderr = stdout, stderr }()
+	os.Stdout, os.Stderr = devNull, devNull
+
+	// A clipboard command that never returns
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "xclip"), []byte("#!/bin/sh\nexec sleep 10\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	start := time.Now()
+	err := clipcat.Run(&clipcat.Config{Paths: []string{tmpDir}, Timeout: 300 * time.Millisecond})
+	var timeoutErr *clipcat.TimeoutError
+	if !errors.As(err, &timeoutErr) || timeoutErr.Phase != "copy" || err.Error() != "timed out after 300ms while copying to the clipboard with xclip" {
+		t.Errorf("Expected a timeout while copying, got %v", err)
+	}
+	if clipcat.ExitCode(err) != clipcat.ExitTimeout {
+		t.Errorf("Expected exit code %d, got %d", clipcat.ExitTimeout, clipcat.ExitCode(err))
+	}
+
+	// Opening a FIFO without a writer blocks like a dead network mount
+	fifo := filepath.Join(tmpDir, "hung.txt")
+	if err := syscall.Mkfifo(fifo, 0644); err != nil {
+		t.Skipf("mkfifo: %v", err)
+	}
+	err = clipcat.Run(&clipcat.Config{Paths: []string{tmpDir}, Timeout: 300 * time.Millisecond})
+	if !errors.As(err, &timeoutErr) || timeoutErr.Phase != "collect" || !strings.Contains(err.Error(), "while collecting files at "+fifo) {
+		t.Errorf("Expected a timeout collecting %s, got %v", fifo, err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Timed out runs should return soon after the deadline, took %v", elapsed)
+	}
+}
+func TestRun_Sinks(t *testing.T) {
+	tmpDir := setupTestDirectory(t)
+	defer os.RemoveAll(tmpDir)
+	saved := fakeXclip(t, 0)
+	out := t.TempDir()
+
+	printed, err := os.Create(filepath.Join(out, "stdout"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer printed.Close()
+	stdout := os.Stdout
+	defer func() { os.Stdout = stdout }()
+	os.Stdout = printed
+
+	bundle, reportFile := filepath.Join(out, "bundle.txt"), filepath.Join(out, "report.json")
+	err = clipcat.Run(&clipcat.Config{Paths: []string{tmpDir}, Output: bundle, Clipboard: "auto", PrintOut: true, Color: "never", Report: "json", ReportFile: reportFile})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	written, _ := os.ReadFile(bundle)
+	// The bundle is streamed to the sinks, and measured on the way
+	var report clipcat.Report
+	data, _ := os.ReadFile(reportFile)
+	if err := json.Unmarshal(data, &report); err != nil || report.Bytes != len(written) || report.Tokens == 0 {
+		t.Errorf("Expected the report to measure the %d bytes written, got %d bytes, %d tokens (%v)", len(written), report.Bytes, report.Tokens, err)
+	}
+	copied, _ := os.ReadFile(saved)
+	shown, _ := os.ReadFile(printed.Name())
+	if len(written) == 0 || !bytes.Equal(written, copied) {
+		t.Errorf("Expected the file and the clipboard to hold the same bundle, got %d and %d bytes", len(written), len(copied))
+	}
+	if !bytes.HasPrefix(shown, written) || !strings.Contains(string(shown), " files to "+bundle+" and the clipboard.") {
+		t.Errorf("Expected stdout to hold the bundle and the message, got:\n%s", shown)
+	}
+
+	// A destination that fails does not cost the others their copy
+	os.Remove(saved)
+	err = clipcat.Run(&clipcat.Config{Paths: []string{tmpDir}, Output: filepath.Join(out, "missing", "bundle.txt"), Clipboard: "xclip"})
+	if err == nil || clipcat.ExitCode(err) != clipcat.ExitError {
+		t.Errorf("Expected the failed --output to exit %d, got %v", clipcat.ExitError, err)
+	}
+	if copied, _ := os.ReadFile(saved); !bytes.Equal(copied, written) {
+		t.Errorf("Expected the clipboard to get the bundle anyway, got %d bytes", len(copied))
+	}
+
+	// --compress leaves the file binary and gives the clipboard base64 text
+	os.Remove(bundle)
+	err = clipcat.Run(&clipcat.Config{Paths: []string{tmpDir}, Output: bundle, Clipboard: "xclip", Compress: unpack.Zstd})
+	if err != nil {
+		t.Fatalf("Run with --compress failed: %v", err)
+	}
+	file, _ := os.ReadFile(bundle)
+	copied, _ = os.ReadFile(saved)
+	if !bytes.HasPrefix(file, []byte{0x28, 0xb5, 0x2f, 0xfd}) || !bytes.HasPrefix(copied, []byte("KLUv/")) {
+		t.Errorf("Expected a zstd file and base64 zstd on the clipboard, got %q and %q", file[:min(len(file), 8)], copied[:min(len(copied), 8)])
+	}
+	for _, data := range [][]byte{file, copied} {
+		if got, err := unpack.Decompress(data); err != nil || !bytes.Equal(got, written) {
+			t.Errorf("Expected the compressed copies to read back as the bundle (err %v)", err)
+		}
+	}
+}
+func TestRun_AppendFileDedupe(t *testing.T) {
+	tmpDir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(tmpDir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	first := write("first.go", "package first\n")
+	write("copy.go", "package first\n")
+	devNull, _ := os.Open(os.DevNull)
+	defer devNull.Close()
+	stdout := os.Stdout
+	defer func() { os.Stdout = stdout }()
+	os.Stdout = devNull
+
+	log := filepath.Join(t.TempDir(), "session.md")
+	cfg := clipcat.Config{Format: "markdown", Output: log, AppendFile: true, DedupeContent: true, Manifest: true, Deterministic: true}
+	run := func(paths ...string) error {
+		cfg := cfg
+		cfg.Paths = paths
+		return clipcat.Run(&cfg)
+	}
+	if err := run(first); err != nil {
+		t.Fatalf("First run failed: %v", err)
+	}
+	write("second.go", "package second\n")
+	if err := run(tmpDir); err != nil {
+		t.Fatalf("Second run failed: %v", err)
+	}
+	data, _ := os.ReadFile(log)
+	out := string(data)
+	if strings.Count(out, "package first") != 1 || !strings.Contains(out, "package second") {
+		t.Errorf("Expected the second run to skip the copies of first.go, got:\n%s", out)
+	}
+	if !strings.Contains(out, "\n---\n\n<!-- clipcat run -->\n\n") {
+		t.Errorf("Expected a run separator between the runs, got:\n%s", out)
+	}
+
+	// Nothing new is nothing to append
+	if err := run(tmpDir); !errors.Is(err, clipcat.ErrNoFiles) {
+		t.Errorf("Expected ErrNoFiles when every file was copied before, got %v", err)
+	}
+}
+func TestRun_EncryptedOutput(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as age")
+	}
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	devNull, _ := os.Open(os.DevNull)
+	defer devNull.Close()
+	stdout := os.Stdout
+	defer func() { os.Stdout = stdout }()
+	os.Stdout = devNull
+
+	// A stand-in for age that keeps the header and base64-encodes the rest
+	bin := t.TempDir()
+	recipients := filepath.Join(bin, "recipients")
+	script := fmt.Sprintf(`#!/bin/sh
+case "$1" in
+--encrypt) echo "$3" >> %s; echo age-encryption.org/v1; base64 ;;
+--decrypt) tail -n +2 | base64 -d ;;
+esac
+`, recipients)
+	if err := os.WriteFile(filepath.Join(bin, "age"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	bundle := filepath.Join(t.TempDir(), "bundle.age")
+	err := clipcat.Run(&clipcat.Config{Paths: []string{tmpDir}, Output: bundle, Compress: "gzip", Encrypt: "age1recipient"})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	data, _ := os.ReadFile(bundle)
+	if !strings.HasPrefix(string(data), "age-encryption.org/v1\n") || strings.Contains(string(data), "package main") {
+		t.Errorf("Expected an age file, got:\n%s", data)
+	}
+	if got, _ := os.ReadFile(recipients); string(got) != "age1recipient\n" {
+		t.Errorf("Expected age to encrypt to age1recipient, got %q", got)
+	}
+
+	restored := t.TempDir()
+	if err := clipcat.Unpack(&clipcat.UnpackConfig{Source: bundle, Dir: restored}); err == nil || !strings.Contains(err.Error(), "--decrypt") {
+		t.Errorf("Expected unpack without --decrypt to fail, got %v", err)
+	}
+	if err := clipcat.Unpack(&clipcat.UnpackConfig{Source: bundle, Dir: restored, Decrypt: true, Identity: "key.txt"}); err != nil {
+		t.Fatalf("Unpack failed: %v", err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(restored, "main.go")); string(got) != "package main\n" {
+		t.Errorf("Expected main.go restored, got %q", got)
+	}
+
+	// Without age the existing file is kept, not emptied
+	os.Remove(filepath.Join(bin, "age"))
+	t.Setenv("PATH", bin)
+	err = clipcat.Run(&clipcat.Config{Paths: []string{tmpDir}, Output: bundle, Encrypt: "age1recipient"})
+	if err == nil || !strings.Contains(err.Error(), "age not found") {
+		t.Errorf("Expected age to be missing, got %v", err)
+	}
+	if kept, _ := os.ReadFile(bundle); !bytes.Equal(kept, data) {
+		t.Errorf("Expected the old bundle to be kept, got %d bytes", len(kept))
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(bundle)); len(entries) != 1 {
+		t.Errorf("Expected no temp file left behind, got %v", entries)
+	}
+}
\ No newline at end of file
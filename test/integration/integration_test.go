@@ -6,8 +6,10 @@ import (
 	"clipcat/pkg/collector"
 	"clipcat/pkg/exclude"
 	"clipcat/pkg/output"
+	"encoding/json"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -270,6 +272,89 @@ func TestWriteTree_SingleRoot(t *testing.T) {
 	}
 }
 
+func TestWriteTree_OverlappingRoots(t *testing.T) {
+	tmpDir := setupTestDirectory(t)
+	defer os.RemoveAll(tmpDir)
+
+	files := []string{
+		filepath.Join(tmpDir, "main.go"),
+		filepath.Join(tmpDir, "src", "app.go"),
+		filepath.Join(tmpDir, "src", "components", "button.go"),
+	}
+	roots := []string{filepath.Join(tmpDir, "src"), tmpDir, filepath.Join(tmpDir, "src", "components")}
+
+	var outputBuf bytes.Buffer
+	output.WriteTree(&outputBuf, roots, files)
+
+	want := filepath.Base(tmpDir) + "/\n-main.go\n-src/\n--app.go\n--components/\n---button.go\n"
+	if got := outputBuf.String(); got != want {
+		t.Errorf("Expected one merged tree:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestBuildTree(t *testing.T) {
+	tmpDir := setupTestDirectory(t)
+	defer os.RemoveAll(tmpDir)
+
+	src := filepath.Join(tmpDir, "src")
+	app := filepath.Join(src, "app.go")
+	button := filepath.Join(src, "components", "button.go")
+	size := func(path string) int64 {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return info.Size()
+	}
+
+	excluded := []output.Exclusion{
+		{Path: filepath.Join(src, "utils", "format.go"), Rule: "-e format.go"},
+		{Path: filepath.Join(src, "components"), Dir: true, Rule: "-e components/"},
+	}
+	roots := output.BuildTree([]string{src}, []string{app, button}, map[string]string{button: "F2"}, excluded)
+
+	var buf bytes.Buffer
+	if err := output.WriteTreeJSON(&buf, roots); err != nil {
+		t.Fatalf("WriteTreeJSON failed: %v", err)
+	}
+	var got []output.TreeNode
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("tree JSON does not parse: %v\n%s", err, buf.String())
+	}
+
+	want := []output.TreeNode{{Name: "src", Type: "directory", Size: size(app) + size(button), Children: []*output.TreeNode{
+		{Name: "app.go", Type: "file", Size: size(app)},
+		{Name: "components", Type: "directory", Size: size(button), Children: []*output.TreeNode{
+			{Name: "button.go", Type: "file", Size: size(button), ID: "F2"},
+		}, Excluded: "-e components/"},
+		{Name: "utils", Type: "directory", Children: []*output.TreeNode{
+			{Name: "format.go", Type: "file", Size: size(filepath.Join(src, "utils", "format.go")), Excluded: "-e format.go"},
+		}},
+	}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected tree:\n%s", buf.String())
+	}
+}
+
+func TestDrawTree_Excluded(t *testing.T) {
+	tmpDir := setupTestDirectory(t)
+	defer os.RemoveAll(tmpDir)
+
+	excluded := []output.Exclusion{
+		{Path: filepath.Join(tmpDir, "node_modules"), Dir: true, Rule: "default node_modules/"},
+		{Path: filepath.Join(tmpDir, "src", "debug.log"), Rule: "-e *.log"},
+	}
+	files := []string{filepath.Join(tmpDir, "main.go"), filepath.Join(tmpDir, "src", "app.go")}
+
+	var buf bytes.Buffer
+	output.DrawTree(&buf, output.BuildTree([]string{tmpDir}, files, nil, excluded))
+
+	want := filepath.Base(tmpDir) + "/\n-main.go\n-src/\n--app.go\n--debug.log [excluded: -e *.log]\n-node_modules/ [excluded: default node_modules/]\n"
+	if got := buf.String(); got != want {
+		t.Errorf("Expected:\n%s\ngot:\n%s", want, got)
+	}
+}
+
 func TestWriteTree_MultipleRoots(t *testing.T) {
 	tmpDir := setupTestDirectory(t)
 	defer os.RemoveAll(tmpDir)
@@ -831,8 +916,8 @@ func TestEndToEnd_UnreadableFiles(t *testing.T) {
 	stdout := buf.String()
 
 	// Should contain the unreadable file indicator
-	if !strings.Contains(stdout, "[unreadable]") {
-		t.Error("Expected [unreadable] indicator for unreadable file")
+	if !strings.Contains(stdout, "[unreadable: permission denied, 14B]") {
+		t.Error("Expected [unreadable: permission denied, 14B] indicator for unreadable file")
 	}
 
 	// Should still contain the readable file's content
@@ -0,0 +1,1203 @@
+package integration_test
+
+import (
+	"bytes"
+	"clipcat/pkg/clipcat"
+	"clipcat/pkg/config"
+	"clipcat/pkg/exclude"
+	"clipcat/pkg/output"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLibrary_Write(t *testing.T) {
+	tmpDir := setupTestDirectory(t)
+	defer os.RemoveAll(tmpDir)
+
+	var buf, warnings bytes.Buffer
+	b := clipcat.New(
+		clipcat.WithPaths(filepath.Join(tmpDir, "src"), filepath.Join(tmpDir, "missing")),
+		clipcat.WithExcludes("format.go"),
+		clipcat.WithTree(true),
+		clipcat.WithWarnings(&warnings),
+	)
+	if err := b.Write(context.Background(), &buf); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	out := buf.String()
+	if !strings.HasPrefix(out, "==============\nFILE HIERARCHY\n") {
+		t.Errorf("Expected the tree first, got:\n%s", out)
+	}
+	for _, want := range []string{"app.go", "package src", "button.go", "package components"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in output", want)
+		}
+	}
+	if strings.Contains(out, "package utils") {
+		t.Error("Excluded file should not be rendered")
+	}
+	if !strings.Contains(warnings.String(), "missing") {
+		t.Errorf("Expected a warning about the missing input, got %q", warnings.String())
+	}
+
+	files, err := b.Files(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(tmpDir, "src", "app.go"), filepath.Join(tmpDir, "src", "components", "button.go")}
+	if strings.Join(files, ",") != strings.Join(want, ",") {
+		t.Errorf("Files() = %v, want %v", files, want)
+	}
+}
+
+// sectionWriter records each write, and fails once limit writes are made.
+type sectionWriter struct {
+	writes []string
+	limit  int
+}
+
+func (w *sectionWriter) Write(p []byte) (int, error) {
+	if w.limit > 0 && len(w.writes) == w.limit {
+		return 0, errors.New("disk full")
+	}
+	w.writes = append(w.writes, string(p))
+	return len(p), nil
+}
+
+func TestLibrary_WriteStreams(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		os.WriteFile(filepath.Join(tmpDir, name), []byte("content of "+name+"\n"), 0644)
+	}
+
+	// Each file reaches w on its own rather than in one buffered write
+	var w sectionWriter
+	if err := clipcat.New(clipcat.WithPaths(tmpDir)).Write(context.Background(), &w); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if len(w.writes) < 3 || !strings.Contains(w.writes[0], "content of a.txt") || strings.Contains(w.writes[0], "content of b.txt") {
+		t.Errorf("Expected one write per file, got %q", w.writes)
+	}
+
+	w = sectionWriter{limit: 1}
+	if err := clipcat.New(clipcat.WithPaths(tmpDir)).Write(context.Background(), &w); err == nil || !strings.Contains(err.Error(), "disk full") {
+		t.Errorf("Expected the write error, got %v", err)
+	}
+}
+
+func TestLibrary_DefaultExcludesAndErrors(t *testing.T) {
+	tmpDir := setupTestDirectory(t)
+	defer os.RemoveAll(tmpDir)
+
+	var buf bytes.Buffer
+	err := clipcat.New(clipcat.WithPaths(filepath.Join(tmpDir, "node_modules"))).Write(context.Background(), &buf)
+	if !errors.Is(err, clipcat.ErrNoFiles) {
+		t.Errorf("Expected ErrNoFiles with default excludes, got %v", err)
+	}
+
+	buf.Reset()
+	err = clipcat.New(clipcat.WithPaths(filepath.Join(tmpDir, "node_modules")), clipcat.WithDefaultExcludes()).Write(context.Background(), &buf)
+	if err != nil || !strings.Contains(buf.String(), "pkg.json") {
+		t.Errorf("Expected node_modules without default excludes, got err=%v output=%q", err, buf.String())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = clipcat.New(clipcat.WithPaths(tmpDir)).Write(ctx, &buf)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+
+func TestLibrary_WithFormat(t *testing.T) {
+	tmpDir := setupTestDirectory(t)
+	defer os.RemoveAll(tmpDir)
+
+	var buf bytes.Buffer
+	err := clipcat.New(clipcat.WithPaths(filepath.Join(tmpDir, "src")), clipcat.WithFormat("markdown")).Write(context.Background(), &buf)
+	if err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "## "+filepath.Join(tmpDir, "src", "app.go")+"\n\n```go\npackage src\n```\n") {
+		t.Errorf("Expected markdown sections, got:\n%s", buf.String())
+	}
+
+	err = clipcat.New(clipcat.WithPaths(tmpDir), clipcat.WithFormat("nope")).Write(context.Background(), &buf)
+	if err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
+
+func TestLibrary_WithFilters(t *testing.T) {
+	tmpDir := setupTestDirectory(t)
+	defer os.RemoveAll(tmpDir)
+
+	upper := func(path string, content []byte) []byte { return bytes.ToUpper(content) }
+	var buf bytes.Buffer
+	err := clipcat.New(clipcat.WithPaths(filepath.Join(tmpDir, "src", "app.go")), clipcat.WithFilters(upper)).Write(context.Background(), &buf)
+	if err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "PACKAGE SRC") {
+		t.Errorf("Expected filtered content, got:\n%s", buf.String())
+	}
+}
+
+func TestLibrary_ConcurrentReadsKeepOrder(t *testing.T) {
+	tmpDir := t.TempDir()
+	for i := range 200 {
+		path := filepath.Join(tmpDir, fmt.Sprintf("dir%d", i%7), fmt.Sprintf("file%d.txt", i))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(strings.Repeat(fmt.Sprintf("line %d\n", i), i)), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var sequential, concurrent bytes.Buffer
+	if err := clipcat.New(clipcat.WithPaths(tmpDir), clipcat.WithJobs(1)).Write(context.Background(), &sequential); err != nil {
+		t.Fatal(err)
+	}
+	if err := clipcat.New(clipcat.WithPaths(tmpDir), clipcat.WithJobs(16)).Write(context.Background(), &concurrent); err != nil {
+		t.Fatal(err)
+	}
+	if sequential.String() != concurrent.String() {
+		t.Error("Output with 16 jobs differs from output with 1 job")
+	}
+}
+
+func TestLibrary_WithLong(t *testing.T) {
+	tmpDir := setupTestDirectory(t)
+	defer os.RemoveAll(tmpDir)
+
+	var buf bytes.Buffer
+	err := clipcat.New(clipcat.WithPaths(filepath.Join(tmpDir, "src")), clipcat.WithLong(true), clipcat.WithOnlyTree(true)).Write(context.Background(), &buf)
+	if err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "FILE LISTING") || strings.Contains(out, "FILE HIERARCHY") {
+		t.Errorf("Expected a listing instead of the tree, got:\n%s", out)
+	}
+	if !strings.Contains(out, "-rw-r--r--  11  ") || !strings.Contains(out, filepath.Join(tmpDir, "src", "app.go")+"\n") {
+		t.Errorf("Expected mode, size and path for app.go, got:\n%s", out)
+	}
+	if strings.Contains(out, "package src") {
+		t.Errorf("--only-tree should leave out file contents, got:\n%s", out)
+	}
+}
+
+func TestLibrary_JSONWarnings(t *testing.T) {
+	tmpDir := setupTestDirectory(t)
+	defer os.RemoveAll(tmpDir)
+
+	var buf, warnings bytes.Buffer
+	missing := filepath.Join(tmpDir, "missing.go")
+	err := clipcat.New(
+		clipcat.WithPaths(filepath.Join(tmpDir, "main.go"), missing),
+		clipcat.WithFormat("json"),
+		clipcat.WithWarnings(&warnings),
+	).Write(context.Background(), &buf)
+	if err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	var doc struct {
+		Warnings []struct{ Kind, Path, Message string }
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("json output does not parse: %v", err)
+	}
+	if len(doc.Warnings) != 1 || doc.Warnings[0].Kind != "not-exist" || doc.Warnings[0].Path != missing {
+		t.Errorf("Expected a not-exist warning for %s, got %+v", missing, doc.Warnings)
+	}
+	if !strings.Contains(warnings.String(), "Warning: Skipping non-existent path: "+missing) {
+		t.Errorf("Expected the warning on the warnings writer too, got %q", warnings.String())
+	}
+}
+
+func TestLibrary_WithTreeShowExcluded(t *testing.T) {
+	tmpDir := setupTestDirectory(t)
+	defer os.RemoveAll(tmpDir)
+
+	var buf bytes.Buffer
+	err := clipcat.New(
+		clipcat.WithPaths(filepath.Join(tmpDir, "src")),
+		clipcat.WithExcludes("utils/"),
+		clipcat.WithTreeShowExcluded(true),
+		clipcat.WithOnlyTree(true),
+	).Write(context.Background(), &buf)
+	if err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if out := buf.String(); !strings.Contains(out, "\n-utils/ [excluded: -e utils/]\n") || !strings.Contains(out, "\n-app.go\n") {
+		t.Errorf("Expected utils/ marked as excluded in the tree, got:\n%s", out)
+	}
+}
+
+func TestLibrary_WithSummary(t *testing.T) {
+	tmpDir := setupTestDirectory(t)
+	defer os.RemoveAll(tmpDir)
+
+	src := filepath.Join(tmpDir, "src")
+	for _, format := range []string{"plain", "json"} {
+		var buf bytes.Buffer
+		err := clipcat.New(
+			clipcat.WithPaths(src),
+			clipcat.WithExcludes("format.go"),
+			clipcat.WithTree(true),
+			clipcat.WithSummary(true),
+			clipcat.WithFormat(format),
+		).Write(context.Background(), &buf)
+		if err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+		out := buf.String()
+
+		if format == "json" {
+			var doc struct {
+				Summary struct {
+					Roots    []string
+					Files    int
+					Lines    int
+					Tokens   int
+					Excludes []string
+				}
+			}
+			if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+				t.Fatalf("json output does not parse: %v", err)
+			}
+			s := doc.Summary
+			if s.Files != 2 || s.Lines != 2 || s.Tokens == 0 || len(s.Roots) != 1 || s.Roots[0] != src {
+				t.Errorf("Unexpected json summary: %+v", s)
+			}
+			if strings.Join(s.Excludes, ",") != "format.go,default excludes" {
+				t.Errorf("Unexpected excludes: %v", s.Excludes)
+			}
+			continue
+		}
+
+		if !strings.HasPrefix(out, "=======\nSUMMARY\n=======\n\ngenerated: ") {
+			t.Errorf("Expected the summary first, got:\n%s", out)
+		}
+		for _, want := range []string{"\nclipcat:   ", "\nroots:     " + src + "\n", "\nfiles:     2\n", "\nlines:     2\n", "\nexcludes:  format.go, default excludes\n\n==============\nFILE HIERARCHY\n"} {
+			if !strings.Contains(out, want) {
+				t.Errorf("Expected %q in summary, got:\n%s", want, out)
+			}
+		}
+	}
+}
+
+func TestLibrary_WithPathsOnly(t *testing.T) {
+	tmpDir := setupTestDirectory(t)
+	defer os.RemoveAll(tmpDir)
+
+	var buf bytes.Buffer
+	err := clipcat.New(
+		clipcat.WithPaths(filepath.Join(tmpDir, "src")),
+		clipcat.WithPathsOnly(true),
+		clipcat.WithTree(true),
+		clipcat.WithLabel(func(path string) string {
+			rel, _ := filepath.Rel(tmpDir, path)
+			return filepath.ToSlash(rel)
+		}),
+	).Write(context.Background(), &buf)
+	if err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	want := "src/app.go\nsrc/components/button.go\nsrc/utils/format.go\n"
+	if buf.String() != want {
+		t.Errorf("Got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+func TestLibrary_WithDeterministic(t *testing.T) {
+	originalDir, _ := os.Getwd()
+	defer os.Chdir(originalDir)
+
+	// Two identical trees at different locations render the same bytes
+	var outputs []string
+	for range 2 {
+		tmpDir := setupTestDirectory(t)
+		defer os.RemoveAll(tmpDir)
+		os.Chdir(tmpDir)
+
+		var buf bytes.Buffer
+		err := clipcat.New(
+			clipcat.WithPaths("src", filepath.Join(tmpDir, "main.go")),
+			clipcat.WithLong(true),
+			clipcat.WithSummary(true),
+			clipcat.WithDeterministic(true),
+		).Write(context.Background(), &buf)
+		if err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+		out := buf.String()
+		if strings.Contains(out, tmpDir) {
+			t.Errorf("Expected no absolute paths, got:\n%s", out)
+		}
+		outputs = append(outputs, out)
+	}
+
+	out := outputs[0]
+	if outputs[1] != out {
+		t.Errorf("Outputs differ:\n%s\n---\n%s", out, outputs[1])
+	}
+	if strings.Contains(out, "generated:") || strings.Contains(out, "FILE LISTING") {
+		t.Errorf("Expected no timestamps, got:\n%s", out)
+	}
+	for _, want := range []string{"\nroots:     src, main.go\n", "FILE HIERARCHY", "\nsrc/utils/format.go\n", "\nmain.go\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in output, got:\n%s", want, out)
+		}
+	}
+}
+func TestLibrary_WithManifest(t *testing.T) {
+	tmpDir := setupTestDirectory(t)
+	defer os.RemoveAll(tmpDir)
+
+	// The hash is of the file as read from disk, not after filters
+	app := filepath.Join(tmpDir, "src", "app.go")
+	sum := sha256.Sum256([]byte("package src"))
+	want := hex.EncodeToString(sum[:]) + "  11  " + app + "\n"
+
+	upper := func(path string, content []byte) []byte { return bytes.ToUpper(content) }
+	var buf bytes.Buffer
+	err := clipcat.New(clipcat.WithPaths(app), clipcat.WithFilters(upper), clipcat.WithManifest(true)).Write(context.Background(), &buf)
+	if err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "PACKAGE SRC") || !strings.HasSuffix(out, "========\nMANIFEST\n========\n\n"+want+"\n") {
+		t.Errorf("Expected the contents and then the manifest, got:\n%s", out)
+	}
+
+	buf.Reset()
+	err = clipcat.New(clipcat.WithPaths(filepath.Join(tmpDir, "src")), clipcat.WithManifestOnly(true), clipcat.WithFormat("json")).Write(context.Background(), &buf)
+	if err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	var doc struct {
+		Files    []json.RawMessage
+		Manifest []struct {
+			Path   string
+			Size   int64
+			SHA256 string
+		}
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("json output does not parse: %v", err)
+	}
+	if len(doc.Files) != 0 || len(doc.Manifest) != 3 {
+		t.Fatalf("Expected a manifest of 3 files and no contents, got %s", buf.String())
+	}
+	if m := doc.Manifest[0]; m.Path != app || m.Size != 11 || m.SHA256 != hex.EncodeToString(sum[:]) {
+		t.Errorf("Unexpected manifest entry: %+v", m)
+	}
+
+	err = clipcat.New(clipcat.WithPaths(app), clipcat.WithManifestOnly(true), clipcat.WithFormat("repomix")).Write(context.Background(), &buf)
+	if err == nil {
+		t.Error("Expected an error for a format without a manifest")
+	}
+}
+
+func TestLibrary_HeadTailStreamsLargeFiles(t *testing.T) {
+	// Over the streaming threshold, so only the kept lines are held
+	log := filepath.Join(t.TempDir(), "big.log")
+	content := "first\n" + strings.Repeat("0123456789abcdef\n", 300_000) + "last\n"
+	if err := os.WriteFile(log, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256([]byte(content))
+
+	var buf bytes.Buffer
+	cfg := clipcat.Config{Paths: []string{log}, HeadLines: 1, TailLines: 1, Manifest: true}
+	if err := clipcat.New(clipcat.WithConfig(cfg)).Write(context.Background(), &buf); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "first\n[... 300,000 lines omitted ...]\nlast\n") {
+		t.Errorf("Expected the first and last lines around a marker, got:\n%.500s", out)
+	}
+	if !strings.Contains(out, hex.EncodeToString(sum[:])+"  "+fmt.Sprint(len(content))+"  "+log) {
+		t.Errorf("Expected the manifest to describe the whole file, got:\n%.500s", out)
+	}
+}
+
+func TestLibrary_MaxLinesPerFile(t *testing.T) {
+	dir := t.TempDir()
+	lines := func(n int) string {
+		var b strings.Builder
+		for i := 1; i <= n; i++ {
+			fmt.Fprintf(&b, "line %d\n", i)
+		}
+		return b.String()
+	}
+	for name, content := range map[string]string{"app.log": lines(10), "main.go": lines(10), "go.sum": lines(10)} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buf bytes.Buffer
+	cfg := clipcat.Config{Paths: []string{dir}, MaxLinesPerFile: 4, MaxLinesByName: map[string]int{".log": 2, "go.sum": 0}}
+	if err := clipcat.New(clipcat.WithConfig(cfg)).Write(context.Background(), &buf); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"line 1\nline 2\n[... 6 lines omitted ...]\nline 9\nline 10\n", // main.go, by the flag
+		"line 1\n[... 8 lines omitted ...]\nline 10\n",                 // app.log, by extension
+		lines(10), // go.sum, exempt by name
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in the output, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestLibrary_WithMaxFiles(t *testing.T) {
+	tmpDir := setupTestDirectory(t)
+	defer os.RemoveAll(tmpDir)
+
+	// The first files in sort order are kept, whatever order the walk found
+	var warnings bytes.Buffer
+	b := clipcat.New(clipcat.WithPaths(filepath.Join(tmpDir, "src")), clipcat.WithMaxFiles(2), clipcat.WithWarnings(&warnings))
+	files, err := b.Files(context.Background())
+	if err != nil {
+		t.Fatalf("Files failed: %v", err)
+	}
+	want := []string{filepath.Join(tmpDir, "src", "app.go"), filepath.Join(tmpDir, "src", "components", "button.go")}
+	if !slices.Equal(files, want) {
+		t.Errorf("Expected %v, got %v", want, files)
+	}
+	dropped := filepath.Join(tmpDir, "src", "utils", "format.go")
+	if got := warnings.String(); got != "Warning: --max-files 2: left out 1 of 3 files ("+dropped+")\n" {
+		t.Errorf("Unexpected warning: %q", got)
+	}
+
+	var buf bytes.Buffer
+	if err := b.Write(context.Background(), &buf); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if strings.Contains(buf.String(), "package utils") || !strings.Contains(buf.String(), "package components") {
+		t.Errorf("Expected only the first 2 files, got:\n%s", buf.String())
+	}
+}
+
+// removingFormatter deletes path once the tree is written, which is after
+// collection and before any file is read.
+type removingFormatter struct {
+	output.Formatter
+	path string
+}
+
+func (f removingFormatter) WriteTree(w io.Writer, roots, files []string) error {
+	os.Remove(f.path)
+	return f.Formatter.WriteTree(w, roots, files)
+}
+
+func TestLibrary_FileRemovedDuringRun(t *testing.T) {
+	for _, strict := range []bool{false, true} {
+		tmpDir := setupTestDirectory(t)
+		defer os.RemoveAll(tmpDir)
+		app := filepath.Join(tmpDir, "src", "app.go")
+
+		plain, err := output.NewFormatter("plain")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf, warnings bytes.Buffer
+		err = clipcat.New(
+			clipcat.WithPaths(filepath.Join(tmpDir, "src")),
+			clipcat.WithTree(true),
+			clipcat.WithFormatter(removingFormatter{plain, app}),
+			clipcat.WithStrict(strict),
+			clipcat.WithWarnings(&warnings),
+		).Write(context.Background(), &buf)
+
+		if strict {
+			if !errors.Is(err, clipcat.ErrChanged) {
+				t.Errorf("Expected ErrChanged with strict, got %v", err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+		if !strings.Contains(buf.String(), app+"\n"+strings.Repeat("=", len(app))+"\n\n[removed during run]\n") {
+			t.Errorf("Expected a placeholder for the removed file, got:\n%s", buf.String())
+		}
+		if !strings.Contains(buf.String(), "package components") {
+			t.Errorf("Expected the other files to be copied, got:\n%s", buf.String())
+		}
+		if !strings.Contains(warnings.String(), app+" was removed or changed during the run") {
+			t.Errorf("Expected a warning, got %q", warnings.String())
+		}
+	}
+}
+
+func TestLibrary_StrictWarnings(t *testing.T) {
+	tmpDir := setupTestDirectory(t)
+	defer os.RemoveAll(tmpDir)
+
+	for _, strict := range []bool{false, true} {
+		var buf bytes.Buffer
+		err := clipcat.New(
+			clipcat.WithPaths(filepath.Join(tmpDir, "src"), filepath.Join(tmpDir, "missing.go"), filepath.Join(tmpDir, "*.rs")),
+			clipcat.WithStrict(strict),
+		).Write(context.Background(), &buf)
+
+		// The bundle is written either way
+		if !strings.Contains(buf.String(), "package components") {
+			t.Errorf("strict=%v: expected the files to be written, got:\n%s", strict, buf.String())
+		}
+		if !strict {
+			if err != nil {
+				t.Errorf("Write failed: %v", err)
+			}
+			continue
+		}
+		var strictErr *clipcat.StrictError
+		if !errors.As(err, &strictErr) {
+			t.Fatalf("Expected a StrictError, got %v", err)
+		}
+		want := []string{
+			"Skipping non-existent path: " + filepath.Join(tmpDir, "missing.go"),
+			"Pattern '" + filepath.Join(tmpDir, "*.rs") + "' matched no files",
+		}
+		if !slices.Equal(strictErr.Problems, want) {
+			t.Errorf("Problems = %q, want %q", strictErr.Problems, want)
+		}
+	}
+}
+
+func TestLibrary_WithRun(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh syntax")
+	}
+	tmpDir := setupTestDirectory(t)
+	defer os.RemoveAll(tmpDir)
+
+	var buf, warnings bytes.Buffer
+	err := clipcat.New(
+		clipcat.WithPaths(filepath.Join(tmpDir, "main.go")),
+		clipcat.WithRun("echo out; echo err >&2; exit 3", "no-such-command-clipcat"),
+		clipcat.WithWarnings(&warnings),
+	).Write(context.Background(), &buf)
+	if err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	out := buf.String()
+	header := "$ echo out; echo err >&2; exit 3"
+	want := strings.Repeat("=", len(header)) + "\n" + header + "\n" + strings.Repeat("=", len(header)) + "\n\nout\nerr\n[exit status 3]\n"
+	if !strings.Contains(out, "func main() {}") || !strings.Contains(out, want) {
+		t.Errorf("Expected the file and then the command output, got:\n%s", out)
+	}
+	// The shell reports a missing command as exit status 127
+	if !strings.Contains(out, "$ no-such-command-clipcat\n") || !strings.Contains(out, "[exit status 127]\n") {
+		t.Errorf("Expected the failing command's output, got:\n%s", out)
+	}
+}
+
+func TestLibrary_WithEnvInfo(t *testing.T) {
+	tmpDir := setupTestDirectory(t)
+	defer os.RemoveAll(tmpDir)
+	t.Setenv("GOFLAGS", "-mod=readonly")
+	t.Setenv("CLIPCAT_TEST_SECRET", "hidden")
+	t.Setenv("CLIPCAT_TEST_EXTRA", "shown")
+
+	var buf bytes.Buffer
+	err := clipcat.New(clipcat.WithPaths(filepath.Join(tmpDir, "main.go")), clipcat.WithEnvInfo(true, "CLIPCAT_TEST_EXTRA")).Write(context.Background(), &buf)
+	if err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	out := buf.String()
+	_, env, ok := strings.Cut(out, "===========\nENVIRONMENT\n===========\n\n")
+	if !ok {
+		t.Fatalf("Expected an ENVIRONMENT section, got:\n%s", out)
+	}
+	for _, want := range []string{"os:      " + runtime.GOOS + "/" + runtime.GOARCH, "  GOFLAGS=-mod=readonly\n", "  CLIPCAT_TEST_EXTRA=shown\n"} {
+		if !strings.Contains(env, want) {
+			t.Errorf("Expected %q in the environment section, got:\n%s", want, env)
+		}
+	}
+	// Only allowlisted and requested variables are reported
+	if strings.Contains(env, "hidden") {
+		t.Errorf("Unexpected variable in the environment section:\n%s", env)
+	}
+}
+
+func TestLibrary_WithGroupBy(t *testing.T) {
+	tmpDir := setupTestDirectory(t)
+	defer os.RemoveAll(tmpDir)
+	originalDir, _ := os.Getwd()
+	defer os.Chdir(originalDir)
+	os.Chdir(tmpDir)
+
+	// groups lists each file as "group path", from the json format
+	groups := func(mode string, paths ...string) string {
+		t.Helper()
+		var buf bytes.Buffer
+		err := clipcat.New(clipcat.WithPaths(paths...), clipcat.WithGroupBy(mode), clipcat.WithFormat("json"), clipcat.WithDeterministic(true)).Write(context.Background(), &buf)
+		if err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+		var doc struct{ Files []struct{ Path, Group string } }
+		if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+			t.Fatalf("json output does not parse: %v", err)
+		}
+		var lines []string
+		for _, f := range doc.Files {
+			lines = append(lines, f.Group+" "+f.Path)
+		}
+		return strings.Join(lines, ", ")
+	}
+
+	want := "./ main.go, src/ src/app.go, src/ src/components/button.go, tests/ tests/integration_test.go"
+	if got := groups(clipcat.GroupDir, "main.go", "src/app.go", "src/components", "tests"); got != want {
+		t.Errorf("--group-by dir:\n got %q\nwant %q", got, want)
+	}
+
+	// Groups keep their files together even where sorting interleaves them
+	if err := os.WriteFile("src/notes.md", []byte("# Notes"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	want = ".md README.md, .md src/notes.md, .go main.go, .go src/app.go"
+	if got := groups(clipcat.GroupExt, "main.go", "README.md", "src/app.go", "src/notes.md"); got != want {
+		t.Errorf("--group-by ext:\n got %q\nwant %q", got, want)
+	}
+
+	want = "src/components src/components/button.go, src/utils src/utils/format.go"
+	if got := groups(clipcat.GroupRoot, "src/utils", "src/components"); got != want {
+		t.Errorf("--group-by root:\n got %q\nwant %q", got, want)
+	}
+
+	// Overlapping inputs: files belong to the most specific one
+	want = "src src/app.go, src src/notes.md, src/components src/components/button.go, src/utils src/utils/format.go"
+	if got := groups(clipcat.GroupRoot, "src", "src/components", "src/utils"); got != want {
+		t.Errorf("--group-by root with overlapping inputs:\n got %q\nwant %q", got, want)
+	}
+
+	var buf bytes.Buffer
+	err := clipcat.New(clipcat.WithPaths("main.go", "src/app.go"), clipcat.WithGroupBy(clipcat.GroupDir), clipcat.WithFormat("markdown"), clipcat.WithDeterministic(true)).Write(context.Background(), &buf)
+	if err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), "# ./\n\n## main.go\n") || !strings.Contains(buf.String(), "```\n\n# src/\n\n## src/app.go\n") {
+		t.Errorf("Expected a heading per group, got:\n%s", buf.String())
+	}
+}
+
+func TestLibrary_QuickfixAndDiagnostics(t *testing.T) {
+	tmpDir := setupTestDirectory(t)
+	defer os.RemoveAll(tmpDir)
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	os.Chdir(tmpDir)
+
+	var lines strings.Builder
+	for i := 1; i <= 30; i++ {
+		fmt.Fprintf(&lines, "line %d\n", i)
+	}
+	os.WriteFile(filepath.Join(tmpDir, "long.go"), []byte(lines.String()), 0644)
+	quickfix := filepath.Join(t.TempDir(), "errors.txt")
+	os.WriteFile(quickfix, []byte("# example\nlong.go:12:4: undefined: x\nsrc/app.go:1: unused import\ngone.go:3: y\n"), 0644)
+
+	var buf, warnings bytes.Buffer
+	b := clipcat.New(clipcat.WithQuickfix(quickfix), clipcat.WithContextLines(1), clipcat.WithWarnings(&warnings))
+	if err := b.Write(context.Background(), &buf); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	got := buf.String()
+	want := "[... 10 lines omitted ...]\n11 | line 11\n12 | line 12\n13 | line 13\n[... 17 lines omitted ...]\n"
+	if !strings.Contains(got, want) || !strings.Contains(got, filepath.Join(tmpDir, "src", "app.go")) {
+		t.Errorf("Expected an excerpt of long.go and src/app.go, got:\n%s", got)
+	}
+	if !strings.Contains(warnings.String(), "gone.go not found") {
+		t.Errorf("Expected a warning for the missing file, got %q", warnings.String())
+	}
+
+	// ESLint output lists clean files with no messages
+	diagnostics := filepath.Join(t.TempDir(), "lint.json")
+	os.WriteFile(diagnostics, []byte(`[{"filePath":"long.go","messages":[{"line":2,"endLine":3}]},{"filePath":"README.md","messages":[]}]`), 0644)
+	buf.Reset()
+	b = clipcat.New(clipcat.WithDiagnostics(diagnostics), clipcat.WithContextLines(0))
+	if err := b.Write(context.Background(), &buf); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	got = buf.String()
+	if !strings.Contains(got, "[... 1 line omitted ...]\n 2 | line 2\n 3 | line 3\n[... 27 lines omitted ...]\n") || strings.Contains(got, "README.md") {
+		t.Errorf("Expected only the excerpt of long.go, got:\n%s", got)
+	}
+}
+
+func TestLibrary_WithGrep(t *testing.T) {
+	tmpDir := setupTestDirectory(t)
+	defer os.RemoveAll(tmpDir)
+
+	var buf bytes.Buffer
+	b := clipcat.New(clipcat.WithPaths(tmpDir), clipcat.WithGrep("package components"), clipcat.WithGrepRegexp(`^package m\w+`), clipcat.WithGrepCounts(true))
+	if err := b.Write(context.Background(), &buf); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	got := buf.String()
+	button := filepath.Join(tmpDir, "src", "components", "button.go")
+	if !strings.Contains(got, button+"\n1 match\n") || strings.Contains(got, "package utils") {
+		t.Errorf("Expected only matching files with counts, got:\n%s", got)
+	}
+
+	err := clipcat.New(clipcat.WithPaths(tmpDir), clipcat.WithGrep("no such text")).Write(context.Background(), io.Discard)
+	if !errors.Is(err, clipcat.ErrNoFiles) {
+		t.Errorf("Expected ErrNoFiles, got %v", err)
+	}
+}
+
+func TestLibrary_GrepContext(t *testing.T) {
+	tmpDir := t.TempDir()
+	var lines strings.Builder
+	for i := 1; i <= 20; i++ {
+		if i == 8 || i == 10 {
+			fmt.Fprintf(&lines, "use FooService %d\n", i)
+		} else {
+			fmt.Fprintf(&lines, "line %d\n", i)
+		}
+	}
+	os.WriteFile(filepath.Join(tmpDir, "svc.go"), []byte(lines.String()), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "other.go"), []byte("nothing here\n"), 0644)
+
+	var buf bytes.Buffer
+	// Excerpts are not numbered twice with --line-numbers
+	cfg := clipcat.Config{Paths: []string{tmpDir}, Grep: []string{"FooService"}, Excerpt: true, ContextLines: 1, LineNumbers: true}
+	b := clipcat.New(clipcat.WithConfig(cfg))
+	if err := b.Write(context.Background(), &buf); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	want := "[... 6 lines omitted ...]\n 7 | line 7\n 8 | use FooService 8\n 9 | line 9\n10 | use FooService 10\n11 | line 11\n[... 9 lines omitted ...]\n"
+	if got := buf.String(); !strings.Contains(got, want) || strings.Contains(got, "other.go") {
+		t.Errorf("Expected the matches with one line of context, got:\n%s", got)
+	}
+}
+
+func TestLibrary_WithSummarizeLocks(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "main.go"), []byte("package main\n"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "go.sum"), []byte("golang.org/x/sync v0.7.0 h1:x=\ngolang.org/x/sync v0.7.0/go.mod h1:y=\n"), 0644)
+
+	files, err := clipcat.New(clipcat.WithPaths(tmpDir)).Files(context.Background())
+	if err != nil || len(files) != 1 {
+		t.Fatalf("Expected go.sum to be skipped by default, got %v (%v)", files, err)
+	}
+
+	var buf bytes.Buffer
+	if err := clipcat.New(clipcat.WithPaths(tmpDir), clipcat.WithSummarizeLocks(true)).Write(context.Background(), &buf); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if want := "[lockfile go.sum: 1 dependency]\ngolang.org/x/sync v0.7.0\n"; !strings.Contains(buf.String(), want) || strings.Contains(buf.String(), "h1:") {
+		t.Errorf("Expected go.sum summarized, got:\n%s", buf.String())
+	}
+}
+
+func TestLibrary_WithTablePreview(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "big.csv"), []byte("id,name\n"+strings.Repeat("1,x\n", 100)), 0644)
+	// A bare quote in an unquoted field is not valid CSV
+	os.WriteFile(filepath.Join(tmpDir, "bad.csv"), []byte("a,b\n\"x\"y,1\n"), 0644)
+
+	var buf bytes.Buffer
+	if err := clipcat.New(clipcat.WithPaths(tmpDir), clipcat.WithTablePreview(1), clipcat.WithManifest(true)).Write(context.Background(), &buf); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	got := buf.String()
+	if !strings.Contains(got, "id | name\n---+-----\n1  | x\n[table: 100 rows, first 1 shown]\n") {
+		t.Errorf("Expected a preview of big.csv, got:\n%s", got)
+	}
+	if !strings.Contains(got, "a,b\n\"x\"y,1\n") {
+		t.Errorf("Expected bad.csv copied as text, got:\n%s", got)
+	}
+	if !strings.Contains(got, fmt.Sprintf("%d", 8+4*100)) {
+		t.Errorf("Expected the manifest to list the size on disk, got:\n%s", got)
+	}
+}
+func TestLibrary_WithFilterCommands(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "a.txt"), []byte("hello acme\n"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "secret.txt"), []byte("token\n"), 0644)
+
+	var buf, warnings bytes.Buffer
+	b := clipcat.New(clipcat.WithPaths(tmpDir), clipcat.WithWarnings(&warnings),
+		clipcat.WithFilterCommands(`sed s/acme/EXAMPLE/`, `case "$CLIPCAT_FILE" in *secret*) exit 1;; esac; tr a-z A-Z`))
+	if err := b.Write(context.Background(), &buf); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	got := buf.String()
+	if !strings.Contains(got, "HELLO EXAMPLE\n") {
+		t.Errorf("Expected a.txt through both commands, got:\n%s", got)
+	}
+	if strings.Contains(got, "token") || !strings.Contains(got, "[withheld: --filter-cmd failed]") {
+		t.Errorf("Expected secret.txt withheld, got:\n%s", got)
+	}
+	if !strings.Contains(warnings.String(), "secret.txt: --filter-cmd") {
+		t.Errorf("Expected a warning for secret.txt, got %q", warnings.String())
+	}
+}
+
+func TestLibrary_RedactsDiffs(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	tmpDir := t.TempDir()
+	file := filepath.Join(tmpDir, "config.go")
+	os.WriteFile(file, []byte("package config\n"), 0644)
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "."},
+		{"-c", "user.name=t", "-c", "user.email=t@example.com", "commit", "-q", "-m", "init"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = tmpDir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	os.WriteFile(file, []byte("package config\n\nconst apiKey = \"sk-abcdefghijklmnopqrstuvwxyz\"\n"), 0644)
+	originalDir, _ := os.Getwd()
+	defer os.Chdir(originalDir)
+	os.Chdir(tmpDir)
+
+	for _, diffOnly := range []bool{false, true} {
+		var buf bytes.Buffer
+		b := clipcat.New(clipcat.WithConfig(clipcat.Config{Paths: []string{file}, WithDiff: "HEAD", DiffOnly: diffOnly, Redact: true}))
+		if err := b.Write(context.Background(), &buf); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+		got := buf.String()
+		if strings.Contains(got, "sk-abcdefghij") {
+			t.Errorf("Expected the secret redacted in the diff (diff only: %v), got:\n%s", diffOnly, got)
+		}
+		if !strings.Contains(got, "+const apiKey = \"[REDACTED]\"") {
+			t.Errorf("Expected the redacted changed line in the diff (diff only: %v), got:\n%s", diffOnly, got)
+		}
+	}
+}
+
+func TestLibrary_Policy(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tmpDir, "secrets"), 0755)
+	os.WriteFile(filepath.Join(tmpDir, "main.go"), []byte("package main\n"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "server.pem"), []byte("PRIVATE\n"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "secrets", "db.txt"), []byte("hunter2\n"), 0644)
+	policy := filepath.Join(t.TempDir(), "policy.toml")
+	os.WriteFile(policy, []byte(`deny = ["*.pem", "secrets/"]`+"\n"), 0644)
+	saved := config.PolicyPath
+	config.PolicyPath = policy
+	defer func() { config.PolicyPath = saved }()
+
+	// No flag brings forbidden files back
+	var buf, warnings bytes.Buffer
+	b := clipcat.New(clipcat.WithPaths(tmpDir), clipcat.WithWarnings(&warnings),
+		clipcat.WithConfig(clipcat.Config{NoDefaultExcludes: true, Paths: []string{tmpDir}}))
+	if err := b.Write(context.Background(), &buf); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if got := buf.String(); !strings.Contains(got, "package main") || strings.Contains(got, "PRIVATE") || strings.Contains(got, "hunter2") {
+		t.Errorf("Expected forbidden files left out, got:\n%s", got)
+	}
+	if !strings.Contains(warnings.String(), "2 files left out by policy "+policy) {
+		t.Errorf("Expected a policy warning, got %q", warnings.String())
+	}
+
+	// Naming a forbidden file is refused
+	err := clipcat.New(clipcat.WithPaths(filepath.Join(tmpDir, "server.pem"))).Write(context.Background(), io.Discard)
+	var policyErr *exclude.PolicyError
+	if !errors.As(err, &policyErr) || !strings.Contains(err.Error(), "forbidden by policy "+policy+" *.pem") {
+		t.Errorf("Expected a policy refusal, got %v", err)
+	}
+
+	os.WriteFile(policy, []byte("deny = \"*.pem\"\n"), 0644)
+	if err := clipcat.New(clipcat.WithPaths(tmpDir)).Write(context.Background(), io.Discard); err == nil {
+		t.Error("Expected an invalid policy to fail the copy")
+	}
+}
+func TestLibrary_WriteStats(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tmpDir, "src", "util"), 0755)
+	os.WriteFile(filepath.Join(tmpDir, "src", "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "src", "util", "util.go"), []byte("package util\n"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "src", "util", "notes"), []byte("no newline"), 0644)
+
+	var buf bytes.Buffer
+	if err := clipcat.New(clipcat.WithPaths(tmpDir)).WriteStats(context.Background(), &buf); err != nil {
+		t.Fatalf("WriteStats failed: %v", err)
+	}
+	got := buf.String()
+	src, util := filepath.Join(tmpDir, "src"), filepath.Join(tmpDir, "src", "util")
+	for _, want := range []string{
+		"     3       52 B        5 ",
+		fmt.Sprintf("100%%  %s\n", src),
+		"     2       23 B        2 ",
+		"  .go\n", "  (none)\n",
+		"3 files, 52 B, 5 lines, ~",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected %q in:\n%s", want, got)
+		}
+	}
+	if !strings.Contains(got, "  "+util+"\n") || strings.Contains(got, "  "+tmpDir+"\n") {
+		t.Errorf("Expected directories up to %s, got:\n%s", src, got)
+	}
+}
+
+func TestLibrary_WithSmartOrder(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"README.md":          "# demo\n",
+		"go.mod":             "module example.com/demo\n\ngo 1.22\n",
+		"aaa.go":             "package demo\n",
+		"cmd/demo/main.go":   "package main\n\nimport (\n\t\"fmt\"\n\n\t\"example.com/demo/store\"\n)\n\nfunc main() { fmt.Println(store.Get()) }\n",
+		"store/store.go":     "package store\n\nimport \"example.com/demo/util\"\n\nfunc Get() string { return util.Name }\n",
+		"util/util.go":       "package util\n\nconst Name = \"x\"\n",
+		"web/app.ts":         "import { helper } from './lib/helper.js';\nimport './lib/helper';\n",
+		"web/lib/helper.ts":  "export const helper = 1;\n",
+		"web/other.ts":       "const x = require('./lib/helper');\n",
+		"py/tool.py":         "from .shared import thing\nimport os\n",
+		"py/shared.py":       "thing = 1\n",
+		"py/zz_unrelated.py": "print('hi')\n",
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := clipcat.New(clipcat.WithPaths(dir), clipcat.WithSmartOrder(true)).Files(context.Background())
+	if err != nil {
+		t.Fatalf("Files failed: %v", err)
+	}
+	var got []string
+	for _, file := range files {
+		rel, _ := filepath.Rel(dir, file)
+		got = append(got, filepath.ToSlash(rel))
+	}
+	want := []string{
+		"README.md", "go.mod", "cmd/demo/main.go",
+		// Imported twice, then once each, in sort order
+		"web/lib/helper.ts", "py/shared.py", "store/store.go", "util/util.go",
+		"aaa.go", "py/tool.py", "py/zz_unrelated.py", "web/app.ts", "web/other.ts",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Smart order:\n got %v\nwant %v", got, want)
+	}
+}
+
+func TestLibrary_WithGoDeps(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not found")
+	}
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"go.mod":          "module example.com/demo\n\ngo 1.22\n",
+		"api/api.go":      "package api\n\nimport (\n\t\"fmt\"\n\n\t\"example.com/demo/store\"\n)\n\nvar _ = fmt.Sprint(store.Name)\n",
+		"api/api_test.go": "package api\n",
+		"store/store.go":  "package store\n\nimport \"example.com/demo/util\"\n\nvar Name = util.Name\n",
+		"util/util.go":    "package util\n\nconst Name = \"x\"\n",
+		"other/other.go":  "package other\n",
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	os.Chdir(dir)
+
+	for _, tt := range []struct {
+		depth int
+		want  string
+	}{
+		{0, "api/api.go,store/store.go,util/util.go"},
+		{1, "api/api.go,store/store.go"},
+	} {
+		files, err := clipcat.New(clipcat.WithGoDeps("./api"), clipcat.WithGoDepsDepth(tt.depth)).Files(context.Background())
+		if err != nil {
+			t.Fatalf("Files failed: %v", err)
+		}
+		var got []string
+		for _, file := range files {
+			rel, _ := filepath.Rel(dir, file)
+			got = append(got, filepath.ToSlash(rel))
+		}
+		if strings.Join(got, ",") != tt.want {
+			t.Errorf("Depth %d: got %v, want %s", tt.depth, got, tt.want)
+		}
+	}
+
+	if _, err := clipcat.New(clipcat.WithGoDeps("./missing")).Files(context.Background()); err == nil {
+		t.Error("Expected an error for a missing package")
+	}
+}
+
+func TestLibrary_WithFollowImports(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"src/main.tsx":                "import React from 'react';\nimport { App } from './App';\nimport './styles.css';\n",
+		"src/App.tsx":                 "import {\n  total,\n} from './lib/cart.js';\nconst Lazy = import('./pages/Lazy');\n",
+		"src/lib/cart.ts":             "export * from '../util';\nexport const total = 0;\n",
+		"src/util/index.ts":           "const pad = require('../../node_modules/pad/index.js');\n",
+		"src/pages/Lazy.tsx":          "export default function Lazy() {}\n",
+		"src/styles.css":              "body {}\n",
+		"src/unused.ts":               "export {};\n",
+		"node_modules/pad/index.js":   "module.exports = 1;\n",
+		"node_modules/react/index.js": "module.exports = 1;\n",
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := clipcat.New(clipcat.WithFollowImports(filepath.Join(dir, "src", "main.tsx"))).Files(context.Background())
+	if err != nil {
+		t.Fatalf("Files failed: %v", err)
+	}
+	var got []string
+	for _, file := range files {
+		rel, _ := filepath.Rel(dir, file)
+		got = append(got, filepath.ToSlash(rel))
+	}
+	want := "src/App.tsx,src/main.tsx,src/styles.css,src/lib/cart.ts,src/pages/Lazy.tsx,src/util/index.ts"
+	if strings.Join(got, ",") != want {
+		t.Errorf("Followed imports: got %v, want %s", got, want)
+	}
+
+	if _, err := clipcat.New(clipcat.WithFollowImports(filepath.Join(dir, "src", "missing.ts"))).Files(context.Background()); err == nil {
+		t.Error("Expected an error for a missing entry file")
+	}
+}
+
+func TestLibrary_WithTestsAndSources(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		"go/parse.go", "go/parse_test.go", "go/lonely.go",
+		"web/cart.ts", "web/cart.spec.ts", "web/__tests__/cart.test.ts",
+		"py/tool.py", "py/tests/test_tool.py",
+		"app/src/main/java/x/Foo.java", "app/src/test/java/x/FooTest.java",
+		"skip/gen.go", "skip/gen_test.go",
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("x\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	in := func(names ...string) []string {
+		var paths []string
+		for _, name := range names {
+			paths = append(paths, filepath.Join(dir, filepath.FromSlash(name)))
+		}
+		return paths
+	}
+	rel := func(files []string) string {
+		var got []string
+		for _, file := range files {
+			r, _ := filepath.Rel(dir, file)
+			got = append(got, filepath.ToSlash(r))
+		}
+		return strings.Join(got, ",")
+	}
+
+	b := clipcat.New(clipcat.WithPaths(in("go/parse.go", "go/lonely.go", "web/cart.ts", "py/tool.py", "app/src/main/java/x/Foo.java", "skip/gen.go")...),
+		clipcat.WithExcludes("gen_test.go"), clipcat.WithTests(true))
+	files, err := b.Files(context.Background())
+	if err != nil {
+		t.Fatalf("Files failed: %v", err)
+	}
+	want := "app/src/main/java/x/Foo.java,app/src/test/java/x/FooTest.java,go/lonely.go,go/parse.go,go/parse_test.go," +
+		"py/tool.py,py/tests/test_tool.py,skip/gen.go,web/cart.spec.ts,web/cart.ts,web/__tests__/cart.test.ts"
+	if got := rel(files); got != want {
+		t.Errorf("WithTests:\n got %s\nwant %s", got, want)
+	}
+
+	files, err = clipcat.New(clipcat.WithPaths(in("go/parse_test.go", "web/__tests__/cart.test.ts", "py/tests/test_tool.py")...), clipcat.WithSources(true)).Files(context.Background())
+	if err != nil {
+		t.Fatalf("Files failed: %v", err)
+	}
+	want = "go/parse.go,go/parse_test.go,py/tool.py,py/tests/test_tool.py,web/cart.ts,web/__tests__/cart.test.ts"
+	if got := rel(files); got != want {
+		t.Errorf("WithSources:\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestLibrary_WithPreset(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		"go.mod", "main.go", "Makefile", "README.md", "bin/tool.go",
+		"web/package.json", "web/src/app.ts", "web/coverage/report.js", "web/notes.txt",
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("x\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rel := func(files []string) string {
+		var got []string
+		for _, file := range files {
+			r, _ := filepath.Rel(dir, file)
+			got = append(got, filepath.ToSlash(r))
+		}
+		return strings.Join(got, ",")
+	}
+
+	tests := []struct {
+		opts []clipcat.Option
+		want string
+	}{
+		{[]clipcat.Option{clipcat.WithPaths(dir), clipcat.WithPreset("auto")}, "Makefile,go.mod,main.go"},
+		{[]clipcat.Option{clipcat.WithPaths(dir, filepath.Join(dir, "web")), clipcat.WithPreset("auto")},
+			"Makefile,go.mod,main.go,web/package.json,web/src/app.ts"},
+		{[]clipcat.Option{clipcat.WithPaths(dir), clipcat.WithPreset("go"), clipcat.WithLanguages("go", "markdown")}, "README.md,main.go"},
+		{[]clipcat.Option{clipcat.WithPaths(filepath.Join(dir, "web")), clipcat.WithPreset("none")},
+			"web/notes.txt,web/package.json,web/coverage/report.js,web/src/app.ts"},
+	}
+	for i, tt := range tests {
+		var warnings bytes.Buffer
+		files, err := clipcat.New(append(tt.opts, clipcat.WithWarnings(&warnings))...).Files(context.Background())
+		if err != nil {
+			t.Fatalf("%d: Files failed: %v", i, err)
+		}
+		if got := rel(files); got != tt.want {
+			t.Errorf("%d: got %s, want %s", i, got, tt.want)
+		}
+	}
+}
\ No newline at end of file
@@ -0,0 +1,104 @@
+package integration_test
+
+import (
+	"clipcat/pkg/clipcat"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestServe(t *testing.T) {
+	tmpDir := setupTestDirectory(t)
+	defer os.RemoveAll(tmpDir)
+
+	server := httptest.NewServer(clipcat.ServeHandler(tmpDir, "secret", "chrome-extension://allowed"))
+	defer server.Close()
+
+	get := func(path, token string) (int, string, string) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, resp.Header.Get("Content-Type"), string(body)
+	}
+
+	if status, _, _ := get("/bundle?path=src", ""); status != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without a token, got %d", status)
+	}
+	if status, _, _ := get("/bundle?path=src", "wrong"); status != http.StatusUnauthorized {
+		t.Errorf("Expected 401 with a wrong token, got %d", status)
+	}
+	if status, _, _ := get("/bundle?path=src&token=secret", ""); status != http.StatusUnauthorized {
+		t.Errorf("Expected 401 with the token in the query, got %d", status)
+	}
+
+	status, ctype, body := get("/bundle?path=src&exclude=format.go&format=markdown&tree=1", "secret")
+	if status != http.StatusOK || !strings.HasPrefix(ctype, "text/markdown") {
+		t.Fatalf("GET /bundle = %d %s:\n%s", status, ctype, body)
+	}
+	for _, want := range []string{"src/app.go", "package src", "src/components/button.go"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected %q in bundle:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "package utils") || strings.Contains(body, tmpDir) {
+		t.Errorf("Bundle should hold neither excluded files nor absolute paths:\n%s", body)
+	}
+
+	// Anchored excludes are relative to the served directory, not the cwd
+	status, _, body = get("/bundle?path=.&exclude=src/components/", "secret")
+	if status != http.StatusOK || strings.Contains(body, "package components") || !strings.Contains(body, "package src") {
+		t.Errorf("Expected src/components/ excluded from the served directory, got %d:\n%s", status, body)
+	}
+
+	status, _, body = get("/tree?path=src", "secret")
+	if status != http.StatusOK {
+		t.Fatalf("GET /tree = %d: %s", status, body)
+	}
+	var tree struct {
+		Files []struct {
+			Path string `json:"path"`
+			Size int64  `json:"size"`
+		} `json:"files"`
+	}
+	if err := json.Unmarshal([]byte(body), &tree); err != nil {
+		t.Fatalf("Invalid JSON from /tree: %v\n%s", err, body)
+	}
+	if len(tree.Files) != 3 || tree.Files[0].Path != "src/app.go" || tree.Files[0].Size != int64(len("package src")) {
+		t.Errorf("Unexpected /tree files: %+v", tree.Files)
+	}
+
+	for _, path := range []string{"../etc", "/etc/passwd", "https://example.com/x"} {
+		if status, _, _ := get("/bundle?path="+path, "secret"); status != http.StatusBadRequest {
+			t.Errorf("Expected 400 for path %q, got %d", path, status)
+		}
+	}
+	if status, _, _ := get("/bundle?path=src&exclude=*.go", "secret"); status != http.StatusNotFound {
+		t.Errorf("Expected 404 when nothing matches, got %d", status)
+	}
+
+	for origin, want := range map[string]string{"chrome-extension://allowed": "chrome-extension://allowed", "https://evil.example": ""} {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+"/tree", nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		req.Header.Set("Origin", origin)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if got := resp.Header.Get("Access-Control-Allow-Origin"); got != want {
+			t.Errorf("Access-Control-Allow-Origin for %s = %q, want %q", origin, got, want)
+		}
+	}
+}
\ No newline at end of file
@@ -0,0 +1,118 @@
+package unit_test
+
+import (
+	"clipcat/pkg/clipcat"
+	"clipcat/pkg/collector"
+	"clipcat/pkg/exclude"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// benchSizes are the synthetic tree sizes; the 100k trees are skipped with
+// -short since creating them takes a while.
+var benchSizes = []int{10_000, 100_000}
+
+// syntheticPaths returns n relative file paths, 100 per directory and two
+// directory levels deep, with every tenth directory a node_modules or build
+// directory and every tenth file a .log file, for the excludes to prune.
+func syntheticPaths(n int) []string {
+	paths := make([]string, n)
+	for i := range paths {
+		dir := i / 100
+		top := fmt.Sprintf("pkg%03d", dir/10)
+		sub := fmt.Sprintf("mod%02d", dir%10)
+		switch dir % 10 {
+		case 3:
+			sub = "node_modules"
+		case 7:
+			sub = "build"
+		}
+		ext := ".go"
+		if i%10 == 9 {
+			ext = ".log"
+		}
+		paths[i] = filepath.Join(top, sub, fmt.Sprintf("file%03d%s", i%100, ext))
+	}
+	return paths
+}
+
+// syntheticTree writes syntheticPaths(n) under a temporary directory.
+func syntheticTree(b *testing.B, n int) string {
+	b.Helper()
+	if n > 10_000 && testing.Short() {
+		b.Skip("large tree skipped in -short mode")
+	}
+	root := b.TempDir()
+	content := []byte("package main\n\nfunc main() {\n\tprintln(\"hello\")\n}\n")
+	for _, path := range syntheticPaths(n) {
+		path = filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			b.Fatal(err)
+		}
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			b.Fatal(err)
+		}
+	}
+	return root
+}
+
+func benchMatcher(b *testing.B) *exclude.ExcludeMatcher {
+	b.Helper()
+	ignoreFile := filepath.Join(b.TempDir(), ".gitignore")
+	if err := os.WriteFile(ignoreFile, []byte("*.log\nbuild/\n!keep.log\n"), 0644); err != nil {
+		b.Fatal(err)
+	}
+	matcher, err := exclude.BuildMatcher([]string{ignoreFile}, []string{"node_modules/", "**/*_test.go", "*.tmp"}, false)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return matcher
+}
+
+func BenchmarkCollectFiles(b *testing.B) {
+	for _, n := range benchSizes {
+		b.Run(fmt.Sprintf("%dk", n/1000), func(b *testing.B) {
+			root := syntheticTree(b, n)
+			matcher := benchMatcher(b)
+			b.ResetTimer()
+			for b.Loop() {
+				if _, err := collector.CollectFiles([]string{root}, matcher, false); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkShouldExclude(b *testing.B) {
+	matcher := benchMatcher(b)
+	for _, n := range benchSizes {
+		b.Run(fmt.Sprintf("%dk", n/1000), func(b *testing.B) {
+			paths := syntheticPaths(n)
+			for b.Loop() {
+				for _, path := range paths {
+					matcher.ShouldExclude(path, false)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkBundleWrite(b *testing.B) {
+	for _, n := range benchSizes {
+		b.Run(fmt.Sprintf("%dk", n/1000), func(b *testing.B) {
+			root := syntheticTree(b, n)
+			bundler := clipcat.New(clipcat.WithPaths(root), clipcat.WithTree(true), clipcat.WithWarnings(io.Discard))
+			b.ResetTimer()
+			for b.Loop() {
+				if err := bundler.Write(context.Background(), io.Discard); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
\ No newline at end of file
@@ -3,8 +3,12 @@ package unit_test
 import (
 	"bytes"
 	"clipcat/pkg/clipcat"
+	"errors"
+	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 )
@@ -324,6 +328,62 @@ func TestParseArgs_ValidArguments(t *testing.T) {
 	}
 }
 
+func TestParseArgs_PathsFrom(t *testing.T) {
+	list := filepath.Join(t.TempDir(), "paths.txt")
+	content := "# billing context\n\ncmd/billing/\n  internal/**/*.go  \n!**/*_test.go\n"
+	if err := os.WriteFile(list, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"clipcat", "main.go", "--paths-from", list, "-e", "*.tmp"}
+
+	cfg := clipcat.ParseArgs()
+	if want := []string{"main.go", "cmd/billing/", "internal/**/*.go"}; !slices.Equal(cfg.Paths, want) {
+		t.Errorf("Paths %q, want %q", cfg.Paths, want)
+	}
+	if !slices.Contains(cfg.Excludes, "**/*_test.go") || !slices.Contains(cfg.Excludes, "*.tmp") {
+		t.Errorf("Excludes %q should hold the ! line and -e", cfg.Excludes)
+	}
+}
+
+func TestParseArgs_ArgFile(t *testing.T) {
+	dir := t.TempDir()
+	inner := filepath.Join(dir, "inner.args")
+	outer := filepath.Join(dir, "outer.args")
+	files := map[string]string{
+		inner: "--ext go # Go only\n",
+		outer: "# review\n--format markdown -e '*_test.go'\n\"docs/design notes.md\" src\\win.go @" + inner + "\n",
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"clipcat", "main.go", "@" + outer, "@@literal"}
+
+	cfg := clipcat.ParseArgs()
+	if want := []string{"main.go", "docs/design notes.md", `src\win.go`, "@literal"}; !slices.Equal(cfg.Paths, want) {
+		t.Errorf("Paths %q, want %q", cfg.Paths, want)
+	}
+	if cfg.Format != "markdown" || !slices.Equal(cfg.Excludes, []string{"*_test.go"}) || !slices.Equal(cfg.Languages, []string{"go"}) {
+		t.Errorf("Options from the argument files not applied: format %q, excludes %q, languages %q", cfg.Format, cfg.Excludes, cfg.Languages)
+	}
+
+	for _, content := range []string{"'unterminated", "@" + filepath.Join(dir, "loop.args")} {
+		if err := os.WriteFile(filepath.Join(dir, "loop.args"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := clipcat.ExpandArgFiles([]string{"@" + filepath.Join(dir, "loop.args")}); err == nil {
+			t.Errorf("Expected an error for an argument file holding %q", content)
+		}
+	}
+}
+
 // Helper function to run a command that might call os.Exit
 func runWithExitCapture(t *testing.T, fn func()) (stderr string, exited bool) {
 	// Capture stderr
@@ -358,4 +418,50 @@ func runWithExitCapture(t *testing.T, fn func()) (stderr string, exited bool) {
 	os.Stderr = oldStderr
 
 	return buf.String(), exited
-}
\ No newline at end of file
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"success", nil, clipcat.ExitOK},
+		{"generic error", errors.New("boom"), clipcat.ExitError},
+		{"no files", clipcat.ErrNoFiles, clipcat.ExitNoFiles},
+		{"wrapped no files", fmt.Errorf("gh: %w", clipcat.ErrNoFiles), clipcat.ExitNoFiles},
+		{"clipboard", &clipcat.ClipboardError{Err: errors.New("no clipboard command found")}, clipcat.ExitClipboard},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := clipcat.ExitCode(tt.err); got != tt.want {
+				t.Errorf("ExitCode(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRun_NoFilesMatched(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(dir+"/a.log", []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := clipcat.Run(&clipcat.Config{Paths: []string{dir}, Excludes: []string{"*.log"}})
+	if !errors.Is(err, clipcat.ErrNoFiles) {
+		t.Errorf("Expected ErrNoFiles, got %v", err)
+	}
+}
+
+func TestRun_ConfirmOverRefusesWithoutTerminal(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(dir+"/big.txt", bytes.Repeat([]byte("x"), 4096), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := clipcat.Run(&clipcat.Config{Paths: []string{dir}, ConfirmOver: 1024})
+	if err == nil || !strings.Contains(err.Error(), "--force") {
+		t.Errorf("Expected refusal mentioning --force, got %v", err)
+	}
+}
@@ -1,8 +1,11 @@
 package unit_test
 
 import (
+	"bytes"
 	"clipcat/internal/clipboard"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
 	"testing"
 )
@@ -74,4 +77,99 @@ func isKnownClipboardError(err error) bool {
 		}
 	}
 	return false
+}
+
+func TestCFHTML_Offsets(t *testing.T) {
+	fragment := "<pre>héllo &amp; bye</pre>"
+	data := string(clipboard.CFHTML([]byte(fragment)))
+
+	offset := func(name string) int {
+		_, rest, ok := strings.Cut(data, name+":")
+		if !ok {
+			t.Fatalf("Missing %s in header:\n%s", name, data)
+		}
+		n, err := strconv.Atoi(rest[:10])
+		if err != nil {
+			t.Fatalf("Bad %s offset %q", name, rest[:10])
+		}
+		return n
+	}
+
+	if got := data[offset("StartFragment"):offset("EndFragment")]; got != fragment {
+		t.Errorf("Fragment offsets select %q, want %q", got, fragment)
+	}
+	html := data[offset("StartHTML"):offset("EndHTML")]
+	if !strings.HasPrefix(html, "<html>") || !strings.HasSuffix(html, "</html>") {
+		t.Errorf("HTML offsets select %q", html)
+	}
+	if offset("EndHTML") != len(data) {
+		t.Errorf("EndHTML = %d, want %d", offset("EndHTML"), len(data))
+	}
+}
+
+func TestUTF16(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []uint16
+	}{
+		{"", nil},
+		{"a\nb", []uint16{'a', '\r', '\n', 'b'}},
+		{"a\r\nb", []uint16{'a', '\r', '\n', 'b'}},
+		{"日本", []uint16{0x65E5, 0x672C}},
+		{"😀", []uint16{0xD83D, 0xDE00}}, // a surrogate pair
+		{"\xffa", []uint16{0xFFFD, 'a'}},
+	}
+	for _, tt := range tests {
+		got := clipboard.UTF16([]byte(tt.in))
+		if len(got) < 2 || got[0] != 0xFF || got[1] != 0xFE {
+			t.Errorf("UTF16(%q) = % x, want a little-endian BOM first", tt.in, got)
+			continue
+		}
+		units := make([]uint16, 0, len(got)/2-1)
+		for i := 2; i+1 < len(got); i += 2 {
+			units = append(units, uint16(got[i])|uint16(got[i+1])<<8)
+		}
+		if !slices.Equal(units, tt.want) {
+			t.Errorf("UTF16(%q) = %x, want %x", tt.in, units, tt.want)
+		}
+		if size := clipboard.PayloadSize("clip.exe", []byte(tt.in)); size != int64(len(got)) {
+			t.Errorf("PayloadSize(clip.exe, %q) = %d, want %d", tt.in, size, len(got))
+		}
+	}
+
+	if size := clipboard.PayloadSize("xclip", []byte("日本\n")); size != 7 {
+		t.Errorf("PayloadSize(xclip) = %d, want 7", size)
+	}
+}
+
+func TestBackend_NoneFound(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	_, err := clipboard.Backend()
+	want := "no clipboard command found (tried xclip, xsel, pbcopy, clip.exe, wl-copy, termux-clipboard-set)"
+	if err == nil || err.Error() != want {
+		t.Errorf("Backend() error = %v, want %q", err, want)
+	}
+}
+
+
+func TestNewOSC52(t *testing.T) {
+	tests := []struct {
+		tmux bool
+		want string
+	}{
+		{false, "\x1b]52;c;aGVsbG8gd29ybGQ=\a"},
+		{true, "\x1bPtmux;\x1b\x1b]52;c;aGVsbG8gd29ybGQ=\a\x1b\\"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		w := clipboard.NewOSC52(&buf, tt.tmux)
+		w.Write([]byte("hello "))
+		w.Write([]byte("world"))
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != tt.want {
+			t.Errorf("NewOSC52(tmux %v) wrote %q, want %q", tt.tmux, buf.String(), tt.want)
+		}
+	}
 }
\ No newline at end of file
@@ -1,13 +1,21 @@
 package unit_test
 
 import (
+	"bytes"
 	"clipcat/pkg/collector"
 	"clipcat/pkg/exclude"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestCollectFiles_EdgeCases(t *testing.T) {
@@ -121,27 +129,14 @@ func TestCollectFiles_SymbolicLinks(t *testing.T) {
 			t.Fatalf("CollectFiles failed: %v", err)
 		}
 		
-		// Should find both original and link
-		if len(files) != 2 {
-			t.Errorf("Expected 2 files, got %d", len(files))
-		}
-		
-		foundOriginal := false
-		foundLink := false
-		for _, file := range files {
-			if strings.HasSuffix(file, "original.txt") {
-				foundOriginal = true
-			}
-			if strings.HasSuffix(file, "link.txt") {
-				foundLink = true
-			}
+		// The link is an alias of original.txt, so the file is collected
+		// once, under the first path the walk reaches (link.txt)
+		if len(files) != 1 {
+			t.Fatalf("Expected 1 file, got %d: %v", len(files), files)
 		}
 		
-		if !foundOriginal {
-			t.Error("Expected to find original.txt")
-		}
-		if !foundLink {
-			t.Error("Expected to find link.txt")
+		if !strings.HasSuffix(files[0], "link.txt") {
+			t.Errorf("Expected link.txt, got %s", files[0])
 		}
 	})
 
@@ -630,4 +625,589 @@ func TestCollectFiles_Deduplication_Advanced(t *testing.T) {
 	if !strings.HasSuffix(files[0], "test.txt") {
 		t.Errorf("Expected test.txt, got %s", files[0])
 	}
+}
+
+func TestCollectFiles_IgnoreMarker(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	files := map[string]string{
+		"keep.go":     "package main\n",
+		"scratch.go":  "// clipcat:ignore\npackage main\n",
+		"secrets.env": "# clipcat:ignore\nTOKEN=abc\n",
+		"script.sh":   "#!/bin/sh\n# clipcat:ignore\necho hi\n",
+		"late.txt":    "first line\n// clipcat:ignore\n",
+		"notes.md":    "<!-- clipcat:ignore -->\n# Notes\n",
+		"readme.txt":  "mentions the marker later\nclipcat:ignore\n",
+		"empty.txt":   "",
+		"shebang.py":  "#!/usr/bin/env python3\nprint('hi')\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	matcher, _ := exclude.BuildMatcher([]string{}, []string{}, false)
+
+	// Both directory walks and explicitly named files honor the marker
+	got, err := collector.CollectFiles([]string{tmpDir, filepath.Join(tmpDir, "scratch.go")}, matcher, false)
+	if err != nil {
+		t.Fatalf("CollectFiles failed: %v", err)
+	}
+
+	names := getBasenames(got)
+	want := map[string]bool{"keep.go": true, "late.txt": true, "readme.txt": true, "empty.txt": true, "shebang.py": true}
+	if len(names) != len(want) {
+		t.Errorf("Expected %d files, got %d: %v", len(want), len(names), names)
+	}
+	for _, name := range names {
+		if !want[name] {
+			t.Errorf("File %q should have been skipped by its ignore marker", name)
+		}
+	}
+}
+
+func TestCollect_DefaultExcludes(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	files := []string{
+		"main.go",
+		"go.sum",
+		"node_modules/pkg/index.js",
+		"web/node_modules/dep/lib.js",
+		"web/app.js",
+		"__pycache__/mod.pyc",
+		".git/HEAD",
+	}
+	for _, name := range files {
+		path := filepath.Join(tmpDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("x\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	matcher, _ := exclude.BuildMatcher([]string{}, []string{}, false)
+	collect := func(paths []string, defaults []string) []string {
+		got, err := collector.Collect(collector.Options{Paths: paths, Matcher: matcher, Defaults: defaults})
+		if err != nil {
+			t.Fatalf("Collect failed: %v", err)
+		}
+		var rels []string
+		for _, file := range got {
+			rel, _ := filepath.Rel(tmpDir, file)
+			rels = append(rels, filepath.ToSlash(rel))
+		}
+		return rels
+	}
+
+	got := collect([]string{tmpDir}, exclude.DefaultExcludes)
+	if strings.Join(got, ",") != "main.go,web/app.js" {
+		t.Errorf("Default excludes not applied while walking: %v", got)
+	}
+
+	// Explicitly named files bypass the defaults
+	got = collect([]string{filepath.Join(tmpDir, "go.sum")}, exclude.DefaultExcludes)
+	if len(got) != 1 || got[0] != "go.sum" {
+		t.Errorf("Expected literal go.sum to be collected, got %v", got)
+	}
+
+	// Without defaults everything is walked
+	if got = collect([]string{tmpDir}, nil); len(got) != len(files) {
+		t.Errorf("Expected %d files without defaults, got %v", len(files), got)
+	}
+
+	// The user's own matcher is unchanged by WithDefaults
+	if matcher.ShouldExclude(filepath.Join(tmpDir, "go.sum"), false) {
+		t.Error("WithDefaults must not modify the original matcher")
+	}
+}
+
+
+func TestCollect_ModifiedBetween(t *testing.T) {
+	tmpDir := t.TempDir()
+	now := time.Now()
+
+	ages := map[string]time.Duration{
+		"fresh.go": time.Hour,
+		"week.go":  5 * 24 * time.Hour,
+		"old.go":   400 * 24 * time.Hour,
+	}
+	for name, age := range ages {
+		path := filepath.Join(tmpDir, name)
+		if err := os.WriteFile(path, []byte("x\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		mtime := now.Add(-age)
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	matcher, _ := exclude.BuildMatcher([]string{}, []string{}, false)
+	collect := func(after, before time.Time) string {
+		got, err := collector.Collect(collector.Options{Paths: []string{tmpDir}, Matcher: matcher, ModifiedAfter: after, ModifiedBefore: before})
+		if err != nil {
+			t.Fatalf("Collect failed: %v", err)
+		}
+		var names []string
+		for _, file := range got {
+			names = append(names, filepath.Base(file))
+		}
+		sort.Strings(names)
+		return strings.Join(names, ",")
+	}
+
+	if got := collect(now.Add(-7*24*time.Hour), time.Time{}); got != "fresh.go,week.go" {
+		t.Errorf("Newer than 7 days: got %s", got)
+	}
+	if got := collect(time.Time{}, now.Add(-24*time.Hour)); got != "old.go,week.go" {
+		t.Errorf("Older than 1 day: got %s", got)
+	}
+	if got := collect(now.Add(-30*24*time.Hour), now.Add(-24*time.Hour)); got != "week.go" {
+		t.Errorf("Between 30 and 1 days: got %s", got)
+	}
+	if got := collect(time.Time{}, time.Time{}); got != "fresh.go,old.go,week.go" {
+		t.Errorf("No time range: got %s", got)
+	}
+}
+func TestCollect_AnchoredGlobs(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	files := []string{
+		"work/main.go",
+		"work/src/app.go",
+		"work/src/sub/util.go",
+		"work/src/notes.txt",
+		"other/lib.go",
+		"other/pkg/deep.go",
+	}
+	for _, name := range files {
+		path := filepath.Join(tmpDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("x\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	originalDir, _ := os.Getwd()
+	defer os.Chdir(originalDir)
+	os.Chdir(filepath.Join(tmpDir, "work"))
+
+	matcher, _ := exclude.BuildMatcher([]string{}, []string{}, false)
+	collect := func(pattern, root string) string {
+		got, err := collector.Collect(collector.Options{Paths: []string{pattern}, Matcher: matcher, Root: root, Warnings: io.Discard})
+		if err != nil {
+			t.Fatalf("Collect(%s) failed: %v", pattern, err)
+		}
+		var rels []string
+		for _, file := range got {
+			rel, _ := filepath.Rel(tmpDir, file)
+			rels = append(rels, filepath.ToSlash(rel))
+		}
+		sort.Strings(rels)
+		return strings.Join(rels, ",")
+	}
+
+	tests := []struct {
+		pattern string
+		root    string
+		want    string
+	}{
+		{"src/**/*.go", "", "work/src/app.go,work/src/sub/util.go"},
+		{"src/*.go", "", "work/src/app.go"},
+		{"../other/*.go", "", "other/lib.go"},
+		{"../other/**/*.go", "", "other/lib.go,other/pkg/deep.go"},
+		{filepath.ToSlash(filepath.Join(tmpDir, "other")) + "/pkg/*.go", "", "other/pkg/deep.go"},
+		{"*.go", "", "work/main.go,work/src/app.go,work/src/sub/util.go"},
+		{"*.go", "../other", "other/lib.go,other/pkg/deep.go"},
+		{"pkg/*.go", "../other", "other/pkg/deep.go"},
+		{"missing/*.go", "", ""},
+	}
+	for _, tt := range tests {
+		if got := collect(tt.pattern, tt.root); got != tt.want {
+			t.Errorf("Collect(%q, root %q) = %s, want %s", tt.pattern, tt.root, got, tt.want)
+		}
+	}
+
+	if dir, rest := collector.GlobBase("{src,lib}/**/*.go"); dir != "" || rest != "{src,lib}/**/*.go" {
+		t.Errorf("Brace expansion must not be taken as a directory, got %q %q", dir, rest)
+	}
+}
+func TestCollect_BraceExpansion(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	files := []string{
+		"main.go",
+		"main.ts",
+		"main.py",
+		"src/app.go",
+		"src/ui/view.ts",
+		"lib/util.go",
+		"build/out.go",
+		"dist/out.ts",
+	}
+	for _, name := range files {
+		path := filepath.Join(tmpDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("x\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	originalDir, _ := os.Getwd()
+	defer os.Chdir(originalDir)
+	os.Chdir(tmpDir)
+
+	collect := func(pattern string, excludes ...string) string {
+		matcher, _ := exclude.BuildMatcher([]string{}, excludes, false)
+		got, err := collector.Collect(collector.Options{Paths: []string{pattern}, Matcher: matcher, Warnings: io.Discard})
+		if err != nil {
+			t.Fatalf("Collect(%s) failed: %v", pattern, err)
+		}
+		var rels []string
+		for _, file := range got {
+			rel, _ := filepath.Rel(tmpDir, file)
+			rels = append(rels, filepath.ToSlash(rel))
+		}
+		sort.Strings(rels)
+		return strings.Join(rels, ",")
+	}
+
+	tests := []struct {
+		pattern  string
+		excludes []string
+		want     string
+	}{
+		{"main.{go,ts}", nil, "main.go,main.ts"},
+		{"src/**/*.{go,ts}", nil, "src/app.go,src/ui/view.ts"},
+		{"{src,lib}/*.go", nil, "lib/util.go,src/app.go"},
+		{"**/*.{go,ts}", []string{"{build,dist}/"}, "lib/util.go,main.go,main.ts,src/app.go,src/ui/view.ts"},
+	}
+	for _, tt := range tests {
+		if got := collect(tt.pattern, tt.excludes...); got != tt.want {
+			t.Errorf("Collect(%q, -e %v) = %s, want %s", tt.pattern, tt.excludes, got, tt.want)
+		}
+	}
+}
+func TestCollect_SymlinkAliases(t *testing.T) {
+	tmpDir := t.TempDir()
+	src := filepath.Join(tmpDir, "src")
+	if err := os.MkdirAll(src, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(src, filepath.Join(tmpDir, "alias")); err != nil {
+		t.Skip("Symbolic links not supported on this system")
+	}
+	if err := os.Symlink(filepath.Join(src, "main.go"), filepath.Join(tmpDir, "main-link.go")); err != nil {
+		t.Fatal(err)
+	}
+
+	originalDir, _ := os.Getwd()
+	defer os.Chdir(originalDir)
+	os.Chdir(tmpDir)
+
+	matcher, _ := exclude.BuildMatcher([]string{}, []string{}, false)
+	files, err := collector.Collect(collector.Options{
+		Paths:   []string{"./src", "src/", "alias/main.go", "main-link.go"},
+		Matcher: matcher,
+	})
+	if err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("Expected aliases of src/main.go to collapse to 1 file, got %v", files)
+	}
+	if rel, _ := filepath.Rel(tmpDir, files[0]); filepath.ToSlash(rel) != "src/main.go" {
+		t.Errorf("Expected the first path found (src/main.go), got %s", files[0])
+	}
+}
+func TestCollect_GlobPrunesExcludedDirectories(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	files := []string{
+		"index.js",
+		"web/app.js",
+		"web/node_modules/dep/lib.js",
+		"web/vendor/x.js",
+		"api/vendor/y.js",
+		"api/gen/z.js",
+	}
+	for _, name := range files {
+		path := filepath.Join(tmpDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("x\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	originalDir, _ := os.Getwd()
+	defer os.Chdir(originalDir)
+	os.Chdir(tmpDir)
+
+	// Path-aware excludes apply to walked paths, which are absolute
+	matcher, _ := exclude.BuildMatcher(nil, []string{"web/vendor/", "api/*/z.js"}, false)
+	for _, input := range []string{"**/*.js", tmpDir} {
+		got, err := collector.Collect(collector.Options{Paths: []string{input}, Matcher: matcher, Defaults: []string{"node_modules/"}})
+		if err != nil {
+			t.Fatalf("Collect failed: %v", err)
+		}
+		var rels []string
+		for _, file := range got {
+			rel, _ := filepath.Rel(tmpDir, file)
+			rels = append(rels, filepath.ToSlash(rel))
+		}
+		sort.Strings(rels)
+		if want := "api/vendor/y.js,index.js,web/app.js"; strings.Join(rels, ",") != want {
+			t.Errorf("Collect(%s) = %v, want %s", input, rels, want)
+		}
+	}
+
+	for _, dir := range []string{"web/vendor", "web/node_modules"} {
+		if !matcher.WithDefaults([]string{"node_modules/"}).ShouldExclude(filepath.Join(tmpDir, dir), true) {
+			t.Errorf("Expected %s to be pruned", dir)
+		}
+	}
+}
+
+func TestGrep(t *testing.T) {
+	tmpDir := t.TempDir()
+	var files []string
+	for i := range 20 {
+		file := filepath.Join(tmpDir, fmt.Sprintf("f%02d.go", i))
+		content := "package p\n"
+		if i%3 == 0 {
+			content += strings.Repeat("var _ FooService\n", i/3+1)
+		}
+		os.WriteFile(file, []byte(content), 0644)
+		files = append(files, file)
+	}
+	files = append(files, filepath.Join(tmpDir, "missing.go"))
+
+	matched, counts, err := collector.Grep(context.Background(), files, regexp.MustCompile(`FooService`), 4)
+	if err != nil {
+		t.Fatalf("Grep failed: %v", err)
+	}
+	var got []string
+	for i, file := range matched {
+		got = append(got, fmt.Sprintf("%s:%d", filepath.Base(file), counts[i]))
+	}
+	want := "f00.go:1 f03.go:2 f06.go:3 f09.go:4 f12.go:5 f15.go:6 f18.go:7"
+	if strings.Join(got, " ") != want {
+		t.Errorf("Grep = %v, want %s", got, want)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := collector.Grep(ctx, files, regexp.MustCompile(`x`), 1); err != context.Canceled {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestCollect_Force(t *testing.T) {
+	tmpDir := t.TempDir()
+	for name, content := range map[string]string{
+		"main.go":         "package main\n",
+		"go.sum":          "x\n",
+		"build/gen.go":    "package build\n",
+		"scratch.go":      "// clipcat:ignore\npackage main\n",
+		"secrets/key.pem": "KEY\n",
+	} {
+		path := filepath.Join(tmpDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	matcher, _ := exclude.BuildMatcher(nil, []string{"build/", "*.go"}, false)
+	force := []string{filepath.Join(tmpDir, "go.sum"), filepath.Join(tmpDir, "build", "gen.go"), filepath.Join(tmpDir, "scratch.go")}
+	got, err := collector.Collect(collector.Options{
+		Paths:     []string{tmpDir},
+		Matcher:   matcher,
+		Defaults:  exclude.DefaultExcludes,
+		Languages: []string{"python"},
+		Force:     force,
+		Warnings:  io.Discard,
+	})
+	if err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+	names := getBasenames(got)
+	sort.Strings(names)
+	if strings.Join(names, ",") != "gen.go,go.sum,scratch.go" {
+		t.Errorf("Forced files should bypass excludes, defaults, filters and markers, got %v", names)
+	}
+
+	// The policy still wins
+	_, err = collector.Collect(collector.Options{
+		Matcher: matcher,
+		Force:   []string{filepath.Join(tmpDir, "secrets", "key.pem")},
+		Policy:  exclude.NewPolicy("policy.toml", []string{"*.pem"}),
+	})
+	var policyErr *exclude.PolicyError
+	if !errors.As(err, &policyErr) {
+		t.Errorf("Expected a PolicyError for a forced file the policy forbids, got %v", err)
+	}
+}
+
+func TestCollectEntries(t *testing.T) {
+	tmpDir := t.TempDir()
+	for name, content := range map[string]string{
+		"main.go":          "package main\n",
+		"README.md":        "# Readme\n",
+		"src/app.go":       "package src\n",
+		"docs/guide/a.md":  "# A\n",
+		"docs/guide/b.txt": "b\n",
+	} {
+		path := filepath.Join(tmpDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mtime := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(filepath.Join(tmpDir, "src", "app.go"), mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+
+	matcher, _ := exclude.BuildMatcher(nil, nil, false)
+	src := filepath.Join(tmpDir, "src")
+	glob := filepath.Join(tmpDir, "docs", "**", "*.md")
+	entries, _, err := collector.CollectEntries(context.Background(), collector.Options{
+		Paths:    []string{src, filepath.Join(tmpDir, "main.go"), glob},
+		Matcher:  matcher,
+		Force:    []string{filepath.Join(tmpDir, "README.md")},
+		Warnings: io.Discard,
+	})
+	if err != nil {
+		t.Fatalf("CollectEntries failed: %v", err)
+	}
+
+	var got []string
+	for _, e := range entries {
+		got = append(got, fmt.Sprintf("%s %s %s %d", e.Match, e.Input, e.Rel, e.Size))
+		if e.Path != filepath.Join(e.Root, e.Rel) {
+			t.Errorf("%s: Path should be Root joined with Rel, got %s and %s", e.Path, e.Root, e.Rel)
+		}
+	}
+	want := []string{
+		fmt.Sprintf("force %s README.md 9", filepath.Join(tmpDir, "README.md")),
+		fmt.Sprintf("dir %s app.go 12", src),
+		fmt.Sprintf("literal %s main.go 13", filepath.Join(tmpDir, "main.go")),
+		fmt.Sprintf("glob %s %s 4", glob, filepath.Join("guide", "a.md")),
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Unexpected entries:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if app := entries[1]; !app.ModTime.Equal(mtime) || !app.Mode.IsRegular() {
+		t.Errorf("Expected the mode and modification time of app.go, got %v %v", app.Mode, app.ModTime)
+	}
+}
+
+func TestCollectEntries_Warnings(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(tmpDir, "gone.go"), filepath.Join(tmpDir, "link.go")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+	if err := os.Mkdir(filepath.Join(tmpDir, "docs"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	var stderr bytes.Buffer
+	matcher, _ := exclude.BuildMatcher(nil, nil, false)
+	opts := collector.Options{
+		Paths:    []string{tmpDir, filepath.Join(tmpDir, "missing.go"), filepath.Join(tmpDir, "*.rs")},
+		Matcher:  matcher,
+		Force:    []string{filepath.Join(tmpDir, "docs")},
+		Warnings: &stderr,
+	}
+	entries, warnings, err := collector.CollectEntries(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("CollectEntries failed: %v", err)
+	}
+	if len(entries) != 1 || filepath.Base(entries[0].Path) != "main.go" {
+		t.Errorf("Expected only main.go, got %v", entries)
+	}
+
+	var kinds []string
+	for _, w := range warnings {
+		kinds = append(kinds, w.Kind+" "+filepath.Base(w.Path))
+	}
+	want := []string{
+		collector.WarnForceDir + " docs",
+		collector.WarnBrokenLink + " link.go",
+		collector.WarnNotExist + " missing.go",
+		collector.WarnNoMatch + " *.rs",
+	}
+	if strings.Join(kinds, ", ") != strings.Join(want, ", ") {
+		t.Errorf("Expected warnings %v, got %v", want, kinds)
+	}
+	if stderr.Len() > 0 {
+		t.Errorf("CollectEntries should return warnings instead of printing them, printed:\n%s", stderr.String())
+	}
+
+	// Collect still prints them
+	if _, err := collector.Collect(opts); err != nil {
+		t.Fatal(err)
+	}
+	if got := stderr.String(); !strings.Contains(got, "Warning: Skipping broken symlink: "+filepath.Join(tmpDir, "link.go")+"\n") {
+		t.Errorf("Expected Collect to print the warnings, got:\n%s", got)
+	}
+}
+
+func TestCollectEntries_EmptyPatterns(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, name := range []string{"main.go", "util.go", "app.min.js"} {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte("x\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	glob := func(pattern string) string { return filepath.Join(tmpDir, pattern) }
+
+	matcher, _ := exclude.BuildMatcher(nil, []string{"*.min.js"}, false)
+	entries, warnings, err := collector.CollectEntries(context.Background(), collector.Options{
+		// main.go is already in when m*.go matches it, which still counts
+		Paths:   []string{glob("*.go"), glob("*.pyx"), glob("m*.go"), glob("*.js"), glob("nodir/*.go")},
+		Matcher: matcher,
+	})
+	if err != nil {
+		t.Fatalf("CollectEntries failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("Expected main.go and util.go, got %v", entries)
+	}
+
+	var got []string
+	for _, w := range warnings {
+		if w.Kind == collector.WarnNoMatch {
+			got = append(got, w.Message)
+		}
+	}
+	want := []string{
+		"Pattern '" + glob("*.pyx") + "' matched no files",
+		"Pattern '" + glob("*.js") + "' matched no files", // only excluded files
+		"Pattern '" + glob("nodir/*.go") + "' matched no files",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Expected warnings %q, got %q", want, got)
+	}
 }
\ No newline at end of file
@@ -0,0 +1,240 @@
+package unit_test
+
+import (
+	"bytes"
+	"clipcat/pkg/clipcat"
+	"clipcat/pkg/config"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConfigParse(t *testing.T) {
+	data := []byte(`# top-level
+name = "x # not a comment"
+enabled = true
+limit = 1_000
+
+[profiles.review]
+description = 'Go sources'   # comment
+args = [
+  "src/", "-e", "*_test.go", # split over lines
+  "-t",
+]
+`)
+	tables, err := config.Parse(data)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	top := tables[""]
+	if top["name"] != "x # not a comment" || top["enabled"] != true || top["limit"] != int64(1000) {
+		t.Errorf("Unexpected top-level values: %#v", top)
+	}
+	review := tables["profiles.review"]
+	if review["description"] != "Go sources" {
+		t.Errorf("Unexpected description: %#v", review["description"])
+	}
+	want := []string{"src/", "-e", "*_test.go", "-t"}
+	if !reflect.DeepEqual(review["args"], want) {
+		t.Errorf("Expected args %v, got %#v", want, review["args"])
+	}
+
+	for _, bad := range []string{"key", "[open", `k = "unterminated`, "k = [1, 2]", "k = bare"} {
+		if _, err := config.Parse([]byte(bad)); err == nil {
+			t.Errorf("Expected error for %q", bad)
+		}
+	}
+}
+
+func TestConfigMerge_Profiles(t *testing.T) {
+	cfg := &config.Config{}
+	user := `[profiles.review]
+args = ["."]
+[profiles.docs]
+description = "Docs"
+args = ["**/*.md"]
+`
+	project := `[profiles.review]
+args = ["src/", "-t"]
+`
+	if err := cfg.Merge([]byte(user), "user.toml"); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Merge([]byte(project), "project.toml"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := cfg.ProfileNames(); !reflect.DeepEqual(got, []string{"docs", "review"}) {
+		t.Errorf("Unexpected profile names: %v", got)
+	}
+	review := cfg.Profiles["review"]
+	if review.Source != "project.toml" || !reflect.DeepEqual(review.Args, []string{"src/", "-t"}) {
+		t.Errorf("Project profile should win: %+v", review)
+	}
+
+	if cfg.DefaultExcludes != nil {
+		t.Errorf("DefaultExcludes should stay unset, got %v", cfg.DefaultExcludes)
+	}
+	if err := cfg.Merge([]byte("default_excludes = [\"build/\", \"*.lock\"]\n"), "project.toml"); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cfg.DefaultExcludes, []string{"build/", "*.lock"}) {
+		t.Errorf("Unexpected DefaultExcludes: %v", cfg.DefaultExcludes)
+	}
+
+	if err := cfg.Merge([]byte("[profiles.bad]\nargs = \"-t\"\n"), "bad.toml"); err == nil {
+		t.Error("Expected error for non-array args")
+	}
+	if err := cfg.Merge([]byte("[profiles.bad]\ntypo = true\n"), "bad.toml"); err == nil {
+		t.Error("Expected error for unknown profile key")
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		var buf bytes.Buffer
+		if err := clipcat.Completion(shell, &buf); err != nil {
+			t.Fatalf("%s: %v", shell, err)
+		}
+		out := buf.String()
+		for _, want := range []string{"clipcat", "exclude-from", "only-tree", "unpack"} {
+			if !strings.Contains(out, want) {
+				t.Errorf("%s completion missing %q", shell, want)
+			}
+		}
+	}
+
+	if err := clipcat.Completion("tcsh", &bytes.Buffer{}); err == nil {
+		t.Error("Expected error for unsupported shell")
+	}
+}
+
+
+func TestConfigMerge_Languages(t *testing.T) {
+	cfg := &config.Config{}
+	data := `[languages]
+".tpl" = "html"
+"Justfile" = "makefile"
+`
+	if err := cfg.Merge([]byte(data), "project.toml"); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{".tpl": "html", "Justfile": "makefile"}
+	if !reflect.DeepEqual(cfg.Languages, want) {
+		t.Errorf("Languages = %v, want %v", cfg.Languages, want)
+	}
+
+	if err := cfg.Merge([]byte("[languages]\n\".x\" = 1\n"), "bad.toml"); err == nil {
+		t.Error("Expected an error for a non-string language")
+	}
+}
+
+func TestConfigMerge_MaxLinesPerFile(t *testing.T) {
+	cfg := &config.Config{}
+	data := `[max_lines_per_file]
+".log" = 200
+"go.sum" = 0
+`
+	if err := cfg.Merge([]byte(data), "project.toml"); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int{".log": 200, "go.sum": 0}
+	if !reflect.DeepEqual(cfg.MaxLinesPerFile, want) {
+		t.Errorf("MaxLinesPerFile = %v, want %v", cfg.MaxLinesPerFile, want)
+	}
+
+	for _, bad := range []string{`".log" = "200"`, `".log" = -1`} {
+		if err := cfg.Merge([]byte("[max_lines_per_file]\n"+bad+"\n"), "bad.toml"); err == nil {
+			t.Errorf("Expected an error for %s", bad)
+		}
+	}
+}
+
+func TestConfigMerge_Handlers(t *testing.T) {
+	cfg := &config.Config{}
+	data := `[handlers.".json"]
+handler = "jq ."
+`
+	if err := cfg.Merge([]byte(data), "user.toml"); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]*config.Handler{".json": {Key: ".json", Command: "jq .", Source: "user.toml"}}
+	if !reflect.DeepEqual(cfg.Handlers, want) {
+		t.Errorf("Handlers = %+v, want %+v", cfg.Handlers, want)
+	}
+
+	for _, bad := range []string{"[handlers.x]\nhandler = 1\n", "[handlers.x]\ncommand = \"jq\"\n", "[handlers.x]\n"} {
+		if err := cfg.Merge([]byte(bad), "bad.toml"); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
+
+func TestConfigMerge_Hooks(t *testing.T) {
+	cfg := &config.Config{}
+	data := `[hooks]
+pre_copy = "check-size"
+post_copy = "notify-send clipcat"
+`
+	if err := cfg.Merge([]byte(data), "user.toml"); err != nil {
+		t.Fatal(err)
+	}
+	if *cfg.PreCopy != (config.Hook{Command: "check-size", Source: "user.toml"}) || cfg.PostCopy.Command != "notify-send clipcat" {
+		t.Errorf("Hooks = %+v, %+v", cfg.PreCopy, cfg.PostCopy)
+	}
+
+	for _, bad := range []string{"[hooks]\npre_copy = true\n", "[hooks]\non_copy = \"x\"\n"} {
+		if err := cfg.Merge([]byte(bad), "bad.toml"); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
+
+func TestConfigMerge_Audit(t *testing.T) {
+	cfg := &config.Config{}
+	if err := cfg.Merge([]byte("[audit]\nenabled = true\npath = \"/var/log/clipcat.jsonl\"\n"), "user.toml"); err != nil {
+		t.Fatal(err)
+	}
+	want := config.Audit{Enabled: true, Path: "/var/log/clipcat.jsonl", Source: "user.toml"}
+	if cfg.Audit != want {
+		t.Errorf("Audit = %+v, want %+v", cfg.Audit, want)
+	}
+
+	for _, bad := range []string{"[audit]\nenabled = \"yes\"\n", "[audit]\nfile = \"x\"\n"} {
+		if err := cfg.Merge([]byte(bad), "bad.toml"); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
+
+func TestConfigMerge_Ask(t *testing.T) {
+	cfg := &config.Config{}
+	data := `[ask]
+provider = "openai"
+url = "http://localhost:11434/v1/chat/completions"
+model = "qwen2.5-coder"
+max_tokens = 2048
+`
+	if err := cfg.Merge([]byte(data), "user.toml"); err != nil {
+		t.Fatal(err)
+	}
+	want := config.Ask{Provider: "openai", URL: "http://localhost:11434/v1/chat/completions", Model: "qwen2.5-coder", MaxTokens: 2048}
+	if cfg.Ask != want {
+		t.Errorf("Ask = %+v, want %+v", cfg.Ask, want)
+	}
+
+	// A project file overrides single keys
+	if err := cfg.Merge([]byte("[ask]\nmodel = \"llama3\"\n"), "project.toml"); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Ask.Model != "llama3" || cfg.Ask.URL != want.URL {
+		t.Errorf("Ask after override = %+v", cfg.Ask)
+	}
+
+	for _, bad := range []string{"[ask]\ntemperature = \"1\"\n", "[ask]\nmax_tokens = \"many\"\n", "[ask]\nmodel = 3\n"} {
+		if err := cfg.Merge([]byte(bad), "bad.toml"); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
\ No newline at end of file
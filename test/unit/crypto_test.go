@@ -0,0 +1,37 @@
+package unit_test
+
+import (
+	"clipcat/pkg/crypto"
+	"testing"
+)
+
+func TestCryptoRecipient(t *testing.T) {
+	tests := map[string]string{
+		"age:age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p": "age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p",
+		"age:ssh-ed25519 AAAAC3Nza dev@host":                                 "ssh-ed25519 AAAAC3Nza dev@host",
+	}
+	for spec, want := range tests {
+		if got, err := crypto.Recipient(spec); err != nil || got != want {
+			t.Errorf("Recipient(%q) = %q, %v; want %q", spec, got, err, want)
+		}
+	}
+	for _, spec := range []string{"age1ql3z7hjy", "gpg:dev@example.com", "age:", "age:  "} {
+		if _, err := crypto.Recipient(spec); err == nil {
+			t.Errorf("Recipient(%q): expected an error", spec)
+		}
+	}
+}
+
+func TestCryptoIsEncrypted(t *testing.T) {
+	tests := map[string]bool{
+		"age-encryption.org/v1\n-> X25519 abc\n":           true,
+		"\n-----BEGIN AGE ENCRYPTED FILE-----\nYWdl\n":     true,
+		"====\nmain.go\n====\n\npackage main\n\n":          false,
+		"age-encryption.org/v1 is the header of age files": false,
+	}
+	for data, want := range tests {
+		if got := crypto.IsEncrypted([]byte(data)); got != want {
+			t.Errorf("IsEncrypted(%q) = %v, want %v", data, got, want)
+		}
+	}
+}
\ No newline at end of file
@@ -692,4 +692,242 @@ temp[0-9]/
 			}
 		})
 	}
+}
+
+func TestMatchPath_SharedHelpers(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		target   string
+		expected bool
+	}{
+		{"*.go", "main.go", true},
+		{"*.go", "src/main.go", false},
+		{"**/*.go", "src/pkg/main.go", true},
+		{"*.{js,ts}", "app.ts", true},
+		{"*.{js,ts}", "app.tsx", false},
+		{"file[0-9].txt", "file7.txt", true},
+		{"[", "[", false}, // malformed patterns never match
+	}
+
+	for _, tt := range tests {
+		if got := exclude.MatchPath(tt.pattern, tt.target); got != tt.expected {
+			t.Errorf("MatchPath(%q, %q) = %v, want %v", tt.pattern, tt.target, got, tt.expected)
+		}
+	}
+
+	if !exclude.IsGlobPattern("src/*.go") || exclude.IsGlobPattern("src/main.go") {
+		t.Error("IsGlobPattern misclassified a path")
+	}
+	if !exclude.IsDoublestarPattern("**/*.go") || exclude.IsDoublestarPattern("*.go") {
+		t.Error("IsDoublestarPattern misclassified a pattern")
+	}
+}
+
+func TestExcludeMatcherExplain_Provenance(t *testing.T) {
+	ignoreFile := filepath.Join(t.TempDir(), ".gitignore")
+	content := "# comment\n*.log\nvendor/\n!keep.log\n"
+	if err := os.WriteFile(ignoreFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	matcher, err := exclude.BuildMatcher([]string{ignoreFile}, []string{"*_test.go", "build/"}, false)
+	if err != nil {
+		t.Fatalf("BuildMatcher failed: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		isDir    bool
+		excluded bool
+		rule     string // expected Rule.String(), "" for no rule
+	}{
+		{"gitignore line", "debug.log", false, true, ignoreFile + ":2: *.log"},
+		{"gitignore directory", "vendor/lib/x.go", false, true, ignoreFile + ":3: vendor/"},
+		{"negation re-includes", "keep.log", false, false, ignoreFile + ":4: !keep.log"},
+		{"-e basename pattern", "pkg/a_test.go", false, true, "-e *_test.go"},
+		{"-e directory pattern", "build/out.bin", false, true, "-e build/"},
+		{"no rule", "main.go", false, false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			excluded, rule := matcher.Explain(tt.path, tt.isDir)
+			if excluded != tt.excluded {
+				t.Errorf("Explain(%q) excluded = %v, want %v", tt.path, excluded, tt.excluded)
+			}
+			got := ""
+			if rule != nil {
+				got = rule.String()
+			}
+			if got != tt.rule {
+				t.Errorf("Explain(%q) rule = %q, want %q", tt.path, got, tt.rule)
+			}
+			if matcher.ShouldExclude(tt.path, tt.isDir) != excluded {
+				t.Errorf("ShouldExclude(%q) disagrees with Explain", tt.path)
+			}
+		})
+	}
+
+	_, rule := matcher.Explain("keep.log", false)
+	if rule == nil || !rule.Negated {
+		t.Errorf("Expected a negated rule for keep.log, got %+v", rule)
+	}
+}
+
+func TestExcludeMatcher_GitignoreIgnoreCase(t *testing.T) {
+	ignoreFile := filepath.Join(t.TempDir(), ".gitignore")
+	content := "Build/\n*.LOG\n!Keep.log\n"
+	if err := os.WriteFile(ignoreFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	exact, err := exclude.BuildMatcher([]string{ignoreFile}, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	folded, err := exclude.BuildMatcher([]string{ignoreFile}, nil, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path   string
+		exact  bool
+		folded bool
+	}{
+		{"build/out.bin", false, true},
+		{"Build/out.bin", true, true},
+		{"debug.log", false, true},
+		{"DEBUG.LOG", true, true},
+		{"keep.log", false, false},
+		{"main.go", false, false},
+	}
+	for _, tt := range tests {
+		if got := exact.ShouldExclude(tt.path, false); got != tt.exact {
+			t.Errorf("case-sensitive ShouldExclude(%q) = %v, want %v", tt.path, got, tt.exact)
+		}
+		if got := folded.ShouldExclude(tt.path, false); got != tt.folded {
+			t.Errorf("case-insensitive ShouldExclude(%q) = %v, want %v", tt.path, got, tt.folded)
+		}
+	}
+
+	// Provenance still reports the line as written
+	_, rule := folded.Explain("keep.log", false)
+	if rule == nil || !rule.Negated || rule.Pattern != "!Keep.log" {
+		t.Errorf("Expected negated rule !Keep.log, got %+v", rule)
+	}
+}
+
+func TestExcludeMatcher_CompiledPatterns(t *testing.T) {
+	matcher, err := exclude.BuildMatcher(nil, []string{" node_modules/ ", "*cache*/", "src/gen*/", "docs/*.md", "*.log"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path  string
+		isDir bool
+		rule  string // "" when kept
+	}{
+		// Nested directories are excluded themselves, not just their contents,
+		// so walks never enter them
+		{"node_modules", true, "-e  node_modules/ "},
+		{"web/node_modules", true, "-e  node_modules/ "},
+		{"web/node_modules/pkg/index.js", false, "-e  node_modules/ "},
+		{"node_modules_old", true, ""},
+		{"a/.cache/b", true, "-e *cache*/"},
+		{"src/generated/x.go", false, "-e src/gen*/"},
+		{"docs/intro.md", false, "-e docs/*.md"},
+		{"docs", true, ""},
+		{"logs/app.log", false, "-e *.log"},
+		{"app.log", true, ""},
+	}
+	for _, tt := range tests {
+		// Twice, so the second directory lookup comes from the cache
+		for range 2 {
+			excluded, rule := matcher.Explain(tt.path, tt.isDir)
+			got := ""
+			if rule != nil {
+				got = rule.String()
+			}
+			if excluded != (tt.rule != "") || got != tt.rule {
+				t.Errorf("Explain(%q, %v) = %v, %q; want rule %q", tt.path, tt.isDir, excluded, got, tt.rule)
+			}
+		}
+	}
+
+	// Defaults get their own cache, so the -e matcher's answers do not leak
+	walk := matcher.WithDefaults([]string{"vendor/"})
+	if matcher.ShouldExclude("vendor", true) || !walk.ShouldExclude("vendor", true) {
+		t.Error("Expected vendor/ to be excluded only by the matcher with defaults")
+	}
+}
+
+func TestPolicy_Forbids(t *testing.T) {
+	policy := exclude.NewPolicy("/etc/clipcat/policy.toml", []string{"*.pem", "secrets/", "config/*.key", "**/.env"})
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/home/me/app/tls/server.pem", "*.pem"},
+		{"/home/me/app/TLS/Server.PEM", "*.pem"},
+		{"/home/me/app/secrets/db.txt", "secrets/"},
+		{"/home/me/app/secrets/nested/a.go", "secrets/"},
+		{"/home/me/app/deploy/config/prod.key", "config/*.key"},
+		{"/home/me/app/.env", "**/.env"},
+		{"/home/me/app/main.go", ""},
+		{"/home/me/app/secrets.go", ""},
+		{"/home/me/app/config/prod.keys", ""},
+	}
+	for _, tt := range tests {
+		rule, forbidden := policy.Forbids(filepath.FromSlash(tt.path))
+		got := ""
+		if forbidden {
+			got = rule.Pattern
+		}
+		if got != tt.want {
+			t.Errorf("Forbids(%s) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+
+	var none *exclude.Policy
+	if _, forbidden := none.Forbids("/a.pem"); forbidden {
+		t.Error("Expected a nil policy to forbid nothing")
+	}
+
+	// Changing into a forbidden directory does not get around the policy
+	secrets := filepath.Join(t.TempDir(), "app", "secrets")
+	os.MkdirAll(secrets, 0755)
+	originalDir, _ := os.Getwd()
+	defer os.Chdir(originalDir)
+	for _, pattern := range []string{"secrets/", "**/secrets/**"} {
+		for _, dir := range []string{secrets, filepath.Dir(secrets)} {
+			os.Chdir(dir)
+			policy = exclude.NewPolicy("/etc/clipcat/policy.toml", []string{pattern})
+			if _, forbidden := policy.Forbids(filepath.Join(secrets, "db.txt")); !forbidden {
+				t.Errorf("Expected %s to forbid secrets/db.txt from %s", pattern, dir)
+			}
+			if _, forbidden := policy.Forbids("db.txt"); dir == secrets && !forbidden {
+				t.Errorf("Expected %s to forbid a relative path inside secrets/", pattern)
+			}
+		}
+	}
+}
+
+func TestExcludeMatcher_WithBase(t *testing.T) {
+	// A checkout outside the working directory, as for `clipcat gh`
+	checkout := filepath.Join(t.TempDir(), "repo")
+
+	matcher, err := exclude.BuildMatcher(nil, []string{"docs/*"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	matcher = matcher.WithBase(checkout)
+	if !matcher.ShouldExclude(filepath.Join(checkout, "docs", "a.md"), false) {
+		t.Error("Expected docs/* to match inside the checkout")
+	}
+	if matcher.ShouldExclude(filepath.Join(checkout, "src", "docs", "a.md"), false) {
+		t.Error("Expected docs/* to match from the checkout root only")
+	}
 }
\ No newline at end of file
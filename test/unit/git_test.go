@@ -0,0 +1,227 @@
+package unit_test
+
+import (
+	"clipcat/internal/git"
+	"clipcat/pkg/collector"
+	"clipcat/pkg/exclude"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// setupGitRepo creates a repository with committed, untracked and ignored
+// files and changes into it for the duration of the test.
+func setupGitRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		".gitignore":      "*.log\n",
+		"main.go":         "package main\n",
+		"src/app.go":      "package src\n",
+		"src/app_test.go": "package src\n",
+		"build/out.go":    "package build\n",
+	}
+	for path, content := range files {
+		full := filepath.Join(tmpDir, path)
+		os.MkdirAll(filepath.Dir(full), 0755)
+		if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	gitRun(t, tmpDir, "init", "-q")
+	gitRun(t, tmpDir, "add", ".")
+	gitRun(t, tmpDir, "commit", "-q", "-m", "initial")
+
+	// Untracked and ignored files must not be collected in --git mode
+	os.WriteFile(filepath.Join(tmpDir, "scratch.go"), []byte("package main\n"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "debug.log"), []byte("log\n"), 0644)
+
+	originalDir, _ := os.Getwd()
+	t.Cleanup(func() { os.Chdir(originalDir) })
+	os.Chdir(tmpDir)
+
+	return tmpDir
+}
+
+func gitRun(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v failed: %v\n%s", args, err, out)
+	}
+	return string(out)
+}
+
+func relNames(t *testing.T, root string, files []string) []string {
+	t.Helper()
+	root, _ = filepath.EvalSymlinks(root)
+	var names []string
+	for _, f := range files {
+		f, _ = filepath.EvalSymlinks(f)
+		rel, _ := filepath.Rel(root, f)
+		names = append(names, filepath.ToSlash(rel))
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestCollect_GitMode(t *testing.T) {
+	tmpDir := setupGitRepo(t)
+
+	tests := []struct {
+		name     string
+		paths    []string
+		excludes []string
+		expected []string
+	}{
+		{"directory", []string{"."}, nil, []string{".gitignore", "build/out.go", "main.go", "src/app.go", "src/app_test.go"}},
+		{"glob", []string{"**/*.go"}, nil, []string{"build/out.go", "main.go", "src/app.go", "src/app_test.go"}},
+		{"dir excludes prune tracked files", []string{"."}, []string{"build/", "*_test.go"}, []string{".gitignore", "main.go", "src/app.go"}},
+		{"literal untracked file", []string{"scratch.go", "src"}, nil, []string{"scratch.go", "src/app.go", "src/app_test.go"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matcher, _ := exclude.BuildMatcher(nil, tt.excludes, false)
+			files, err := collector.Collect(collector.Options{Paths: tt.paths, Matcher: matcher, Git: true})
+			if err != nil {
+				t.Fatalf("Collect failed: %v", err)
+			}
+			got := relNames(t, tmpDir, files)
+			if strings.Join(got, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("got %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCollect_GitModeOutsideRepo(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	tmpDir := t.TempDir()
+
+	matcher, _ := exclude.BuildMatcher(nil, nil, false)
+	if _, err := collector.Collect(collector.Options{Paths: []string{tmpDir}, Matcher: matcher, Git: true}); err == nil {
+		t.Error("Expected an error when --git is used outside a repository")
+	}
+}
+
+func TestGit_ChangedStagedUnstaged(t *testing.T) {
+	tmpDir := setupGitRepo(t)
+	gitRun(t, tmpDir, "tag", "base")
+
+	// One committed change, one staged, one unstaged, one untracked (scratch.go
+	// from setup); debug.log stays ignored
+	os.WriteFile(filepath.Join(tmpDir, "main.go"), []byte("package main // v2\n"), 0644)
+	gitRun(t, tmpDir, "commit", "-q", "-am", "change main")
+	os.WriteFile(filepath.Join(tmpDir, "src/app.go"), []byte("package src // staged\n"), 0644)
+	gitRun(t, tmpDir, "add", "src/app.go")
+	os.WriteFile(filepath.Join(tmpDir, "build/out.go"), []byte("package build // wip\n"), 0644)
+
+	join := func(files []string, err error) string {
+		if err != nil {
+			t.Fatalf("git query failed: %v", err)
+		}
+		sort.Strings(files)
+		return strings.Join(files, ",")
+	}
+
+	if got := join(git.ChangedFiles(".", "base")); got != "build/out.go,main.go,scratch.go,src/app.go" {
+		t.Errorf("ChangedFiles = %s", got)
+	}
+	if got := join(git.ChangedFiles(".", "HEAD~1")); got != "build/out.go,main.go,scratch.go,src/app.go" {
+		t.Errorf("ChangedFiles(HEAD~1) = %s", got)
+	}
+	if got := join(git.StagedFiles(".")); got != "src/app.go" {
+		t.Errorf("StagedFiles = %s", got)
+	}
+	if got := join(git.UnstagedFiles(".")); got != "build/out.go,scratch.go" {
+		t.Errorf("UnstagedFiles = %s", got)
+	}
+
+	// Restricting the collector to the staged set
+	root, _ := filepath.EvalSymlinks(tmpDir)
+	matcher, _ := exclude.BuildMatcher(nil, nil, false)
+	files, err := collector.Collect(collector.Options{
+		Paths:   []string{"."},
+		Matcher: matcher,
+		Only:    map[string]bool{filepath.Join(root, "src", "app.go"): true},
+	})
+	if err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+	if got := relNames(t, tmpDir, files); len(got) != 1 || got[0] != "src/app.go" {
+		t.Errorf("Expected only src/app.go, got %v", got)
+	}
+}
+
+func TestGit_Diff(t *testing.T) {
+	tmpDir := setupGitRepo(t)
+	base := strings.TrimSpace(gitRun(t, tmpDir, "rev-parse", "HEAD"))
+
+	os.WriteFile(filepath.Join(tmpDir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644)
+
+	diff, err := git.Diff(".", base, "main.go")
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+	if !strings.Contains(string(diff), "+func main() {}") || !strings.Contains(string(diff), "--- a/main.go") {
+		t.Errorf("Expected a unified diff for main.go, got:\n%s", diff)
+	}
+
+	diff, err = git.Diff(".", base, "src/app.go")
+	if err != nil || len(diff) != 0 {
+		t.Errorf("Expected no diff for an unchanged file, got %q (err %v)", diff, err)
+	}
+
+	if git.MergeBase(".", "HEAD") != base {
+		t.Error("MergeBase of HEAD should be HEAD itself")
+	}
+}
+
+func TestGit_LastCommit(t *testing.T) {
+	tmpDir := setupGitRepo(t)
+
+	commit, err := git.LastCommit(".", "main.go")
+	if err != nil || commit == nil {
+		t.Fatalf("LastCommit failed: %v", err)
+	}
+	if commit.Author != "test" || len(commit.Hash) < 7 || len(commit.Date) != len("2006-01-02") {
+		t.Errorf("Unexpected commit %+v", commit)
+	}
+
+	commit, err = git.LastCommit(".", filepath.Join(tmpDir, "scratch.go"))
+	if err != nil || commit != nil {
+		t.Errorf("Expected no commit for an untracked file, got %+v (err %v)", commit, err)
+	}
+}
+
+
+func TestGit_Head(t *testing.T) {
+	tmpDir := setupGitRepo(t)
+	want := strings.TrimSpace(gitRun(t, tmpDir, "rev-parse", "--short", "HEAD"))
+	wantBranch := strings.TrimSpace(gitRun(t, tmpDir, "rev-parse", "--abbrev-ref", "HEAD"))
+
+	// setupGitRepo leaves untracked files behind
+	branch, commit, dirty, err := git.Head(".")
+	if err != nil {
+		t.Fatalf("Head: %v", err)
+	}
+	if branch != wantBranch || commit != want || !dirty {
+		t.Errorf("Head = %q, %q, dirty %v; want %q, %q, dirty true", branch, commit, dirty, wantBranch, want)
+	}
+
+	if _, _, _, err := git.Head(t.TempDir()); err == nil {
+		t.Error("Expected an error outside a repository")
+	}
+}
\ No newline at end of file
@@ -0,0 +1,109 @@
+package unit_test
+
+import (
+	"clipcat/pkg/lang"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLangDetect(t *testing.T) {
+	tests := []struct {
+		path    string
+		content string
+		want    string
+	}{
+		{"/p/main.go", "", "go"},
+		{"/p/App.TSX", "", "tsx"},
+		{"/p/Dockerfile", "", "dockerfile"},
+		{"/p/Makefile", "", "makefile"},
+		{"/p/go.mod", "", "go-mod"},
+		{"/p/bin/tool", "#!/usr/bin/env python3\nprint(1)\n", "python"},
+		{"/p/bin/run", "#!/bin/bash\n", "bash"},
+		{"/p/bin/s", "#!/usr/bin/env -S node --no-warnings\n", "javascript"},
+		{"/p/bin/py", "#!/usr/local/bin/python3.12\n", "python"},
+		{"/p/notes", "plain text\n", ""},
+	}
+
+	for _, tt := range tests {
+		if got := lang.Detect(tt.path, []byte(tt.content)); got != tt.want {
+			t.Errorf("Detect(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestLangRegisterAndMatch(t *testing.T) {
+	lang.Register(".tplx", "html")
+	lang.Register("Justfilex", "makefile")
+	if got := lang.Detect("/p/page.TPLX", nil); got != "html" {
+		t.Errorf("Registered extension: got %q", got)
+	}
+	if got := lang.Detect("/p/Justfilex", nil); got != "makefile" {
+		t.Errorf("Registered file name: got %q", got)
+	}
+
+	dir := t.TempDir()
+	script := filepath.Join(dir, "deploy")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\necho hi\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path  string
+		names []string
+		want  bool
+	}{
+		{"/p/a.ts", []string{"ts"}, true},
+		{"/p/a.ts", []string{".ts"}, true},
+		{"/p/a.ts", []string{"typescript"}, true},
+		{"/p/a.py", []string{"go", "python"}, true},
+		{"/p/a.py", []string{"go"}, false},
+		{script, []string{"bash"}, true},
+		{script, []string{"python"}, false},
+	}
+	for _, tt := range tests {
+		if got := lang.Match(tt.path, tt.names); got != tt.want {
+			t.Errorf("Match(%q, %v) = %v, want %v", tt.path, tt.names, got, tt.want)
+		}
+	}
+}
+
+func TestCategory(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "deploy")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\necho hi\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/p/main.go", "code"},
+		{script, "code"},
+		{"/p/Makefile", "code"},
+		{"/p/config.yaml", "config"},
+		{"/p/Dockerfile", "config"},
+		{"/p/go.mod", "config"},
+		{"/p/.editorconfig", "config"},
+		{"/p/README.md", "docs"},
+		{"/p/LICENSE", "docs"},
+		{"/p/guide.rst", "docs"},
+		{"/p/users.csv", "data"},
+		{"/p/notes.txt", "text"},
+		{"/p/build.log", "text"},
+		{"/p/logo.png", ""},
+	}
+	for _, tt := range tests {
+		if got := lang.Category(tt.path); got != tt.want {
+			t.Errorf("Category(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+
+	if !lang.MatchCategory("/p/main.go", []string{"docs", "code"}) {
+		t.Error("MatchCategory should match any listed category")
+	}
+	if lang.MatchCategory("/p/logo.png", []string{"text", "code", "config", "docs", "data"}) {
+		t.Error("Binary files should match no category")
+	}
+}
\ No newline at end of file
@@ -0,0 +1,87 @@
+package unit_test
+
+import (
+	"clipcat/internal/llm"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLLMStream(t *testing.T) {
+	streams := map[string]string{
+		"anthropic": `event: message_start
+data: {"type":"message_start","message":{}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"The loop "}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"never exits."}}
+
+event: message_stop
+data: {"type":"message_stop"}
+
+`,
+		"openai": `data: {"choices":[{"delta":{"role":"assistant"}}]}
+
+data: {"choices":[{"delta":{"content":"The loop "}}]}
+
+data: {"choices":[{"delta":{"content":"never exits."}}]}
+
+data: [DONE]
+
+`,
+	}
+
+	for provider, stream := range streams {
+		t.Run(provider, func(t *testing.T) {
+			var got struct {
+				Model    string `json:"model"`
+				Stream   bool   `json:"stream"`
+				Messages []struct {
+					Role, Content string
+				} `json:"messages"`
+			}
+			var auth string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				auth = r.Header.Get("Authorization") + r.Header.Get("x-api-key")
+				json.NewDecoder(r.Body).Decode(&got)
+				w.Header().Set("Content-Type", "text/event-stream")
+				w.Write([]byte(stream))
+			}))
+			defer server.Close()
+
+			var out strings.Builder
+			ep := llm.Endpoint{Provider: provider, URL: server.URL, Model: "m1", APIKey: "k"}
+			if err := llm.Stream(context.Background(), ep, "Why?\n\nbundle", &out); err != nil {
+				t.Fatalf("Stream failed: %v", err)
+			}
+			if out.String() != "The loop never exits." {
+				t.Errorf("answer = %q", out.String())
+			}
+			if got.Model != "m1" || !got.Stream || len(got.Messages) != 1 || got.Messages[0].Content != "Why?\n\nbundle" {
+				t.Errorf("request = %+v", got)
+			}
+			if !strings.HasSuffix(auth, "k") {
+				t.Errorf("API key not sent, got auth %q", auth)
+			}
+		})
+	}
+}
+
+func TestLLMStream_APIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"type":"error","error":{"type":"authentication_error","message":"invalid x-api-key"}}`))
+	}))
+	defer server.Close()
+
+	ep := llm.Endpoint{Provider: "anthropic", URL: server.URL, Model: "m1"}
+	err := llm.Stream(context.Background(), ep, "q", &strings.Builder{})
+	if err == nil || !strings.Contains(err.Error(), "invalid x-api-key") {
+		t.Errorf("Expected the API error message, got %v", err)
+	}
+}
\ No newline at end of file
@@ -0,0 +1,60 @@
+package unit_test
+
+import (
+	"clipcat/internal/picker"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestPickerFilter(t *testing.T) {
+	items := []string{"docs/main.md", "cmd/clipcat/main.go", "pkg/output/markdown.go", "internal/maintenance/x.go", "README.md"}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", items},
+		{"main.go", []string{"cmd/clipcat/main.go", "internal/maintenance/x.go"}},
+		// The file name beats a scattered match across directories
+		{"main", []string{"docs/main.md", "cmd/clipcat/main.go", "internal/maintenance/x.go"}},
+		{"pkgout", []string{"pkg/output/markdown.go"}},
+		{"zzz", nil},
+		// Upper case makes the query case-sensitive
+		{"readme", []string{"README.md"}},
+		{"Readme", nil},
+	}
+	for _, tt := range tests {
+		if got := picker.Filter(tt.query, items); !slices.Equal(got, tt.want) {
+			t.Errorf("Filter(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestPickerRun(t *testing.T) {
+	items := []string{"a.go", "b.go", "c.go", "notes.md"}
+
+	tests := []struct {
+		keys string
+		want []string
+		err  error
+	}{
+		{"\r", []string{"a.go"}, nil},             // the entry under the cursor
+		{"\x1b[B\x1b[B\r", []string{"c.go"}, nil}, // Down twice
+		{"\t\t\r", []string{"a.go", "b.go"}, nil}, // Tab selects and moves on
+		{"md\r", []string{"notes.md"}, nil},
+		{"x\x7fnotes\r", []string{"notes.md"}, nil}, // Backspace
+		{".go\x01\r", []string{"a.go", "b.go", "c.go"}, nil},
+		{"zz\r", nil, picker.ErrCanceled},
+		{"\x1b", nil, picker.ErrCanceled},
+		{"\x03", nil, picker.ErrCanceled},
+	}
+	for _, tt := range tests {
+		got, err := picker.Run(strings.NewReader(tt.keys), io.Discard, items, 80, 10)
+		if !errors.Is(err, tt.err) || !slices.Equal(got, tt.want) {
+			t.Errorf("Run(%q) = %v, %v; want %v, %v", tt.keys, got, err, tt.want, tt.err)
+		}
+	}
+}
\ No newline at end of file
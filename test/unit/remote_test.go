@@ -0,0 +1,147 @@
+package unit_test
+
+import (
+	"clipcat/pkg/remote"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIsURL(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected bool
+	}{
+		{"https://example.com/spec.md", true},
+		{"http://localhost:8080/raw", true},
+		{"src/main.go", false},
+		{"https-notes.txt", false},
+		{"ftp://example.com/file", false},
+	}
+
+	for _, tt := range tests {
+		if got := remote.IsURL(tt.path); got != tt.expected {
+			t.Errorf("IsURL(%q) = %v, want %v", tt.path, got, tt.expected)
+		}
+	}
+}
+
+func TestFetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/spec.md":
+			w.Write([]byte("# Spec\nbody"))
+		case "/slow":
+			time.Sleep(200 * time.Millisecond)
+			w.Write([]byte("late"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	t.Run("full_body", func(t *testing.T) {
+		data, truncated, err := remote.Fetch(context.Background(), server.URL+"/spec.md", time.Second, 0)
+		if err != nil {
+			t.Fatalf("Fetch failed: %v", err)
+		}
+		if truncated || string(data) != "# Spec\nbody" {
+			t.Errorf("Unexpected result: %q (truncated=%v)", data, truncated)
+		}
+	})
+
+	t.Run("size_cap", func(t *testing.T) {
+		data, truncated, err := remote.Fetch(context.Background(), server.URL+"/spec.md", time.Second, 6)
+		if err != nil {
+			t.Fatalf("Fetch failed: %v", err)
+		}
+		if !truncated || string(data) != "# Spec" {
+			t.Errorf("Expected body capped at 6 bytes, got %q (truncated=%v)", data, truncated)
+		}
+	})
+
+	t.Run("not_found", func(t *testing.T) {
+		_, _, err := remote.Fetch(context.Background(), server.URL+"/missing", time.Second, 0)
+		if err == nil || !strings.Contains(err.Error(), "404") {
+			t.Errorf("Expected 404 error, got %v", err)
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		_, _, err := remote.Fetch(context.Background(), server.URL+"/slow", 50*time.Millisecond, 0)
+		if err == nil {
+			t.Error("Expected timeout error")
+		}
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		_, _, err := remote.Fetch(ctx, server.URL+"/slow", time.Second, 0)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected the context deadline to stop the fetch, got %v", err)
+		}
+	})
+}
+
+func TestParseGitHubSpec(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected remote.GitHubSpec
+		cloneURL string
+		label    string
+	}{
+		{
+			input:    "golang/go",
+			expected: remote.GitHubSpec{Owner: "golang", Repo: "go"},
+			cloneURL: "https://github.com/golang/go.git",
+			label:    "golang/go",
+		},
+		{
+			input:    "golang/go/src/net/http@go1.22.0",
+			expected: remote.GitHubSpec{Owner: "golang", Repo: "go", Path: "src/net/http", Ref: "go1.22.0"},
+			cloneURL: "https://github.com/golang/go.git",
+			label:    "golang/go@go1.22.0",
+		},
+		{
+			input:    "owner/repo.git@3f2a9c1",
+			expected: remote.GitHubSpec{Owner: "owner", Repo: "repo", Ref: "3f2a9c1"},
+			cloneURL: "https://github.com/owner/repo.git",
+			label:    "owner/repo@3f2a9c1",
+		},
+		{
+			input:    "gist:aa5a315d61ae9438b18d",
+			expected: remote.GitHubSpec{Gist: "aa5a315d61ae9438b18d"},
+			cloneURL: "https://gist.github.com/aa5a315d61ae9438b18d.git",
+			label:    "gist:aa5a315d61ae9438b18d",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			spec, err := remote.ParseGitHubSpec(tt.input)
+			if err != nil {
+				t.Fatalf("ParseGitHubSpec(%q) failed: %v", tt.input, err)
+			}
+			if *spec != tt.expected {
+				t.Errorf("got %+v, want %+v", *spec, tt.expected)
+			}
+			if spec.CloneURL() != tt.cloneURL {
+				t.Errorf("CloneURL() = %q, want %q", spec.CloneURL(), tt.cloneURL)
+			}
+			if spec.String() != tt.label {
+				t.Errorf("String() = %q, want %q", spec.String(), tt.label)
+			}
+		})
+	}
+
+	for _, bad := range []string{"golang", "/repo", "owner/repo@", "gist:"} {
+		if _, err := remote.ParseGitHubSpec(bad); err == nil {
+			t.Errorf("Expected error for spec %q", bad)
+		}
+	}
+}
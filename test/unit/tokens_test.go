@@ -0,0 +1,130 @@
+package unit_test
+
+import (
+	"clipcat/pkg/tokens"
+	"strings"
+	"testing"
+)
+
+func TestTokensEstimate(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		min, max int
+	}{
+		{"empty", "", 0, 0},
+		{"sentence", "Hello world, this is a test.", 7, 9},
+		{"digits", "1234567", 3, 3},
+		{"cjk", "你好世界", 4, 4},
+		{"indented code", "func main() {\n\tfmt.Println(\"hi\")\n}\n", 10, 16},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tokens.Estimate([]byte(tt.text)); got < tt.min || got > tt.max {
+				t.Errorf("Estimate(%q) = %d, want %d..%d", tt.text, got, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+// The cl100k_base counts below were measured with tiktoken.
+func TestTokensEstimate_ReferenceCounts(t *testing.T) {
+	reference := []struct {
+		text  string
+		count int
+	}{
+		{"hello world", 2},
+		{"hallo world!", 4},
+		{"Hallo Welt!", 3},
+		{"Bonjour le monde!", 4},
+		{"Ciao mondo!", 4},
+		{"Hej världen!", 7},
+		{"¡Hola mundo!", 4},
+		{"2 + 2 = 4", 7},
+		{"tiktoken is great!", 6},
+		{"antidisestablishmentarianism", 6},
+		{"Привет мир!", 6},
+		{"你好世界！", 6},
+		{"こんにちは世界！", 5},
+		{"안녕하세요 세계!", 10},
+		{"hello world!你好，世界！", 10},
+	}
+
+	var got, want int
+	for _, r := range reference {
+		got += tokens.Estimate([]byte(r.text))
+		want += r.count
+	}
+	// Short and non-Latin texts are the worst case; --model promises no better than this
+	if diff := float64(got-want) / float64(want); diff < -0.2 || diff > 0.2 {
+		t.Errorf("Estimate totals %d tokens, want within 20%% of tiktoken's %d", got, want)
+	}
+}
+
+// The estimates for a code sample are pinned so that changes to the
+// heuristic show up in review.
+func TestTokensForModel_CodeSample(t *testing.T) {
+	code := []byte(`package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// main prints its arguments, one per line.
+func main() {
+	for i, arg := range os.Args[1:] {
+		fmt.Printf("%d: %s\n", i+1, arg)
+	}
+}
+`)
+	for model, want := range map[string]int{"gpt-4": 70, "gpt-4o": 65, "claude-3.5": 81, "bytes": 43} {
+		count, err := tokens.ForModel(model)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := count(code); got != want {
+			t.Errorf("%s: %d tokens, want %d", model, got, want)
+		}
+	}
+}
+
+func TestTokensEstimate_CodeDenserThanBytes(t *testing.T) {
+	code := []byte(strings.Repeat("\tif err != nil {\n\t\treturn fmt.Errorf(\"x: %w\", err)\n\t}\n", 50))
+	if est, flat := tokens.Estimate(code), tokens.Bytes(code); est <= flat {
+		t.Errorf("Estimate = %d, want more than the bytes/4 estimate %d for code", est, flat)
+	}
+}
+
+func TestTokensForModel(t *testing.T) {
+	data := []byte(strings.Repeat("The quick brown fox jumps over the lazy dog. ", 20))
+
+	def, err := tokens.ForModel("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := def(data), tokens.Estimate(data); got != want {
+		t.Errorf("default model = %d, want the cl100k estimate %d", got, want)
+	}
+
+	claude, err := tokens.ForModel("Claude-3.5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claude(data) <= def(data) {
+		t.Errorf("claude-3.5 = %d, want more than gpt-4's %d", claude(data), def(data))
+	}
+
+	bytes, err := tokens.ForModel("bytes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := bytes(data), (len(data)+3)/4; got != want {
+		t.Errorf("bytes model = %d, want %d", got, want)
+	}
+
+	if _, err := tokens.ForModel("gpt-99"); err == nil || !strings.Contains(err.Error(), "gpt-4o") {
+		t.Errorf("ForModel(gpt-99) error = %v, want unknown model listing known names", err)
+	}
+}
\ No newline at end of file
@@ -0,0 +1,249 @@
+package unit_test
+
+import (
+	"bytes"
+	"clipcat/pkg/output"
+	"clipcat/pkg/unpack"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestUnpackParse_RoundTrip(t *testing.T) {
+	files := []unpack.File{
+		{Path: "/home/dev/proj/main.go", Content: []byte("package main\n")},
+		{Path: "/home/dev/proj/pkg/util.go", Content: []byte("no trailing newline")},
+		{Path: "/home/dev/proj/empty.txt", Content: []byte("")},
+		{Path: "/home/dev/proj/notes.md", Content: []byte("====\nnot a header\n\n")},
+	}
+
+	// Build a bundle the same way clipcat.Run does, including a tree section
+	var buf bytes.Buffer
+	output.WriteHeader(&buf, "SUMMARY")
+	buf.WriteString("files:     4\n\n")
+	output.WriteHeader(&buf, "FILE HIERARCHY")
+	buf.WriteString("proj/\n-main.go\n\n")
+	output.WriteHeader(&buf, "FILE LISTING")
+	output.WriteListing(&buf, []output.Entry{{Path: "/home/dev/proj/main.go", Mode: 0644, Size: 13}})
+	buf.WriteString("\n")
+	output.WriteHeader(&buf, output.GroupLabel("proj/"))
+	for _, f := range files {
+		output.WriteHeader(&buf, f.Path)
+		buf.Write(f.Content)
+		buf.WriteString("\n")
+	}
+	// A later run appended with --append-file
+	output.WriteHeader(&buf, output.RunLabel("2026-01-02T03:04:05Z"))
+	output.WriteHeader(&buf, "/home/dev/proj/secret.key")
+	buf.WriteString("[unreadable]\n\n")
+	output.WriteHeader(&buf, "/home/dev/proj/logo.png")
+	buf.WriteString("[image: logo.png 32x32 2KB]\n\n")
+	output.WriteHeader(&buf, output.DiffLabel("/home/dev/proj/main.go", "main"))
+	buf.WriteString("diff --git a/main.go b/main.go\n\n")
+	output.WriteHeader(&buf, "https://example.com/spec.md")
+	buf.WriteString("remote\n\n")
+
+	parsed := unpack.Parse(buf.Bytes())
+	if len(parsed) != len(files) {
+		t.Fatalf("Expected %d files, got %d: %+v", len(files), len(parsed), parsed)
+	}
+	for i, f := range files {
+		if parsed[i].Path != f.Path {
+			t.Errorf("File %d: path %q, want %q", i, parsed[i].Path, f.Path)
+		}
+		if !bytes.Equal(parsed[i].Content, f.Content) {
+			t.Errorf("File %d: content %q, want %q", i, parsed[i].Content, f.Content)
+		}
+	}
+}
+
+func TestUnpackRelativize(t *testing.T) {
+	rels, err := unpack.Relativize([]unpack.File{
+		{Path: "/home/dev/proj/main.go"},
+		{Path: "/home/dev/proj/pkg/util.go"},
+		{Path: "C:\\Users\\dev\\proj\\go.mod"},
+		{Path: "owner/repo@v1/README.md"},
+	})
+	if err != nil {
+		t.Fatalf("Relativize failed: %v", err)
+	}
+
+	// The Windows path shares no directory with the others, so the common
+	// root collapses to "/"
+	expected := []string{"home/dev/proj/main.go", "home/dev/proj/pkg/util.go", "Users/dev/proj/go.mod", "owner/repo@v1/README.md"}
+	for i, want := range expected {
+		if rels[i] != filepath.FromSlash(want) {
+			t.Errorf("rels[%d] = %q, want %q", i, rels[i], want)
+		}
+	}
+
+	rels, _ = unpack.Relativize([]unpack.File{{Path: "/a/b/c.go"}, {Path: "/a/b/d/e.go"}})
+	if rels[0] != "c.go" || rels[1] != filepath.FromSlash("d/e.go") {
+		t.Errorf("Expected paths relative to /a/b, got %v", rels)
+	}
+
+	if _, err := unpack.Relativize([]unpack.File{{Path: "../../etc/passwd"}}); err == nil {
+		t.Error("Expected paths escaping the destination to be rejected")
+	}
+}
+
+func TestUnpackParse_MetaHeaders(t *testing.T) {
+	var buf bytes.Buffer
+	output.WriteHeaderMeta(&buf, "/p/a.go", "commit 3f2a9c1, Jane Doe, 2024-05-01")
+	buf.WriteString("package a\n\n")
+	output.WriteHeaderMeta(&buf, "/p/a-much-longer-file-name-than-meta.go", "commit 1, X, 2024")
+	buf.WriteString("package b\n\n")
+	output.WriteHeaderMeta(&buf, "/p/plain.go", "")
+	buf.WriteString("package c\n\n")
+
+	parsed := unpack.Parse(buf.Bytes())
+	if len(parsed) != 3 {
+		t.Fatalf("Expected 3 files, got %d: %+v", len(parsed), parsed)
+	}
+	for i, want := range []string{"package a\n", "package b\n", "package c\n"} {
+		if string(parsed[i].Content) != want {
+			t.Errorf("File %d content %q, want %q", i, parsed[i].Content, want)
+		}
+	}
+	if parsed[1].Path != "/p/a-much-longer-file-name-than-meta.go" {
+		t.Errorf("Unexpected path %q", parsed[1].Path)
+	}
+}
+
+
+func TestUnpackParse_Formats(t *testing.T) {
+	files := []output.File{
+		{Path: "/home/dev/proj/main.go", Content: []byte("package main\n\n// a <b> & \"c\"\n"), ID: "F1"},
+		{Path: "/home/dev/proj/README.md", Content: []byte("# proj\n\n```go\nx := 1\n```\n"), Meta: "commit 3f2a9c1", ID: "F2"},
+		{Path: "/home/dev/proj/empty.txt", Content: []byte(""), ID: "F3"},
+	}
+	want := []string{"/home/dev/proj/main.go", "/home/dev/proj/README.md", "/home/dev/proj/empty.txt"}
+	skipped := []output.File{
+		{Path: "/home/dev/proj/secret.key", Unreadable: true, Error: "permission denied"},
+		{Path: "/home/dev/proj/gone.go", Removed: true},
+		{Path: "/home/dev/proj/main.go", DiffRef: "main", Content: []byte("@@ -1 +1 @@\n-x\n+y\n")},
+		{Path: "https://example.com/spec.md", Content: []byte("remote\n")},
+	}
+
+	for _, format := range []string{"plain", "markdown", "xml", "json", "repomix", "llms-full"} {
+		f, err := output.NewFormatter(format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		f.BeginDocument(&buf)
+		f.WriteTree(&buf, []string{"/home/dev/proj"}, want)
+		for _, file := range append(files, skipped...) {
+			f.WriteFile(&buf, file)
+		}
+		f.EndDocument(&buf)
+
+		parsed := unpack.Parse(buf.Bytes())
+		var got []string
+		for _, p := range parsed {
+			got = append(got, p.Path)
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("%s: parsed paths %q, want %q\n%s", format, got, want, buf.String())
+			continue
+		}
+		for i, file := range files {
+			if !bytes.Equal(parsed[i].Content, file.Content) {
+				t.Errorf("%s: %s content %q, want %q", format, file.Path, parsed[i].Content, file.Content)
+			}
+		}
+	}
+}
+
+func TestUnpackParse_AppendedJSON(t *testing.T) {
+	bundle := `{"files": [{"path": "a.go", "content": "package a\n"}]}
+{"files": [{"path": "b.go", "content": "package b\n"}, {"path": "c.go", "unreadable": true, "content": ""}]}
+`
+	parsed := unpack.Parse([]byte(bundle))
+	if len(parsed) != 2 || parsed[0].Path != "a.go" || parsed[1].Path != "b.go" || string(parsed[1].Content) != "package b\n" {
+		t.Errorf("Unexpected files: %+v", parsed)
+	}
+}
+
+func TestUnpackCompare(t *testing.T) {
+	old := []unpack.File{
+		{Path: "same.go", Content: []byte("package a\n")},
+		{Path: "edit.go", Content: []byte("1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n")},
+		{Path: "gone.go", Content: []byte("x\ny\n")},
+	}
+	new := []unpack.File{
+		{Path: "new.go", Content: []byte("fresh")},
+		{Path: "same.go", Content: []byte("package a\n")},
+		{Path: "edit.go", Content: []byte("1\n2\nthree\n4\n5\n6\n7\n8\n9\n10\n11\n")},
+	}
+
+	changes, unchanged := unpack.Compare(old, new)
+	if unchanged != 1 {
+		t.Errorf("Expected 1 unchanged file, got %d", unchanged)
+	}
+	var got []string
+	for _, c := range changes {
+		got = append(got, fmt.Sprintf("%s %s +%d -%d", c.Kind, c.Path, c.Added, c.Removed))
+	}
+	want := []string{"added new.go +1 -0", "changed edit.go +2 -1", "removed gone.go +0 -2"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("Changes %q, want %q", got, want)
+	}
+
+	// The two edits are more than twice the context apart, so two hunks
+	wantDiff := "--- a/edit.go\n+++ b/edit.go\n" +
+		"@@ -1,5 +1,5 @@\n 1\n 2\n-3\n+three\n 4\n 5\n" +
+		"@@ -9,2 +9,3 @@\n 9\n 10\n+11\n"
+	if diff := string(changes[1].Unified(2)); diff != wantDiff {
+		t.Errorf("Unified diff:\n%s\nwant:\n%s", diff, wantDiff)
+	}
+	wantAdded := "--- /dev/null\n+++ b/new.go\n@@ -0,0 +1 @@\n+fresh\n\\ No newline at end of file\n"
+	if diff := string(changes[0].Unified(3)); diff != wantAdded {
+		t.Errorf("Unified diff:\n%s\nwant:\n%s", diff, wantAdded)
+	}
+}
+
+func TestUnpackDecompress(t *testing.T) {
+	bundle := []byte("====\nmain.go\n====\n\npackage main\n\n")
+	tests := map[string]struct {
+		data []byte
+		want []byte
+	}{
+		"plain": {bundle, bundle},
+	}
+	for _, method := range unpack.Compressions {
+		raw, err := unpack.Compress(bundle, method, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		text, err := unpack.Compress(bundle, method, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Tickets and chats wrap long lines
+		var wrapped []byte
+		for len(text) > 60 {
+			wrapped = append(append(wrapped, text[:60]...), '\n')
+			text = text[60:]
+		}
+		wrapped = append(wrapped, text...)
+
+		tests[method] = struct{ data, want []byte }{raw, bundle}
+		tests[method+" base64"] = struct{ data, want []byte }{append([]byte("  "), wrapped...), bundle}
+		tests[method+" appended"] = struct{ data, want []byte }{append(slices.Clone(raw), raw...), append(slices.Clone(bundle), bundle...)}
+	}
+	for name, tt := range tests {
+		got, err := unpack.Decompress(tt.data)
+		if err != nil || !bytes.Equal(got, tt.want) {
+			t.Errorf("%s: Decompress() = %q, %v; want %q", name, got, err, tt.want)
+		}
+	}
+
+	if _, err := unpack.Decompress([]byte{0x28, 0xb5, 0x2f, 0xfd, 0}); err == nil {
+		t.Errorf("Expected an error for a corrupt zstd bundle")
+	}
+	if _, err := unpack.Compress(bundle, "brotli", false); err == nil {
+		t.Errorf("Expected an error for an unknown compression")
+	}
+}
\ No newline at end of file
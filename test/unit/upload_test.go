@@ -0,0 +1,103 @@
+package unit_test
+
+import (
+	"clipcat/internal/upload"
+	"clipcat/pkg/unpack"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUpload_CustomURL(t *testing.T) {
+	var received, contentType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("Expected POST, got %s", r.Method)
+		}
+		body, _ := io.ReadAll(r.Body)
+		received, contentType = string(body), r.Header.Get("Content-Type")
+		switch r.URL.Path {
+		case "/location":
+			w.Header().Set("Location", "https://paste.example/loc")
+			w.WriteHeader(http.StatusCreated)
+		case "/fail":
+			http.Error(w, "quota exceeded", http.StatusTooManyRequests)
+		default:
+			w.Write([]byte("https://paste.example/abc123\n"))
+		}
+	}))
+	defer server.Close()
+
+	url, err := upload.Upload(context.Background(), server.URL+"/", []byte("bundle contents"))
+	if err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+	if url != "https://paste.example/abc123" {
+		t.Errorf("Expected URL from response body, got %q", url)
+	}
+	if received != "bundle contents" {
+		t.Errorf("Server received %q", received)
+	}
+
+	url, err = upload.Upload(context.Background(), server.URL+"/location", []byte("x"))
+	if err != nil || url != "https://paste.example/loc" {
+		t.Errorf("Expected Location header URL, got %q (err %v)", url, err)
+	}
+
+	// --compress uploads are labelled as such
+	for method, want := range map[string]string{unpack.Gzip: "application/gzip", unpack.Zstd: "application/zstd"} {
+		data, _ := unpack.Compress([]byte("bundle contents"), method, false)
+		if _, err = upload.Upload(context.Background(), server.URL+"/", data); err != nil || contentType != want {
+			t.Errorf("Expected a %s upload, got Content-Type %q (err %v)", method, contentType, err)
+		}
+	}
+	if !upload.Binary(server.URL+"/") || upload.Binary("gist") || upload.Binary("paste.rs") {
+		t.Errorf("Expected only gist and paste.rs to need text")
+	}
+
+	_, err = upload.Upload(context.Background(), server.URL+"/fail", []byte("x"))
+	if err == nil || !strings.Contains(err.Error(), "quota exceeded") {
+		t.Errorf("Expected server error to be surfaced, got %v", err)
+	}
+}
+
+func TestUpload_Canceled(t *testing.T) {
+	// A paste service that never answers
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := upload.Upload(ctx, server.URL+"/", []byte("x"))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the deadline to stop the upload, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Upload took %v after the deadline", elapsed)
+	}
+}
+
+func TestUpload_InvalidTargets(t *testing.T) {
+	if _, err := upload.Upload(context.Background(), "pastebin", []byte("x")); err == nil {
+		t.Error("Expected error for unknown target")
+	}
+
+	t.Setenv("GITHUB_TOKEN", "")
+	t.Setenv("GH_TOKEN", "")
+	if _, err := upload.Upload(context.Background(), "gist", []byte("x")); err == nil || !strings.Contains(err.Error(), "GITHUB_TOKEN") {
+		t.Errorf("Expected missing token error for gist, got %v", err)
+	}
+}
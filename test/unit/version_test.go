@@ -0,0 +1,40 @@
+package unit_test
+
+import (
+	"clipcat/pkg/clipcat"
+	"encoding/json"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestGetVersionInfo(t *testing.T) {
+	oldVersion, oldCommit, oldDate := clipcat.Version, clipcat.Commit, clipcat.Date
+	defer func() { clipcat.Version, clipcat.Commit, clipcat.Date = oldVersion, oldCommit, oldDate }()
+
+	clipcat.Version, clipcat.Commit, clipcat.Date = "v1.2.3", "abc123", "2024-05-01T00:00:00Z"
+	info := clipcat.GetVersionInfo()
+	if info.Version != "v1.2.3" || info.Commit != "abc123" || info.Date != "2024-05-01T00:00:00Z" {
+		t.Errorf("Injected metadata not reported: %+v", info)
+	}
+	if info.GoVersion != runtime.Version() || !strings.Contains(info.Platform, "/") {
+		t.Errorf("Unexpected runtime details: %+v", info)
+	}
+
+	data, err := json.Marshal(info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{`"version"`, `"commit"`, `"date"`, `"go_version"`, `"platform"`} {
+		if !strings.Contains(string(data), key) {
+			t.Errorf("Expected key %s in JSON %s", key, data)
+		}
+	}
+
+	// Without ldflags, commit and date are never left empty
+	clipcat.Commit, clipcat.Date = "", ""
+	info = clipcat.GetVersionInfo()
+	if info.Commit == "" || info.Date == "" {
+		t.Errorf("Expected fallback commit/date, got %+v", info)
+	}
+}